@@ -1,18 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
+	"net"
 	"os"
 	"runtime"
 	"unsafe"
 
-	"game-engine/rts/internal/camera"
-	"game-engine/rts/internal/gameobject"
-	"game-engine/rts/internal/mesh"
+	"game-engine/rts/internal/formation"
+	"game-engine/rts/internal/lockstep"
+	"game-engine/rts/internal/navmesh"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/scene"
 	"game-engine/rts/internal/shader"
-	"game-engine/rts/internal/texture"
+	"game-engine/rts/internal/sim"
 
 	"github.com/go-gl/gl/v4.6-core/gl"
 	"github.com/go-gl/glfw/v3.2/glfw"
@@ -27,186 +30,127 @@ const (
 var (
 	shaders         = []*shader.Shader{}
 	drawMode uint32 = gl.FILL
-)
-
-type State interface {
-	OnEnter()
-	OnUpdate(dt float32)
-	OnLeave()
-}
-
-type EmptyState struct{}
-
-func (s *EmptyState) OnEnter()            {}
-func (s *EmptyState) OnUpdate(dt float32) {}
-func (s *EmptyState) OnLeave()            {}
-
-type BasicState struct {
-	name        string
-	timeInState float32
-}
-
-func (s *BasicState) OnEnter() {
-	fmt.Printf("Entering state %q\n", s.name)
-}
-func (s *BasicState) OnUpdate(dt float32) {
-	s.timeInState += dt
-	fmt.Printf("In state %s for %v\n", s.name, s.timeInState)
-}
-func (s *BasicState) OnLeave() {
-	fmt.Printf("Leaving state %q\n", s.name)
-}
-func NewBasicState(name string) BasicState {
-	return BasicState{
-		name: name,
-	}
-}
-
-type Worker struct {
-	fsm              FSM
-	idleState        WorkerIdleState
-	choppingState    WorkerChoppingState
-	treePickingState WorkerTreePickingState
-	walkState        WorkerWalkState
 
-	trees         *[]*gameobject.SolidGameObject
-	gameObject    *gameobject.SolidGameObject
-	currentTarget *gameobject.SolidGameObject
-}
-
-func (w *Worker) Idle() {
-	w.fsm.ChangeState(&w.idleState)
-}
-func (w *Worker) Walk() {
-	w.fsm.ChangeState(&w.walkState)
-}
-func (w *Worker) Chop() {
-	w.fsm.ChangeState(&w.choppingState)
-}
-func (w *Worker) PickTree() {
-	w.fsm.ChangeState(&w.treePickingState)
-}
-
-type WorkerIdleState struct {
-	worker             *Worker
-	timeSinceLastPrint float32
-}
-
-func (s *WorkerIdleState) OnUpdate(dt float32) {
-	s.timeSinceLastPrint += dt
-	if s.timeSinceLastPrint > 1.0 {
-		s.timeSinceLastPrint = 0.0
-		fmt.Printf("Worker is chillin\n")
-	}
-}
-func (s *WorkerIdleState) OnEnter() {
-	s.timeSinceLastPrint = 0.0
-	fmt.Printf("Entering idle state\n")
-}
-func (s *WorkerIdleState) OnLeave() { fmt.Printf("Leaving idle state\n") }
-
-var treeChoppingDuration float32 = 10.0
+	brain     = flag.String("brain", "fsm", "what controls the workers, fsm (state machine), bt (behaviour tree) or goap (planner)")
+	workers   = flag.Int("workers", 5, "number of workers")
+	avoidance = flag.String("avoidance", "orca", "how workers avoid each other, orca or separation")
+	useMesh   = flag.Bool("navmesh", false, "find paths over a navigation mesh instead of the grid")
+	meshOBJ   = flag.String("navmesh-obj", "", "write the navigation mesh to this OBJ file, to look at it")
+	heights   = flag.String("terrain", "", "hills, or a grayscale PNG heightmap, for ground that isn't flat")
+	seed      = flag.Int64("seed", 1, "seed of the generated map")
+	record    = flag.String("record", "", "record the game to this replay file")
+	replay    = flag.String("replay", "", "play back this replay file, checking that it plays out the same")
+	host      = flag.String("host", "", "host a match on this address, like :7000, for other players to join")
+	players   = flag.Int("players", 2, "number of players in a hosted match")
+	join      = flag.String("join", "", "join the match hosted on this address")
+	load      = flag.String("load", "", "continue the game saved in this file")
+	quicksave = flag.String("save", "quicksave.json", "file F5 saves the game to")
+	sceneFile = flag.String("scene", "resources/scenes/default.yaml", "scene file of what the game is drawn with")
+)
 
-type WorkerChoppingState struct {
-	worker             *Worker
-	timeSinceLastPrint float32
-	timeSpentChopping  float32
-}
+//nolint:funlen,gocognit,gocyclo,maintidx // foo
+func main() {
+	flag.Parse()
+	runtime.LockOSThread()
 
-func (s *WorkerChoppingState) OnUpdate(dt float32) {
-	s.timeSinceLastPrint += dt
-	if s.timeSinceLastPrint > 1.0 {
-		s.timeSinceLastPrint = 0
-		fmt.Printf("Chopping tree: %.1f/%.1f\n", s.timeSpentChopping, treeChoppingDuration)
+	if *load != "" && (*host != "" || *join != "") {
+		log.Fatal("saved games can't be continued as a match")
+	}
+	if *replay != "" && (*host != "" || *join != "") {
+		log.Fatal("replays can't be played back as a match")
+	}
+
+	// In a match every player runs the same game in lockstep, so the host tells the others how the game is made
+	config := sim.Config{
+		Seed:      *seed,
+		Terrain:   *heights,
+		Workers:   *workers,
+		Brain:     *brain,
+		Avoidance: *avoidance,
+		NavMesh:   *useMesh || *meshOBJ != "",
+	}
+	var transport *lockstep.TCP
+	var err error
+	switch {
+	case *host != "":
+		listener, err := net.Listen("tcp", *host)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Waiting for %d players on %s\n", *players-1, listener.Addr())
+		config.Players = *players
+		transport, err = lockstep.Host(listener, config)
+		listener.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	case *join != "":
+		transport, err = lockstep.Join(*join)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config = transport.Game()
+		fmt.Printf("Joined %s as player %d, playing seed %d\n", *join, transport.Player(), config.Seed)
+	}
+
+	// The game is simulated at a fixed rate, however fast frames are drawn
+	var game *sim.Game
+	var recorded *sim.Replay
+	var session *lockstep.Session
+	if *replay != "" {
+		recorded, err = sim.LoadReplay(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game, err = sim.NewReplayGame(recorded)
+	} else if *load != "" {
+		var saved *sim.Save
+		saved, err = sim.ReadSaveFile(*load)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game, err = sim.LoadGame(saved)
+	} else {
+		game, err = sim.NewGame(config)
 	}
-
-	s.timeSpentChopping += dt
-	if s.timeSpentChopping >= treeChoppingDuration {
-		fmt.Printf("Timber!\n")
-		*s.worker.trees, _ = chopTree(s.worker.currentTarget, *s.worker.trees)
-		s.worker.PickTree()
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-func (s *WorkerChoppingState) OnEnter() {
-	s.timeSpentChopping = 0
-	s.timeSinceLastPrint = 0
-	fmt.Printf("Entering chopping state\n")
-}
-func (s *WorkerChoppingState) OnLeave() { fmt.Printf("Leaving chopping state\n") }
-
-type WorkerTreePickingState struct {
-	worker *Worker
-}
-
-func (s *WorkerTreePickingState) OnUpdate(dt float32) {
-	targetIndex, exists := findClosestTree(*s.worker.trees, s.worker.gameObject)
-	if !exists {
-		fmt.Printf("No more trees\n")
-		s.worker.Idle()
-		return
+	player := resource.Player(0)
+	if transport != nil {
+		session = lockstep.NewSession(game, transport, lockstep.Config{Players: transport.Players()})
+		defer session.Close()
+		player = session.Player()
+	}
+	if *record != "" {
+		recording := game.Record()
+		defer func() {
+			if err := sim.SaveReplay(*record, recording); err != nil {
+				log.Print(err)
+			}
+		}()
 	}
-	trees := *s.worker.trees
-	s.worker.currentTarget = trees[targetIndex]
-	s.worker.Walk()
-}
-func (s *WorkerTreePickingState) OnEnter() { fmt.Printf("Entering tree picking state\n") }
-func (s *WorkerTreePickingState) OnLeave() { fmt.Printf("Leaving tree picking state\n") }
-
-type WorkerWalkState struct {
-	worker             *Worker
-	timeSinceLastPrint float32
-}
-
-func (s *WorkerWalkState) OnUpdate(dt float32) {
-	target := s.worker.currentTarget
-	worker := s.worker.gameObject
-	moveDir := target.Position.Sub(worker.Position)
-	moveDir[1] = 0.0 // Don't touch the height
-	moveDir = moveDir.Normalize()
-	worker.Position = worker.Position.Add(moveDir.Mul(dt))
-	dist := worker.Position.Sub(target.Position)
-	dist[1] = 0.0
-	remainingDistance := dist.Len()
-	if remainingDistance < 0.05 {
-		s.worker.Chop()
-		return
+	if *meshOBJ != "" {
+		writeNavMesh(game.World.NavMesh(), *meshOBJ)
 	}
 
-	s.timeSinceLastPrint += dt
-	if s.timeSinceLastPrint > 1.0 {
-		s.timeSinceLastPrint = 0.0
-		fmt.Printf("Walking towards tree %v, remaining distance: %v\n", s.worker.currentTarget, remainingDistance)
+	level, err := scene.Load(*sceneFile)
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-func (s *WorkerWalkState) OnEnter() { fmt.Printf("Entering walking state\n") }
-func (s *WorkerWalkState) OnLeave() { fmt.Printf("Leaving walking state\n") }
-
-type FSM struct {
-	currentState State
-}
-
-func (fsm *FSM) Run(dt float32) {
-	fsm.currentState.OnUpdate(dt)
-}
-func (fsm *FSM) ChangeState(newState State) {
-	fsm.currentState.OnLeave()
-	fsm.currentState = newState
-	fsm.currentState.OnEnter()
-}
-func NewFSM() *FSM {
-	return &FSM{currentState: &EmptyState{}}
-}
-
-//nolint:funlen,gocognit,gocyclo,maintidx // foo
-func main() {
-	runtime.LockOSThread()
 
 	// Init GLFW/OpenGL
 	window, clean := initGlfw()
 	defer clean()
 	initOpenGL()
 
-	camera := camera.NewCamera(mgl32.Vec3{4.0, 4.0, 10.0}, windowWidth, windowHeight)
+	// The meshes, materials and objects drawn, and where the camera starts, come from the scene
+	drawn, err := level.Instantiate(game, windowWidth, windowHeight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	shaders = drawn.Shaders
+	camera := drawn.Camera
 
 	keyCallback := func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
 		switch key {
@@ -305,122 +249,81 @@ func main() {
 	window.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
 
 	setGlobalGLState()
-	wd, _ := os.Getwd()
-
-	///////////// XYZ Gizmo //////////////
-	xyzShader, err := shader.NewXYZGizmoShader()
-	if err != nil {
-		log.Fatal(err)
-	}
-	xyzMesh, err := mesh.FromFile(wd + "/resources/meshes/xyz-gizmo.obj")
-	if err != nil {
-		log.Fatal("error making thing", err)
-	}
-	xyz := &gameobject.XYZGizmo{
-		Position: mgl32.Vec3{0.0, 0.0, -1.0},
-		Scale:    mgl32.Vec3{1.0, 1.0, 1.0},
-		Mesh:     &xyzMesh,
-		Shader:   &xyzShader,
-	}
 
-	//////////// Light ///////////////
-
-	lampPos := mgl32.Vec3{10.0, 10.0, -10.0}
-	lampColor := mgl32.Vec3{1.0, 1.0, 1.0}
-
-	s, err := shader.NewSolidShader(mgl32.Vec3{0.2, 0.4, 0.2})
-	if err != nil {
-		log.Fatal(err)
+	// What the player does is given to the game as commands, applied at the start of the next tick. When
+	// playing back a replay the commands come from the replay instead, and in a match they are sent to the
+	// other players to be run a few turns later.
+	issue := func(c sim.Command) {
+		if recorded != nil {
+			return
+		}
+		if session != nil {
+			session.Issue(c)
+			return
+		}
+		c.Tick = game.Tick()
+		if err := game.Issue(c); err != nil {
+			fmt.Printf("Can't issue %s: %v\n", c.Type, err)
+		}
 	}
-	s.SetLightPos(lampPos)
-	s.SetLightColor(lampColor)
-
-	//////////// Ground //////////////
-
-	bevelCube, err := mesh.FromFile(wd + "/resources/meshes/bevel-cube2.obj")
-	if err != nil {
-		log.Fatal("error making thing", err)
+	all := []int{}
+	for i, w := range game.Workers {
+		if w.Player() == player {
+			all = append(all, i)
+		}
 	}
-
-	sizeX, sizeZ := 10, 10
-	land := make([][]*gameobject.SolidGameObject, sizeX)
-	for x := 0; x < sizeX; x++ {
-		land[x] = make([]*gameobject.SolidGameObject, sizeZ)
-		for z := 0; z < sizeZ; z++ {
-			land[x][z] = &gameobject.SolidGameObject{
-				Position: mgl32.Vec3{float32(x) * 2.0, 2.0, float32(z) * -2.0},
-				Scale:    mgl32.Vec3{1.0, 0.5, 1.0},
-				Mesh:     &bevelCube,
-				Shader:   &s,
+	stockpile := game.World.Stockpiles[player]
+	issue(sim.Command{Type: sim.Select, Units: all})
+
+	// P pauses the simulation, [ and ] slow it down and speed it up and F5 saves the game, unless playing a match
+	// 1 to 9 select a worker and 0 all of them, F gathers them next to the stockpile in the next formation, H
+	// sends them back to work and B builds a stockpile under the camera
+	shape := formation.Line
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		keyCallback(w, key, scancode, action, mods)
+		if action != glfw.Press {
+			return
+		}
+		if key >= glfw.Key0 && key <= glfw.Key9 {
+			selected := all
+			if i := int(key - glfw.Key1); key != glfw.Key0 && i < len(all) {
+				selected = []int{all[i]}
 			}
+			issue(sim.Command{Type: sim.Select, Units: selected})
+			return
 		}
-	}
-
-	/////////// Trees ///////////////
-
-	treeShader, err := shader.NewSolidShader(mgl32.Vec3{0.0, 1.0, 0.0})
-	if err != nil {
-		log.Fatal(err)
-	}
-	s.SetLightPos(lampPos)
-	s.SetLightColor(lampColor)
-
-	treeMesh, err := mesh.FromFile(wd + "/resources/meshes/cylinder.obj")
-	if err != nil {
-		log.Fatal("error loading tree mesh", err)
-	}
-	totalNrTrees := 500
-	trees := make([]*gameobject.SolidGameObject, totalNrTrees)
-	for i := 0; i < totalNrTrees; i++ {
-		x := rand.Float32()*9.5 - 0.25
-		z := rand.Float32()*9.5 - 0.25
-		trees[i] = &gameobject.SolidGameObject{
-			Position: mgl32.Vec3{x * 2.0, 3.0, z * -2.0},
-			Scale:    mgl32.Vec3{0.1, 0.5, 0.1},
-			Mesh:     &treeMesh,
-			Shader:   &treeShader,
+		if session != nil && (key == glfw.KeyP || key == glfw.KeyLeftBracket || key == glfw.KeyRightBracket || key == glfw.KeyF5) {
+			return
 		}
-	}
-
-	/////////// Worker ///////////////
-
-	workerShader, err := shader.NewSolidShader(mgl32.Vec3{0.75, 0.75, 0.75})
-	if err != nil {
-		log.Fatal(err)
-	}
-	workerShader.SetLightPos(lampPos)
-	workerShader.SetLightColor(lampColor)
-	workerMesh, err := mesh.FromFile(wd + "/resources/meshes/cube.obj")
-	if err != nil {
-		log.Fatal("error loading worker mesh", err)
-	}
-	worker := gameobject.SolidGameObject{
-		Position: mgl32.Vec3{0.0, 2.5, 0.0},
-		Scale:    mgl32.Vec3{0.2, 0.2, 0.2},
-		Mesh:     &workerMesh,
-		Shader:   &workerShader,
-	}
-	theAlmightyWorkerMan := &Worker{}
-	theAlmightyWorkerMan.fsm = *NewFSM()
-	theAlmightyWorkerMan.idleState = WorkerIdleState{worker: theAlmightyWorkerMan}
-	theAlmightyWorkerMan.choppingState = WorkerChoppingState{worker: theAlmightyWorkerMan}
-	theAlmightyWorkerMan.treePickingState = WorkerTreePickingState{worker: theAlmightyWorkerMan}
-	theAlmightyWorkerMan.walkState = WorkerWalkState{worker: theAlmightyWorkerMan}
-
-	theAlmightyWorkerMan.trees = &trees
-	theAlmightyWorkerMan.gameObject = &worker
-	theAlmightyWorkerMan.PickTree()
-	// theAlmightyWorkerMan.currentTarget = trees[0]
-	// closestTree, _ := findClosestTree(trees, &worker)
-
-	//////////////////////////
-
-	cube := makeCube()
-	grid := makeGrid()
-
-	// --------------------------------------------------------------------------------------------
+		switch key {
+		case glfw.KeyP:
+			game.Paused = !game.Paused
+		case glfw.KeyLeftBracket:
+			game.Scale /= 2
+			fmt.Printf("Simulation speed %gx\n", game.Scale)
+		case glfw.KeyRightBracket:
+			game.Scale *= 2
+			fmt.Printf("Simulation speed %gx\n", game.Scale)
+		case glfw.KeyF5:
+			if err := sim.WriteSaveFile(*quicksave, game.Save(), sim.JSON); err != nil {
+				fmt.Printf("Saving: %v\n", err)
+			} else {
+				fmt.Printf("Saved to %s\n", *quicksave)
+			}
+		case glfw.KeyF:
+			fmt.Printf("Workers gather in a %s\n", shape)
+			destination := stockpile.Position.Add(mgl32.Vec3{-2.0, 0.0, 2.0})
+			issue(sim.Command{Type: sim.Move, Target: destination, Formation: shape})
+			shape = (shape + 1) % (formation.Wedge + 1)
+		case glfw.KeyH:
+			issue(sim.Command{Type: sim.Gather})
+		case glfw.KeyB:
+			issue(sim.Command{Type: sim.Build, Target: camera.Position()})
+		}
+	})
 
-	previousTime := float32(glfw.GetTime())
+	previousTime := glfw.GetTime()
+	replayDone := false
 
 	// Game loop
 	for !window.ShouldClose() {
@@ -428,54 +331,33 @@ func main() {
 		gl.Viewport(0, 0, windowWidth, windowHeight)
 
 		// Calculate time since last frame
-		time := float32(glfw.GetTime())
-		dt := time - previousTime
+		time := glfw.GetTime()
+		elapsed := time - previousTime
 		previousTime = time
-
-		// Update resources
-		camera.Update(dt)
-		cube.Update(dt)
-		for x := 0; x < sizeX; x++ {
-			for z := 0; z < sizeZ; z++ {
-				land[x][z].Update(dt)
-				land[x][z].Render(camera)
-			}
+		dt := float32(elapsed)
+		if session == nil {
+			game.Advance(elapsed)
+		} else if err := session.Advance(elapsed); err != nil {
+			log.Fatal(err)
 		}
-		for i := 0; i < len(trees); i++ {
-			trees[i].Update(dt)
-			trees[i].Render(camera)
+		if recorded != nil && !replayDone && (game.Desync() != nil || game.Tick() >= uint64(recorded.Ticks())) {
+			replayDone = true
+			if err := game.Desync(); err != nil {
+				fmt.Printf("Replay: %v\n", err)
+			} else {
+				fmt.Printf("Replay played out the same for %d ticks\n", recorded.Ticks())
+			}
 		}
 
-		//////// worker //////////
-		// target := trees[closestTree]
-		// moveDir := target.Position.Sub(worker.Position)
-		// moveDir[1] = 0.0 // Don't touch the height
-		// moveDir = moveDir.Normalize()
-		// worker.Position = worker.Position.Add(moveDir.Mul(dt))
-		// dist := worker.Position.Sub(target.Position)
-		// dist[1] = 0.0
-		// d := dist.Len()
-		// if d < 0.05 {
-		// 	trees, totalNrTrees = chopTree(closestTree, trees)
-		// 	closestTree, _ = findClosestTree(trees, &worker)
-		// }
-		theAlmightyWorkerMan.fsm.Run(dt)
-
-		worker.Update(dt)
-		worker.Render(camera)
-		//////////////////////////
-
-		// Render resources
-		cube.Render(camera)
-
-		// Draw grid last for some reason? Why is this?
-		grid.Render(camera)
-
-		/////
+		// The land, units and objects are entities, the units drawn between where the last two ticks left them
+		camera.Update(dt)
+		if err := drawn.Update(dt); err != nil {
+			log.Fatal(err)
+		}
+		drawn.Render(camera)
 
 		gl.Clear(gl.DEPTH_BUFFER_BIT)
-		xyz.Update(dt)
-		xyz.Render(camera)
+		drawn.RenderOverlay(camera)
 
 		// Maintenance
 		window.SwapBuffers()
@@ -483,112 +365,6 @@ func main() {
 	}
 }
 
-func chopTree(tree *gameobject.SolidGameObject, trees []*gameobject.SolidGameObject) (remainingTrees []*gameobject.SolidGameObject, totalNrTrees int) {
-	nrTrees := len(trees)
-	index := 0
-	for i := 0; i < nrTrees; i++ {
-		if trees[i] == tree {
-			index = i
-			break
-		}
-	}
-	trees[index], trees[nrTrees-1] = trees[nrTrees-1], trees[index]
-	trees = trees[:nrTrees-1]
-	return trees, nrTrees - 1
-}
-
-func findClosestTree(trees []*gameobject.SolidGameObject, worker *gameobject.SolidGameObject) (treeIndex int, targetExists bool) {
-	nrTrees := len(trees)
-	if nrTrees == 0 {
-		return 0, false
-	}
-	closestTree := 0
-	closestSqLen := trees[closestTree].Position.Sub(worker.Position).LenSqr()
-	for i := 0; i < nrTrees; i++ {
-		sqLen := trees[i].Position.Sub(worker.Position).LenSqr()
-		if sqLen < closestSqLen {
-			closestTree = i
-			closestSqLen = sqLen
-		}
-	}
-	return closestTree, true
-}
-
-func makeThing() *gameobject.GameObject {
-	wd, _ := os.Getwd()
-	thing, err := mesh.FromFile(wd + "/resources/meshes/cube.obj")
-	if err != nil {
-		log.Fatal("error making thing", err)
-	}
-
-	shader, err := shader.NewGenericShader()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// texture, err := texture.New(wd + "/resources/textures/square.png")
-	// if err != nil {
-	// 	log.Fatal("error loading texture", err)
-	// }
-
-	obj := gameobject.NewBuilder().
-		Position(mgl32.Vec3{0.0, 3.0, 0.0}).
-		// Scale(mgl32.Vec3{0.05, 0.05, 0.05}).
-		Mesh(&thing).Shader(&shader). //Texture(&texture).
-		Build()
-
-	return obj
-}
-
-func makeCube() gameobject.GameObject {
-	cubeMesh := mesh.MakeCube()
-	// wd, _ := os.Getwd()
-	// cubeMesh, err := mesh.FromFile(wd + "/resources/mesh/cube.obj")
-
-	cubeShader, err := shader.NewBasicShader()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	wd, _ := os.Getwd()
-	squareTexture, err := texture.New(wd + "/resources/textures/square.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	cube := gameobject.GameObject{
-		Position: mgl32.Vec3{0.0, 0.0, 0.0},
-		Rotation: mgl32.Vec3{0.0, 0.0, 0.0},
-		Scale:    mgl32.Vec3{1.0, 1.0, 1.0},
-
-		Shader:  &cubeShader,
-		Texture: &squareTexture,
-		Mesh:    &cubeMesh,
-	}
-
-	return cube
-}
-
-func makeGrid() gameobject.GameObject {
-	gridMesh := mesh.MakeGrid()
-
-	gridShader, err := shader.NewGridShader()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	grid := gameobject.GameObject{
-		Position: mgl32.Vec3{0.0, 0.0, 0.0},
-		Rotation: mgl32.Vec3{0.0, 0.0, 0.0},
-		Scale:    mgl32.Vec3{1.0, 1.0, 1.0},
-
-		Shader: &gridShader,
-		Mesh:   &gridMesh,
-	}
-
-	return grid
-}
-
 func reloadShaders() {
 	fmt.Printf("Reloading shaders\n")
 
@@ -599,6 +375,21 @@ func reloadShaders() {
 	}
 }
 
+// writeNavMesh writes the polygons of a navigation mesh to an OBJ file, just above the ground workers walk on.
+func writeNavMesh(m *navmesh.NavMesh, name string) {
+	f, err := os.Create(name)
+	if err != nil {
+		fmt.Printf("Error writing navigation mesh: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if err := m.WriteOBJ(f, 2.05); err != nil {
+		fmt.Printf("Error writing navigation mesh: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote %d polygons to %s\n", len(m.Polygons()), name)
+}
+
 // initGlfw initializes glfw and returns a Window to use.
 func initGlfw() (*glfw.Window, func()) {
 	if err := glfw.Init(); err != nil {
@@ -0,0 +1,111 @@
+// Command sim runs the game without a window or GL context for a number of ticks, then prints how it went.
+// It is for testing the AI and the balance of the game on machines without a GPU.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"game-engine/rts/internal/sim"
+)
+
+var (
+	ticks     = flag.Int("ticks", 60*sim.DefaultRate, "number of ticks to simulate")
+	seed      = flag.Int64("seed", 1, "seed of the generated map")
+	workers   = flag.Int("workers", 5, "number of workers")
+	brain     = flag.String("brain", "fsm", "what controls the workers, fsm (state machine), bt (behaviour tree) or goap (planner)")
+	avoidance = flag.String("avoidance", "orca", "how workers avoid each other, orca or separation")
+	useMesh   = flag.Bool("navmesh", false, "find paths over a navigation mesh instead of the grid")
+	heights   = flag.String("terrain", "", "hills, or a grayscale PNG heightmap, for ground that isn't flat")
+	verbose   = flag.Bool("v", false, "print what the workers are doing")
+	record    = flag.String("record", "", "record the game to this replay file")
+	replay    = flag.String("replay", "", "play back this replay file instead, checking that it plays out the same")
+	load      = flag.String("load", "", "continue the game saved in this file instead")
+	save      = flag.String("save", "", "save the game to this file when done")
+	binary    = flag.Bool("binary", false, "save in the binary format instead of JSON")
+)
+
+func main() {
+	flag.Parse()
+	stdout := os.Stdout
+
+	// The workers print every change of state, which drowns out the stats
+	if !*verbose {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer devNull.Close()
+		os.Stdout = devNull
+	}
+
+	var game *sim.Game
+	var err error
+	if *replay != "" {
+		var recorded *sim.Replay
+		recorded, err = sim.LoadReplay(*replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*ticks = recorded.Ticks()
+		game, err = sim.NewReplayGame(recorded)
+	} else if *load != "" {
+		var saved *sim.Save
+		saved, err = sim.ReadSaveFile(*load)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game, err = sim.LoadGame(saved)
+	} else {
+		game, err = sim.NewGame(sim.Config{
+			Seed:      *seed,
+			Terrain:   *heights,
+			Workers:   *workers,
+			Brain:     *brain,
+			Avoidance: *avoidance,
+			NavMesh:   *useMesh,
+		})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	var recording *sim.Replay
+	if *record != "" {
+		recording = game.Record()
+	}
+
+	started := time.Now()
+	game.Run(*ticks)
+	took := time.Since(started)
+	os.Stdout = stdout
+
+	fmt.Print(game.Stats())
+	fmt.Printf("took %v, %.0f ticks/s\n", took.Round(time.Millisecond), float64(*ticks)/took.Seconds())
+	if recording != nil {
+		if err := sim.SaveReplay(*record, recording); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *save != "" {
+		format := sim.JSON
+		if *binary {
+			format = sim.Binary
+		}
+		if err := sim.WriteSaveFile(*save, game.Save(), format); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *load != "" || *save != "" {
+		// Games continued from a save end with the same hash as games run the whole way through
+		fmt.Printf("tick %d, hash %x\n", game.Tick(), game.Hash())
+	}
+	if *replay != "" {
+		if err := game.Desync(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("replay played out the same for %d ticks\n", *ticks)
+	}
+}
@@ -8,11 +8,11 @@ require (
 	github.com/go-gl/glfw v0.0.0-20220622232848-a6c407ee30a0
 	github.com/go-gl/mathgl v1.0.0
 	github.com/stretchr/testify v1.8.4
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/image v0.0.0-20210216034530-4410531fe030 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
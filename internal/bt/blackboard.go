@@ -0,0 +1,34 @@
+package bt
+
+// Blackboard stores the data nodes of a tree share with each other.
+type Blackboard struct {
+	values map[string]any
+}
+
+func NewBlackboard() *Blackboard {
+	return &Blackboard{values: map[string]any{}}
+}
+
+func (b *Blackboard) Set(key string, value any) {
+	b.values[key] = value
+}
+
+func (b *Blackboard) Get(key string) (any, bool) {
+	value, exists := b.values[key]
+	return value, exists
+}
+
+func (b *Blackboard) Has(key string) bool {
+	_, exists := b.values[key]
+	return exists
+}
+
+func (b *Blackboard) Delete(key string) {
+	delete(b.values, key)
+}
+
+// Get returns the value stored under key if it exists and has the type T.
+func Get[T any](b *Blackboard, key string) (T, bool) {
+	value, ok := b.values[key].(T)
+	return value, ok
+}
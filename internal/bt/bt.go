@@ -0,0 +1,75 @@
+package bt
+
+import "game-engine/rts/internal/fixed"
+
+// Status is the result of ticking a node.
+type Status int
+
+const (
+	// Running means the node has not finished yet and should be ticked again.
+	Running Status = iota
+	Success
+	Failure
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "Running"
+	case Success:
+		return "Success"
+	case Failure:
+		return "Failure"
+	}
+	return "Unknown"
+}
+
+// Context is passed to every node when the tree is ticked.
+type Context struct {
+	// DT is the time since the last tick.
+	DT float32
+	// Time is the total time the tree has been ticked for. It is added up in fixed point, so timers run out on
+	// the same tick on every machine.
+	Time fixed.Scalar
+
+	Blackboard *Blackboard
+}
+
+// Node is a node in a behaviour tree.
+type Node interface {
+	// Tick runs the node and returns its status.
+	Tick(ctx *Context) Status
+	// Reset aborts the node if it is running, so the next tick starts it from the beginning.
+	Reset()
+}
+
+// Tree is a behaviour tree together with the blackboard of the agent it controls.
+type Tree struct {
+	root Node
+	ctx  Context
+}
+
+// New creates a tree with an empty blackboard.
+func New(root Node) *Tree {
+	return &Tree{
+		root: root,
+		ctx:  Context{Blackboard: NewBlackboard()},
+	}
+}
+
+// Tick runs the tree once. It should be called with the same dt as the rest of the game loop.
+func (t *Tree) Tick(dt float32) Status {
+	t.ctx.DT = dt
+	t.ctx.Time += fixed.FromFloat32(dt)
+	return t.root.Tick(&t.ctx)
+}
+
+// Reset aborts all running nodes.
+func (t *Tree) Reset() {
+	t.root.Reset()
+}
+
+// Blackboard returns the blackboard used by the tree.
+func (t *Tree) Blackboard() *Blackboard {
+	return t.ctx.Blackboard
+}
@@ -0,0 +1,279 @@
+package bt
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scripted is a node returning the given statuses in order, repeating the last one.
+type scripted struct {
+	statuses []Status
+	ticks    int
+	resets   int
+}
+
+func newScripted(statuses ...Status) *scripted {
+	return &scripted{statuses: statuses}
+}
+
+func (s *scripted) Tick(_ *Context) Status {
+	status := s.statuses[min(s.ticks, len(s.statuses)-1)]
+	s.ticks++
+	return status
+}
+
+func (s *scripted) Reset() {
+	s.resets++
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func tickN(tree *Tree, n int, dt float32) []Status {
+	statuses := []Status{}
+	for i := 0; i < n; i++ {
+		statuses = append(statuses, tree.Tick(dt))
+	}
+	return statuses
+}
+
+func TestSequence(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		children []*scripted
+		expected []Status
+	}{
+		{
+			desc:     "all succeed",
+			children: []*scripted{newScripted(Success), newScripted(Running, Success)},
+			expected: []Status{Running, Success},
+		},
+		{
+			desc:     "second fails",
+			children: []*scripted{newScripted(Success), newScripted(Running, Failure)},
+			expected: []Status{Running, Failure},
+		},
+		{
+			desc:     "first fails",
+			children: []*scripted{newScripted(Failure), newScripted(Success)},
+			expected: []Status{Failure, Failure},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			nodes := []Node{}
+			for _, child := range tc.children {
+				nodes = append(nodes, child)
+			}
+			tree := New(NewSequence(nodes...))
+
+			assert.Equal(t, tc.expected, tickN(tree, len(tc.expected), 0.1))
+		})
+	}
+}
+
+func TestSequenceResumesRunningChild(t *testing.T) {
+	first := newScripted(Success)
+	second := newScripted(Running, Running, Success)
+	tree := New(NewSequence(first, second))
+
+	assert.Equal(t, []Status{Running, Running, Success}, tickN(tree, 3, 0.1))
+	assert.Equal(t, 1, first.ticks)
+	assert.Equal(t, 3, second.ticks)
+}
+
+func TestSelector(t *testing.T) {
+	first := newScripted(Failure)
+	second := newScripted(Running, Success)
+	third := newScripted(Success)
+	tree := New(NewSelector(first, second, third))
+
+	assert.Equal(t, []Status{Running, Success}, tickN(tree, 2, 0.1))
+	assert.Equal(t, 1, first.ticks)
+	assert.Equal(t, 0, third.ticks)
+
+	tree = New(NewSelector(newScripted(Failure), newScripted(Failure)))
+	assert.Equal(t, Failure, tree.Tick(0.1))
+}
+
+func TestParallel(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		success        Policy
+		failure        Policy
+		first, second  []Status
+		expected       []Status
+		expectedResets int
+	}{
+		{
+			desc:     "require one success",
+			success:  RequireOne,
+			failure:  RequireAll,
+			first:    []Status{Running, Running},
+			second:   []Status{Running, Success},
+			expected: []Status{Running, Success},
+		},
+		{
+			desc:     "require all successes",
+			success:  RequireAll,
+			failure:  RequireOne,
+			first:    []Status{Success},
+			second:   []Status{Running, Running, Success},
+			expected: []Status{Running, Running, Success},
+		},
+		{
+			desc:     "require one failure",
+			success:  RequireAll,
+			failure:  RequireOne,
+			first:    []Status{Running},
+			second:   []Status{Running, Failure},
+			expected: []Status{Running, Failure},
+		},
+		{
+			desc:     "require all of both, finished with mixed results",
+			success:  RequireAll,
+			failure:  RequireAll,
+			first:    []Status{Success},
+			second:   []Status{Running, Running, Failure},
+			expected: []Status{Running, Running, Failure},
+		},
+		{
+			desc:     "require all of both, all succeed",
+			success:  RequireAll,
+			failure:  RequireAll,
+			first:    []Status{Running, Success},
+			second:   []Status{Success},
+			expected: []Status{Running, Success},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			first := newScripted(tc.first...)
+			tree := New(NewParallel(tc.success, tc.failure, first, newScripted(tc.second...)))
+
+			assert.Equal(t, tc.expected, tickN(tree, len(tc.expected), 0.1))
+			assert.Equal(t, 1, first.resets, "children are reset when finished")
+		})
+	}
+}
+
+func TestParallelDoesNotTickFinishedChildren(t *testing.T) {
+	first := newScripted(Success)
+	tree := New(NewParallel(RequireAll, RequireOne, first, newScripted(Running, Running, Success)))
+
+	tickN(tree, 3, 0.1)
+
+	assert.Equal(t, 1, first.ticks)
+}
+
+func TestInverter(t *testing.T) {
+	tree := New(NewInverter(newScripted(Success, Running, Failure)))
+
+	assert.Equal(t, []Status{Failure, Running, Success}, tickN(tree, 3, 0.1))
+}
+
+func TestRepeat(t *testing.T) {
+	child := newScripted(Running, Success, Success, Success)
+	tree := New(NewRepeat(3, child))
+
+	assert.Equal(t, []Status{Running, Running, Running, Success}, tickN(tree, 4, 0.1))
+
+	tree = New(NewRepeat(0, newScripted(Success, Success, Failure)))
+	assert.Equal(t, []Status{Running, Running, Failure}, tickN(tree, 3, 0.1))
+}
+
+func TestCooldown(t *testing.T) {
+	child := newScripted(Success)
+	tree := New(NewCooldown(1.0, child))
+
+	statuses := tickN(tree, 6, 0.25)
+
+	assert.Equal(t, []Status{Success, Failure, Failure, Failure, Success, Failure}, statuses)
+	assert.Equal(t, 2, child.ticks)
+}
+
+func TestTimeout(t *testing.T) {
+	child := newScripted(Running)
+	tree := New(NewTimeout(1.0, child))
+
+	assert.Equal(t, []Status{Running, Running, Running, Failure, Running}, tickN(tree, 5, 0.25))
+	assert.Equal(t, 1, child.resets)
+
+	tree = New(NewTimeout(1.0, newScripted(Running, Success)))
+	assert.Equal(t, []Status{Running, Success}, tickN(tree, 2, 0.75))
+}
+
+func TestActionAndCondition(t *testing.T) {
+	tree := New(NewSequence(
+		NewCondition(func(ctx *Context) bool { return !ctx.Blackboard.Has("done") }),
+		NewAction(func(ctx *Context) Status {
+			count, _ := Get[int](ctx.Blackboard, "count")
+			ctx.Blackboard.Set("count", count+1)
+			if count+1 < 3 {
+				return Running
+			}
+			ctx.Blackboard.Set("done", true)
+			return Success
+		}),
+	))
+
+	assert.Equal(t, []Status{Running, Running, Success, Failure}, tickN(tree, 4, 0.1))
+	count, ok := Get[int](tree.Blackboard(), "count")
+	assert.True(t, ok)
+	assert.Equal(t, 3, count)
+}
+
+func TestBlackboard(t *testing.T) {
+	bb := NewBlackboard()
+	bb.Set("target", "tree")
+
+	_, ok := Get[int](bb, "target")
+	assert.False(t, ok, "wrong type")
+	target, ok := Get[string](bb, "target")
+	assert.True(t, ok)
+	assert.Equal(t, "tree", target)
+
+	bb.Delete("target")
+	_, ok = bb.Get("target")
+	assert.False(t, ok)
+}
+
+func TestSnapshot(t *testing.T) {
+	// Leaves keep nothing themselves, so the tree is all there is to save
+	success := NewAction(func(*Context) Status { return Success })
+	running := NewAction(func(*Context) Status { return Running })
+	newTree := func() *Tree {
+		return New(NewSequence(
+			NewCooldown(1.0, success),
+			NewParallel(RequireAll, RequireOne, NewRepeat(3, success), NewTimeout(1.0, running)),
+		))
+	}
+	tree := newTree()
+	tickN(tree, 2, 0.25)
+	snapshot := tree.Snapshot()
+	assert.Equal(t, fixed.FromFloat(0.5), snapshot.Time)
+	assert.Equal(t, []NodeState{
+		{Current: 1},
+		{Time: fixed.FromFloat(1.25)},
+		{Statuses: []Status{Running, Running}},
+		{Current: 2},
+		{Time: fixed.FromFloat(0.5)},
+	}, snapshot.Nodes)
+
+	restored := newTree()
+	assert.NoError(t, restored.Restore(snapshot))
+	assert.Equal(t, snapshot, restored.Snapshot())
+	assert.Equal(t, tickN(tree, 10, 0.25), tickN(restored, 10, 0.25), "carries on the same")
+
+	other := New(NewSelector(success))
+	assert.ErrorIs(t, other.Restore(snapshot), ErrOtherTree)
+	snapshot.Nodes[0].Current = 2
+	assert.ErrorIs(t, newTree().Restore(snapshot), ErrOtherTree, "no such child")
+}
@@ -0,0 +1,142 @@
+package bt
+
+// Sequence ticks its children in order until one fails. It succeeds when all children have succeeded. A
+// running child is resumed on the next tick.
+type Sequence struct {
+	children []Node
+	current  int
+}
+
+func NewSequence(children ...Node) *Sequence {
+	return &Sequence{children: children}
+}
+
+func (s *Sequence) Tick(ctx *Context) Status {
+	for s.current < len(s.children) {
+		switch s.children[s.current].Tick(ctx) {
+		case Running:
+			return Running
+		case Failure:
+			s.Reset()
+			return Failure
+		case Success:
+			s.current++
+		}
+	}
+	s.Reset()
+	return Success
+}
+
+func (s *Sequence) Reset() {
+	for _, child := range s.children {
+		child.Reset()
+	}
+	s.current = 0
+}
+
+// Selector ticks its children in order until one succeeds. It fails when all children have failed. A
+// running child is resumed on the next tick.
+type Selector struct {
+	children []Node
+	current  int
+}
+
+func NewSelector(children ...Node) *Selector {
+	return &Selector{children: children}
+}
+
+func (s *Selector) Tick(ctx *Context) Status {
+	for s.current < len(s.children) {
+		switch s.children[s.current].Tick(ctx) {
+		case Running:
+			return Running
+		case Success:
+			s.Reset()
+			return Success
+		case Failure:
+			s.current++
+		}
+	}
+	s.Reset()
+	return Failure
+}
+
+func (s *Selector) Reset() {
+	for _, child := range s.children {
+		child.Reset()
+	}
+	s.current = 0
+}
+
+// Policy decides how many children of a Parallel need to finish with a status.
+type Policy int
+
+const (
+	RequireOne Policy = iota
+	RequireAll
+)
+
+// Parallel ticks all unfinished children every tick. It succeeds or fails as soon as enough children have
+// finished according to its policies, aborting the children that are still running. When all children have
+// finished without satisfying either policy, such as some succeeding and some failing when both require all,
+// it fails.
+type Parallel struct {
+	children      []Node
+	statuses      []Status
+	successPolicy Policy
+	failurePolicy Policy
+}
+
+func NewParallel(successPolicy, failurePolicy Policy, children ...Node) *Parallel {
+	return &Parallel{
+		children:      children,
+		statuses:      make([]Status, len(children)),
+		successPolicy: successPolicy,
+		failurePolicy: failurePolicy,
+	}
+}
+
+func (p *Parallel) Tick(ctx *Context) Status {
+	successes, failures, running := 0, 0, 0
+	for i, child := range p.children {
+		if p.statuses[i] == Running {
+			p.statuses[i] = child.Tick(ctx)
+		}
+		switch p.statuses[i] {
+		case Success:
+			successes++
+		case Failure:
+			failures++
+		case Running:
+			running++
+		}
+	}
+
+	if p.satisfied(p.failurePolicy, failures) {
+		p.Reset()
+		return Failure
+	}
+	if p.satisfied(p.successPolicy, successes) {
+		p.Reset()
+		return Success
+	}
+	if running == 0 {
+		p.Reset()
+		return Failure
+	}
+	return Running
+}
+
+func (p *Parallel) Reset() {
+	for i, child := range p.children {
+		child.Reset()
+		p.statuses[i] = Running
+	}
+}
+
+func (p *Parallel) satisfied(policy Policy, count int) bool {
+	if policy == RequireAll {
+		return count == len(p.children)
+	}
+	return count > 0
+}
@@ -0,0 +1,119 @@
+package bt
+
+import "game-engine/rts/internal/fixed"
+
+// Inverter turns the success of its child into failure and the other way around.
+type Inverter struct {
+	child Node
+}
+
+func NewInverter(child Node) *Inverter {
+	return &Inverter{child: child}
+}
+
+func (i *Inverter) Tick(ctx *Context) Status {
+	switch status := i.child.Tick(ctx); status {
+	case Success:
+		return Failure
+	case Failure:
+		return Success
+	default:
+		return status
+	}
+}
+
+func (i *Inverter) Reset() {
+	i.child.Reset()
+}
+
+// Repeat restarts its child every time it succeeds, until it has succeeded the given number of times. It
+// fails as soon as the child fails. A Repeat with zero or fewer times repeats until the child fails.
+type Repeat struct {
+	child Node
+	times int
+	count int
+}
+
+func NewRepeat(times int, child Node) *Repeat {
+	return &Repeat{child: child, times: times}
+}
+
+func (r *Repeat) Tick(ctx *Context) Status {
+	switch r.child.Tick(ctx) {
+	case Failure:
+		r.Reset()
+		return Failure
+	case Success:
+		r.count++
+		if r.times > 0 && r.count >= r.times {
+			r.Reset()
+			return Success
+		}
+		// Start over on the next tick, restarting right away could loop forever
+		r.child.Reset()
+	case Running:
+	}
+	return Running
+}
+
+func (r *Repeat) Reset() {
+	r.child.Reset()
+	r.count = 0
+}
+
+// Cooldown fails without ticking its child until the duration has passed since the child last finished.
+type Cooldown struct {
+	child    Node
+	duration fixed.Scalar
+	readyAt  fixed.Scalar
+}
+
+func NewCooldown(duration float32, child Node) *Cooldown {
+	return &Cooldown{child: child, duration: fixed.FromFloat32(duration)}
+}
+
+func (c *Cooldown) Tick(ctx *Context) Status {
+	if ctx.Time < c.readyAt {
+		return Failure
+	}
+	status := c.child.Tick(ctx)
+	if status != Running {
+		c.readyAt = ctx.Time + c.duration
+	}
+	return status
+}
+
+// Reset aborts the child but keeps the cooldown.
+func (c *Cooldown) Reset() {
+	c.child.Reset()
+}
+
+// Timeout aborts its child and fails if the child has been running for longer than the duration.
+type Timeout struct {
+	child    Node
+	duration fixed.Scalar
+	elapsed  fixed.Scalar
+}
+
+func NewTimeout(duration float32, child Node) *Timeout {
+	return &Timeout{child: child, duration: fixed.FromFloat32(duration)}
+}
+
+func (t *Timeout) Tick(ctx *Context) Status {
+	t.elapsed += fixed.FromFloat32(ctx.DT)
+	status := t.child.Tick(ctx)
+	if status != Running {
+		t.elapsed = 0
+		return status
+	}
+	if t.elapsed >= t.duration {
+		t.Reset()
+		return Failure
+	}
+	return Running
+}
+
+func (t *Timeout) Reset() {
+	t.child.Reset()
+	t.elapsed = 0
+}
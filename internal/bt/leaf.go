@@ -0,0 +1,35 @@
+package bt
+
+// Action is a leaf node running a function every tick.
+type Action struct {
+	run func(ctx *Context) Status
+}
+
+func NewAction(run func(ctx *Context) Status) *Action {
+	return &Action{run: run}
+}
+
+func (a *Action) Tick(ctx *Context) Status {
+	return a.run(ctx)
+}
+
+// Reset does nothing, actions keep their state on the blackboard.
+func (a *Action) Reset() {}
+
+// Condition is a leaf node that succeeds if its check passes and fails otherwise.
+type Condition struct {
+	check func(ctx *Context) bool
+}
+
+func NewCondition(check func(ctx *Context) bool) *Condition {
+	return &Condition{check: check}
+}
+
+func (c *Condition) Tick(ctx *Context) Status {
+	if c.check(ctx) {
+		return Success
+	}
+	return Failure
+}
+
+func (c *Condition) Reset() {}
@@ -0,0 +1,155 @@
+package bt
+
+import (
+	"errors"
+	"fmt"
+
+	"game-engine/rts/internal/fixed"
+)
+
+// ErrOtherTree is returned when restoring a snapshot taken of a tree made of other nodes.
+var ErrOtherTree = errors.New("snapshot is of another tree")
+
+// Snapshot is where a tree is, for saving it: how long it has been ticked for and what its nodes keep between
+// ticks. The blackboard isn't part of it, its values can be anything, so whoever puts them there has to save
+// them.
+type Snapshot struct {
+	Time fixed.Scalar `json:"time"`
+	// Nodes are the states of the nodes that keep any, in the order they are found going depth first.
+	Nodes []NodeState `json:"nodes"`
+}
+
+// NodeState is what a node keeps between ticks.
+type NodeState struct {
+	// Current is the running child of a Sequence or Selector, or how many times a Repeat has succeeded.
+	Current int `json:"current,omitempty"`
+	// Statuses are those of the children of a Parallel.
+	Statuses []Status `json:"statuses,omitempty"`
+	// Time is when a Cooldown is ready again, or how long a Timeout has been running.
+	Time fixed.Scalar `json:"time,omitempty"`
+}
+
+// stateful is a node keeping state between ticks, which is saved with the tree.
+type stateful interface {
+	state() NodeState
+	restore(s NodeState) error
+}
+
+// parent is a node with children.
+type parent interface {
+	childNodes() []Node
+}
+
+// Snapshot returns where the tree is.
+func (t *Tree) Snapshot() Snapshot {
+	s := Snapshot{Time: t.ctx.Time}
+	walk(t.root, func(n stateful) error {
+		s.Nodes = append(s.Nodes, n.state())
+		return nil
+	})
+	return s
+}
+
+// Restore puts the tree back where a snapshot of a tree built the same way was taken. Nodes are not reset or
+// ticked. The tree can be partly restored when it fails.
+func (t *Tree) Restore(s Snapshot) error {
+	count := 0
+	walk(t.root, func(stateful) error {
+		count++
+		return nil
+	})
+	if count != len(s.Nodes) {
+		return fmt.Errorf("%w: %d nodes keep state instead of %d", ErrOtherTree, count, len(s.Nodes))
+	}
+	i := 0
+	err := walk(t.root, func(n stateful) error {
+		i++
+		return n.restore(s.Nodes[i-1])
+	})
+	if err != nil {
+		return err
+	}
+	t.ctx.Time = s.Time
+	return nil
+}
+
+// walk visits the nodes keeping state depth first, until visit returns an error.
+func walk(n Node, visit func(n stateful) error) error {
+	if s, ok := n.(stateful); ok {
+		if err := visit(s); err != nil {
+			return err
+		}
+	}
+	if p, ok := n.(parent); ok {
+		for _, child := range p.childNodes() {
+			if err := walk(child, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreCurrent returns the running child of a composite with count children, which is the first when none
+// is running.
+func restoreCurrent(s NodeState, count int) (int, error) {
+	if s.Current < 0 || s.Current >= count && s.Current != 0 {
+		return 0, fmt.Errorf("%w: running child %d of %d", ErrOtherTree, s.Current, count)
+	}
+	return s.Current, nil
+}
+
+func (s *Sequence) childNodes() []Node { return s.children }
+func (s *Sequence) state() NodeState   { return NodeState{Current: s.current} }
+
+func (s *Sequence) restore(state NodeState) (err error) {
+	s.current, err = restoreCurrent(state, len(s.children))
+	return err
+}
+
+func (s *Selector) childNodes() []Node { return s.children }
+func (s *Selector) state() NodeState   { return NodeState{Current: s.current} }
+
+func (s *Selector) restore(state NodeState) (err error) {
+	s.current, err = restoreCurrent(state, len(s.children))
+	return err
+}
+
+func (p *Parallel) childNodes() []Node { return p.children }
+func (p *Parallel) state() NodeState {
+	return NodeState{Statuses: append([]Status{}, p.statuses...)}
+}
+
+func (p *Parallel) restore(state NodeState) error {
+	if len(state.Statuses) != len(p.children) {
+		return fmt.Errorf("%w: statuses of %d children instead of %d", ErrOtherTree, len(state.Statuses), len(p.children))
+	}
+	copy(p.statuses, state.Statuses)
+	return nil
+}
+
+func (i *Inverter) childNodes() []Node { return []Node{i.child} }
+
+func (r *Repeat) childNodes() []Node { return []Node{r.child} }
+func (r *Repeat) state() NodeState   { return NodeState{Current: r.count} }
+
+func (r *Repeat) restore(state NodeState) error {
+	r.count = state.Current
+	return nil
+}
+
+func (c *Cooldown) childNodes() []Node { return []Node{c.child} }
+func (c *Cooldown) state() NodeState   { return NodeState{Time: c.readyAt} }
+
+func (c *Cooldown) restore(state NodeState) error {
+	c.readyAt = state.Time
+	return nil
+}
+
+func (t *Timeout) childNodes() []Node { return []Node{t.child} }
+func (t *Timeout) state() NodeState   { return NodeState{Time: t.elapsed} }
+
+func (t *Timeout) restore(state NodeState) error {
+	t.elapsed = state.Time
+	return nil
+}
@@ -107,6 +107,12 @@ func (c *Camera) AddPitch(pitch float32) {
 	c.pitch = mgl32.Clamp(c.pitch, -89.5, 89.5)
 }
 
+// Look turns the camera to a yaw and pitch in degrees.
+func (c *Camera) Look(yaw, pitch float32) {
+	c.yaw = yaw
+	c.pitch = mgl32.Clamp(pitch, -89.5, 89.5)
+}
+
 func (c *Camera) View() mgl32.Mat4 {
 	return c.view
 }
@@ -0,0 +1,90 @@
+package ecs
+
+import "testing"
+
+// benchmarkEntities is how many entities the benchmarks are run with.
+const benchmarkEntities = 100000
+
+// benchmarkWorld returns a world of entities that all have a position, every other a velocity and every
+// tenth a health.
+func benchmarkWorld() (*World, []Entity) {
+	w := NewWorld()
+	entities := make([]Entity, benchmarkEntities)
+	for i := range entities {
+		e := w.Spawn()
+		Add(w, e, position{X: float32(i)})
+		if i%2 == 0 {
+			Add(w, e, velocity{X: 1, Y: 1})
+		}
+		if i%10 == 0 {
+			Add(w, e, health(100))
+		}
+		entities[i] = e
+	}
+	return w, entities
+}
+
+func BenchmarkSpawn(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchmarkWorld()
+	}
+}
+
+// BenchmarkEach visits the entities of a world of 100000.
+func BenchmarkEach(b *testing.B) {
+	w, _ := benchmarkWorld()
+	b.Run("one", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Each1(w, func(_ Entity, p *position) {
+				p.Y++
+			})
+		}
+	})
+	b.Run("two", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Each2(w, func(_ Entity, p *position, v *velocity) {
+				p.X += v.X
+				p.Y += v.Y
+			})
+		}
+	})
+	b.Run("three", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Each3(w, func(_ Entity, p *position, v *velocity, h *health) {
+				*h--
+			})
+		}
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	w, entities := benchmarkWorld()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Get[velocity](w, entities[i%len(entities)])
+	}
+}
+
+// BenchmarkCommands queues adding and removing a component of every entity, and despawning a tenth, and
+// flushes them.
+func BenchmarkCommands(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		w, _ := benchmarkWorld()
+		b.StartTimer()
+		c := w.Commands()
+		Each1(w, func(e Entity, p *position) {
+			if e.Index()%2 == 0 {
+				RemoveLater[velocity](c, e)
+			} else {
+				AddLater(c, e, velocity{X: 1})
+			}
+			if e.Index()%10 == 0 {
+				c.Despawn(e)
+			}
+		})
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
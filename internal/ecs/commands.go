@@ -0,0 +1,68 @@
+package ecs
+
+import "errors"
+
+// Commands is a buffer of structural changes, made when the world is flushed. Systems queue changes in it
+// while they visit entities, which they can't change then.
+type Commands struct {
+	world    *World
+	commands []func(w *World) error
+	// reserved are the entities spawned by the commands, which aren't alive until they are flushed.
+	reserved map[Entity]bool
+}
+
+// Spawn returns an entity that is spawned when the commands are flushed. Components can be queued for it
+// straight away.
+func (c *Commands) Spawn() Entity {
+	e := c.world.reserve()
+	if c.reserved == nil {
+		c.reserved = map[Entity]bool{}
+	}
+	c.reserved[e] = true
+	c.commands = append(c.commands, func(w *World) error {
+		delete(c.reserved, e)
+		w.alive[e.Index()] = true
+		return nil
+	})
+	return e
+}
+
+// Despawn queues despawning an entity.
+func (c *Commands) Despawn(e Entity) {
+	c.commands = append(c.commands, func(w *World) error {
+		return w.Despawn(e)
+	})
+}
+
+// Len returns how many changes are queued.
+func (c *Commands) Len() int {
+	return len(c.commands)
+}
+
+// AddLater queues giving an entity a component.
+func AddLater[T any](c *Commands, e Entity, component T) {
+	c.commands = append(c.commands, func(w *World) error {
+		return Add(w, e, component)
+	})
+}
+
+// RemoveLater queues removing a component from an entity.
+func RemoveLater[T any](c *Commands, e Entity) {
+	c.commands = append(c.commands, func(w *World) error {
+		return Remove[T](w, e)
+	})
+}
+
+func (c *Commands) flush() error {
+	var errs []error
+	for _, command := range c.commands {
+		if err := command(c.world); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := range c.commands {
+		c.commands[i] = nil
+	}
+	c.commands = c.commands[:0]
+	return errors.Join(errs...)
+}
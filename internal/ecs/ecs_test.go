@@ -0,0 +1,290 @@
+package ecs
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type position struct{ X, Y float32 }
+
+type velocity struct{ X, Y float32 }
+
+type health int
+
+func TestEntities(t *testing.T) {
+	w := NewWorld()
+	a, b := w.Spawn(), w.Spawn()
+	assert.NotEqual(t, a, b)
+	assert.True(t, w.Alive(a))
+	assert.False(t, w.Alive(Entity(0)), "the zero entity is never alive")
+	assert.Equal(t, 2, w.Len())
+
+	assert.NoError(t, w.Despawn(a))
+	assert.False(t, w.Alive(a))
+	assert.ErrorIs(t, w.Despawn(a), ErrDead)
+
+	// The index is reused by a new generation, which the old entity isn't
+	c := w.Spawn()
+	assert.Equal(t, a.Index(), c.Index())
+	assert.Equal(t, a.Generation()+1, c.Generation())
+	assert.False(t, w.Alive(a))
+	assert.True(t, w.Alive(c))
+	assert.Equal(t, 2, w.Len())
+}
+
+func TestComponents(t *testing.T) {
+	w := NewWorld()
+	e := w.Spawn()
+	assert.Nil(t, Get[position](w, e))
+	assert.NoError(t, Add(w, e, position{1, 2}))
+	assert.NoError(t, Add(w, e, health(10)))
+	assert.Equal(t, &position{1, 2}, Get[position](w, e))
+	assert.True(t, Has[health](w, e))
+	assert.False(t, Has[velocity](w, e))
+
+	Get[position](w, e).X = 5
+	assert.NoError(t, Add(w, e, health(7)), "replaces")
+	assert.Equal(t, position{5, 2}, *Get[position](w, e))
+	assert.Equal(t, health(7), *Get[health](w, e))
+	assert.Equal(t, 1, Count[health](w))
+
+	// Removing keeps the components of other entities
+	others := []Entity{w.Spawn(), w.Spawn()}
+	for i, other := range others {
+		assert.NoError(t, Add(w, other, health(i)))
+	}
+	assert.NoError(t, Remove[health](w, e))
+	assert.NoError(t, Remove[health](w, e), "removing what isn't there")
+	assert.False(t, Has[health](w, e))
+	assert.True(t, Has[position](w, e))
+	for i, other := range others {
+		assert.Equal(t, health(i), *Get[health](w, other))
+	}
+
+	assert.NoError(t, w.Despawn(e))
+	assert.Nil(t, Get[position](w, e))
+	assert.Equal(t, 0, Count[position](w))
+	assert.ErrorIs(t, Add(w, e, velocity{}), ErrDead)
+	assert.ErrorIs(t, Remove[velocity](w, e), ErrDead)
+	reused := w.Spawn()
+	assert.Equal(t, e.Index(), reused.Index())
+	assert.Nil(t, Get[position](w, reused), "a new entity at the index has none of the components of the old one")
+}
+
+func TestQueries(t *testing.T) {
+	w := NewWorld()
+	entities := make([]Entity, 10)
+	for i := range entities {
+		entities[i] = w.Spawn()
+		Add(w, entities[i], position{X: float32(i)})
+		if i%2 == 0 {
+			Add(w, entities[i], velocity{X: 1})
+		}
+		if i%3 == 0 {
+			Add(w, entities[i], health(i))
+		}
+	}
+
+	visited := func(each func(f func(e Entity))) []int {
+		indices := []int{}
+		each(func(e Entity) { indices = append(indices, int(e.Index())) })
+		sort.Ints(indices)
+		return indices
+	}
+	testCases := []struct {
+		desc     string
+		each     func(f func(e Entity))
+		expected []int
+	}{
+		{
+			desc:     "one",
+			each:     func(f func(Entity)) { Each1(w, func(e Entity, _ *health) { f(e) }) },
+			expected: []int{0, 3, 6, 9},
+		},
+		{
+			desc:     "two",
+			each:     func(f func(Entity)) { Each2(w, func(e Entity, _ *position, _ *velocity) { f(e) }) },
+			expected: []int{0, 2, 4, 6, 8},
+		},
+		{
+			desc:     "two, fewer of the second",
+			each:     func(f func(Entity)) { Each2(w, func(e Entity, _ *position, _ *health) { f(e) }) },
+			expected: []int{0, 3, 6, 9},
+		},
+		{
+			desc:     "three",
+			each:     func(f func(Entity)) { Each3(w, func(e Entity, _ *position, _ *velocity, _ *health) { f(e) }) },
+			expected: []int{0, 6},
+		},
+		{
+			desc:     "none",
+			each:     func(f func(Entity)) { Each2(w, func(e Entity, _ *position, _ *string) { f(e) }) },
+			expected: []int{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, visited(tc.each))
+		})
+	}
+
+	// Components are changed in place
+	Each2(w, func(_ Entity, p *position, v *velocity) {
+		p.X += v.X
+	})
+	assert.Equal(t, float32(5), Get[position](w, entities[4]).X)
+	assert.Equal(t, float32(5), Get[position](w, entities[5]).X)
+
+	assert.Panics(t, func() {
+		Each1(w, func(e Entity, _ *velocity) { Remove[velocity](w, e) })
+	}, "no structural changes while visiting")
+	assert.Panics(t, func() {
+		Each1(w, func(e Entity, _ *velocity) { w.Despawn(e) })
+	})
+	assert.NotPanics(t, func() {
+		Each1(w, func(e Entity, _ *velocity) { Add(w, e, velocity{X: 2}) })
+	}, "replacing a component isn't a structural change")
+	assert.NoError(t, Remove[velocity](w, entities[0]), "visiting again after a panic")
+}
+
+func TestCommands(t *testing.T) {
+	w := NewWorld()
+	entities := []Entity{w.Spawn(), w.Spawn(), w.Spawn()}
+	for _, e := range entities {
+		Add(w, e, health(1))
+	}
+	c := w.Commands()
+	var spawned Entity
+	Each1(w, func(e Entity, h *health) {
+		if e == entities[0] {
+			c.Despawn(e)
+			spawned = c.Spawn()
+			AddLater(c, spawned, health(5))
+		}
+		RemoveLater[health](c, entities[1])
+		AddLater(c, e, velocity{X: 1})
+	})
+	assert.Equal(t, 9, c.Len())
+	assert.False(t, w.Alive(spawned), "not spawned until flushed")
+	assert.Equal(t, 3, w.Len())
+	assert.Equal(t, 3, Count[health](w))
+
+	// Adding to the entity despawned first fails, the rest are made
+	assert.ErrorIs(t, w.Flush(), ErrDead)
+	assert.Equal(t, 0, c.Len())
+	assert.True(t, w.Alive(spawned))
+	assert.False(t, w.Alive(entities[0]))
+	assert.Equal(t, 3, w.Len())
+	assert.Equal(t, health(5), *Get[health](w, spawned))
+	assert.False(t, Has[health](w, entities[1]))
+	assert.True(t, Has[velocity](w, entities[1]))
+	assert.True(t, Has[velocity](w, entities[2]))
+	assert.NoError(t, w.Flush(), "nothing left to do")
+}
+
+func TestSchedule(t *testing.T) {
+	ran := []string{}
+	system := func(name string, after, before []string) System {
+		return System{
+			Name:   name,
+			Run:    func(*World, float32) { ran = append(ran, name) },
+			After:  after,
+			Before: before,
+		}
+	}
+	testCases := []struct {
+		desc     string
+		systems  []System
+		expected []string
+		err      error
+	}{
+		{
+			desc:     "in the order added",
+			systems:  []System{system("input", nil, nil), system("move", nil, nil), system("render", nil, nil)},
+			expected: []string{"input", "move", "render"},
+		},
+		{
+			desc: "after",
+			systems: []System{
+				system("render", []string{"move"}, nil),
+				system("move", []string{"input"}, nil),
+				system("input", nil, nil),
+			},
+			expected: []string{"input", "move", "render"},
+		},
+		{
+			desc: "before",
+			systems: []System{
+				system("render", nil, nil),
+				system("sound", nil, nil),
+				system("move", nil, []string{"render"}),
+			},
+			expected: []string{"sound", "move", "render"},
+		},
+		{
+			desc:    "unknown",
+			systems: []System{system("move", []string{"input"}, nil)},
+			err:     ErrUnknownSystem,
+		},
+		{
+			desc: "cycle",
+			systems: []System{
+				system("a", []string{"c"}, nil),
+				system("b", []string{"a"}, nil),
+				system("c", []string{"b"}, []string{"a"}),
+			},
+			err: ErrSystemCycle,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &Schedule{}
+			for _, system := range tc.systems {
+				assert.NoError(t, s.Add(system))
+			}
+			order, err := s.Order()
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, order)
+
+			ran = []string{}
+			err = s.Run(NewWorld(), 0.1)
+			assert.ErrorIs(t, err, tc.err)
+			if tc.err == nil {
+				assert.Equal(t, tc.expected, ran)
+			}
+		})
+	}
+
+	s := &Schedule{}
+	assert.NoError(t, s.Add(system("move", nil, nil)))
+	assert.ErrorIs(t, s.Add(system("move", nil, nil)), ErrDuplicateSystem)
+}
+
+func TestScheduleFlushes(t *testing.T) {
+	w := NewWorld()
+	s := &Schedule{}
+	s.Add(System{Name: "move", After: []string{"spawn"}, Run: func(w *World, dt float32) {
+		Each2(w, func(_ Entity, p *position, v *velocity) {
+			p.X += v.X * dt
+		})
+	}})
+	s.Add(System{Name: "spawn", Run: func(w *World, _ float32) {
+		e := w.Commands().Spawn()
+		AddLater(w.Commands(), e, position{})
+		AddLater(w.Commands(), e, velocity{X: 1})
+	}})
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Run(w, 1))
+	}
+	// Each entity spawned is moved the same update, seeing what spawn queued
+	moved := []float32{}
+	Each1(w, func(_ Entity, p *position) { moved = append(moved, p.X) })
+	assert.Equal(t, []float32{3, 2, 1}, moved)
+
+	s.Add(System{Name: "fail", Run: func(w *World, _ float32) {
+		w.Commands().Despawn(Entity(0))
+	}})
+	assert.ErrorIs(t, s.Run(w, 1), ErrDead)
+}
@@ -0,0 +1,58 @@
+package ecs
+
+// Each1, Each2 and Each3 call f with every entity that has components of all the types, and pointers to the
+// components, to read or change. Entities are visited in the order their first component type stores them,
+// or the type with the fewest components when there are several, so the same changes to a world visit the
+// same entities in the same order. f can't add or remove entities or components, see World.Commands.
+func Each1[A any](w *World, f func(Entity, *A)) {
+	a := storageOf[A](w)
+	w.iterating++
+	defer func() { w.iterating-- }()
+	for i := range a.dense {
+		f(a.entities[i], &a.dense[i])
+	}
+}
+
+func Each2[A, B any](w *World, f func(Entity, *A, *B)) {
+	a, b := storageOf[A](w), storageOf[B](w)
+	w.iterating++
+	defer func() { w.iterating-- }()
+	if b.len() < a.len() {
+		for i, e := range b.entities {
+			if ca := a.get(e.Index()); ca != nil {
+				f(e, ca, &b.dense[i])
+			}
+		}
+		return
+	}
+	for i, e := range a.entities {
+		if cb := b.get(e.Index()); cb != nil {
+			f(e, &a.dense[i], cb)
+		}
+	}
+}
+
+func Each3[A, B, C any](w *World, f func(Entity, *A, *B, *C)) {
+	a, b, c := storageOf[A](w), storageOf[B](w), storageOf[C](w)
+	w.iterating++
+	defer func() { w.iterating-- }()
+	entities := smallest(a.entities, b.entities, c.entities)
+	for _, e := range entities {
+		index := e.Index()
+		ca, cb, cc := a.get(index), b.get(index), c.get(index)
+		if ca != nil && cb != nil && cc != nil {
+			f(e, ca, cb, cc)
+		}
+	}
+}
+
+// smallest returns the shortest list of entities, the first of the shortest.
+func smallest(lists ...[]Entity) []Entity {
+	shortest := lists[0]
+	for _, list := range lists[1:] {
+		if len(list) < len(shortest) {
+			shortest = list
+		}
+	}
+	return shortest
+}
@@ -0,0 +1,147 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDuplicateSystem is returned when adding a system with the name of one already added.
+	ErrDuplicateSystem = errors.New("duplicate system")
+	// ErrUnknownSystem is returned when a system runs before or after one that wasn't added.
+	ErrUnknownSystem = errors.New("unknown system")
+	// ErrSystemCycle is returned when systems have to run before themselves.
+	ErrSystemCycle = errors.New("systems ordered in a cycle")
+)
+
+// System changes the world each update.
+type System struct {
+	Name string
+	Run  func(w *World, dt float32)
+	// After and Before are the names of the systems this one runs after and before.
+	After  []string
+	Before []string
+}
+
+// Schedule runs systems in order: each after the systems it declares it runs after, and before those it runs
+// before. Systems that aren't ordered run in the order they were added.
+type Schedule struct {
+	systems []System
+	// order is the indices of the systems in the order they run, nil when it needs sorting again.
+	order []int
+}
+
+// Add adds a system to the schedule.
+func (s *Schedule) Add(system System) error {
+	for _, other := range s.systems {
+		if other.Name == system.Name {
+			return fmt.Errorf("%w %q", ErrDuplicateSystem, system.Name)
+		}
+	}
+	s.systems = append(s.systems, system)
+	s.order = nil
+	return nil
+}
+
+// Order returns the names of the systems in the order they run.
+func (s *Schedule) Order() ([]string, error) {
+	if err := s.sort(); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(s.order))
+	for i, system := range s.order {
+		names[i] = s.systems[system].Name
+	}
+	return names, nil
+}
+
+// Run runs every system once, flushing the command buffer of the world after each, so the next system sees
+// the changes it queued.
+func (s *Schedule) Run(w *World, dt float32) error {
+	if err := s.sort(); err != nil {
+		return err
+	}
+	for _, i := range s.order {
+		system := s.systems[i]
+		system.Run(w, dt)
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("flushing the commands of %s: %w", system.Name, err)
+		}
+	}
+	return nil
+}
+
+// sort orders the systems, each time the first one that doesn't have to wait for another.
+func (s *Schedule) sort() error {
+	if s.order != nil || len(s.systems) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(s.systems))
+	for i, system := range s.systems {
+		index[system.Name] = i
+	}
+	// waiting are the systems each system waits for
+	waiting := make([]map[int]bool, len(s.systems))
+	for i := range waiting {
+		waiting[i] = map[int]bool{}
+	}
+	for i, system := range s.systems {
+		for _, name := range system.After {
+			other, exists := index[name]
+			if !exists {
+				return fmt.Errorf("%w %q, which %s runs after", ErrUnknownSystem, name, system.Name)
+			}
+			waiting[i][other] = true
+		}
+		for _, name := range system.Before {
+			other, exists := index[name]
+			if !exists {
+				return fmt.Errorf("%w %q, which %s runs before", ErrUnknownSystem, name, system.Name)
+			}
+			waiting[other][i] = true
+		}
+	}
+
+	order := make([]int, 0, len(s.systems))
+	done := make([]bool, len(s.systems))
+	for len(order) < len(s.systems) {
+		next := -1
+		for i := range s.systems {
+			if !done[i] && ready(waiting[i], done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return fmt.Errorf("%w: %s", ErrSystemCycle, s.blocked(done))
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	s.order = order
+	return nil
+}
+
+// ready reports if all the systems a system waits for are done.
+func ready(waiting map[int]bool, done []bool) bool {
+	for other := range waiting {
+		if !done[other] {
+			return false
+		}
+	}
+	return true
+}
+
+// blocked returns the names of the systems that aren't done.
+func (s *Schedule) blocked(done []bool) string {
+	names := ""
+	for i, system := range s.systems {
+		if !done[i] {
+			if names != "" {
+				names += ", "
+			}
+			names += system.Name
+		}
+	}
+	return names
+}
@@ -0,0 +1,67 @@
+package ecs
+
+import "reflect"
+
+// componentStorage is what the world needs of the storage of any type of component.
+type componentStorage interface {
+	remove(index uint32)
+	len() int
+}
+
+// storage holds the components of one type densely, in the order they were added, with the entities they
+// belong to. sparse gives the position in dense of the component of the entity at each index, plus one, so
+// zero is none.
+type storage[T any] struct {
+	dense    []T
+	entities []Entity
+	sparse   []uint32
+}
+
+// storageOf returns the storage of components of a type, making it the first time.
+func storageOf[T any](w *World) *storage[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if s, exists := w.storages[t]; exists {
+		return s.(*storage[T])
+	}
+	s := &storage[T]{}
+	w.storages[t] = s
+	return s
+}
+
+func (s *storage[T]) get(index uint32) *T {
+	if int(index) >= len(s.sparse) || s.sparse[index] == 0 {
+		return nil
+	}
+	return &s.dense[s.sparse[index]-1]
+}
+
+func (s *storage[T]) add(e Entity, component T) {
+	index := e.Index()
+	if int(index) >= len(s.sparse) {
+		s.sparse = append(s.sparse, make([]uint32, int(index)+1-len(s.sparse))...)
+	}
+	s.dense = append(s.dense, component)
+	s.entities = append(s.entities, e)
+	s.sparse[index] = uint32(len(s.dense))
+}
+
+// remove moves the last component into the place of the removed one, keeping the components dense.
+func (s *storage[T]) remove(index uint32) {
+	if int(index) >= len(s.sparse) || s.sparse[index] == 0 {
+		return
+	}
+	i, last := s.sparse[index]-1, len(s.dense)-1
+	s.dense[i] = s.dense[last]
+	s.entities[i] = s.entities[last]
+	s.sparse[s.entities[i].Index()] = i + 1
+	s.sparse[index] = 0
+
+	var zero T
+	s.dense[last] = zero
+	s.dense = s.dense[:last]
+	s.entities = s.entities[:last]
+}
+
+func (s *storage[T]) len() int {
+	return len(s.dense)
+}
@@ -0,0 +1,171 @@
+// Package ecs is an entity component system. Entities are IDs, components are plain values stored densely by
+// type, queries visit the entities with a set of components, and systems run over the world in a declared
+// order. Nothing in it needs a GL context, so the simulation and tests can use it headless.
+//
+// Structural changes, spawning and despawning entities and adding and removing components, can't be made
+// while a query is visiting entities. Systems queue them in the command buffer of the world instead, which is
+// flushed after each system.
+package ecs
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrDead is returned when changing an entity that was despawned, or never spawned.
+var ErrDead = errors.New("entity isn't alive")
+
+// Entity is the index of an entity in its world and a generation, counting how many entities have had the
+// index before. An entity that has been despawned isn't alive even after its index is reused. The zero
+// Entity is never alive.
+type Entity uint64
+
+// Index returns the index of the entity in its world.
+func (e Entity) Index() uint32 {
+	return uint32(e)
+}
+
+// Generation returns how many entities had the index before.
+func (e Entity) Generation() uint32 {
+	return uint32(e >> 32)
+}
+
+func newEntity(index, generation uint32) Entity {
+	return Entity(generation)<<32 | Entity(index)
+}
+
+// World holds entities and their components.
+type World struct {
+	// generations are the generations of the entity at each index, starting at 1.
+	generations []uint32
+	alive       []bool
+	// free are the indices of despawned entities, to reuse.
+	free     []uint32
+	storages map[reflect.Type]componentStorage
+	// iterating is how many queries are visiting entities.
+	iterating int
+	commands  Commands
+}
+
+// NewWorld returns an empty world.
+func NewWorld() *World {
+	w := &World{storages: map[reflect.Type]componentStorage{}}
+	w.commands.world = w
+	return w
+}
+
+// Spawn returns a new entity without components.
+func (w *World) Spawn() Entity {
+	e := w.reserve()
+	w.alive[e.Index()] = true
+	return e
+}
+
+// reserve returns a new entity that isn't alive yet.
+func (w *World) reserve() Entity {
+	if n := len(w.free); n > 0 {
+		index := w.free[n-1]
+		w.free = w.free[:n-1]
+		return newEntity(index, w.generations[index])
+	}
+	w.generations = append(w.generations, 1)
+	w.alive = append(w.alive, false)
+	return newEntity(uint32(len(w.generations)-1), 1)
+}
+
+// Alive reports if an entity has been spawned and not despawned.
+func (w *World) Alive(e Entity) bool {
+	index := e.Index()
+	return int(index) < len(w.generations) && w.generations[index] == e.Generation() && w.alive[index]
+}
+
+// Despawn removes an entity and its components.
+func (w *World) Despawn(e Entity) error {
+	if !w.Alive(e) {
+		return ErrDead
+	}
+	w.structuralChange()
+	index := e.Index()
+	for _, s := range w.storages {
+		s.remove(index)
+	}
+	w.alive[index] = false
+	w.generations[index]++
+	w.free = append(w.free, index)
+	return nil
+}
+
+// Len returns how many entities are alive.
+func (w *World) Len() int {
+	return len(w.generations) - len(w.free) - w.reserved()
+}
+
+// reserved returns how many entities are reserved by the command buffer and not spawned yet.
+func (w *World) reserved() int {
+	return len(w.commands.reserved)
+}
+
+// Commands returns the command buffer of the world, to make structural changes later.
+func (w *World) Commands() *Commands {
+	return &w.commands
+}
+
+// Flush makes the changes queued in the command buffer, in the order they were queued. It returns the errors
+// of changes to entities that were no longer alive.
+func (w *World) Flush() error {
+	return w.commands.flush()
+}
+
+func (w *World) structuralChange() {
+	if w.iterating > 0 {
+		panic("ecs: entities and components can't be added or removed while visiting them, use the command buffer")
+	}
+}
+
+// Add gives an entity a component, replacing the one of the same type it has.
+func Add[T any](w *World, e Entity, component T) error {
+	if !w.Alive(e) {
+		return ErrDead
+	}
+	s := storageOf[T](w)
+	if c := s.get(e.Index()); c != nil {
+		*c = component
+		return nil
+	}
+	w.structuralChange()
+	s.add(e, component)
+	return nil
+}
+
+// Remove removes the component of a type from an entity, if it has one.
+func Remove[T any](w *World, e Entity) error {
+	if !w.Alive(e) {
+		return ErrDead
+	}
+	s := storageOf[T](w)
+	if s.get(e.Index()) == nil {
+		return nil
+	}
+	w.structuralChange()
+	s.remove(e.Index())
+	return nil
+}
+
+// Get returns the component of a type of an entity, nil if it has none. The pointer is good until the next
+// structural change.
+func Get[T any](w *World, e Entity) *T {
+	if !w.Alive(e) {
+		return nil
+	}
+	return storageOf[T](w).get(e.Index())
+}
+
+// Has reports if an entity has a component of a type.
+func Has[T any](w *World, e Entity) bool {
+	return Get[T](w, e) != nil
+}
+
+// Count returns how many entities have a component of a type.
+func Count[T any](w *World) int {
+	return len(storageOf[T](w).entities)
+}
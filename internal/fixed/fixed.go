@@ -0,0 +1,342 @@
+// Package fixed is fixed-point math for the simulation. Unlike floats, which can round differently on other
+// CPUs and with other compilers, fixed-point numbers are integers and give the same results on every machine,
+// so games running in lockstep stay in step.
+package fixed
+
+//go:generate go run gen_tables.go
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Scalar is a Q32.32 fixed-point number: 32 bits of integer and 32 bits of fraction.
+type Scalar int64
+
+const (
+	fractionBits = 32
+	// tableSize is how many steps the lookup tables have, see gen_tables.go.
+	tableSize = 256
+
+	One  Scalar = 1 << fractionBits
+	Half Scalar = One / 2
+	// Pi is rounded to the nearest Scalar.
+	Pi Scalar = 13493037705
+	// Max is the largest Scalar, Div returns it when the quotient doesn't fit.
+	Max Scalar = math.MaxInt64
+	// Min is the smallest Scalar.
+	Min Scalar = math.MinInt64
+)
+
+// FromInt returns the Scalar of an integer.
+func FromInt(i int) Scalar {
+	return Scalar(i) << fractionBits
+}
+
+// FromFloat returns the Scalar closest to a float. It is exact for floats with few enough fractional bits,
+// like those read from configs, and is the same on every machine for the same float.
+func FromFloat(f float64) Scalar {
+	return Scalar(math.Round(f * float64(One)))
+}
+
+// FromFloat32 returns the Scalar closest to a float32.
+func FromFloat32(f float32) Scalar {
+	return FromFloat(float64(f))
+}
+
+// Ratio returns a / b, like 1/3, without going through a float.
+func Ratio(a, b int) Scalar {
+	return FromInt(a).Div(FromInt(b))
+}
+
+// Float returns the float closest to s, to hand results to code outside the simulation, like rendering.
+func (s Scalar) Float() float64 {
+	return float64(s) / float64(One)
+}
+
+func (s Scalar) Float32() float32 {
+	return float32(s.Float())
+}
+
+// Int returns s rounded down to an integer.
+func (s Scalar) Int() int {
+	return int(s >> fractionBits)
+}
+
+func (s Scalar) String() string {
+	return strconv.FormatFloat(s.Float(), 'f', -1, 64)
+}
+
+// Mul returns s * t, rounded to the nearest Scalar.
+func (s Scalar) Mul(t Scalar) Scalar {
+	negative := (s < 0) != (t < 0)
+	hi, lo := bits.Mul64(abs(s), abs(t))
+	// Round to nearest, halves away from zero
+	lo, carry := bits.Add64(lo, 1<<(fractionBits-1), 0)
+	hi += carry
+	product := Scalar(hi<<fractionBits | lo>>fractionBits)
+	if negative {
+		return -product
+	}
+	return product
+}
+
+// Div returns s / t, rounded towards zero. It returns Max or Min if the quotient doesn't fit, and when
+// dividing by zero.
+func (s Scalar) Div(t Scalar) Scalar {
+	negative := (s < 0) != (t < 0)
+	numerator, denominator := abs(s), abs(t)
+	hi, lo := numerator>>fractionBits, numerator<<fractionBits
+	if hi >= denominator {
+		if negative {
+			return Min
+		}
+		return Max
+	}
+	quotient, _ := bits.Div64(hi, lo, denominator)
+	if quotient > math.MaxInt64 {
+		quotient = math.MaxInt64
+	}
+	if negative {
+		return -Scalar(quotient)
+	}
+	return Scalar(quotient)
+}
+
+// Abs returns the absolute value of s.
+func (s Scalar) Abs() Scalar {
+	if s < 0 {
+		return -s
+	}
+	return s
+}
+
+// Floor returns s rounded down to an integer.
+func (s Scalar) Floor() Scalar {
+	return s &^ (One - 1)
+}
+
+// Frac returns the fraction of s, s - Floor(s).
+func (s Scalar) Frac() Scalar {
+	return s & (One - 1)
+}
+
+// Clamp returns s limited to between low and high.
+func (s Scalar) Clamp(low, high Scalar) Scalar {
+	switch {
+	case s < low:
+		return low
+	case s > high:
+		return high
+	}
+	return s
+}
+
+// Lerp returns the Scalar a fraction t of the way from s to u.
+func (s Scalar) Lerp(u, t Scalar) Scalar {
+	return s + (u - s).Mul(t)
+}
+
+// Sqrt returns the square root of s, rounded down. It is 0 for negative numbers.
+func (s Scalar) Sqrt() Scalar {
+	if s <= 0 {
+		return 0
+	}
+	// The root of s is the integer root of s * One, which has up to 96 bits
+	return Scalar(sqrt128(uint64(s)>>fractionBits, uint64(s)<<fractionBits))
+}
+
+// sqrt128 returns the integer square root of hi * 2^64 + lo, which is less than 2^95, one bit at a time from
+// the highest.
+func sqrt128(hi, lo uint64) uint64 {
+	root := uint64(0)
+	for bit := 47; bit >= 0; bit-- {
+		candidate := root | 1<<bit
+		squareHi, squareLo := bits.Mul64(candidate, candidate)
+		if squareHi < hi || squareHi == hi && squareLo <= lo {
+			root = candidate
+		}
+	}
+	return root
+}
+
+// Sin returns the sine of an angle in radians, interpolating between the entries of a lookup table.
+func Sin(angle Scalar) Scalar {
+	// The angle in steps of the table, between 0 and a full turn of 4 quarter turns
+	turn := 4 * tableSize * One
+	position := angle.Mul(FromInt(2*tableSize)).Div(Pi) % turn
+	if position < 0 {
+		position += turn
+	}
+	quarter, step, fraction := position.Int()/tableSize, position.Int()%tableSize, position.Frac()
+	if quarter%2 == 1 {
+		// The second half of each half turn mirrors the first
+		step, fraction = tableSize-step-1, One-fraction
+		if fraction == One {
+			step, fraction = step+1, 0
+		}
+	}
+	sin := sinTable[step]
+	if fraction != 0 {
+		sin = sin.Lerp(sinTable[step+1], fraction)
+	}
+	if quarter >= 2 {
+		return -sin
+	}
+	return sin
+}
+
+// Cos returns the cosine of an angle in radians.
+func Cos(angle Scalar) Scalar {
+	return Sin(angle + Pi/2)
+}
+
+// Atan2 returns the angle in radians between the x axis and the direction x, y, between -Pi and Pi.
+func Atan2(y, x Scalar) Scalar {
+	if x == 0 && y == 0 {
+		return 0
+	}
+	// Look up the angle in the first octant, where 0 <= y <= x, and turn it to the octant of the direction
+	ax, ay := x.Abs(), y.Abs()
+	swapped := ay > ax
+	if swapped {
+		ax, ay = ay, ax
+	}
+	position := ay.Div(ax).Mul(FromInt(tableSize))
+	step, fraction := position.Int(), position.Frac()
+	angle := atanTable[step]
+	if fraction != 0 {
+		angle = angle.Lerp(atanTable[step+1], fraction)
+	}
+	if swapped {
+		angle = Pi/2 - angle
+	}
+	if x < 0 {
+		angle = Pi - angle
+	}
+	if y < 0 {
+		return -angle
+	}
+	return angle
+}
+
+// Vec2 is a vector of Scalars, like mgl32.Vec2.
+type Vec2 [2]Scalar
+
+// FromVec2 returns the Vec2 closest to a float vector.
+func FromVec2(v mgl32.Vec2) Vec2 {
+	return Vec2{FromFloat32(v[0]), FromFloat32(v[1])}
+}
+
+func (v Vec2) X() Scalar { return v[0] }
+func (v Vec2) Y() Scalar { return v[1] }
+
+// Float returns the float vector closest to v.
+func (v Vec2) Float() mgl32.Vec2 {
+	return mgl32.Vec2{v[0].Float32(), v[1].Float32()}
+}
+
+func (v Vec2) Add(u Vec2) Vec2 {
+	return Vec2{v[0] + u[0], v[1] + u[1]}
+}
+
+func (v Vec2) Sub(u Vec2) Vec2 {
+	return Vec2{v[0] - u[0], v[1] - u[1]}
+}
+
+// Mul returns v scaled by s.
+func (v Vec2) Mul(s Scalar) Vec2 {
+	return Vec2{v[0].Mul(s), v[1].Mul(s)}
+}
+
+func (v Vec2) Dot(u Vec2) Scalar {
+	return v[0].Mul(u[0]) + v[1].Mul(u[1])
+}
+
+// LenSqr returns the squared length of v, which is enough to compare distances and cheaper than Len.
+func (v Vec2) LenSqr() Scalar {
+	return v.Dot(v)
+}
+
+func (v Vec2) Len() Scalar {
+	return v.LenSqr().Sqrt()
+}
+
+// Normalize returns v scaled to a length of 1, or the zero vector if v is.
+func (v Vec2) Normalize() Vec2 {
+	length := v.Len()
+	if length == 0 {
+		return Vec2{}
+	}
+	return Vec2{v[0].Div(length), v[1].Div(length)}
+}
+
+// Vec3 is a vector of Scalars, like mgl32.Vec3.
+type Vec3 [3]Scalar
+
+// FromVec3 returns the Vec3 closest to a float vector.
+func FromVec3(v mgl32.Vec3) Vec3 {
+	return Vec3{FromFloat32(v[0]), FromFloat32(v[1]), FromFloat32(v[2])}
+}
+
+func (v Vec3) X() Scalar { return v[0] }
+func (v Vec3) Y() Scalar { return v[1] }
+func (v Vec3) Z() Scalar { return v[2] }
+
+// Float returns the float vector closest to v.
+func (v Vec3) Float() mgl32.Vec3 {
+	return mgl32.Vec3{v[0].Float32(), v[1].Float32(), v[2].Float32()}
+}
+
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v[0] + u[0], v[1] + u[1], v[2] + u[2]}
+}
+
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v[0] - u[0], v[1] - u[1], v[2] - u[2]}
+}
+
+// Mul returns v scaled by s.
+func (v Vec3) Mul(s Scalar) Vec3 {
+	return Vec3{v[0].Mul(s), v[1].Mul(s), v[2].Mul(s)}
+}
+
+func (v Vec3) Dot(u Vec3) Scalar {
+	return v[0].Mul(u[0]) + v[1].Mul(u[1]) + v[2].Mul(u[2])
+}
+
+func (v Vec3) Cross(u Vec3) Vec3 {
+	return Vec3{
+		v[1].Mul(u[2]) - v[2].Mul(u[1]),
+		v[2].Mul(u[0]) - v[0].Mul(u[2]),
+		v[0].Mul(u[1]) - v[1].Mul(u[0]),
+	}
+}
+
+// LenSqr returns the squared length of v, which is enough to compare distances and cheaper than Len.
+func (v Vec3) LenSqr() Scalar {
+	return v.Dot(v)
+}
+
+func (v Vec3) Len() Scalar {
+	return v.LenSqr().Sqrt()
+}
+
+// Normalize returns v scaled to a length of 1, or the zero vector if v is.
+func (v Vec3) Normalize() Vec3 {
+	length := v.Len()
+	if length == 0 {
+		return Vec3{}
+	}
+	return Vec3{v[0].Div(length), v[1].Div(length), v[2].Div(length)}
+}
+
+func abs(s Scalar) uint64 {
+	if s < 0 {
+		return uint64(-s)
+	}
+	return uint64(s)
+}
@@ -0,0 +1,129 @@
+package fixed
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArithmetic(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		actual   Scalar
+		expected float64
+	}{
+		{desc: "from int", actual: FromInt(-3), expected: -3},
+		{desc: "from float", actual: FromFloat(2.75), expected: 2.75},
+		{desc: "ratio", actual: Ratio(1, 4), expected: 0.25},
+		{desc: "mul", actual: FromFloat(1.5).Mul(FromFloat(-2.25)), expected: -3.375},
+		{desc: "mul large", actual: FromInt(40000).Mul(FromInt(50000)), expected: 2e9},
+		{desc: "div", actual: FromFloat(-3.375).Div(FromFloat(1.5)), expected: -2.25},
+		{desc: "div by zero", actual: One.Div(0), expected: Max.Float()},
+		{desc: "div overflow", actual: FromInt(-1 << 30).Div(FromFloat(0.125)), expected: Min.Float()},
+		{desc: "floor", actual: FromFloat(-1.25).Floor(), expected: -2},
+		{desc: "frac", actual: FromFloat(-1.25).Frac(), expected: 0.75},
+		{desc: "clamp", actual: FromInt(5).Clamp(0, One), expected: 1},
+		{desc: "lerp", actual: FromInt(2).Lerp(FromInt(4), Half), expected: 3},
+		{desc: "sqrt", actual: FromInt(9).Sqrt(), expected: 3},
+		{desc: "sqrt of fraction", actual: FromFloat(0.25).Sqrt(), expected: 0.5},
+		{desc: "sqrt of large", actual: FromInt(1 << 30).Sqrt(), expected: 1 << 15},
+		{desc: "sqrt of negative", actual: FromInt(-4).Sqrt(), expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.actual.Float())
+		})
+	}
+	assert.Equal(t, -2, FromFloat(-1.25).Int())
+	assert.Equal(t, "1.5", FromFloat(1.5).String())
+	assert.InDelta(t, math.Pi, Pi.Float(), 1e-9)
+}
+
+func TestSqrt(t *testing.T) {
+	for f := 0.001; f < 1e6; f *= 1.37 {
+		s := FromFloat(f)
+		root := s.Sqrt()
+		assert.InDelta(t, math.Sqrt(s.Float()), root.Float(), 1e-9, "of %v", f)
+		// Rounded down
+		assert.LessOrEqual(t, root.Mul(root), s+One>>31)
+	}
+}
+
+func TestTrig(t *testing.T) {
+	for a := -10.0; a < 10; a += 0.01 {
+		angle := FromFloat(a)
+		assert.InDelta(t, math.Sin(a), Sin(angle).Float(), 1e-5, "sin %v", a)
+		assert.InDelta(t, math.Cos(a), Cos(angle).Float(), 1e-5, "cos %v", a)
+		x, y := math.Cos(a)*3, math.Sin(a)*3
+		assert.InDelta(t, math.Atan2(y, x), Atan2(FromFloat(y), FromFloat(x)).Float(), 1e-5, "atan2 %v, %v", y, x)
+	}
+	testCases := []struct {
+		desc     string
+		actual   Scalar
+		expected Scalar
+	}{
+		{desc: "sin 0", actual: Sin(0), expected: 0},
+		{desc: "sin of a quarter turn", actual: Sin(Pi / 2), expected: One},
+		{desc: "sin of a half turn", actual: Sin(Pi), expected: 0},
+		{desc: "cos 0", actual: Cos(0), expected: One},
+		{desc: "atan2 of x", actual: Atan2(0, One), expected: 0},
+		{desc: "atan2 of y", actual: Atan2(One, 0), expected: Pi / 2},
+		{desc: "atan2 of diagonal", actual: Atan2(-One, -One), expected: -(Pi - Pi/4)},
+		{desc: "atan2 of nothing", actual: Atan2(0, 0), expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.InDelta(t, float64(tc.expected), float64(tc.actual), 4)
+		})
+	}
+}
+
+func TestVectors(t *testing.T) {
+	v := FromVec3(mgl32.Vec3{3, 0, -4})
+	u := FromVec3(mgl32.Vec3{1, 2, 0.5})
+	assert.Equal(t, mgl32.Vec3{4, 2, -3.5}, v.Add(u).Float())
+	assert.Equal(t, mgl32.Vec3{2, -2, -4.5}, v.Sub(u).Float())
+	assert.Equal(t, mgl32.Vec3{1.5, 0, -2}, v.Mul(Half).Float())
+	assert.Equal(t, FromInt(1), v.Dot(u))
+	assert.Equal(t, FromInt(25), v.LenSqr())
+	assert.Equal(t, FromInt(5), v.Len())
+	assert.Equal(t, mgl32.Vec3{8, -5.5, 6}, v.Cross(u).Float())
+	assert.InDelta(t, 1.0, v.Normalize().Len().Float(), 1e-9)
+	assert.Equal(t, Vec3{}, Vec3{}.Normalize())
+
+	w := FromVec2(mgl32.Vec2{-6, 8})
+	assert.Equal(t, FromInt(100), w.LenSqr())
+	assert.Equal(t, mgl32.Vec2{-0.6, 0.8}, w.Normalize().Float())
+	assert.Equal(t, mgl32.Vec2{-5, 10}, w.Add(Vec2{One, 2 * One}).Float())
+	assert.Equal(t, FromInt(-6), w.X())
+
+	// Distances far across a map don't overflow
+	far := Vec3{FromInt(20000), 0, FromInt(-20000)}
+	assert.Equal(t, FromInt(800000000), far.LenSqr())
+}
+
+func TestRand(t *testing.T) {
+	r := NewRand(7)
+	first := []uint64{r.Uint64(), r.Uint64(), r.Uint64()}
+	again := NewRand(7)
+	assert.Equal(t, first, []uint64{again.Uint64(), again.Uint64(), again.Uint64()}, "same seed, same numbers")
+	assert.NotEqual(t, first[0], NewRand(8).Uint64())
+	continued := NewRand(int64(r.State()))
+	assert.Equal(t, r.Uint64(), continued.Uint64(), "continues from its state")
+
+	counts := make([]int, 6)
+	for i := 0; i < 6000; i++ {
+		n := r.Intn(6)
+		counts[n]++
+		s := r.Range(-One, FromInt(2))
+		assert.GreaterOrEqual(t, s, -One)
+		assert.Less(t, s, FromInt(2))
+		assert.Less(t, r.Angle(), 2*Pi)
+	}
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 150)
+	}
+	assert.Panics(t, func() { r.Intn(0) })
+}
@@ -0,0 +1,48 @@
+//go:build ignore
+
+// gen_tables writes the lookup tables of the trigonometric functions to tables.go, so they are the same on
+// every machine instead of computed with floats at start up.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"math"
+	"os"
+)
+
+// tableSize must match tableSize in fixed.go.
+const tableSize = 256
+
+func main() {
+	b := &bytes.Buffer{}
+	fmt.Fprintln(b, "// Code generated by gen_tables.go; DO NOT EDIT.")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "package fixed")
+	fmt.Fprintln(b)
+	table(b, "sinTable", "sin over a quarter turn", func(x float64) float64 { return math.Sin(x * math.Pi / 2) })
+	fmt.Fprintln(b)
+	table(b, "atanTable", "atan between 0 and 1", math.Atan)
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("tables.go", source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// table writes a table of f at tableSize+1 points from 0 to 1 in Q32.32.
+func table(b *bytes.Buffer, name, doc string, f func(float64) float64) {
+	fmt.Fprintf(b, "// %s is %s, at %d points.\n", name, doc, tableSize+1)
+	fmt.Fprintf(b, "var %s = [tableSize + 1]Scalar{\n", name)
+	for i := 0; i <= tableSize; i++ {
+		fmt.Fprintf(b, "%d,", int64(math.Round(f(float64(i)/tableSize)*(1<<32))))
+		if i%6 == 5 {
+			fmt.Fprintln(b)
+		}
+	}
+	fmt.Fprintln(b, "}")
+}
@@ -0,0 +1,54 @@
+package fixed
+
+import "math/bits"
+
+// Rand is a pseudo-random number generator giving the same numbers for the same seed on every machine, unlike
+// math/rand whose algorithm may change between Go versions. It is splitmix64.
+type Rand struct {
+	state uint64
+}
+
+// NewRand creates a generator from a seed.
+func NewRand(seed int64) *Rand {
+	return &Rand{state: uint64(seed)}
+}
+
+// State returns the state of the generator, NewRand(int64(State())) continues where it left off.
+func (r *Rand) State() uint64 {
+	return r.state
+}
+
+// Uint64 returns a random number from all 64 bit numbers.
+func (r *Rand) Uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// Intn returns a random number from 0 up to n. It panics if n <= 0.
+func (r *Rand) Intn(n int) int {
+	if n <= 0 {
+		panic("fixed: Intn of a number <= 0")
+	}
+	// The high bits of a 64 bit number times n are evenly spread over 0 to n, close enough for a game
+	hi, _ := bits.Mul64(r.Uint64(), uint64(n))
+	return int(hi)
+}
+
+// Scalar returns a random Scalar from 0 up to 1.
+func (r *Rand) Scalar() Scalar {
+	return Scalar(r.Uint64() >> (64 - fractionBits))
+}
+
+// Range returns a random Scalar from low up to high, which must not be less than low.
+func (r *Rand) Range(low, high Scalar) Scalar {
+	offset, _ := bits.Mul64(uint64(high-low), r.Uint64())
+	return low + Scalar(offset)
+}
+
+// Angle returns a random angle in radians, from 0 up to 2 Pi.
+func (r *Rand) Angle() Scalar {
+	return r.Range(0, 2*Pi)
+}
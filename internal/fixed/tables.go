@@ -0,0 +1,95 @@
+// Code generated by gen_tables.go; DO NOT EDIT.
+
+package fixed
+
+// sinTable is sin over a quarter turn, at 257 points.
+var sinTable = [tableSize + 1]Scalar{
+	0, 26353424, 52705856, 79056303, 105403774, 131747276,
+	158085819, 184418409, 210744057, 237061769, 263370557, 289669429,
+	315957395, 342233465, 368496651, 394745962, 420980412, 447199012,
+	473400776, 499584716, 525749847, 551895183, 578019742, 604122538,
+	630202589, 656258914, 682290530, 708296459, 734275721, 760227338,
+	786150333, 812043729, 837906553, 863737830, 889536587, 915301854,
+	941032661, 966728038, 992387019, 1018008636, 1043591926, 1069135926,
+	1094639673, 1120102207, 1145522571, 1170899806, 1196232957, 1221521071,
+	1246763195, 1271958380, 1297105676, 1322204136, 1347252816, 1372250773,
+	1397197066, 1422090755, 1446930903, 1471716574, 1496446837, 1521120759,
+	1545737412, 1570295869, 1594795204, 1619234497, 1643612827, 1667929275,
+	1692182927, 1716372869, 1740498191, 1764557983, 1788551342, 1812477362,
+	1836335144, 1860123788, 1883842400, 1907490086, 1931065957, 1954569124,
+	1977998702, 2001353810, 2024633568, 2047837100, 2070963532, 2094011993,
+	2116981616, 2139871536, 2162680890, 2185408821, 2208054473, 2230616993,
+	2253095531, 2275489241, 2297797281, 2320018810, 2342152991, 2364198992,
+	2386155981, 2408023134, 2429799626, 2451484637, 2473077351, 2494576955,
+	2515982640, 2537293599, 2558509031, 2579628136, 2600650120, 2621574191,
+	2642399561, 2663125446, 2683751066, 2704275644, 2724698408, 2745018589,
+	2765235421, 2785348143, 2805355999, 2825258235, 2845054101, 2864742853,
+	2884323748, 2903796051, 2923159027, 2942411948, 2961554089, 2980584729,
+	2999503152, 3018308645, 3037000500, 3055578014, 3074040487, 3092387225,
+	3110617535, 3128730733, 3146726136, 3164603066, 3182360851, 3199998822,
+	3217516315, 3234912670, 3252187232, 3269339351, 3286368382, 3303273682,
+	3320054617, 3336710553, 3353240863, 3369644927, 3385922125, 3402071844,
+	3418093478, 3433986423, 3449750080, 3465383855, 3480887161, 3496259414,
+	3511500034, 3526608449, 3541584088, 3556426389, 3571134792, 3585708745,
+	3600147697, 3614451106, 3628618433, 3642649144, 3656542712, 3670298613,
+	3683916329, 3697395348, 3710735162, 3723935269, 3736995171, 3749914379,
+	3762692404, 3775328765, 3787822988, 3800174601, 3812383140, 3824448145,
+	3836369162, 3848145741, 3859777440, 3871263820, 3882604450, 3893798902,
+	3904846754, 3915747591, 3926501002, 3937106583, 3947563934, 3957872662,
+	3968032378, 3978042699, 3987903250, 3997613658, 4007173558, 4016582591,
+	4025840401, 4034946641, 4043900968, 4052703044, 4061352537, 4069849124,
+	4078192482, 4086382299, 4094418266, 4102300081, 4110027446, 4117600071,
+	4125017671, 4132279966, 4139386683, 4146337555, 4153132319, 4159770720,
+	4166252509, 4172577440, 4178745276, 4184755784, 4190608739, 4196303920,
+	4201841112, 4207220108, 4212440704, 4217502704, 4222405917, 4227150159,
+	4231735252, 4236161021, 4240427302, 4244533933, 4248480760, 4252267634,
+	4255894413, 4259360959, 4262667143, 4265812840, 4268797931, 4271622305,
+	4274285855, 4276788480, 4279130086, 4281310585, 4283329896, 4285187942,
+	4286884652, 4288419964, 4289793820, 4291006167, 4292056960, 4292946160,
+	4293673732, 4294239650, 4294643893, 4294886444, 4294967296}
+
+// atanTable is atan between 0 and 1, at 257 points.
+var atanTable = [tableSize + 1]Scalar{
+	0, 16777131, 33553749, 50329344, 67103403, 83875416,
+	100644870, 117411256, 134174063, 150932782, 167686905, 184435923,
+	201179330, 217916620, 234647289, 251370832, 268086748, 284794535,
+	301493695, 318183730, 334864142, 351534439, 368194128, 384842717,
+	401479718, 418104644, 434717012, 451316338, 467902142, 484473948,
+	501031280, 517573666, 534100635, 550611720, 567106458, 583584386,
+	600045046, 616487982, 632912742, 649318876, 665705938, 682073484,
+	698421076, 714748276, 731054652, 747339775, 763603219, 779844561,
+	796063384, 812259271, 828431813, 844580602, 860705235, 876805312,
+	892880439, 908930223, 924954277, 940952219, 956923669, 972868252,
+	988785598, 1004675341, 1020537117, 1036370570, 1052175346, 1067951097,
+	1083697476, 1099414145, 1115100767, 1130757012, 1146382553, 1161977066,
+	1177540236, 1193071749, 1208571296, 1224038573, 1239473281, 1254875126,
+	1270243818, 1285579071, 1300880604, 1316148142, 1331381413, 1346580150,
+	1361744091, 1376872979, 1391966562, 1407024590, 1422046821, 1437033016,
+	1451982941, 1466896367, 1481773068, 1496612825, 1511415421, 1526180647,
+	1540908296, 1555598165, 1570250058, 1584863782, 1599439150, 1613975976,
+	1628474083, 1642933296, 1657353445, 1671734364, 1686075891, 1700377871,
+	1714640149, 1728862579, 1743045016, 1757187321, 1771289359, 1785350998,
+	1799372113, 1813352579, 1827292279, 1841191098, 1855048926, 1868865657,
+	1882641189, 1896375424, 1910068267, 1923719628, 1937329421, 1950897563,
+	1964423976, 1977908584, 1991351318, 2004752108, 2018110892, 2031427610,
+	2044702204, 2057934623, 2071124817, 2084272740, 2097378349, 2110441607,
+	2123462476, 2136440925, 2149376926, 2162270452, 2175121481, 2187929994,
+	2200695975, 2213419410, 2226100291, 2238738610, 2251334363, 2263887549,
+	2276398171, 2288866234, 2301291744, 2313674713, 2326015154, 2338313083,
+	2350568518, 2362781481, 2374951997, 2387080090, 2399165791, 2411209131,
+	2423210143, 2435168865, 2447085334, 2458959593, 2470791683, 2482581652,
+	2494329546, 2506035415, 2517699312, 2529321291, 2540901408, 2552439722,
+	2563936292, 2575391182, 2586804454, 2598176176, 2609506416, 2620795242,
+	2632042727, 2643248943, 2654413966, 2665537873, 2676620741, 2687662651,
+	2698663683, 2709623922, 2720543452, 2731422358, 2742260728, 2753058651,
+	2763816217, 2774533518, 2785210647, 2795847698, 2806444766, 2817001948,
+	2827519342, 2837997048, 2848435164, 2858833794, 2869193038, 2879513001,
+	2889793788, 2900035502, 2910238253, 2920402145, 2930527289, 2940613793,
+	2950661767, 2960671322, 2970642571, 2980575625, 2990470599, 3000327606,
+	3010146761, 3019928180, 3029671979, 3039378274, 3049047184, 3058678827,
+	3068273321, 3077830785, 3087351340, 3096835105, 3106282202, 3115692753,
+	3125066878, 3134404700, 3143706342, 3152971927, 3162201579, 3171395421,
+	3180553577, 3189676173, 3198763333, 3207815182, 3216831846, 3225813450,
+	3234760121, 3243671984, 3252549166, 3261391795, 3270199995, 3278973896,
+	3287713623, 3296419304, 3305091067, 3313729038, 3322333347, 3330904120,
+	3339441485, 3347945570, 3356416503, 3364854413, 3373259426}
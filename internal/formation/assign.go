@@ -0,0 +1,109 @@
+package formation
+
+import (
+	"sort"
+
+	"game-engine/rts/internal/fixed"
+)
+
+// Hungarian solves the assignment problem for a square cost matrix with the Hungarian algorithm, in O(n³). It
+// returns the column assigned to each row, so that the sum of cost[i][assigned[i]] is as small as possible.
+func Hungarian(cost [][]fixed.Scalar) []int {
+	n := len(cost)
+	// Potentials of rows and columns, and the row matched to each column. Indices start at 1, column 0 is the
+	// row being added.
+	u := make([]fixed.Scalar, n+1)
+	v := make([]fixed.Scalar, n+1)
+	match := make([]int, n+1)
+	way := make([]int, n+1)
+	for row := 1; row <= n; row++ {
+		match[0] = row
+		column := 0
+		minimum := make([]fixed.Scalar, n+1)
+		used := make([]bool, n+1)
+		for j := range minimum {
+			minimum[j] = fixed.Max
+		}
+		// Grow an alternating path from the new row until it reaches a free column
+		for match[column] != 0 {
+			used[column] = true
+			i := match[column]
+			delta, next := fixed.Max, 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if reduced := cost[i-1][j-1] - u[i] - v[j]; reduced < minimum[j] {
+					minimum[j] = reduced
+					way[j] = column
+				}
+				if minimum[j] < delta {
+					delta, next = minimum[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minimum[j] -= delta
+				}
+			}
+			column = next
+		}
+		// Flip the path
+		for column != 0 {
+			previous := way[column]
+			match[column] = match[previous]
+			column = previous
+		}
+	}
+
+	assigned := make([]int, n)
+	for j := 1; j <= n; j++ {
+		assigned[match[j]-1] = j - 1
+	}
+	return assigned
+}
+
+// Greedy approximates the assignment problem for a square cost matrix by matching the cheapest free row and
+// column first, in O(n² log n). Ties go to the lowest row, then column, so the result is always the same.
+func Greedy(cost [][]fixed.Scalar) []int {
+	type pair struct {
+		cost        fixed.Scalar
+		row, column int
+	}
+	n := len(cost)
+	pairs := make([]pair, 0, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			pairs = append(pairs, pair{cost[i][j], i, j})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].cost != pairs[b].cost {
+			return pairs[a].cost < pairs[b].cost
+		}
+		if pairs[a].row != pairs[b].row {
+			return pairs[a].row < pairs[b].row
+		}
+		return pairs[a].column < pairs[b].column
+	})
+
+	assigned := make([]int, n)
+	rowDone := make([]bool, n)
+	columnDone := make([]bool, n)
+	left := n
+	for _, p := range pairs {
+		if left == 0 {
+			break
+		}
+		if rowDone[p.row] || columnDone[p.column] {
+			continue
+		}
+		assigned[p.row] = p.column
+		rowDone[p.row], columnDone[p.column] = true, true
+		left--
+	}
+	return assigned
+}
@@ -0,0 +1,186 @@
+// Package formation arranges groups of units into formations, and picks which unit goes to which slot.
+//
+// Slots are laid out in the local frame of the formation, where Y points the way the formation faces and X to
+// its right, and then turned to face the way the group is going. Slots are computed in fixed point, so every
+// machine sends each unit to the same place.
+package formation
+
+import (
+	"game-engine/rts/internal/fixed"
+)
+
+// Shape is how the units of a formation are laid out.
+type Shape int
+
+const (
+	// Line puts units side by side.
+	Line Shape = iota
+	// Column puts units one behind the other.
+	Column
+	// Box puts units in rows about as wide as the box is deep.
+	Box
+	// Wedge puts one unit in front and the others in a V behind it.
+	Wedge
+)
+
+func (s Shape) String() string {
+	switch s {
+	case Line:
+		return "line"
+	case Column:
+		return "column"
+	case Box:
+		return "box"
+	case Wedge:
+		return "wedge"
+	}
+	return "unknown"
+}
+
+// Assignment is how units are given their slots.
+type Assignment int
+
+const (
+	// HungarianAssignment finds the slots that minimise the total distance the units travel.
+	HungarianAssignment Assignment = iota
+	// GreedyAssignment gives the closest unit and slot to each other first, which is faster for large
+	// groups but can make units travel further.
+	GreedyAssignment
+)
+
+// Formation is a template for arranging groups of units.
+type Formation struct {
+	Shape Shape
+	// Spacing is the distance between neighbouring slots.
+	Spacing    float32
+	Assignment Assignment
+}
+
+// Offsets returns the slots of n units in the local frame of the formation. The first slot is at the front in
+// the middle, at the origin, the others get further from it.
+func (f Formation) Offsets(n int) []fixed.Vec2 {
+	spacing := fixed.FromFloat32(f.Spacing)
+	offsets := make([]fixed.Vec2, n)
+	for i := range offsets {
+		switch f.Shape {
+		case Line:
+			offsets[i] = fixed.Vec2{centerOut(i), 0}
+		case Column:
+			offsets[i] = fixed.Vec2{0, fixed.FromInt(-i)}
+		case Box:
+			width := 1
+			for width*width < n {
+				width++
+			}
+			offsets[i] = fixed.Vec2{centerOut(i % width), fixed.FromInt(-(i / width))}
+		case Wedge:
+			row := fixed.FromInt((i + 1) / 2)
+			if i%2 == 1 {
+				offsets[i] = fixed.Vec2{-row, -row}
+			} else {
+				offsets[i] = fixed.Vec2{row, -row}
+			}
+		}
+		offsets[i] = offsets[i].Mul(spacing)
+	}
+	return offsets
+}
+
+// Slots returns the slots of n units in the world, with the formation centered on center and facing the way
+// of facing.
+func (f Formation) Slots(n int, center, facing fixed.Vec2) []fixed.Vec2 {
+	offsets := f.Offsets(n)
+	middle := Center(offsets)
+	for i, offset := range offsets {
+		offsets[i] = center.Add(Rotate(offset.Sub(middle), facing))
+	}
+	return offsets
+}
+
+// FollowSlots returns the slots of n units in the world, with the first slot on the leader and the formation
+// facing the way of facing.
+func (f Formation) FollowSlots(n int, leader, facing fixed.Vec2) []fixed.Vec2 {
+	offsets := f.Offsets(n)
+	for i, offset := range offsets {
+		offsets[i] = leader.Add(Rotate(offset, facing))
+	}
+	return offsets
+}
+
+// Assign returns which slot each unit goes to, so that unit i goes to slots[assigned[i]]. There must be as
+// many slots as units.
+func (f Formation) Assign(units, slots []fixed.Vec2) []int {
+	cost := make([][]fixed.Scalar, len(units))
+	for i, unit := range units {
+		cost[i] = make([]fixed.Scalar, len(slots))
+		for j, slot := range slots {
+			cost[i][j] = unit.Sub(slot).Len()
+		}
+	}
+	if f.Assignment == GreedyAssignment {
+		return Greedy(cost)
+	}
+	return Hungarian(cost)
+}
+
+// Arrange returns where each unit should go for the group to arrive at destination in formation, facing the
+// way from the center of the group to the destination.
+func (f Formation) Arrange(units []fixed.Vec2, destination fixed.Vec2) []fixed.Vec2 {
+	slots := f.Slots(len(units), destination, Facing(units, destination))
+	targets := make([]fixed.Vec2, len(units))
+	for i, slot := range f.Assign(units, slots) {
+		targets[i] = slots[slot]
+	}
+	return targets
+}
+
+// Facing returns the direction from the center of the units to the destination. It faces forward along Y
+// when the units are already centered on the destination.
+func Facing(units []fixed.Vec2, destination fixed.Vec2) fixed.Vec2 {
+	direction := destination.Sub(Center(units))
+	if direction.LenSqr() == 0 {
+		return fixed.Vec2{0, fixed.One}
+	}
+	return direction.Normalize()
+}
+
+// Center returns the average of the points.
+func Center(points []fixed.Vec2) fixed.Vec2 {
+	center := fixed.Vec2{}
+	if len(points) == 0 {
+		return center
+	}
+	for _, p := range points {
+		center = center.Add(p)
+	}
+	n := fixed.FromInt(len(points))
+	return fixed.Vec2{center.X().Div(n), center.Y().Div(n)}
+}
+
+// Rotate turns an offset in the local frame of a formation to face the way of facing, which must be a unit
+// vector.
+func Rotate(offset, facing fixed.Vec2) fixed.Vec2 {
+	right := fixed.Vec2{facing.Y(), -facing.X()}
+	return right.Mul(offset.X()).Add(facing.Mul(offset.Y()))
+}
+
+// centerOut returns the position of the i-th unit in a row filled from the middle outwards, alternating
+// sides: 0, -1, 1, -2, 2...
+func centerOut(i int) fixed.Scalar {
+	side := fixed.FromInt((i + 1) / 2)
+	if i%2 == 1 {
+		return -side
+	}
+	return side
+}
+
+// headingEpsilon is how slow a unit can go before its heading is no longer trusted.
+var headingEpsilon = fixed.FromFloat(1e-4)
+
+// Heading returns the direction of a velocity, or fallback when the unit is barely moving.
+func Heading(velocity, fallback fixed.Vec2) fixed.Vec2 {
+	if velocity.Len() > headingEpsilon {
+		return velocity.Normalize()
+	}
+	return fallback
+}
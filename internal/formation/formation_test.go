@@ -0,0 +1,196 @@
+package formation
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// points converts points to fixed point.
+func points(ps ...mgl32.Vec2) []fixed.Vec2 {
+	converted := make([]fixed.Vec2, len(ps))
+	for i, p := range ps {
+		converted[i] = fixed.FromVec2(p)
+	}
+	return converted
+}
+
+func TestOffsets(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		shape    Shape
+		expected []mgl32.Vec2
+	}{
+		{desc: "line", shape: Line, expected: []mgl32.Vec2{{0, 0}, {-2, 0}, {2, 0}, {-4, 0}, {4, 0}}},
+		{desc: "column", shape: Column, expected: []mgl32.Vec2{{0, 0}, {0, -2}, {0, -4}, {0, -6}, {0, -8}}},
+		{desc: "box", shape: Box, expected: []mgl32.Vec2{{0, 0}, {-2, 0}, {2, 0}, {0, -2}, {-2, -2}}},
+		{desc: "wedge", shape: Wedge, expected: []mgl32.Vec2{{0, 0}, {-2, -2}, {2, -2}, {-4, -4}, {4, -4}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := Formation{Shape: tc.shape, Spacing: 2.0}
+			assert.Equal(t, points(tc.expected...), f.Offsets(5))
+			assert.Equal(t, tc.desc, tc.shape.String())
+		})
+	}
+}
+
+func TestSlotsFaceTheWayOfTravel(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		facing mgl32.Vec2
+		// expected are the slots of a line of three
+		expected []mgl32.Vec2
+	}{
+		{desc: "north", facing: mgl32.Vec2{0, 1}, expected: []mgl32.Vec2{{5, 5}, {4, 5}, {6, 5}}},
+		{desc: "east", facing: mgl32.Vec2{1, 0}, expected: []mgl32.Vec2{{5, 5}, {5, 6}, {5, 4}}},
+		{desc: "south", facing: mgl32.Vec2{0, -1}, expected: []mgl32.Vec2{{5, 5}, {6, 5}, {4, 5}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := Formation{Shape: Line, Spacing: 1.0}
+			slots := f.Slots(3, fixed.FromVec2(mgl32.Vec2{5, 5}), fixed.FromVec2(tc.facing))
+			for i := range tc.expected {
+				assert.InDelta(t, 0, slots[i].Float().Sub(tc.expected[i]).Len(), 1e-5, "slot %d is %v", i, slots[i])
+			}
+		})
+	}
+
+	// A column going east has its first unit furthest east
+	column := Formation{Shape: Column, Spacing: 1.0}.Slots(3, fixed.Vec2{}, fixed.Vec2{fixed.One, 0})
+	assert.Equal(t, fixed.One, column[0].X())
+	assert.Equal(t, -fixed.One, column[2].X())
+
+	// Following puts the leader on the first slot
+	follow := Formation{Shape: Wedge, Spacing: 1.0}.FollowSlots(3, fixed.FromVec2(mgl32.Vec2{2, 2}), fixed.Vec2{0, fixed.One})
+	assert.Equal(t, points(mgl32.Vec2{2, 2}, mgl32.Vec2{1, 1}, mgl32.Vec2{3, 1}), follow)
+}
+
+func TestSlotsAreSpacedApart(t *testing.T) {
+	for _, shape := range []Shape{Line, Column, Box, Wedge} {
+		for n := 1; n <= 20; n++ {
+			f := Formation{Shape: shape, Spacing: 0.5}
+			slots := f.Slots(n, fixed.FromVec2(mgl32.Vec2{1, -3}), fixed.FromVec2(mgl32.Vec2{0.6, 0.8}).Normalize())
+			assert.InDelta(t, 0, Center(slots).Float().Sub(mgl32.Vec2{1, -3}).Len(), 1e-4, "%s of %d is centered", shape, n)
+			for i := range slots {
+				for j := i + 1; j < len(slots); j++ {
+					assert.GreaterOrEqual(t, slots[i].Sub(slots[j]).Len().Float32(), float32(0.5)-1e-4, "%s of %d, slots %d and %d", shape, n, i, j)
+				}
+			}
+		}
+	}
+}
+
+// bruteForce returns the lowest total cost of any assignment.
+func bruteForce(cost [][]fixed.Scalar) fixed.Scalar {
+	n := len(cost)
+	best := fixed.Scalar(-1)
+	columns := make([]int, n)
+	for i := range columns {
+		columns[i] = i
+	}
+	var permute func(k int, total fixed.Scalar)
+	permute = func(k int, total fixed.Scalar) {
+		if k == n {
+			if best < 0 || total < best {
+				best = total
+			}
+			return
+		}
+		for i := k; i < n; i++ {
+			columns[k], columns[i] = columns[i], columns[k]
+			permute(k+1, total+cost[k][columns[k]])
+			columns[k], columns[i] = columns[i], columns[k]
+		}
+	}
+	permute(0, 0)
+	return best
+}
+
+func totalCost(cost [][]fixed.Scalar, assigned []int) fixed.Scalar {
+	total := fixed.Scalar(0)
+	for i, j := range assigned {
+		total += cost[i][j]
+	}
+	return total
+}
+
+func TestAssignment(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n <= 7; n++ {
+		for run := 0; run < 20; run++ {
+			cost := make([][]fixed.Scalar, n)
+			for i := range cost {
+				cost[i] = make([]fixed.Scalar, n)
+				for j := range cost[i] {
+					cost[i][j] = fixed.FromInt(random.Intn(20))
+				}
+			}
+			optimal := fixed.Scalar(0)
+			if n > 0 {
+				optimal = bruteForce(cost)
+			}
+
+			hungarian := Hungarian(cost)
+			assert.ElementsMatch(t, permutation(n), hungarian, "every column once")
+			assert.Equal(t, optimal, totalCost(cost, hungarian), "%v", cost)
+
+			greedy := Greedy(cost)
+			assert.ElementsMatch(t, permutation(n), greedy, "every column once")
+			assert.GreaterOrEqual(t, totalCost(cost, greedy), optimal)
+		}
+	}
+}
+
+func permutation(n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	return p
+}
+
+func TestArrangeDoesNotCrossPaths(t *testing.T) {
+	// Two units side by side going north keep their sides
+	units := points(mgl32.Vec2{-1, 0}, mgl32.Vec2{1, 0})
+	for _, assignment := range []Assignment{HungarianAssignment, GreedyAssignment} {
+		targets := Formation{Shape: Line, Spacing: 1.0, Assignment: assignment}.Arrange(units, fixed.FromVec2(mgl32.Vec2{0, 10}))
+		assert.Less(t, targets[0].X(), targets[1].X())
+	}
+
+	// Greedy takes the slot closest to anyone first, even if it leaves another unit far from its slot
+	units = points(mgl32.Vec2{0, 0}, mgl32.Vec2{1.9, 0})
+	slots := points(mgl32.Vec2{1, 0}, mgl32.Vec2{3, 0})
+	assert.Equal(t, []int{0, 1}, Formation{}.Assign(units, slots))
+	assert.Equal(t, []int{1, 0}, Formation{Assignment: GreedyAssignment}.Assign(units, slots))
+}
+
+func BenchmarkAssignment(b *testing.B) {
+	methods := []struct {
+		desc       string
+		assignment Assignment
+	}{
+		{desc: "hungarian", assignment: HungarianAssignment},
+		{desc: "greedy", assignment: GreedyAssignment},
+	}
+	for _, size := range []int{100, 400} {
+		random := rand.New(rand.NewSource(1))
+		units := make([]fixed.Vec2, size)
+		for i := range units {
+			units[i] = fixed.FromVec2(mgl32.Vec2{random.Float32() * 50, random.Float32() * 50})
+		}
+		for _, m := range methods {
+			f := Formation{Shape: Box, Spacing: 1.0, Assignment: m.assignment}
+			b.Run(fmt.Sprintf("%s/%d", m.desc, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					f.Arrange(units, fixed.FromVec2(mgl32.Vec2{100, 100}))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownState is returned when referring to a state that has not been added to the FSM.
+	ErrUnknownState = errors.New("unknown state")
+	// ErrDuplicateState is returned when adding a state with an ID that is already in use.
+	ErrDuplicateState = errors.New("state already exists")
+	// ErrIllegalTransition is returned when no transition has been declared between two states.
+	ErrIllegalTransition = errors.New("illegal transition")
+	// ErrGuardRejected is returned when transitions exist but all of their guards rejected the change.
+	ErrGuardRejected = errors.New("transition rejected by guard")
+	// ErrNotStarted is returned when changing state before the FSM has been started.
+	ErrNotStarted = errors.New("fsm not started")
+	// ErrAlreadyStarted is returned when starting an FSM that is already running.
+	ErrAlreadyStarted = errors.New("fsm already started")
+)
+
+// TransitionError describes a failed state change. Use errors.Is to check the reason.
+type TransitionError struct {
+	From StateID
+	To   StateID
+	Err  error
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("can't change state from %q to %q: %v", e.From, e.To, e.Err)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
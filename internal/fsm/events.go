@@ -0,0 +1,36 @@
+package fsm
+
+// Event is sent to an FSM to let the active states react to something that happened.
+type Event string
+
+// Handler reacts to an event. It returns false if the event was not handled, letting the parent state
+// handle it instead.
+type Handler func() bool
+
+// On sets the handler for an event while the state, or one of its descendants, is active. Use AnyState to
+// handle events no matter which state is active.
+func (fsm *FSM) On(id StateID, event Event, handler Handler) {
+	if fsm.handlers[id] == nil {
+		fsm.handlers[id] = map[Event]Handler{}
+	}
+	fsm.handlers[id][event] = handler
+}
+
+// Send lets the active states handle an event, starting with the current leaf state and bubbling up to
+// its ancestors until it is handled. Handlers for AnyState are tried last. Send reports if the event was
+// handled.
+func (fsm *FSM) Send(event Event) bool {
+	if !fsm.started {
+		return false
+	}
+
+	for id := fsm.current; id != ""; id = fsm.parents[id] {
+		if handler, exists := fsm.handlers[id][event]; exists && handler() {
+			return true
+		}
+	}
+	if handler, exists := fsm.handlers[AnyState][event]; exists && handler() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package fsm
+
+import "fmt"
+
+// StateID identifies a state within an FSM.
+type StateID string
+
+// AnyState can be used as the source of a transition to allow it from every state.
+const AnyState StateID = "*"
+
+// DefaultHistorySize is the number of previous states remembered by an FSM created with New.
+const DefaultHistorySize = 16
+
+// Guard decides if a transition is allowed to be taken. A nil Guard always allows the transition.
+type Guard func() bool
+
+type transition struct {
+	to    StateID
+	guard Guard
+}
+
+// FSM is a hierarchical finite state machine where only declared transitions are allowed.
+//
+// States added with AddState are top level states, AddSubState nests a state within a parent. The FSM is
+// always in a leaf state, and all of its ancestors are active as well. Changing to a parent state enters
+// its initial child, or the last active child if the parent uses HistoryShallow.
+type FSM struct {
+	states      map[StateID]State
+	transitions map[StateID][]transition
+
+	parents     map[StateID]StateID
+	children    map[StateID][]StateID
+	initial     map[StateID]StateID
+	historyMode map[StateID]HistoryMode
+	lastChild   map[StateID]StateID
+	handlers    map[StateID]map[Event]Handler
+
+	current StateID
+	started bool
+	// changes is incremented on every state change, used to detect changes made while running states.
+	changes uint64
+	chain   []StateID
+
+	history     []StateID
+	historySize int
+}
+
+// New creates an empty FSM that remembers up to DefaultHistorySize previous states.
+func New() *FSM {
+	return &FSM{
+		states:      map[StateID]State{},
+		transitions: map[StateID][]transition{},
+		parents:     map[StateID]StateID{},
+		children:    map[StateID][]StateID{},
+		initial:     map[StateID]StateID{},
+		historyMode: map[StateID]HistoryMode{},
+		lastChild:   map[StateID]StateID{},
+		handlers:    map[StateID]map[Event]Handler{},
+		historySize: DefaultHistorySize,
+	}
+}
+
+// SetHistorySize changes how many previous states are remembered, dropping the oldest if needed.
+func (fsm *FSM) SetHistorySize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	fsm.historySize = size
+	if len(fsm.history) > size {
+		fsm.history = fsm.history[len(fsm.history)-size:]
+	}
+}
+
+// AddState registers a top level state under the given ID.
+func (fsm *FSM) AddState(id StateID, state State) error {
+	if id == AnyState || id == "" {
+		return fmt.Errorf("invalid state id %q", id)
+	}
+	if _, exists := fsm.states[id]; exists {
+		return fmt.Errorf("%q: %w", id, ErrDuplicateState)
+	}
+	fsm.states[id] = state
+	return nil
+}
+
+// AddTransition declares that the FSM may change from one state to another. Use AnyState as from to allow
+// the transition from every state. Several transitions between the same states may be declared, the change
+// is allowed if any of their guards pass. A transition from a parent state is allowed from all of its
+// descendants.
+func (fsm *FSM) AddTransition(from, to StateID, guard Guard) error {
+	if _, exists := fsm.states[from]; !exists && from != AnyState {
+		return fmt.Errorf("transition from %q: %w", from, ErrUnknownState)
+	}
+	if _, exists := fsm.states[to]; !exists {
+		return fmt.Errorf("transition to %q: %w", to, ErrUnknownState)
+	}
+	fsm.transitions[from] = append(fsm.transitions[from], transition{to: to, guard: guard})
+	return nil
+}
+
+// Start enters the initial state. No transition is needed to enter it.
+func (fsm *FSM) Start(initial StateID) error {
+	if fsm.started {
+		return &TransitionError{From: fsm.current, To: initial, Err: ErrAlreadyStarted}
+	}
+	if _, exists := fsm.states[initial]; !exists {
+		return &TransitionError{To: initial, Err: ErrUnknownState}
+	}
+	fsm.started = true
+	fsm.enter("", initial)
+	return nil
+}
+
+// Run updates all active states, from the top level state down to the current leaf state. If a state
+// changes the state of the FSM, states further down the old hierarchy are not updated.
+func (fsm *FSM) Run(dt float32) {
+	if !fsm.started {
+		return
+	}
+
+	fsm.chain = fsm.activeStates(fsm.chain[:0])
+	changes := fsm.changes
+	for _, id := range fsm.chain {
+		fsm.states[id].OnUpdate(dt)
+		if fsm.changes != changes {
+			return
+		}
+	}
+}
+
+// CanChangeState reports if a change to the given state would currently be allowed.
+func (fsm *FSM) CanChangeState(to StateID) bool {
+	return fsm.checkTransition(to) == nil
+}
+
+// ChangeState leaves the current state and enters the new one. The change is rejected with a
+// *TransitionError if it has not been declared or if all guards reject it.
+//
+// States are left from the current leaf up to, but not including, the closest common ancestor of the
+// current state and the new state. The new state is then entered from below that ancestor and down to a
+// leaf state.
+func (fsm *FSM) ChangeState(to StateID) error {
+	if err := fsm.checkTransition(to); err != nil {
+		return &TransitionError{From: fsm.current, To: to, Err: err}
+	}
+
+	previous := fsm.current
+
+	// Strict ancestors of the target are kept active if they are already active
+	keep := map[StateID]bool{}
+	for id := fsm.parents[to]; id != ""; id = fsm.parents[id] {
+		keep[id] = true
+	}
+
+	id := fsm.current
+	for ; id != "" && !keep[id]; id = fsm.parents[id] {
+		fsm.states[id].OnLeave()
+		if parent := fsm.parents[id]; parent != "" {
+			fsm.lastChild[parent] = id
+		}
+	}
+
+	fsm.pushHistory(previous)
+	fsm.changes++
+	fsm.enter(id, to)
+	return nil
+}
+
+// Current returns the ID of the active leaf state.
+func (fsm *FSM) Current() StateID {
+	return fsm.current
+}
+
+// CurrentState returns the active leaf state.
+func (fsm *FSM) CurrentState() State {
+	return fsm.states[fsm.current]
+}
+
+// IsActive reports if the state is the current state or one of its ancestors.
+func (fsm *FSM) IsActive(id StateID) bool {
+	if !fsm.started {
+		return false
+	}
+	for active := fsm.current; active != ""; active = fsm.parents[active] {
+		if active == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveStates returns the active states, from the top level state down to the current leaf state.
+func (fsm *FSM) ActiveStates() []StateID {
+	if !fsm.started {
+		return nil
+	}
+	return fsm.activeStates(nil)
+}
+
+// Previous returns the leaf state that was active before the current one.
+func (fsm *FSM) Previous() (StateID, bool) {
+	if len(fsm.history) == 0 {
+		return "", false
+	}
+	return fsm.history[len(fsm.history)-1], true
+}
+
+// History returns the previously active leaf states, oldest first.
+func (fsm *FSM) History() []StateID {
+	history := make([]StateID, len(fsm.history))
+	copy(history, fsm.history)
+	return history
+}
+
+func (fsm *FSM) checkTransition(to StateID) error {
+	if !fsm.started {
+		return ErrNotStarted
+	}
+	if _, exists := fsm.states[to]; !exists {
+		return ErrUnknownState
+	}
+
+	declared := false
+	check := func(from StateID) bool {
+		for _, t := range fsm.transitions[from] {
+			if t.to != to {
+				continue
+			}
+			declared = true
+			if t.guard == nil || t.guard() {
+				return true
+			}
+		}
+		return false
+	}
+
+	for from := fsm.current; from != ""; from = fsm.parents[from] {
+		if check(from) {
+			return nil
+		}
+	}
+	if check(AnyState) {
+		return nil
+	}
+
+	if declared {
+		return ErrGuardRejected
+	}
+	return ErrIllegalTransition
+}
+
+// enter enters all states below ancestor down to target, and then down to a leaf state.
+func (fsm *FSM) enter(ancestor, target StateID) {
+	path := []StateID{}
+	for id := target; id != ancestor && id != ""; id = fsm.parents[id] {
+		path = append(path, id)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		fsm.current = path[i]
+		fsm.states[path[i]].OnEnter()
+	}
+
+	for child := fsm.childToEnter(fsm.current); child != ""; child = fsm.childToEnter(fsm.current) {
+		fsm.current = child
+		fsm.states[child].OnEnter()
+	}
+}
+
+func (fsm *FSM) activeStates(chain []StateID) []StateID {
+	for id := fsm.current; id != ""; id = fsm.parents[id] {
+		chain = append(chain, id)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+func (fsm *FSM) pushHistory(id StateID) {
+	if fsm.historySize == 0 {
+		return
+	}
+	if len(fsm.history) == fsm.historySize {
+		copy(fsm.history, fsm.history[1:])
+		fsm.history = fsm.history[:len(fsm.history)-1]
+	}
+	fsm.history = append(fsm.history, id)
+}
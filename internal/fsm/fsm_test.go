@@ -0,0 +1,140 @@
+package fsm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingState struct {
+	name string
+	log  *[]string
+}
+
+func (s *recordingState) OnEnter()            { *s.log = append(*s.log, "enter "+s.name) }
+func (s *recordingState) OnUpdate(dt float32) { *s.log = append(*s.log, "update "+s.name) }
+func (s *recordingState) OnLeave()            { *s.log = append(*s.log, "leave "+s.name) }
+
+func newTestFSM(t *testing.T, log *[]string, ids ...StateID) *FSM {
+	t.Helper()
+	fsm := New()
+	for _, id := range ids {
+		assert.NoError(t, fsm.AddState(id, &recordingState{name: string(id), log: log}))
+	}
+	return fsm
+}
+
+func TestChangeState(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a", "b")
+	assert.NoError(t, fsm.AddTransition("a", "b", nil))
+
+	assert.NoError(t, fsm.Start("a"))
+	fsm.Run(0.1)
+	assert.NoError(t, fsm.ChangeState("b"))
+	fsm.Run(0.1)
+
+	assert.Equal(t, StateID("b"), fsm.Current())
+	assert.Equal(t, []string{"enter a", "update a", "leave a", "enter b", "update b"}, log)
+}
+
+func TestChangeStateErrors(t *testing.T) {
+	allow := false
+	testCases := []struct {
+		desc string
+		to   StateID
+		err  error
+	}{
+		{desc: "undeclared", to: "c", err: ErrIllegalTransition},
+		{desc: "guarded", to: "b", err: ErrGuardRejected},
+		{desc: "unknown", to: "x", err: ErrUnknownState},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			log := []string{}
+			fsm := newTestFSM(t, &log, "a", "b", "c")
+			assert.NoError(t, fsm.AddTransition("a", "b", func() bool { return allow }))
+			assert.NoError(t, fsm.Start("a"))
+
+			err := fsm.ChangeState(tc.to)
+
+			var transitionErr *TransitionError
+			assert.True(t, errors.As(err, &transitionErr))
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, StateID("a"), transitionErr.From)
+			assert.Equal(t, tc.to, transitionErr.To)
+			assert.Equal(t, StateID("a"), fsm.Current())
+			assert.Equal(t, []string{"enter a"}, log)
+		})
+	}
+}
+
+func TestChangeStateBeforeStart(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a", "b")
+	assert.NoError(t, fsm.AddTransition("a", "b", nil))
+
+	assert.ErrorIs(t, fsm.ChangeState("b"), ErrNotStarted)
+	assert.ErrorIs(t, fsm.Start("x"), ErrUnknownState)
+	assert.NoError(t, fsm.Start("a"))
+	assert.ErrorIs(t, fsm.Start("a"), ErrAlreadyStarted)
+}
+
+func TestAnyStateTransition(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a", "b", "idle")
+	assert.NoError(t, fsm.AddTransition("a", "b", nil))
+	assert.NoError(t, fsm.AddTransition(AnyState, "idle", nil))
+	assert.NoError(t, fsm.Start("a"))
+
+	assert.True(t, fsm.CanChangeState("idle"))
+	assert.NoError(t, fsm.ChangeState("b"))
+	assert.NoError(t, fsm.ChangeState("idle"))
+	assert.False(t, fsm.CanChangeState("b"))
+}
+
+func TestGuardFallsBackToOtherTransition(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a", "b")
+	assert.NoError(t, fsm.AddTransition("a", "b", func() bool { return false }))
+	assert.NoError(t, fsm.AddTransition(AnyState, "b", func() bool { return true }))
+	assert.NoError(t, fsm.Start("a"))
+
+	assert.NoError(t, fsm.ChangeState("b"))
+}
+
+func TestAddInvalid(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a")
+
+	assert.ErrorIs(t, fsm.AddState("a", &EmptyState{}), ErrDuplicateState)
+	assert.Error(t, fsm.AddState(AnyState, &EmptyState{}))
+	assert.ErrorIs(t, fsm.AddTransition("a", "x", nil), ErrUnknownState)
+	assert.ErrorIs(t, fsm.AddTransition("x", "a", nil), ErrUnknownState)
+}
+
+func TestHistory(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "a", "b", "c")
+	assert.NoError(t, fsm.AddTransition(AnyState, "a", nil))
+	assert.NoError(t, fsm.AddTransition(AnyState, "b", nil))
+	assert.NoError(t, fsm.AddTransition(AnyState, "c", nil))
+	fsm.SetHistorySize(3)
+
+	_, exists := fsm.Previous()
+	assert.False(t, exists)
+
+	assert.NoError(t, fsm.Start("a"))
+	for _, id := range []StateID{"b", "c", "a", "b", "c"} {
+		assert.NoError(t, fsm.ChangeState(id))
+	}
+
+	previous, exists := fsm.Previous()
+	assert.True(t, exists)
+	assert.Equal(t, StateID("b"), previous)
+	assert.Equal(t, []StateID{"c", "a", "b"}, fsm.History())
+
+	fsm.SetHistorySize(1)
+	assert.Equal(t, []StateID{"b"}, fsm.History())
+}
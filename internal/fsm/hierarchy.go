@@ -0,0 +1,71 @@
+package fsm
+
+import "fmt"
+
+// HistoryMode decides which child is entered when changing to a parent state.
+type HistoryMode int
+
+const (
+	// HistoryNone always enters the initial child.
+	HistoryNone HistoryMode = iota
+	// HistoryShallow resumes the child that was active when the parent was last left. The initial child
+	// is entered the first time.
+	HistoryShallow
+)
+
+// AddSubState registers a state as a child of parent. The first child added to a parent becomes its
+// initial state.
+func (fsm *FSM) AddSubState(parent, id StateID, state State) error {
+	if _, exists := fsm.states[parent]; !exists {
+		return fmt.Errorf("parent %q: %w", parent, ErrUnknownState)
+	}
+	if err := fsm.AddState(id, state); err != nil {
+		return err
+	}
+
+	fsm.parents[id] = parent
+	fsm.children[parent] = append(fsm.children[parent], id)
+	if _, exists := fsm.initial[parent]; !exists {
+		fsm.initial[parent] = id
+	}
+	return nil
+}
+
+// SetInitialState sets the child that is entered when changing to parent.
+func (fsm *FSM) SetInitialState(parent, child StateID) error {
+	if _, exists := fsm.states[child]; !exists {
+		return fmt.Errorf("initial state %q: %w", child, ErrUnknownState)
+	}
+	if fsm.parents[child] != parent {
+		return fmt.Errorf("%q is not a child of %q", child, parent)
+	}
+	fsm.initial[parent] = child
+	return nil
+}
+
+// SetHistoryMode sets how parent picks the child to enter.
+func (fsm *FSM) SetHistoryMode(parent StateID, mode HistoryMode) error {
+	if _, exists := fsm.states[parent]; !exists {
+		return fmt.Errorf("parent %q: %w", parent, ErrUnknownState)
+	}
+	fsm.historyMode[parent] = mode
+	return nil
+}
+
+// Parent returns the parent of a state, or an empty ID for top level states.
+func (fsm *FSM) Parent(id StateID) StateID {
+	return fsm.parents[id]
+}
+
+// childToEnter returns the child that should be entered when entering id, or an empty ID for leaf states.
+func (fsm *FSM) childToEnter(id StateID) StateID {
+	if len(fsm.children[id]) == 0 {
+		return ""
+	}
+	if fsm.historyMode[id] == HistoryShallow {
+		if child, exists := fsm.lastChild[id]; exists {
+			return child
+		}
+	}
+	return fsm.initial[id]
+}
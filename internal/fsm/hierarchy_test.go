@@ -0,0 +1,232 @@
+package fsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newWorkingFSM creates the hierarchy
+//
+//	idle
+//	working
+//	  picking
+//	  walking
+//	  chopping
+//	flee
+func newWorkingFSM(t *testing.T, log *[]string) *FSM {
+	t.Helper()
+	fsm := newTestFSM(t, log, "idle", "working", "flee")
+	for _, id := range []StateID{"picking", "walking", "chopping"} {
+		assert.NoError(t, fsm.AddSubState("working", id, &recordingState{name: string(id), log: log}))
+	}
+	assert.NoError(t, fsm.AddTransition("idle", "working", nil))
+	assert.NoError(t, fsm.AddTransition("picking", "walking", nil))
+	assert.NoError(t, fsm.AddTransition("walking", "chopping", nil))
+	assert.NoError(t, fsm.AddTransition("chopping", "picking", nil))
+	assert.NoError(t, fsm.AddTransition("working", "idle", nil))
+	assert.NoError(t, fsm.AddTransition(AnyState, "flee", nil))
+	assert.NoError(t, fsm.AddTransition("flee", "working", nil))
+	return fsm
+}
+
+func TestEnterParentCascades(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	assert.NoError(t, fsm.Start("idle"))
+	log = log[:0]
+
+	assert.NoError(t, fsm.ChangeState("working"))
+
+	assert.Equal(t, StateID("picking"), fsm.Current())
+	assert.Equal(t, []StateID{"working", "picking"}, fsm.ActiveStates())
+	assert.True(t, fsm.IsActive("working"))
+	assert.False(t, fsm.IsActive("idle"))
+	assert.Equal(t, []string{"leave idle", "enter working", "enter picking"}, log)
+}
+
+func TestStartInSubState(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+
+	assert.NoError(t, fsm.Start("walking"))
+
+	assert.Equal(t, []string{"enter working", "enter walking"}, log)
+}
+
+func TestChangeBetweenSiblingsKeepsParent(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	assert.NoError(t, fsm.Start("working"))
+	log = log[:0]
+
+	assert.NoError(t, fsm.ChangeState("walking"))
+
+	assert.Equal(t, []string{"leave picking", "enter walking"}, log)
+}
+
+func TestLeaveParentCascades(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	assert.NoError(t, fsm.Start("working"))
+	assert.NoError(t, fsm.ChangeState("walking"))
+	log = log[:0]
+
+	// Declared on the parent, allowed from the child
+	assert.NoError(t, fsm.ChangeState("idle"))
+
+	assert.Equal(t, []string{"leave walking", "leave working", "enter idle"}, log)
+	previous, _ := fsm.Previous()
+	assert.Equal(t, StateID("walking"), previous)
+}
+
+func TestChangeToActiveParentReenters(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "parent")
+	assert.NoError(t, fsm.AddSubState("parent", "a", &recordingState{name: "a", log: &log}))
+	assert.NoError(t, fsm.AddSubState("parent", "b", &recordingState{name: "b", log: &log}))
+	assert.NoError(t, fsm.AddTransition("parent", "parent", nil))
+	assert.NoError(t, fsm.AddTransition("a", "b", nil))
+	assert.NoError(t, fsm.Start("parent"))
+	assert.NoError(t, fsm.ChangeState("b"))
+	log = log[:0]
+
+	assert.NoError(t, fsm.ChangeState("parent"))
+
+	assert.Equal(t, []string{"leave b", "leave parent", "enter parent", "enter a"}, log)
+}
+
+func TestHistoryResumesLastChild(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		mode     HistoryMode
+		expected StateID
+	}{
+		{desc: "none", mode: HistoryNone, expected: "picking"},
+		{desc: "shallow", mode: HistoryShallow, expected: "chopping"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			log := []string{}
+			fsm := newWorkingFSM(t, &log)
+			assert.NoError(t, fsm.SetHistoryMode("working", tc.mode))
+			assert.NoError(t, fsm.Start("working"))
+			assert.NoError(t, fsm.ChangeState("walking"))
+			assert.NoError(t, fsm.ChangeState("chopping"))
+
+			assert.NoError(t, fsm.ChangeState("flee"))
+			assert.NoError(t, fsm.ChangeState("working"))
+
+			assert.Equal(t, tc.expected, fsm.Current())
+		})
+	}
+}
+
+func TestSetInitialState(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+
+	assert.Error(t, fsm.SetInitialState("working", "idle"))
+	assert.ErrorIs(t, fsm.SetInitialState("working", "x"), ErrUnknownState)
+	assert.NoError(t, fsm.SetInitialState("working", "walking"))
+	assert.NoError(t, fsm.Start("working"))
+
+	assert.Equal(t, StateID("walking"), fsm.Current())
+}
+
+func TestRunUpdatesParentFirst(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	assert.NoError(t, fsm.Start("working"))
+	log = log[:0]
+
+	fsm.Run(0.1)
+
+	assert.Equal(t, []string{"update working", "update picking"}, log)
+}
+
+type changingState struct {
+	recordingState
+	change func()
+}
+
+func (s *changingState) OnUpdate(dt float32) {
+	s.recordingState.OnUpdate(dt)
+	s.change()
+}
+
+func TestRunStopsAfterStateChange(t *testing.T) {
+	log := []string{}
+	fsm := newTestFSM(t, &log, "idle")
+	parent := &changingState{recordingState: recordingState{name: "parent", log: &log}}
+	parent.change = func() { assert.NoError(t, fsm.ChangeState("idle")) }
+	assert.NoError(t, fsm.AddState("parent", parent))
+	assert.NoError(t, fsm.AddSubState("parent", "child", &recordingState{name: "child", log: &log}))
+	assert.NoError(t, fsm.AddTransition("parent", "idle", nil))
+	assert.NoError(t, fsm.Start("parent"))
+	log = log[:0]
+
+	fsm.Run(0.1)
+
+	assert.Equal(t, []string{"update parent", "leave child", "leave parent", "enter idle"}, log)
+}
+
+func TestEventsBubbleToParent(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	handledBy := []string{}
+	fsm.On("walking", "threat", func() bool {
+		handledBy = append(handledBy, "walking")
+		return false
+	})
+	fsm.On("working", "threat", func() bool {
+		handledBy = append(handledBy, "working")
+		return fsm.ChangeState("flee") == nil
+	})
+	fsm.On(AnyState, "threat", func() bool {
+		handledBy = append(handledBy, "any")
+		return true
+	})
+
+	assert.False(t, fsm.Send("threat"), "not started")
+	assert.NoError(t, fsm.Start("working"))
+	assert.NoError(t, fsm.ChangeState("walking"))
+
+	assert.True(t, fsm.Send("threat"))
+	assert.Equal(t, []string{"walking", "working"}, handledBy)
+	assert.Equal(t, StateID("flee"), fsm.Current())
+
+	assert.True(t, fsm.Send("threat"))
+	assert.Equal(t, []string{"walking", "working", "any"}, handledBy)
+	assert.False(t, fsm.Send("unknown"))
+}
+
+func TestSnapshot(t *testing.T) {
+	log := []string{}
+	fsm := newWorkingFSM(t, &log)
+	assert.NoError(t, fsm.SetHistoryMode("working", HistoryShallow))
+	assert.NoError(t, fsm.Start("working"))
+	assert.NoError(t, fsm.ChangeState("walking"))
+	assert.NoError(t, fsm.ChangeState("flee"))
+	snapshot := fsm.Snapshot()
+
+	restored := newWorkingFSM(t, &log)
+	assert.NoError(t, restored.SetHistoryMode("working", HistoryShallow))
+	assert.ErrorIs(t, restored.Restore(snapshot), ErrNotStarted)
+	assert.NoError(t, restored.Start("idle"))
+	log = log[:0]
+	assert.NoError(t, restored.Restore(snapshot))
+	assert.Empty(t, log, "no states left or entered")
+	assert.Equal(t, StateID("flee"), restored.Current())
+	assert.Equal(t, fsm.History(), restored.History())
+
+	// Both resume the same child when going back to work
+	assert.NoError(t, fsm.ChangeState("working"))
+	assert.NoError(t, restored.ChangeState("working"))
+	assert.Equal(t, StateID("walking"), restored.Current())
+	assert.Equal(t, fsm.Snapshot(), restored.Snapshot())
+
+	assert.ErrorIs(t, restored.Restore(Snapshot{Current: "sleeping"}), ErrUnknownState)
+	assert.Error(t, restored.Restore(Snapshot{Current: "working"}), "not a leaf")
+	assert.Error(t, restored.Restore(Snapshot{Current: "idle", LastChild: map[StateID]StateID{"working": "flee"}}))
+}
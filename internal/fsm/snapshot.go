@@ -0,0 +1,52 @@
+package fsm
+
+import "fmt"
+
+// Snapshot is where an FSM is, for saving it: the current leaf state, the children parents resume with
+// HistoryShallow and the previous states.
+type Snapshot struct {
+	Current   StateID             `json:"current"`
+	LastChild map[StateID]StateID `json:"lastChild,omitempty"`
+	History   []StateID           `json:"history,omitempty"`
+}
+
+// Snapshot returns where the FSM is.
+func (fsm *FSM) Snapshot() Snapshot {
+	s := Snapshot{Current: fsm.current, History: fsm.History()}
+	if len(fsm.lastChild) > 0 {
+		s.LastChild = make(map[StateID]StateID, len(fsm.lastChild))
+		for parent, child := range fsm.lastChild {
+			s.LastChild[parent] = child
+		}
+	}
+	return s
+}
+
+// Restore puts a started FSM back where a snapshot was taken. States are not left or entered, whatever they
+// keep themselves has to be restored separately.
+func (fsm *FSM) Restore(s Snapshot) error {
+	if !fsm.started {
+		return ErrNotStarted
+	}
+	if _, exists := fsm.states[s.Current]; !exists {
+		return fmt.Errorf("current state %q: %w", s.Current, ErrUnknownState)
+	}
+	if len(fsm.children[s.Current]) > 0 {
+		return fmt.Errorf("current state %q is not a leaf state", s.Current)
+	}
+	lastChild := make(map[StateID]StateID, len(s.LastChild))
+	for parent, child := range s.LastChild {
+		if fsm.parents[child] != parent {
+			return fmt.Errorf("last child %q is not a child of %q", child, parent)
+		}
+		lastChild[parent] = child
+	}
+	fsm.current = s.Current
+	fsm.lastChild = lastChild
+	fsm.history = fsm.history[:0]
+	for _, id := range s.History {
+		fsm.pushHistory(id)
+	}
+	fsm.changes++
+	return nil
+}
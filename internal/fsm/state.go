@@ -0,0 +1,38 @@
+package fsm
+
+import "fmt"
+
+// State is a single state of an FSM. OnEnter and OnLeave are called when the FSM transitions into and out
+// of the state, OnUpdate is called every time the FSM is run while the state is active.
+type State interface {
+	OnEnter()
+	OnUpdate(dt float32)
+	OnLeave()
+}
+
+type EmptyState struct{}
+
+func (s *EmptyState) OnEnter()            {}
+func (s *EmptyState) OnUpdate(dt float32) {}
+func (s *EmptyState) OnLeave()            {}
+
+type BasicState struct {
+	name        string
+	timeInState float32
+}
+
+func (s *BasicState) OnEnter() {
+	fmt.Printf("Entering state %q\n", s.name)
+}
+func (s *BasicState) OnUpdate(dt float32) {
+	s.timeInState += dt
+	fmt.Printf("In state %s for %v\n", s.name, s.timeInState)
+}
+func (s *BasicState) OnLeave() {
+	fmt.Printf("Leaving state %q\n", s.name)
+}
+func NewBasicState(name string) BasicState {
+	return BasicState{
+		name: name,
+	}
+}
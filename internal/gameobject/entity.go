@@ -0,0 +1,160 @@
+package gameobject
+
+import (
+	"game-engine/rts/internal/camera"
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/mesh"
+	"game-engine/rts/internal/shader"
+	"game-engine/rts/internal/texture"
+
+	"github.com/go-gl/gl/v4.6-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Components of entities that are drawn: a Transform, the Model matrix worked out from it, and a Solid,
+// Textured or Gizmo mesh and shader to draw. Entities moved by the simulation also have where they were
+// before the last tick, to draw them between ticks.
+
+// Model is the model matrix of an entity, set from its Transform by the ModelSystem.
+type Model mgl32.Mat4
+
+// Previous is where an entity moved by the simulation was before the last tick, see SavePositions.
+type Previous mgl32.Vec3
+
+// Solid is a mesh drawn with a solid shader.
+type Solid struct {
+	Mesh   *mesh.Mesh
+	Shader *shader.SolidShader
+}
+
+// Textured is a mesh drawn with a shader of its own, the basic shader with a texture or the grid shader.
+type Textured struct {
+	Mesh    *mesh.Mesh
+	Shader  *shader.Shader
+	Texture *texture.Texture
+}
+
+// Gizmo is a mesh drawn with the XYZ gizmo shader, in the corner of the view wherever the camera looks.
+type Gizmo struct {
+	Mesh   *mesh.Mesh
+	Shader *shader.XYZGizmoShader
+}
+
+// ModelSystem sets the Model of every entity with a Transform.
+var ModelSystem = ecs.System{Name: "models", Run: UpdateModels}
+
+// InterpolationSystem sets the Model of every entity moved by the simulation alpha of the way from where it
+// was before the last tick to where it is now, after the ModelSystem.
+func InterpolationSystem(alpha func() float32) ecs.System {
+	return ecs.System{
+		Name:  "interpolation",
+		After: []string{ModelSystem.Name},
+		Run: func(w *ecs.World, _ float32) {
+			Interpolate(w, alpha())
+		},
+	}
+}
+
+// Spawn spawns an entity drawn with a solid mesh and shader. The zero scale is 1.
+func Spawn(w *ecs.World, transform Transform, solid Solid) ecs.Entity {
+	if transform.Scale == (mgl32.Vec3{}) {
+		transform.Scale = mgl32.Vec3{1.0, 1.0, 1.0}
+	}
+	e := w.Spawn()
+	ecs.Add(w, e, transform)
+	ecs.Add(w, e, Model(transform.Model()))
+	ecs.Add(w, e, solid)
+	return e
+}
+
+// UpdateModels sets the Model of every entity with a Transform.
+func UpdateModels(w *ecs.World, _ float32) {
+	ecs.Each2(w, func(_ ecs.Entity, t *Transform, m *Model) {
+		*m = Model(t.Model())
+	})
+}
+
+// SavePositions remembers where every entity moved by the simulation is before a tick moves it.
+func SavePositions(w *ecs.World, _ float32) {
+	ecs.Each2(w, func(_ ecs.Entity, t *Transform, p *Previous) {
+		*p = Previous(t.Position)
+	})
+}
+
+// Interpolate sets the Model of every entity moved by the simulation alpha of the way from where it was
+// before the last tick to where it is now.
+func Interpolate(w *ecs.World, alpha float32) {
+	ecs.Each3(w, func(_ ecs.Entity, t *Transform, p *Previous, m *Model) {
+		previous := mgl32.Vec3(*p)
+		between := *t
+		between.Position = previous.Add(t.Position.Sub(previous).Mul(alpha))
+		*m = Model(between.Model())
+	})
+}
+
+// RenderSolids draws the entities with a Solid mesh and a Model.
+func RenderSolids(w *ecs.World, c *camera.Camera) {
+	view, projection, position := c.View(), c.Projection(), c.Position()
+	ecs.Each2(w, func(_ ecs.Entity, s *Solid, m *Model) {
+		if s.Shader == nil {
+			return
+		}
+		s.Shader.UseProgram()
+		s.Shader.SetModel(mgl32.Mat4(*m))
+		s.Shader.SetView(view)
+		s.Shader.SetProjection(projection)
+		s.Shader.SetViewPos(position)
+		if s.Mesh != nil {
+			s.Mesh.DrawNonIndex()
+		}
+	})
+	shader.UnbindProgram()
+}
+
+// RenderTextured draws the entities with a Textured mesh and a Model, which are cubes.
+func RenderTextured(w *ecs.World, c *camera.Camera) {
+	view, projection := c.View(), c.Projection()
+	ecs.Each2(w, func(_ ecs.Entity, t *Textured, m *Model) {
+		t.Shader.UseProgram()
+		t.Shader.SetModel(mgl32.Mat4(*m))
+		t.Shader.SetView(view)
+		t.Shader.SetProjection(projection)
+		t.Mesh.Bind()
+		if t.Texture != nil {
+			t.Texture.Bind()
+		}
+		gl.DrawArrays(gl.TRIANGLES, 0, 6*2*3)
+		mesh.Unbind()
+	})
+	shader.UnbindProgram()
+}
+
+// RenderGizmos draws the entities with a Gizmo in the bottom left of the view of the camera.
+func RenderGizmos(w *ecs.World, c *camera.Camera) {
+	camPos := c.Position()
+	camForward := c.Forward()
+	camUp := mgl32.Vec3{0.0, 1.0, 0.0}
+	camRight := camForward.Cross(camUp).Normalize()
+	camUp = camRight.Cross(camForward).Normalize()
+
+	// Position the gizmo at the camera, and move it forward in the direction the camera is looking
+	newPos := camPos.Add(camForward.Mul(5.0))
+	// Move down and left relative to the cameras view direction
+	newPos = newPos.Add(camRight.Mul(-3.0))
+	newPos = newPos.Add(camUp.Mul(-1.5))
+	model := Transform{Position: newPos, Scale: mgl32.Vec3{0.5, 0.5, 0.5}}.Model()
+
+	ecs.Each1(w, func(_ ecs.Entity, g *Gizmo) {
+		if g.Shader == nil {
+			return
+		}
+		g.Shader.UseProgram()
+		g.Shader.SetModel(model)
+		g.Shader.SetView(c.View())
+		g.Shader.SetProjection(c.Projection())
+		if g.Mesh != nil {
+			g.Mesh.DrawNonIndex()
+		}
+	})
+	shader.UnbindProgram()
+}
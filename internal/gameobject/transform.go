@@ -0,0 +1,21 @@
+package gameobject
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Transform is where an object is, how it is turned around x, y and z in radians, and how it is scaled.
+type Transform struct {
+	Position mgl32.Vec3
+	Rotation mgl32.Vec3
+	Scale    mgl32.Vec3
+}
+
+// Model returns the model matrix, which puts the mesh of the object in the world.
+func (t Transform) Model() mgl32.Mat4 {
+	modelMat := mgl32.Ident4()
+	modelMat = modelMat.Mul4(mgl32.Translate3D(t.Position[0], t.Position[1], t.Position[2]))
+	modelMat = modelMat.Mul4(mgl32.Scale3D(t.Scale[0], t.Scale[1], t.Scale[2]))
+	modelMat = modelMat.Mul4(mgl32.HomogRotate3DX(t.Rotation[0]))
+	modelMat = modelMat.Mul4(mgl32.HomogRotate3DY(t.Rotation[1]))
+	modelMat = modelMat.Mul4(mgl32.HomogRotate3DZ(t.Rotation[2]))
+	return modelMat
+}
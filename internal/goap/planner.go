@@ -0,0 +1,141 @@
+package goap
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// MaxPlanIterations limits how many world states the planner explores before giving up.
+const MaxPlanIterations = 10000
+
+// ErrNoPlan is returned when no sequence of actions reaches the goal.
+var ErrNoPlan = errors.New("no plan reaches the goal")
+
+// Action can be performed when its preconditions hold, after which its effects are applied to the world.
+type Action struct {
+	Name          string
+	Preconditions []Condition
+	Effects       []Effect
+	Cost          float32
+}
+
+// Usable reports if the preconditions of the action hold in the world state.
+func (a *Action) Usable(ws WorldState) bool {
+	return ws.Satisfies(a.Preconditions)
+}
+
+// Apply returns a copy of the world state with the effects of the action applied.
+func (a *Action) Apply(ws WorldState) WorldState {
+	next := ws.Clone()
+	for _, effect := range a.Effects {
+		effect.Apply(next)
+	}
+	return next
+}
+
+type node struct {
+	state  WorldState
+	parent *node
+	action *Action
+	cost   float32
+	// estimate is the cost so far plus the heuristic
+	estimate float32
+	index    int
+}
+
+type openList []*node
+
+func (l openList) Len() int           { return len(l) }
+func (l openList) Less(i, j int) bool { return l[i].estimate < l[j].estimate }
+func (l openList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+	l[i].index = i
+	l[j].index = j
+}
+func (l *openList) Push(x any) {
+	n := x.(*node)
+	n.index = len(*l)
+	*l = append(*l, n)
+}
+func (l *openList) Pop() any {
+	old := *l
+	n := old[len(old)-1]
+	*l = old[:len(old)-1]
+	return n
+}
+
+// Plan returns the cheapest sequence of actions that takes the world from start to a state where all goal
+// conditions hold. It uses A* over the world states, where the heuristic is the cost of the cheapest action
+// if the goal is not reached. This never overestimates the remaining cost, so the plan found is the cheapest.
+func Plan(start WorldState, goal []Condition, actions []Action) ([]*Action, error) {
+	if len(actions) == 0 {
+		if start.Satisfies(goal) {
+			return []*Action{}, nil
+		}
+		return nil, ErrNoPlan
+	}
+
+	cheapest := actions[0].Cost
+	for i := range actions {
+		if actions[i].Cost < cheapest {
+			cheapest = actions[i].Cost
+		}
+	}
+	heuristic := func(ws WorldState) float32 {
+		if ws.Satisfies(goal) {
+			return 0
+		}
+		return cheapest
+	}
+
+	open := &openList{}
+	heap.Push(open, &node{state: start, estimate: heuristic(start)})
+	bestCost := map[string]float32{start.key(): 0}
+	closed := map[string]bool{}
+
+	for iterations := 0; open.Len() > 0 && iterations < MaxPlanIterations; iterations++ {
+		current := heap.Pop(open).(*node)
+		key := current.state.key()
+		if closed[key] {
+			continue
+		}
+		if current.state.Satisfies(goal) {
+			return current.path(), nil
+		}
+		closed[key] = true
+
+		for i := range actions {
+			action := &actions[i]
+			if !action.Usable(current.state) {
+				continue
+			}
+			next := action.Apply(current.state)
+			nextKey := next.key()
+			cost := current.cost + action.Cost
+			if best, seen := bestCost[nextKey]; closed[nextKey] || (seen && best <= cost) {
+				continue
+			}
+			bestCost[nextKey] = cost
+			heap.Push(open, &node{
+				state:    next,
+				parent:   current,
+				action:   action,
+				cost:     cost,
+				estimate: cost + heuristic(next),
+			})
+		}
+	}
+
+	return nil, ErrNoPlan
+}
+
+func (n *node) path() []*Action {
+	plan := []*Action{}
+	for current := n; current.action != nil; current = current.parent {
+		plan = append(plan, current.action)
+	}
+	for i, j := 0, len(plan)-1; i < j; i, j = i+1, j-1 {
+		plan[i], plan[j] = plan[j], plan[i]
+	}
+	return plan
+}
@@ -0,0 +1,147 @@
+package goap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func names(plan []*Action) []string {
+	result := []string{}
+	for _, action := range plan {
+		result = append(result, action.Name)
+	}
+	return result
+}
+
+var woodActions = []Action{
+	{
+		Name:          "find-tree",
+		Preconditions: []Condition{Is("has-target", 0)},
+		Effects:       []Effect{{Fact: "has-target", Value: 1}},
+		Cost:          1,
+	},
+	{
+		Name:          "walk-to-tree",
+		Preconditions: []Condition{Is("has-target", 1), Is("at-tree", 0)},
+		Effects:       []Effect{{Fact: "at-tree", Value: 1}, {Fact: "at-stockpile", Value: 0}},
+		Cost:          2,
+	},
+	{
+		Name:          "chop",
+		Preconditions: []Condition{Is("at-tree", 1), Is("carried", 0)},
+		Effects: []Effect{
+			{Fact: "carried", Value: 5},
+			{Fact: "has-target", Value: 0},
+			{Fact: "at-tree", Value: 0},
+		},
+		Cost: 3,
+	},
+	{
+		Name:          "carry-wood",
+		Preconditions: []Condition{Is("carried", 5), Is("at-stockpile", 0)},
+		Effects:       []Effect{{Fact: "at-stockpile", Value: 1}, {Fact: "at-tree", Value: 0}},
+		Cost:          2,
+	},
+	{
+		Name:          "deposit",
+		Preconditions: []Condition{Is("carried", 5), Is("at-stockpile", 1)},
+		Effects:       []Effect{{Fact: "stockpiled", Operation: Add, Value: 5}, {Fact: "carried", Value: 0}},
+		Cost:          1,
+	},
+}
+
+// execute checks that every action in the plan can be performed and returns the resulting state and cost.
+func execute(t *testing.T, start WorldState, plan []*Action) (WorldState, float32) {
+	t.Helper()
+	ws, cost := start, float32(0)
+	for _, action := range plan {
+		assert.True(t, action.Usable(ws), "action %s not usable in %v", action.Name, ws)
+		ws = action.Apply(ws)
+		cost += action.Cost
+	}
+	return ws, cost
+}
+
+func TestPlanNumericGoal(t *testing.T) {
+	goal := []Condition{{Fact: "stockpiled", Comparison: AtLeast, Value: 10}}
+
+	plan, err := Plan(WorldState{}, goal, woodActions)
+
+	assert.NoError(t, err)
+	end, cost := execute(t, WorldState{}, plan)
+	assert.True(t, end.Satisfies(goal))
+	assert.Equal(t, float32(18), cost, "two trips of find, walk, chop, carry and deposit")
+	assert.Len(t, plan, 10)
+}
+
+func TestPlanFromPartialState(t *testing.T) {
+	goal := []Condition{{Fact: "stockpiled", Comparison: AtLeast, Value: 5}}
+
+	plan, err := Plan(WorldState{"carried": 5}, goal, woodActions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carry-wood", "deposit"}, names(plan))
+}
+
+func TestPlanPicksCheapest(t *testing.T) {
+	actions := []Action{
+		{Name: "expensive", Effects: []Effect{{Fact: "done", Value: 1}}, Cost: 10},
+		{Name: "step-1", Effects: []Effect{{Fact: "half", Value: 1}}, Cost: 2},
+		{Name: "step-2", Preconditions: []Condition{Is("half", 1)}, Effects: []Effect{{Fact: "done", Value: 1}}, Cost: 2},
+	}
+
+	plan, err := Plan(WorldState{}, []Condition{Is("done", 1)}, actions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"step-1", "step-2"}, names(plan))
+
+	actions[0].Cost = 3
+	plan, err = Plan(WorldState{}, []Condition{Is("done", 1)}, actions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"expensive"}, names(plan))
+}
+
+func TestPlanGoalAlreadyReached(t *testing.T) {
+	plan, err := Plan(WorldState{"stockpiled": 10}, []Condition{{Fact: "stockpiled", Comparison: AtLeast, Value: 10}}, woodActions)
+
+	assert.NoError(t, err)
+	assert.Empty(t, plan)
+}
+
+func TestPlanUnreachable(t *testing.T) {
+	_, err := Plan(WorldState{}, []Condition{Is("gold", 1)}, woodActions)
+	assert.ErrorIs(t, err, ErrNoPlan)
+
+	_, err = Plan(WorldState{}, []Condition{Is("gold", 1)}, nil)
+	assert.ErrorIs(t, err, ErrNoPlan)
+}
+
+func TestConditions(t *testing.T) {
+	ws := WorldState{"wood": 5}
+	testCases := []struct {
+		desc      string
+		condition Condition
+		holds     bool
+	}{
+		{desc: "equal", condition: Is("wood", 5), holds: true},
+		{desc: "unset is zero", condition: Is("stone", 0), holds: true},
+		{desc: "not equal", condition: Condition{Fact: "wood", Comparison: NotEqual, Value: 5}, holds: false},
+		{desc: "at least", condition: Condition{Fact: "wood", Comparison: AtLeast, Value: 6}, holds: false},
+		{desc: "at most", condition: Condition{Fact: "wood", Comparison: AtMost, Value: 5}, holds: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.holds, tc.condition.Holds(ws))
+		})
+	}
+}
+
+func TestApplyDoesNotChangeState(t *testing.T) {
+	ws := WorldState{"carried": 5, "at-stockpile": 1}
+
+	next := woodActions[4].Apply(ws)
+
+	assert.Equal(t, WorldState{"carried": 5, "at-stockpile": 1}, ws)
+	assert.Equal(t, WorldState{"carried": 0, "at-stockpile": 1, "stockpiled": 5}, next)
+}
@@ -0,0 +1,105 @@
+package goap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WorldState is a set of facts about the world. Facts that are not set have the value 0, boolean facts use
+// 0 and 1.
+type WorldState map[string]int
+
+// Clone returns a copy of the world state.
+func (ws WorldState) Clone() WorldState {
+	clone := make(WorldState, len(ws))
+	for fact, value := range ws {
+		clone[fact] = value
+	}
+	return clone
+}
+
+// Satisfies reports if all conditions hold in the world state.
+func (ws WorldState) Satisfies(conditions []Condition) bool {
+	for _, c := range conditions {
+		if !c.Holds(ws) {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns a string that is equal for world states with the same facts.
+func (ws WorldState) key() string {
+	facts := make([]string, 0, len(ws))
+	for fact, value := range ws {
+		if value != 0 {
+			facts = append(facts, fmt.Sprintf("%s=%d", fact, value))
+		}
+	}
+	sort.Strings(facts)
+	return strings.Join(facts, ",")
+}
+
+// Comparison is used by conditions to compare the value of a fact.
+type Comparison int
+
+const (
+	Equal Comparison = iota
+	NotEqual
+	AtLeast
+	AtMost
+)
+
+// Condition must hold for an action to be performed or for a goal to be reached.
+type Condition struct {
+	Fact       string
+	Comparison Comparison
+	Value      int
+}
+
+// Is creates a condition requiring a fact to have a value.
+func Is(fact string, value int) Condition {
+	return Condition{Fact: fact, Comparison: Equal, Value: value}
+}
+
+// Holds reports if the condition holds in the world state.
+func (c Condition) Holds(ws WorldState) bool {
+	value := ws[c.Fact]
+	switch c.Comparison {
+	case Equal:
+		return value == c.Value
+	case NotEqual:
+		return value != c.Value
+	case AtLeast:
+		return value >= c.Value
+	case AtMost:
+		return value <= c.Value
+	}
+	return false
+}
+
+// Operation is used by effects to change the value of a fact.
+type Operation int
+
+const (
+	Set Operation = iota
+	Add
+)
+
+// Effect changes a fact in the world state when an action has been performed.
+type Effect struct {
+	Fact      string
+	Operation Operation
+	Value     int
+}
+
+// Apply changes the world state according to the effect.
+func (e Effect) Apply(ws WorldState) {
+	switch e.Operation {
+	case Set:
+		ws[e.Fact] = e.Value
+	case Add:
+		ws[e.Fact] += e.Value
+	}
+}
@@ -0,0 +1,272 @@
+// Package lockstep plays a match between players whose games run in step: every player runs the same game,
+// and each turn is only run once the commands of every player for it have arrived. The commands given during
+// a turn are for a turn a few turns later, so they have time to arrive before they are needed.
+package lockstep
+
+import (
+	"errors"
+	"fmt"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/sim"
+)
+
+const (
+	defaultTicksPerTurn     = 2
+	defaultInputDelay       = 3
+	defaultChecksumInterval = 10
+	// resendAfter is how many times Step can be kept waiting for others before inputs are sent again.
+	resendAfter = 10
+	// maxTurns is how many turns Advance runs at most.
+	maxTurns = 5
+)
+
+// ErrDesync is returned when the game of another player has a different state than ours.
+var ErrDesync = errors.New("games out of sync")
+
+// Config is how a match is played. Every player must use the same.
+type Config struct {
+	// Players is how many players there are in the match.
+	Players int
+	// TicksPerTurn is how many ticks of the game are run per turn, 2 if 0.
+	TicksPerTurn int
+	// InputDelay is how many turns after the current one commands are run, 3 if 0. It hides the time it takes
+	// for commands to arrive.
+	InputDelay int
+	// ChecksumInterval is how many turns there are between comparing the states of the games, 10 if 0.
+	ChecksumInterval int
+}
+
+// Session is the end of a match of one player. It runs the game of the player one turn at a time with the
+// commands of all players.
+type Session struct {
+	config    Config
+	game      *sim.Game
+	transport Transport
+	player    resource.Player
+	// turn is the next turn to run
+	turn uint64
+	// local are the commands issued since the inputs were last sent
+	local []sim.Command
+	// sent is the next turn to send the inputs for
+	sent uint64
+	// inputs are the commands of every player for the turns that haven't been run yet, and those of this
+	// player for the turns other players may not have run yet
+	inputs map[uint64]map[resource.Player][]sim.Command
+	// checksums are the hashes of the games of every player for turns that haven't been compared yet
+	checksums map[uint64]map[resource.Player]uint64
+	// waited is how many times in a row Step couldn't run the turn
+	waited int
+	// accumulator is the time that has passed but hasn't been run yet, see Advance
+	accumulator float64
+	err         error
+}
+
+// NewSession starts a match for the player at the end of the transport. The game must not have run yet.
+func NewSession(game *sim.Game, transport Transport, config Config) *Session {
+	if config.TicksPerTurn == 0 {
+		config.TicksPerTurn = defaultTicksPerTurn
+	}
+	if config.InputDelay == 0 {
+		config.InputDelay = defaultInputDelay
+	}
+	if config.ChecksumInterval == 0 {
+		config.ChecksumInterval = defaultChecksumInterval
+	}
+	return &Session{
+		config:    config,
+		game:      game,
+		transport: transport,
+		player:    transport.Player(),
+		inputs:    map[uint64]map[resource.Player][]sim.Command{},
+		checksums: map[uint64]map[resource.Player]uint64{},
+	}
+}
+
+// Player returns the player of the session.
+func (s *Session) Player() resource.Player {
+	return s.player
+}
+
+// Turn returns the next turn to run.
+func (s *Session) Turn() uint64 {
+	return s.turn
+}
+
+// TurnTime returns how many seconds of the game a turn is.
+func (s *Session) TurnTime() float64 {
+	return float64(s.config.TicksPerTurn) * float64(s.game.Step())
+}
+
+// Issue gives a command of the player to the game, it is run after the input delay.
+func (s *Session) Issue(c sim.Command) {
+	s.local = append(s.local, c)
+}
+
+// Step runs the next turn if the commands of every player for it have arrived, and reports if it did.
+func (s *Session) Step() (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	if err := s.sendInputs(); err != nil {
+		return false, s.fail(err)
+	}
+	if err := s.receive(); err != nil {
+		return false, s.fail(err)
+	}
+	if len(s.inputs[s.turn]) < s.config.Players {
+		s.waited++
+		if s.waited%resendAfter == 0 {
+			if err := s.resend(); err != nil {
+				return false, s.fail(err)
+			}
+		}
+		return false, nil
+	}
+	s.waited = 0
+
+	if s.turn%uint64(s.config.ChecksumInterval) == 0 {
+		hash := s.game.Hash()
+		s.addChecksum(s.turn, s.player, hash)
+		if err := s.transport.Send(Message{Type: Checksum, Player: s.player, Turn: s.turn, Hash: hash}); err != nil {
+			return false, s.fail(err)
+		}
+	}
+	if err := s.compareChecksums(); err != nil {
+		return false, s.fail(err)
+	}
+
+	tick := s.turn * uint64(s.config.TicksPerTurn)
+	for player := 0; player < s.config.Players; player++ {
+		for _, c := range s.inputs[s.turn][resource.Player(player)] {
+			c.Tick = tick
+			c.Player = resource.Player(player)
+			if err := s.game.Issue(c); err != nil {
+				fmt.Printf("Ignoring command of player %d: %v\n", player, err)
+			}
+		}
+	}
+	s.game.Run(s.config.TicksPerTurn)
+	// Others may still need our inputs for the turns they haven't run, they are at most the input delay behind
+	delete(s.inputs, s.turn-uint64(s.config.InputDelay)-1)
+	s.turn++
+	return true, nil
+}
+
+// Advance runs the turns that fit in the time that has passed since the last frame, as long as the commands
+// for them have arrived.
+func (s *Session) Advance(elapsed float64) error {
+	s.accumulator += elapsed
+	for turns := 0; s.accumulator >= s.TurnTime(); turns++ {
+		if turns == maxTurns {
+			s.accumulator = s.TurnTime()
+			break
+		}
+		ran, err := s.Step()
+		if err != nil {
+			return err
+		}
+		if !ran {
+			// Waiting for others doesn't make the game go faster once they catch up
+			s.accumulator = s.TurnTime()
+			break
+		}
+		s.accumulator -= s.TurnTime()
+	}
+	return nil
+}
+
+// Close leaves the match.
+func (s *Session) Close() error {
+	return s.transport.Close()
+}
+
+// sendInputs sends the commands issued since the last turn for the turn after the input delay. Turns before
+// the first of those have no commands.
+func (s *Session) sendInputs() error {
+	for ; s.sent <= s.turn+uint64(s.config.InputDelay); s.sent++ {
+		var commands []sim.Command
+		if s.sent >= uint64(s.config.InputDelay) {
+			commands = s.local
+			s.local = nil
+		}
+		s.addInput(s.sent, s.player, commands)
+		if err := s.transport.Send(Message{Type: Input, Player: s.player, Turn: s.sent, Commands: commands}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resend sends the inputs of the player again, in case they were lost.
+func (s *Session) resend() error {
+	for turn, inputs := range s.inputs {
+		commands, ok := inputs[s.player]
+		if !ok {
+			continue
+		}
+		if err := s.transport.Send(Message{Type: Input, Player: s.player, Turn: turn, Commands: commands}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive takes in everything that has arrived from the other players.
+func (s *Session) receive() error {
+	for {
+		m, ok, err := s.transport.Receive()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		switch {
+		case m.Type == Input && m.Turn >= s.turn:
+			s.addInput(m.Turn, m.Player, m.Commands)
+		case m.Type == Checksum:
+			s.addChecksum(m.Turn, m.Player, m.Hash)
+		}
+	}
+}
+
+func (s *Session) addInput(turn uint64, player resource.Player, commands []sim.Command) {
+	if s.inputs[turn] == nil {
+		s.inputs[turn] = map[resource.Player][]sim.Command{}
+	}
+	if _, ok := s.inputs[turn][player]; !ok {
+		s.inputs[turn][player] = commands
+	}
+}
+
+func (s *Session) addChecksum(turn uint64, player resource.Player, hash uint64) {
+	if s.checksums[turn] == nil {
+		s.checksums[turn] = map[resource.Player]uint64{}
+	}
+	s.checksums[turn][player] = hash
+}
+
+// compareChecksums compares the hashes of the games of other players with ours, for the turns we have run.
+func (s *Session) compareChecksums() error {
+	for turn, hashes := range s.checksums {
+		if ours, ok := hashes[s.player]; ok {
+			for player, hash := range hashes {
+				if hash != ours {
+					return fmt.Errorf("%w at turn %d: player %d has %x, player %d has %x", ErrDesync, turn, s.player, ours, player, hash)
+				}
+			}
+		}
+		// The hashes of others for a turn arrive before their inputs for the turns after the input delay,
+		// unless they were lost
+		if len(hashes) == s.config.Players || turn+uint64(s.config.InputDelay)+1 < s.turn {
+			delete(s.checksums, turn)
+		}
+	}
+	return nil
+}
+
+func (s *Session) fail(err error) error {
+	s.err = err
+	return err
+}
@@ -0,0 +1,297 @@
+package lockstep
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/sim"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSessions creates a game and a session for each transport.
+func newSessions(t *testing.T, transports ...Transport) []*Session {
+	sessions := []*Session{}
+	for _, transport := range transports {
+		game, err := sim.NewGame(sim.Config{Seed: 1, Players: len(transports), Workers: 3})
+		assert.NoError(t, err)
+		sessions = append(sessions, NewSession(game, transport, Config{Players: len(transports)}))
+	}
+	return sessions
+}
+
+func loopbacks(players int) []Transport {
+	transports := []Transport{}
+	for _, l := range NewLoopback(players) {
+		transports = append(transports, l)
+	}
+	return transports
+}
+
+// runTo steps every session in turn until they have all run up to a turn.
+func runTo(t *testing.T, turn uint64, sessions ...*Session) {
+	for i := 0; i < 100000; i++ {
+		done := true
+		for _, s := range sessions {
+			if s.Turn() < turn {
+				done = false
+				_, err := s.Step()
+				assert.NoError(t, err)
+			}
+		}
+		if done {
+			return
+		}
+	}
+	t.Fatal("match got stuck")
+}
+
+// assertInStep checks that every session has run its game to the same state.
+func assertInStep(t *testing.T, sessions ...*Session) {
+	for _, s := range sessions[1:] {
+		assert.Equal(t, sessions[0].Turn(), s.Turn())
+		assert.Equal(t, sessions[0].game.Tick(), s.game.Tick())
+		assert.Equal(t, sessions[0].game.Hash(), s.game.Hash())
+	}
+}
+
+func TestLoopbackMatch(t *testing.T) {
+	sessions := newSessions(t, loopbacks(2)...)
+	first, second := sessions[0], sessions[1]
+	assert.Equal(t, resource.Player(1), second.Player())
+	target := first.game.Stockpile.Position.Add(mgl32.Vec3{1, 0, 1})
+	first.Issue(sim.Command{Type: sim.Select, Units: []int{0, 1}})
+	first.Issue(sim.Command{Type: sim.Move, Target: target})
+
+	// The commands are run after the input delay
+	runTo(t, defaultInputDelay, sessions...)
+	for _, s := range sessions {
+		assert.NotEqual(t, worker.StateMoving, s.game.Workers[0].State())
+	}
+	runTo(t, defaultInputDelay+1, sessions...)
+	for _, s := range sessions {
+		assert.Equal(t, worker.StateMoving, s.game.Workers[0].State())
+		assert.Equal(t, uint64((defaultInputDelay+1)*defaultTicksPerTurn), s.game.Tick())
+	}
+
+	// Player 1 can only command its own workers
+	second.Issue(sim.Command{Type: sim.Select, Units: []int{2, 3, 4}})
+	second.Issue(sim.Command{Type: sim.Move, Target: target})
+	second.Issue(sim.Command{Type: sim.Build, Target: mgl32.Vec3{5, 0, -5}})
+	runTo(t, 60, sessions...)
+	assertInStep(t, sessions...)
+	for _, s := range sessions {
+		assert.NotEqual(t, worker.StateMoving, s.game.Workers[2].State(), "of player 0")
+		assert.Equal(t, worker.StateMoving, s.game.Workers[3].State())
+		assert.Len(t, s.game.World.Stockpiles, 3)
+		assert.Equal(t, resource.Player(1), s.game.World.Stockpiles[2].Player)
+	}
+}
+
+func TestWaitsForInputs(t *testing.T) {
+	sessions := newSessions(t, loopbacks(2)...)
+	first, second := sessions[0], sessions[1]
+	for i := 0; i < 10; i++ {
+		ran, err := first.Step()
+		assert.NoError(t, err)
+		assert.False(t, ran, "nothing from player 1 yet")
+	}
+
+	ran, err := second.Step()
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	for i := 0; i < 10; i++ {
+		_, err := first.Step()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(defaultInputDelay+1), first.Turn(), "up to the input delay ahead")
+
+	// Advance keeps to real time, and doesn't catch up on time spent waiting
+	assert.NoError(t, second.Advance(0.5*second.TurnTime()))
+	assert.Equal(t, uint64(1), second.Turn())
+	assert.NoError(t, second.Advance(3.5*second.TurnTime()))
+	assert.Equal(t, uint64(5), second.Turn())
+	assert.NoError(t, second.Advance(10*second.TurnTime()))
+	assert.Equal(t, first.Turn()+defaultInputDelay+1, second.Turn(), "waiting for player 0")
+	runTo(t, 20, sessions...)
+	assertInStep(t, sessions...)
+}
+
+// lossy loses some of the messages sent over a transport.
+type lossy struct {
+	Transport
+	sent int
+}
+
+func (l *lossy) Send(m Message) error {
+	l.sent++
+	if l.sent%3 == 0 {
+		return nil
+	}
+	return l.Transport.Send(m)
+}
+
+func TestLostMessages(t *testing.T) {
+	transports := loopbacks(3)
+	for i := range transports {
+		transports[i] = &lossy{Transport: transports[i]}
+	}
+	sessions := newSessions(t, transports...)
+	recordings := []*sim.Replay{}
+	for _, s := range sessions {
+		recordings = append(recordings, s.game.Record())
+	}
+	sessions[2].Issue(sim.Command{Type: sim.Build, Target: mgl32.Vec3{5, 0, -5}})
+
+	// Players send lost inputs again while they wait, so they all keep going
+	slowest := func() uint64 {
+		turn := sessions[0].Turn()
+		for _, s := range sessions[1:] {
+			if s.Turn() < turn {
+				turn = s.Turn()
+			}
+		}
+		return turn
+	}
+	for i := 0; i < 10000 && slowest() < 50; i++ {
+		for _, s := range sessions {
+			_, err := s.Step()
+			assert.NoError(t, err)
+		}
+	}
+	assert.GreaterOrEqual(t, slowest(), uint64(50))
+	ticks := int(slowest()) * defaultTicksPerTurn
+	for _, recording := range recordings[1:] {
+		assert.Equal(t, recordings[0].Hashes[:ticks], recording.Hashes[:ticks])
+	}
+	assert.Len(t, sessions[0].game.World.Stockpiles, 4)
+}
+
+func TestDesync(t *testing.T) {
+	sessions := newSessions(t, loopbacks(2)...)
+	second := sessions[1]
+	second.game.World.RemoveNode(second.game.World.Nodes[0])
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		for _, s := range sessions {
+			if _, err = s.Step(); err != nil {
+				break
+			}
+		}
+	}
+	assert.ErrorIs(t, err, ErrDesync)
+	_, err = sessions[0].Step()
+	assert.ErrorIs(t, err, ErrDesync, "stays failed")
+}
+
+func TestLeaving(t *testing.T) {
+	sessions := newSessions(t, loopbacks(2)...)
+	runTo(t, 5, sessions...)
+	assert.NoError(t, sessions[1].Close())
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		_, err = sessions[0].Step()
+	}
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+// runConcurrently runs every session up to a turn in its own goroutine, like they would on different machines.
+func runConcurrently(t *testing.T, turn uint64, sessions ...*Session) {
+	var wg sync.WaitGroup
+	for _, s := range sessions {
+		wg.Add(1)
+		go func(s *Session) {
+			defer wg.Done()
+			deadline := time.Now().Add(10 * time.Second)
+			for s.Turn() < turn && time.Now().Before(deadline) {
+				ran, err := s.Step()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !ran {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	transports := make([]Transport, 3)
+	game := sim.Config{Seed: 1, Players: 3, Workers: 3, Brain: "bt", NavMesh: true}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		host, err := Host(listener, game)
+		assert.NoError(t, err)
+		transports[0] = host
+	}()
+	for i := 1; i < 3; i++ {
+		joined, err := Join(listener.Addr().String())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, joined.Players())
+		assert.Equal(t, game, joined.Game(), "the game is made the way the host says")
+		transports[joined.Player()] = joined
+	}
+	wg.Wait()
+
+	sessions := newSessions(t, transports...)
+	sessions[1].Issue(sim.Command{Type: sim.Build, Target: mgl32.Vec3{5, 0, -5}})
+	sessions[2].Issue(sim.Command{Type: sim.Build, Target: mgl32.Vec3{6, 0, -6}})
+	runConcurrently(t, 40, sessions...)
+	assertInStep(t, sessions...)
+	assert.Len(t, sessions[0].game.World.Stockpiles, 5)
+	for _, s := range sessions {
+		assert.NoError(t, s.Close())
+	}
+}
+
+func TestJoinNotWelcomed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		newTCPConn(conn).send(Message{Type: Checksum, Hash: 1})
+	}()
+
+	_, err = Join(listener.Addr().String())
+	assert.ErrorIs(t, err, ErrNotWelcomed)
+}
+
+func TestUDP(t *testing.T) {
+	conns := make([]*net.UDPConn, 2)
+	for i := range conns {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		assert.NoError(t, err)
+		conns[i] = conn
+	}
+	peer := func(i int) []*net.UDPAddr {
+		return []*net.UDPAddr{conns[1-i].LocalAddr().(*net.UDPAddr)}
+	}
+	sessions := newSessions(t, NewUDP(conns[0], 0, peer(0)), NewUDP(conns[1], 1, peer(1)))
+	sessions[1].Issue(sim.Command{Type: sim.Build, Target: mgl32.Vec3{5, 0, -5}})
+	runConcurrently(t, 40, sessions...)
+	assertInStep(t, sessions...)
+	assert.Len(t, sessions[0].game.World.Stockpiles, 3)
+	for _, s := range sessions {
+		assert.NoError(t, s.Close())
+	}
+}
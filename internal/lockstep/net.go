@@ -0,0 +1,218 @@
+package lockstep
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/sim"
+)
+
+// welcome tells a player that joined a host which player it is, and how the game of the match is made.
+const welcome MessageType = "welcome"
+
+// ErrNotWelcomed is returned when joining a host that sends something other than a welcome first.
+var ErrNotWelcomed = errors.New("not welcomed by the host")
+
+// maxDatagram is the size of the largest message that can be received over UDP.
+const maxDatagram = 64 * 1024
+
+// TCP is a transport over TCP, between a host and the players that joined it. The host passes on what each
+// player sends to the others.
+type TCP struct {
+	player resource.Player
+	game   sim.Config
+	// conns are the connections to the players that joined for the host, and to the host for them
+	conns []*tcpConn
+	host  bool
+	inbox
+}
+
+// tcpConn is a connection sending and receiving messages as lines of JSON.
+type tcpConn struct {
+	conn    net.Conn
+	decoder *json.Decoder
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newTCPConn(conn net.Conn) *tcpConn {
+	return &tcpConn{conn: conn, decoder: json.NewDecoder(conn), encoder: json.NewEncoder(conn)}
+}
+
+func (c *tcpConn) send(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encoder.Encode(m)
+}
+
+// Host waits for the other players of a match to join on a listener and returns the transport of the host,
+// which is player 0. The others are players 1 and up in the order they joined. The game of the match is made
+// from game, whose Players is how many players there are, and every player is sent it so they all make the
+// same one.
+func Host(listener net.Listener, game sim.Config) (*TCP, error) {
+	t := &TCP{game: game, host: true}
+	for player := 1; player < game.Players; player++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("waiting for player %d: %w", player, err)
+		}
+		c := newTCPConn(conn)
+		t.conns = append(t.conns, c)
+		if err := c.send(Message{Type: welcome, Player: resource.Player(player), Game: &game}); err != nil {
+			t.Close()
+			return nil, fmt.Errorf("welcoming player %d: %w", player, err)
+		}
+	}
+	for _, c := range t.conns {
+		go t.read(c)
+	}
+	return t, nil
+}
+
+// Join connects to the host of a match and waits to be told which player it is, and how the game of the match
+// is made, see Game.
+func Join(address string) (*TCP, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	c := newTCPConn(conn)
+	hello := Message{}
+	if err := c.decoder.Decode(&hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("joining %s: %w", address, err)
+	}
+	if hello.Type != welcome || hello.Game == nil {
+		conn.Close()
+		return nil, fmt.Errorf("joining %s: %w, got a %s message", address, ErrNotWelcomed, hello.Type)
+	}
+	t := &TCP{player: hello.Player, game: *hello.Game, conns: []*tcpConn{c}}
+	go t.read(c)
+	return t, nil
+}
+
+// read receives messages from a connection until it is closed, and passes them on if this is the host.
+func (t *TCP) read(from *tcpConn) {
+	for {
+		m := Message{}
+		if err := from.decoder.Decode(&m); err != nil {
+			t.fail(fmt.Errorf("receiving: %w", err))
+			return
+		}
+		t.push(m)
+		if !t.host {
+			continue
+		}
+		for _, c := range t.conns {
+			if c != from {
+				if err := c.send(m); err != nil {
+					t.fail(fmt.Errorf("passing on: %w", err))
+				}
+			}
+		}
+	}
+}
+
+func (t *TCP) Player() resource.Player {
+	return t.player
+}
+
+// Players returns how many players there are in the match.
+func (t *TCP) Players() int {
+	return t.game.Players
+}
+
+// Game returns how the game of the match is made, the same for every player.
+func (t *TCP) Game() sim.Config {
+	return t.game
+}
+
+func (t *TCP) Send(m Message) error {
+	if err := t.failed(); err != nil {
+		return err
+	}
+	for _, c := range t.conns {
+		if err := c.send(m); err != nil {
+			return fmt.Errorf("sending: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t *TCP) Receive() (Message, bool, error) {
+	return t.pop()
+}
+
+func (t *TCP) Close() error {
+	t.fail(ErrClosed)
+	for _, c := range t.conns {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// UDP is a transport sending each message to every other player in a datagram. Datagrams can be lost, which
+// Session makes up for by sending inputs again while it waits for others.
+type UDP struct {
+	player resource.Player
+	conn   *net.UDPConn
+	peers  []*net.UDPAddr
+	inbox
+}
+
+// NewUDP creates a transport for a player sending from conn to the other players at peers.
+func NewUDP(conn *net.UDPConn, player resource.Player, peers []*net.UDPAddr) *UDP {
+	u := &UDP{player: player, conn: conn, peers: peers}
+	go u.read()
+	return u
+}
+
+// read receives datagrams until the connection is closed, ignoring any that aren't messages.
+func (u *UDP) read() {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, _, err := u.conn.ReadFromUDP(buf)
+		if err != nil {
+			u.fail(fmt.Errorf("receiving: %w", err))
+			return
+		}
+		m := Message{}
+		if err := json.Unmarshal(buf[:n], &m); err == nil {
+			u.push(m)
+		}
+	}
+}
+
+func (u *UDP) Player() resource.Player {
+	return u.player
+}
+
+func (u *UDP) Send(m Message) error {
+	if err := u.failed(); err != nil {
+		return err
+	}
+	datagram, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	for _, peer := range u.peers {
+		if _, err := u.conn.WriteToUDP(datagram, peer); err != nil {
+			return fmt.Errorf("sending: %w", err)
+		}
+	}
+	return nil
+}
+
+func (u *UDP) Receive() (Message, bool, error) {
+	return u.pop()
+}
+
+func (u *UDP) Close() error {
+	u.fail(ErrClosed)
+	return u.conn.Close()
+}
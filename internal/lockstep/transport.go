@@ -0,0 +1,132 @@
+package lockstep
+
+import (
+	"errors"
+	"sync"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/sim"
+)
+
+// ErrClosed is returned when using a transport that has been closed.
+var ErrClosed = errors.New("transport closed")
+
+// MessageType is what a message holds.
+type MessageType string
+
+const (
+	// Input holds the commands of a player for a turn.
+	Input MessageType = "input"
+	// Checksum holds the hash of the state of the game of a player at the start of a turn.
+	Checksum MessageType = "checksum"
+)
+
+// Message is what players send each other.
+type Message struct {
+	Type     MessageType     `json:"type"`
+	Player   resource.Player `json:"player"`
+	Turn     uint64          `json:"turn"`
+	Commands []sim.Command   `json:"commands,omitempty"`
+	Hash     uint64          `json:"hash,omitempty"`
+	// Game is how the game of the match is made, when welcoming a player that joined.
+	Game *sim.Config `json:"game,omitempty"`
+}
+
+// Transport sends messages between the players of a match.
+type Transport interface {
+	// Player returns the player on this end.
+	Player() resource.Player
+	// Send sends a message to every other player.
+	Send(m Message) error
+	// Receive returns the next message from another player, or false if there is none yet. It doesn't wait.
+	Receive() (Message, bool, error)
+	Close() error
+}
+
+// inbox is a queue of received messages, for transports receiving them in the background.
+type inbox struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+func (in *inbox) push(m Message) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.messages = append(in.messages, m)
+}
+
+// fail makes Receive return err once the messages received before are read.
+func (in *inbox) fail(err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.err == nil {
+		in.err = err
+	}
+}
+
+// failed returns the error given to fail.
+func (in *inbox) failed() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.err
+}
+
+func (in *inbox) pop() (Message, bool, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.messages) == 0 {
+		return Message{}, false, in.err
+	}
+	m := in.messages[0]
+	in.messages = in.messages[1:]
+	return m, true, nil
+}
+
+// Loopback is a transport between players in the same process, for tests and playing against yourself.
+type Loopback struct {
+	player resource.Player
+	peers  []*Loopback
+	inbox
+}
+
+// NewLoopback creates connected transports for a number of players, one for each.
+func NewLoopback(players int) []*Loopback {
+	transports := make([]*Loopback, players)
+	for i := range transports {
+		transports[i] = &Loopback{player: resource.Player(i), peers: transports}
+	}
+	return transports
+}
+
+func (l *Loopback) Player() resource.Player {
+	return l.player
+}
+
+func (l *Loopback) Send(m Message) error {
+	if err := l.failed(); err != nil {
+		return err
+	}
+	for _, peer := range l.peers {
+		if peer != l {
+			peer.push(m)
+		}
+	}
+	return nil
+}
+
+func (l *Loopback) Receive() (Message, bool, error) {
+	return l.pop()
+}
+
+// Close stops the transport, the other players get ErrClosed from Receive once they have read everything sent
+// before.
+func (l *Loopback) Close() error {
+	l.fail(ErrClosed)
+	for _, peer := range l.peers {
+		if peer != l {
+			peer.fail(ErrClosed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+// Package mapgen generates maps from a seed: hills, lakes and rocky ground from noise, forests, resource
+// deposits, and start positions that are fair to all players. The same config always generates the same map.
+package mapgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/noise"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/tilemap"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ErrTooSmall is returned when the map has no room for the players.
+var ErrTooSmall = errors.New("map too small")
+
+const (
+	// minSize is the fewest tiles a map can be across, for the starts and their deposits to fit.
+	minSize = 24
+	// edgeMargin is how many tiles the starts are from the edge of the map.
+	edgeMargin = 7
+	// waterLevel and rockLevel are the heights of the height noise below which tiles are water and above which
+	// they are rock.
+	waterLevel = -0.2
+	rockLevel  = 0.35
+	// forestLevel is how moist grass has to be to be forest.
+	forestLevel = 0.1
+	// clearing is how many tiles around a start are cleared of water, rock and trees, to build on.
+	clearing float32 = 3
+	// depositClearing is how many tiles around a deposit are kept clear of trees.
+	depositClearing float32 = 1
+)
+
+// layout is where the deposits of each player are around their start: distance in tiles, and angle from the
+// direction to the middle of the map.
+var layout = []struct {
+	nodeType resource.NodeType
+	distance float32
+	angle    float64
+}{
+	{nodeType: resource.BerryBush, distance: 3.5, angle: 2.2},
+	{nodeType: resource.BerryBush, distance: 3.5, angle: -2.2},
+	{nodeType: resource.Rock, distance: 4.0, angle: 0.6},
+	{nodeType: resource.Rock, distance: 4.5, angle: 0.9},
+	{nodeType: resource.GoldMine, distance: 5.0, angle: -0.7},
+}
+
+// Config describes the map to generate.
+type Config struct {
+	Seed int64
+	// Width and Height are the number of tiles along x and z.
+	Width, Height int
+	// Origin is the world x and z of the corner of tile 0,0.
+	Origin mgl32.Vec2
+	// TileSize is 1 if 0.
+	TileSize float32
+	// Players is how many start positions there are, 2 if 0.
+	Players int
+	// FeatureSize is about how many tiles across hills, lakes and forests are, 12 if 0.
+	FeatureSize float32
+	// BaseHeight is the height of the ground on average, and Relief how much higher or lower it gets, 0.3 if 0.
+	BaseHeight float32
+	Relief     float32
+	// TreeSpacing is the least distance between trees, 0.7 tiles if 0.
+	TreeSpacing float32
+}
+
+// withDefaults returns the config with zero fields set to their defaults.
+func (c Config) withDefaults() Config {
+	if c.TileSize == 0 {
+		c.TileSize = 1
+	}
+	if c.Players == 0 {
+		c.Players = 2
+	}
+	if c.FeatureSize == 0 {
+		c.FeatureSize = 12
+	}
+	if c.Relief == 0 {
+		c.Relief = 0.3
+	}
+	if c.TreeSpacing == 0 {
+		c.TreeSpacing = 0.7 * c.TileSize
+	}
+	return c
+}
+
+// Deposit is where a resource node is placed.
+type Deposit struct {
+	Type     resource.NodeType
+	Position mgl32.Vec2
+}
+
+// Map is a generated map.
+type Map struct {
+	Tiles *tilemap.Map
+	// Trees are the world x and z of each tree.
+	Trees    []mgl32.Vec2
+	Deposits []Deposit
+	// Starts are the world x and z where each player starts.
+	Starts []mgl32.Vec2
+}
+
+// Generate generates a map.
+func Generate(config Config) (*Map, error) {
+	config = config.withDefaults()
+	if config.Width < minSize || config.Height < minSize {
+		return nil, fmt.Errorf("%w: %dx%d tiles, need at least %dx%d", ErrTooSmall, config.Width, config.Height, minSize, minSize)
+	}
+	g := &generator{
+		config: config,
+		random: rand.New(rand.NewSource(config.Seed)),
+		m:      &Map{Tiles: tilemap.New(config.Width, config.Height, config.Origin, config.TileSize)},
+	}
+	g.terrain()
+	g.starts()
+	g.deposits()
+	g.connect()
+	g.trees()
+	return g.m, nil
+}
+
+// generator holds what is being generated.
+type generator struct {
+	config Config
+	random *rand.Rand
+	m      *Map
+}
+
+// terrain sets the height and terrain of each tile from two layers of noise, one for height and one for
+// moisture.
+func (g *generator) terrain() {
+	height := noise.Fractal{Noise: noise.NewPerlin(g.config.Seed), Octaves: 4}
+	moisture := noise.Fractal{Noise: noise.NewPerlin(g.config.Seed + 1), Octaves: 3}
+	for y := 0; y < g.config.Height; y++ {
+		for x := 0; x < g.config.Width; x++ {
+			nx, ny := float64(x)/float64(g.config.FeatureSize), float64(y)/float64(g.config.FeatureSize)
+			h := height.At(nx, ny)
+			tile := tilemap.Tile{Terrain: tilemap.Grass, Height: g.height(h)}
+			switch {
+			case h < waterLevel:
+				// Lakes are flat
+				tile = tilemap.Tile{Terrain: tilemap.Water, Height: g.height(waterLevel)}
+			case h > rockLevel:
+				tile.Terrain = tilemap.Rock
+			case moisture.At(nx+100, ny+100) > forestLevel:
+				tile.Terrain = tilemap.Forest
+			}
+			g.m.Tiles.Set(pathfinding.Tile{X: x, Y: y}, tile)
+		}
+	}
+}
+
+// height returns the height of the ground for a value of the height noise.
+func (g *generator) height(h float64) float32 {
+	return g.config.BaseHeight + g.config.Relief*float32(h)
+}
+
+// starts places the players evenly on a circle around the middle of the map, turned by a random angle, and
+// clears the ground around them.
+func (g *generator) starts() {
+	radius := (float32(min(g.config.Width, g.config.Height))/2 - edgeMargin) * g.config.TileSize
+	offset := g.random.Float64() * 2 * math.Pi
+	for i := 0; i < g.config.Players; i++ {
+		angle := offset + 2*math.Pi*float64(i)/float64(g.config.Players)
+		start := g.center().Add(direction(angle).Mul(radius))
+		g.m.Starts = append(g.m.Starts, start)
+		g.clear(start, clearing*g.config.TileSize)
+	}
+}
+
+// deposits places the same deposits around each start, turned to face the middle of the map, and a gold mine
+// in the middle for the players to fight over.
+func (g *generator) deposits() {
+	for _, start := range g.m.Starts {
+		facing := g.center().Sub(start).Normalize()
+		for _, d := range layout {
+			position := start.Add(rotate(facing, d.angle).Mul(d.distance * g.config.TileSize))
+			g.m.Deposits = append(g.m.Deposits, Deposit{Type: d.nodeType, Position: position})
+		}
+	}
+	g.m.Deposits = append(g.m.Deposits, Deposit{Type: resource.GoldMine, Position: g.center()})
+	for _, d := range g.m.Deposits {
+		g.clear(d.Position, 0)
+	}
+}
+
+// connect makes sure every player can walk to the other players and to all deposits, cutting a straight way
+// through water and rock where they can't.
+func (g *generator) connect() {
+	first := g.tileAt(g.m.Starts[0])
+	targets := append([]mgl32.Vec2{}, g.m.Starts[1:]...)
+	for _, d := range g.m.Deposits {
+		targets = append(targets, d.Position)
+	}
+	for _, target := range targets {
+		if _, err := pathfinding.FindPath(g.m.Tiles, first, g.tileAt(target), pathfinding.NoCornerCutting); err == nil {
+			continue
+		}
+		g.cut(g.m.Starts[0], target)
+	}
+}
+
+// cut turns the tiles along a straight line into grass, with no diagonal steps so that it can be walked.
+func (g *generator) cut(from, to mgl32.Vec2) {
+	steps := int(math.Ceil(float64(to.Sub(from).Len()/g.config.TileSize))) * 4
+	previous := g.tileAt(from)
+	for i := 0; i <= steps; i++ {
+		t := g.tileAt(from.Add(to.Sub(from).Mul(float32(i) / float32(steps))))
+		if t.X != previous.X && t.Y != previous.Y {
+			g.makeWalkable(pathfinding.Tile{X: t.X, Y: previous.Y})
+		}
+		g.makeWalkable(t)
+		previous = t
+	}
+}
+
+// trees fills the forests with trees, Poisson-disc spaced, away from starts and deposits.
+func (g *generator) trees() {
+	size := mgl32.Vec2{float32(g.config.Width), float32(g.config.Height)}.Mul(g.config.TileSize)
+	candidates := PoissonDisc(g.random, g.config.Origin, g.config.Origin.Add(size), g.config.TreeSpacing)
+	for _, p := range candidates {
+		if g.m.Tiles.At(g.tileAt(p)).Terrain != tilemap.Forest || g.near(p) {
+			continue
+		}
+		g.m.Trees = append(g.m.Trees, p)
+	}
+}
+
+// near reports if a position is too close to a start or deposit for a tree.
+func (g *generator) near(p mgl32.Vec2) bool {
+	for _, start := range g.m.Starts {
+		if p.Sub(start).Len() < clearing*g.config.TileSize {
+			return true
+		}
+	}
+	for _, d := range g.m.Deposits {
+		if p.Sub(d.Position).Len() < depositClearing*g.config.TileSize {
+			return true
+		}
+	}
+	return false
+}
+
+// clear turns the tiles within radius of a position into grass, and always the tile it is on.
+func (g *generator) clear(p mgl32.Vec2, radius float32) {
+	center := g.tileAt(p)
+	reach := int(math.Ceil(float64(radius / g.config.TileSize)))
+	for y := center.Y - reach; y <= center.Y+reach; y++ {
+		for x := center.X - reach; x <= center.X+reach; x++ {
+			t := pathfinding.Tile{X: x, Y: y}
+			c := g.m.Tiles.Center(t)
+			if t == center || (mgl32.Vec2{c.X(), c.Z()}).Sub(p).Len() <= radius {
+				g.m.Tiles.SetTerrain(t, tilemap.Grass)
+			}
+		}
+	}
+}
+
+// makeWalkable turns a tile into grass if it can't be walked on.
+func (g *generator) makeWalkable(t pathfinding.Tile) {
+	if !g.m.Tiles.Walkable(t) {
+		g.m.Tiles.SetTerrain(t, tilemap.Grass)
+	}
+}
+
+func (g *generator) tileAt(p mgl32.Vec2) pathfinding.Tile {
+	return g.m.Tiles.TileAt(fixed.FromVec2(p))
+}
+
+// center returns the world x and z of the middle of the map.
+func (g *generator) center() mgl32.Vec2 {
+	return g.config.Origin.Add(mgl32.Vec2{float32(g.config.Width), float32(g.config.Height)}.Mul(g.config.TileSize / 2))
+}
+
+func direction(angle float64) mgl32.Vec2 {
+	return mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))}
+}
+
+// rotate turns v counter-clockwise by angle.
+func rotate(v mgl32.Vec2, angle float64) mgl32.Vec2 {
+	c, s := float32(math.Cos(angle)), float32(math.Sin(angle))
+	return mgl32.Vec2{v.X()*c - v.Y()*s, v.X()*s + v.Y()*c}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// depositChars are how deposits of each kind are drawn by Format.
+var depositChars = map[resource.Kind]byte{
+	resource.Stone: 'S',
+	resource.Gold:  'G',
+	resource.Food:  'B',
+}
+
+// Format draws the map like tilemap.Map.Format, with tiles that have trees drawn as T, deposits as S, G and
+// B for stone, gold and berries, and starts as the number of the player. Below it the height of each tile
+// is drawn from 0 for the lowest tile to 9 for the highest.
+func (m *Map) Format() string {
+	width, height := m.Tiles.Size()
+	rows := strings.Split(strings.TrimSuffix(m.Tiles.Format(), "\n"), "\n")
+	grid := make([][]byte, len(rows))
+	for y, row := range rows {
+		grid[y] = []byte(row)
+	}
+	mark := func(p mgl32.Vec2, c byte) {
+		if t := m.Tiles.TileAt(fixed.FromVec2(p)); m.Tiles.InBounds(t) {
+			grid[t.Y][t.X] = c
+		}
+	}
+	for _, tree := range m.Trees {
+		mark(tree, 'T')
+	}
+	for _, d := range m.Deposits {
+		mark(d.Position, depositChars[d.Type.Kind])
+	}
+	for i, start := range m.Starts {
+		mark(start, byte('1'+i%9))
+	}
+
+	low, high := float32(math.Inf(1)), float32(math.Inf(-1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			h := m.Tiles.At(pathfinding.Tile{X: x, Y: y}).Height
+			if h < low {
+				low = h
+			}
+			if h > high {
+				high = h
+			}
+		}
+	}
+	var sb strings.Builder
+	for _, row := range grid {
+		sb.Write(row)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			level := 0
+			if high > low {
+				level = int((m.Tiles.At(pathfinding.Tile{X: x, Y: y}).Height - low) / (high - low) * 9.999)
+			}
+			sb.WriteByte(byte('0' + level))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
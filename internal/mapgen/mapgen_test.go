@@ -0,0 +1,140 @@
+package mapgen
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/tilemap"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+var configs = []Config{
+	{Seed: 1, Width: 40, Height: 28},
+	{Seed: 2, Width: 32, Height: 32, Players: 4},
+	{Seed: 3, Width: 48, Height: 24, Players: 3, Origin: mgl32.Vec2{-10, 5}, TileSize: 0.5},
+}
+
+func TestGolden(t *testing.T) {
+	for _, config := range configs {
+		name := fmt.Sprintf("seed%d.golden", config.Seed)
+		t.Run(name, func(t *testing.T) {
+			m, err := Generate(config)
+			assert.NoError(t, err)
+			golden := filepath.Join("testdata", name)
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(m.Format()), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), m.Format(), "run go test -update after changing the generator on purpose")
+		})
+	}
+}
+
+func TestSameSeedSameMap(t *testing.T) {
+	a, err := Generate(Config{Seed: 7, Width: 30, Height: 30})
+	assert.NoError(t, err)
+	b, err := Generate(Config{Seed: 7, Width: 30, Height: 30})
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	c, err := Generate(Config{Seed: 8, Width: 30, Height: 30})
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.Format(), c.Format())
+}
+
+func TestFairStarts(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		config := Config{Seed: seed, Width: 32, Height: 28, Players: 2 + int(seed%3)}
+		m, err := Generate(config)
+		assert.NoError(t, err)
+		assert.Len(t, m.Starts, config.Players)
+
+		// Every player gets the same deposits at the same distances, they come first for each player in turn
+		deposits := func(player int) []string {
+			found := []string{}
+			for _, d := range m.Deposits[player*len(layout) : (player+1)*len(layout)] {
+				found = append(found, fmt.Sprintf("%s %.3f", d.Type.Kind, d.Position.Sub(m.Starts[player]).Len()))
+			}
+			sort.Strings(found)
+			return found
+		}
+		assert.Len(t, m.Deposits, config.Players*len(layout)+1, "and one in the middle")
+		for i, start := range m.Starts {
+			assert.Equal(t, deposits(0), deposits(i), "seed %d, player %d", seed, i)
+
+			// Room to build around the start, and every deposit and other player can be walked to
+			center := m.Tiles.TileAt(fixed.FromVec2(start))
+			for y := center.Y - 1; y <= center.Y+1; y++ {
+				for x := center.X - 1; x <= center.X+1; x++ {
+					assert.Equal(t, tilemap.Grass, m.Tiles.At(pathfinding.Tile{X: x, Y: y}).Terrain, "seed %d, player %d", seed, i)
+				}
+			}
+			for _, d := range m.Deposits {
+				goal := m.Tiles.TileAt(fixed.FromVec2(d.Position))
+				_, err := pathfinding.FindPath(m.Tiles, center, goal, pathfinding.NoCornerCutting)
+				assert.NoError(t, err, "seed %d, player %d to %v", seed, i, d)
+			}
+			for _, tree := range m.Trees {
+				assert.GreaterOrEqual(t, tree.Sub(start).Len(), clearing)
+			}
+		}
+	}
+}
+
+func TestTrees(t *testing.T) {
+	config := Config{Seed: 4, Width: 32, Height: 32, TreeSpacing: 0.5}
+	m, err := Generate(config)
+	assert.NoError(t, err)
+	assert.Greater(t, len(m.Trees), 100)
+	for i, tree := range m.Trees {
+		assert.Equal(t, tilemap.Forest, m.Tiles.At(m.Tiles.TileAt(fixed.FromVec2(tree))).Terrain)
+		for _, other := range m.Trees[i+1:] {
+			assert.GreaterOrEqual(t, tree.Sub(other).Len(), config.TreeSpacing)
+		}
+		for _, d := range m.Deposits {
+			assert.GreaterOrEqual(t, tree.Sub(d.Position).Len(), depositClearing)
+		}
+	}
+}
+
+func TestPoissonDisc(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	min, max := mgl32.Vec2{-5, 2}, mgl32.Vec2{15, 12}
+	points := PoissonDisc(random, min, max, 1.0)
+	for i, p := range points {
+		assert.True(t, p.X() >= min.X() && p.Y() >= min.Y() && p.X() < max.X() && p.Y() < max.Y(), "%v", p)
+		for _, other := range points[i+1:] {
+			assert.GreaterOrEqual(t, p.Sub(other).Len(), float32(1.0))
+		}
+	}
+	// No gaps big enough for another point: every spot is close to a point
+	for i := 0; i < 200; i++ {
+		spot := min.Add(mgl32.Vec2{random.Float32() * 20, random.Float32() * 10})
+		closest := float32(100)
+		for _, p := range points {
+			if d := p.Sub(spot).Len(); d < closest {
+				closest = d
+			}
+		}
+		assert.Less(t, closest, float32(2.0), "gap at %v", spot)
+	}
+
+	assert.Empty(t, PoissonDisc(random, max, min, 1.0))
+}
+
+func TestTooSmall(t *testing.T) {
+	_, err := Generate(Config{Width: 20, Height: 30})
+	assert.ErrorIs(t, err, ErrTooSmall)
+}
@@ -0,0 +1,78 @@
+package mapgen
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// poissonTries is how many points are tried around each point before giving up on finding room for another.
+const poissonTries = 30
+
+// PoissonDisc returns points spread over the area between min and max, no two closer than spacing, with no
+// room left for another. It is Bridson's algorithm: new points are tried around the points already placed,
+// and a grid of cells small enough to hold one point each finds the points close to a new one.
+func PoissonDisc(random *rand.Rand, min, max mgl32.Vec2, spacing float32) []mgl32.Vec2 {
+	size := max.Sub(min)
+	if size.X() <= 0 || size.Y() <= 0 {
+		return nil
+	}
+	cellSize := spacing / math.Sqrt2
+	columns := int(math.Ceil(float64(size.X() / cellSize)))
+	rows := int(math.Ceil(float64(size.Y() / cellSize)))
+	// grid holds the index of the point in each cell plus one, 0 for none
+	grid := make([]int, columns*rows)
+	cell := func(p mgl32.Vec2) (int, int) {
+		local := p.Sub(min).Mul(1 / cellSize)
+		return int(local.X()), int(local.Y())
+	}
+	fits := func(p mgl32.Vec2, points []mgl32.Vec2) bool {
+		if p.X() < min.X() || p.Y() < min.Y() || p.X() >= max.X() || p.Y() >= max.Y() {
+			return false
+		}
+		cx, cy := cell(p)
+		for y := cy - 2; y <= cy+2; y++ {
+			for x := cx - 2; x <= cx+2; x++ {
+				if x < 0 || y < 0 || x >= columns || y >= rows {
+					continue
+				}
+				if i := grid[y*columns+x]; i > 0 && points[i-1].Sub(p).Len() < spacing {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	first := min.Add(mgl32.Vec2{random.Float32() * size.X(), random.Float32() * size.Y()})
+	points := []mgl32.Vec2{first}
+	cx, cy := cell(first)
+	grid[cy*columns+cx] = 1
+	active := []int{0}
+	for len(active) > 0 {
+		a := random.Intn(len(active))
+		center := points[active[a]]
+		placed := false
+		for try := 0; try < poissonTries; try++ {
+			// Somewhere between spacing and twice spacing away
+			angle := random.Float64() * 2 * math.Pi
+			distance := spacing * (1 + random.Float32())
+			p := center.Add(mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))}.Mul(distance))
+			if !fits(p, points) {
+				continue
+			}
+			points = append(points, p)
+			x, y := cell(p)
+			grid[y*columns+x] = len(points)
+			active = append(active, len(points)-1)
+			placed = true
+			break
+		}
+		if !placed {
+			active[a] = active[len(active)-1]
+			active = active[:len(active)-1]
+		}
+	}
+	return points
+}
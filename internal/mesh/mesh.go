@@ -38,10 +38,6 @@ func (m *Mesh) DrawNonIndex() {
 }
 
 func FromFile(filepath string) (Mesh, error) {
-	if err := gl.Init(); err != nil {
-		panic(err)
-	}
-
 	filecontent, err := objloader.ReadFile(filepath)
 	if err != nil {
 		fmt.Printf("error: %+v\n", err)
@@ -49,6 +45,16 @@ func FromFile(filepath string) (Mesh, error) {
 	}
 
 	vertexArray, hasUV, hasNormal := objloader.Load(filecontent)
+	return FromVertices(vertexArray, hasUV, hasNormal), nil
+}
+
+// FromVertices creates a mesh from triangles laid out like objloader.Load returns them: for each vertex its
+// position, then its UV and normal if the mesh has them.
+func FromVertices(vertexArray []float32, hasUV, hasNormal bool) Mesh {
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
 	stride := 3
 	if hasUV {
 		stride += 2
@@ -95,7 +101,7 @@ func FromFile(filepath string) (Mesh, error) {
 		Vao:     vao,
 		Vbo:     vbo,
 		nrVerts: int32(len(vertexArray) / stride),
-	}, nil
+	}
 }
 
 func MakeCube() Mesh {
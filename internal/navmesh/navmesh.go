@@ -0,0 +1,428 @@
+// Package navmesh finds paths over a navigation mesh: convex walkable polygons covering the ground units can
+// reach, built from a tile map minus the footprints of obstacles such as trees.
+//
+// The ground is split into cells smaller than tiles. Cells are walkable when an agent standing anywhere in
+// them clears all blocked tiles and obstacles by its radius, and walkable cells are merged into rectangles.
+// Paths go from polygon to polygon and are pulled tight with the funnel algorithm, so they are straight over
+// open ground instead of zig-zagging from tile to tile. The mesh is built and searched in fixed point, so
+// every machine finds the same polygons and paths.
+package navmesh
+
+import (
+	"errors"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/pathfinding"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+var (
+	ErrNoPath = errors.New("no path")
+	// ErrEmpty is returned when there is no walkable ground at all to find paths over.
+	ErrEmpty = errors.New("navigation mesh is empty")
+)
+
+// noPolygon is the polygon of cells that are not walkable.
+const noPolygon = -1
+
+// Obstacle is something round units can't walk through, like the trunk of a tree.
+type Obstacle struct {
+	Center mgl32.Vec2
+	Radius float32
+}
+
+// Config describes how the mesh is laid over the tile map.
+type Config struct {
+	// Origin is the world x and z of the corner of tile 0,0, tile x runs along world x and tile y along world z.
+	Origin   mgl32.Vec2
+	TileSize float32
+	// CellsPerTile is how many cells each side of a tile is split in. More cells follow the shape of obstacles
+	// more closely, but make more polygons.
+	CellsPerTile int
+	// AgentRadius is how far units keep from blocked tiles, obstacles and the edge of the map.
+	AgentRadius float32
+}
+
+// Polygon is a convex walkable area of the mesh, the polygons of a mesh are rectangles.
+type Polygon struct {
+	// Min and Max are the corners of the polygon.
+	Min, Max fixed.Vec2
+
+	cells   cellRect
+	portals []portal
+	alive   bool
+}
+
+// portal is an edge shared by two polygons, with left and right as seen walking through it.
+type portal struct {
+	to          int
+	left, right fixed.Vec2
+}
+
+// cellRect are the cells x0 <= x < x1 and y0 <= y < y1.
+type cellRect struct {
+	x0, y0, x1, y1 int
+}
+
+// NavMesh is a navigation mesh over a tile map. Obstacles can be added and removed, only the polygons around
+// them are rebuilt.
+type NavMesh struct {
+	config Config
+	tiles  pathfinding.Map
+	// origin, tileSize and agentRadius are the config in fixed point
+	origin      fixed.Vec2
+	tileSize    fixed.Scalar
+	agentRadius fixed.Scalar
+	cellSize    fixed.Scalar
+	width       int
+	height      int
+	// clear are the cells away from blocked tiles and the edge of the map
+	clear []bool
+	// blockers is how many obstacles cover each cell
+	blockers  []int
+	obstacles []Obstacle
+	polygons  []Polygon
+	// free are the indices of removed polygons, reused for new ones
+	free []int
+	// owners are the polygons each cell is in
+	owners []int
+}
+
+// New builds a navigation mesh over the passable tiles of a tile map, around the obstacles.
+func New(tiles pathfinding.Map, obstacles []Obstacle, config Config) *NavMesh {
+	if config.CellsPerTile < 1 {
+		config.CellsPerTile = 1
+	}
+	tilesX, tilesY := tiles.Size()
+	tileSize := fixed.FromFloat32(config.TileSize)
+	m := &NavMesh{
+		config:      config,
+		tiles:       tiles,
+		origin:      fixed.FromVec2(config.Origin),
+		tileSize:    tileSize,
+		cellSize:    tileSize.Div(fixed.FromInt(config.CellsPerTile)),
+		agentRadius: fixed.FromFloat32(config.AgentRadius),
+		width:       tilesX * config.CellsPerTile,
+		height:      tilesY * config.CellsPerTile,
+	}
+	m.clear = make([]bool, m.width*m.height)
+	m.blockers = make([]int, m.width*m.height)
+	m.owners = make([]int, m.width*m.height)
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			m.clear[y*m.width+x] = m.clearOfTiles(x, y)
+			m.owners[y*m.width+x] = noPolygon
+		}
+	}
+	for _, o := range obstacles {
+		m.obstacles = append(m.obstacles, o)
+		m.cover(o, 1)
+	}
+	m.rebuild(cellRect{0, 0, m.width, m.height})
+	return m
+}
+
+// AddObstacle adds an obstacle and rebuilds the polygons around it.
+func (m *NavMesh) AddObstacle(o Obstacle) {
+	m.obstacles = append(m.obstacles, o)
+	m.rebuild(m.cover(o, 1))
+}
+
+// RemoveObstacle removes an obstacle, e.g. when a tree is chopped down, and rebuilds the polygons around it.
+// It returns false if there is no such obstacle.
+func (m *NavMesh) RemoveObstacle(o Obstacle) bool {
+	for i, other := range m.obstacles {
+		if other == o {
+			m.obstacles = append(m.obstacles[:i], m.obstacles[i+1:]...)
+			m.rebuild(m.cover(o, -1))
+			return true
+		}
+	}
+	return false
+}
+
+// Obstacles returns the obstacles of the mesh.
+func (m *NavMesh) Obstacles() []Obstacle {
+	return m.obstacles
+}
+
+// Polygons returns the polygons of the mesh.
+func (m *NavMesh) Polygons() []Polygon {
+	polygons := make([]Polygon, 0, len(m.polygons)-len(m.free))
+	for _, p := range m.polygons {
+		if p.alive {
+			polygons = append(polygons, Polygon{Min: p.Min, Max: p.Max})
+		}
+	}
+	return polygons
+}
+
+// cover adds count to the blockers of the cells an obstacle keeps agents out of, and returns those cells.
+func (m *NavMesh) cover(o Obstacle, count int) cellRect {
+	center := fixed.FromVec2(o.Center)
+	reach := fixed.FromFloat32(o.Radius) + m.agentRadius
+	area := m.cellsIn(center.Sub(fixed.Vec2{reach, reach}), center.Add(fixed.Vec2{reach, reach}))
+	for y := area.y0; y < area.y1; y++ {
+		for x := area.x0; x < area.x1; x++ {
+			min, max := m.cellBounds(x, y)
+			if distanceToRect(center, min, max) < reach {
+				m.blockers[y*m.width+x] += count
+			}
+		}
+	}
+	return area
+}
+
+// clearOfTiles reports if agents anywhere in a cell keep their radius from blocked tiles and the edge of the
+// map.
+func (m *NavMesh) clearOfTiles(x, y int) bool {
+	min, max := m.cellBounds(x, y)
+	radius := m.agentRadius
+	if !m.passable(m.tileAt(min.Add(max).Mul(fixed.Half))) {
+		return false
+	}
+	first := m.tileAt(min.Sub(fixed.Vec2{radius, radius}))
+	last := m.tileAt(max.Add(fixed.Vec2{radius, radius}))
+	for ty := first.Y; ty <= last.Y; ty++ {
+		for tx := first.X; tx <= last.X; tx++ {
+			tile := pathfinding.Tile{X: tx, Y: ty}
+			if m.passable(tile) {
+				continue
+			}
+			tileMin := m.origin.Add(fixed.Vec2{fixed.FromInt(tx), fixed.FromInt(ty)}.Mul(m.tileSize))
+			tileMax := tileMin.Add(fixed.Vec2{m.tileSize, m.tileSize})
+			if rectDistance(min, max, tileMin, tileMax) < radius {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// passable reports if a tile is in the map and can be walked on.
+func (m *NavMesh) passable(t pathfinding.Tile) bool {
+	width, height := m.tiles.Size()
+	return t.X >= 0 && t.Y >= 0 && t.X < width && t.Y < height && m.tiles.Cost(t) > 0
+}
+
+// tileAt returns the tile a point is on, it may be outside the map.
+func (m *NavMesh) tileAt(p fixed.Vec2) pathfinding.Tile {
+	local := p.Sub(m.origin)
+	return pathfinding.Tile{X: local.X().Div(m.tileSize).Int(), Y: local.Y().Div(m.tileSize).Int()}
+}
+
+// cellAt returns the cell a point is on, it may be outside the mesh.
+func (m *NavMesh) cellAt(p fixed.Vec2) (int, int) {
+	local := p.Sub(m.origin)
+	return local.X().Div(m.cellSize).Int(), local.Y().Div(m.cellSize).Int()
+}
+
+// cellsIn returns the cells overlapping the area between min and max, within the mesh.
+func (m *NavMesh) cellsIn(min, max fixed.Vec2) cellRect {
+	x0, y0 := m.cellAt(min)
+	x1, y1 := m.cellAt(max)
+	return cellRect{clamp(x0, 0, m.width), clamp(y0, 0, m.height), clamp(x1+1, 0, m.width), clamp(y1+1, 0, m.height)}
+}
+
+// cellBounds returns the corners of a cell.
+func (m *NavMesh) cellBounds(x, y int) (fixed.Vec2, fixed.Vec2) {
+	return m.corner(x, y), m.corner(x+1, y+1)
+}
+
+// corner returns the position of the corner at the bottom left of a cell.
+func (m *NavMesh) corner(x, y int) fixed.Vec2 {
+	return m.origin.Add(fixed.Vec2{fixed.FromInt(x), fixed.FromInt(y)}.Mul(m.cellSize))
+}
+
+// walkable reports if a cell is in the mesh and agents can stand anywhere in it.
+func (m *NavMesh) walkable(x, y int) bool {
+	i := y*m.width + x
+	return m.clear[i] && m.blockers[i] == 0
+}
+
+// owner returns the polygon a cell is in, or noPolygon.
+func (m *NavMesh) owner(x, y int) int {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return noPolygon
+	}
+	return m.owners[y*m.width+x]
+}
+
+// rebuild replaces the polygons overlapping an area with new ones. Every walkable cell outside the area keeps
+// its polygon, so only the cells of the removed polygons need to be merged again.
+func (m *NavMesh) rebuild(area cellRect) {
+	// Remove the polygons overlapping the area, the new ones go over their cells and the area
+	bounds := area
+	for y := area.y0; y < area.y1; y++ {
+		for x := area.x0; x < area.x1; x++ {
+			if p := m.owners[y*m.width+x]; p != noPolygon {
+				bounds = bounds.union(m.polygons[p].cells)
+				m.removePolygon(p)
+			}
+		}
+	}
+
+	// Merge the free walkable cells into rectangles, as wide as possible and then as tall as possible
+	free := func(x, y int) bool {
+		return m.walkable(x, y) && m.owners[y*m.width+x] == noPolygon
+	}
+	added := []int{}
+	for y := bounds.y0; y < bounds.y1; y++ {
+		for x := bounds.x0; x < bounds.x1; x++ {
+			if !free(x, y) {
+				continue
+			}
+			r := cellRect{x, y, x + 1, y + 1}
+			for r.x1 < bounds.x1 && free(r.x1, y) {
+				r.x1++
+			}
+		grow:
+			for r.y1 < bounds.y1 {
+				for cx := r.x0; cx < r.x1; cx++ {
+					if !free(cx, r.y1) {
+						break grow
+					}
+				}
+				r.y1++
+			}
+			added = append(added, m.addPolygon(r))
+		}
+	}
+
+	// Connect the new polygons, and their neighbours to them
+	bounds = cellRect{clamp(bounds.x0-1, 0, m.width), clamp(bounds.y0-1, 0, m.height), clamp(bounds.x1+1, 0, m.width), clamp(bounds.y1+1, 0, m.height)}
+	connected := map[int]bool{}
+	for y := bounds.y0; y < bounds.y1; y++ {
+		for x := bounds.x0; x < bounds.x1; x++ {
+			if p := m.owners[y*m.width+x]; p != noPolygon && !connected[p] {
+				connected[p] = true
+				m.connect(p)
+			}
+		}
+	}
+}
+
+// addPolygon adds a polygon over the cells of r and returns its index.
+func (m *NavMesh) addPolygon(r cellRect) int {
+	p := Polygon{Min: m.corner(r.x0, r.y0), Max: m.corner(r.x1, r.y1), cells: r, alive: true}
+	var index int
+	if n := len(m.free); n > 0 {
+		index = m.free[n-1]
+		m.free = m.free[:n-1]
+		m.polygons[index] = p
+	} else {
+		index = len(m.polygons)
+		m.polygons = append(m.polygons, p)
+	}
+	for y := r.y0; y < r.y1; y++ {
+		for x := r.x0; x < r.x1; x++ {
+			m.owners[y*m.width+x] = index
+		}
+	}
+	return index
+}
+
+// removePolygon removes a polygon, leaving its cells without one.
+func (m *NavMesh) removePolygon(index int) {
+	r := m.polygons[index].cells
+	for y := r.y0; y < r.y1; y++ {
+		for x := r.x0; x < r.x1; x++ {
+			m.owners[y*m.width+x] = noPolygon
+		}
+	}
+	m.polygons[index] = Polygon{}
+	m.free = append(m.free, index)
+}
+
+// connect finds the portals of a polygon by walking around its edges. Left and right are seen walking out of
+// the polygon, with y going up.
+func (m *NavMesh) connect(index int) {
+	p := &m.polygons[index]
+	r := p.cells
+	p.portals = p.portals[:0]
+
+	// Each side is walked from its right end to its left end, as seen leaving the polygon. Corner i is between
+	// cells i-1 and i of the side.
+	sides := []struct {
+		length       int
+		cell, corner func(i int) (int, int)
+	}{
+		// Bottom, leaving towards -y with +x on the left
+		{r.x1 - r.x0, func(i int) (int, int) { return r.x0 + i, r.y0 - 1 }, func(i int) (int, int) { return r.x0 + i, r.y0 }},
+		// Right, leaving towards +x with +y on the left
+		{r.y1 - r.y0, func(i int) (int, int) { return r.x1, r.y0 + i }, func(i int) (int, int) { return r.x1, r.y0 + i }},
+		// Top, leaving towards +y with -x on the left
+		{r.x1 - r.x0, func(i int) (int, int) { return r.x1 - 1 - i, r.y1 }, func(i int) (int, int) { return r.x1 - i, r.y1 }},
+		// Left, leaving towards -x with -y on the left
+		{r.y1 - r.y0, func(i int) (int, int) { return r.x0 - 1, r.y1 - 1 - i }, func(i int) (int, int) { return r.x0, r.y1 - i }},
+	}
+	for _, side := range sides {
+		start := 0
+		for i := 1; i <= side.length; i++ {
+			current := m.owner(side.cell(start))
+			if i < side.length && m.owner(side.cell(i)) == current {
+				continue
+			}
+			if current != noPolygon {
+				p.portals = append(p.portals, portal{
+					to:    current,
+					right: m.corner(side.corner(start)),
+					left:  m.corner(side.corner(i)),
+				})
+			}
+			start = i
+		}
+	}
+}
+
+// union returns the smallest rectangle covering both r and other.
+func (r cellRect) union(other cellRect) cellRect {
+	if other.x0 < r.x0 {
+		r.x0 = other.x0
+	}
+	if other.y0 < r.y0 {
+		r.y0 = other.y0
+	}
+	if other.x1 > r.x1 {
+		r.x1 = other.x1
+	}
+	if other.y1 > r.y1 {
+		r.y1 = other.y1
+	}
+	return r
+}
+
+// distanceToRect returns the distance from a point to the closest point of a rectangle.
+func distanceToRect(p, min, max fixed.Vec2) fixed.Scalar {
+	return clampPoint(p, min, max).Sub(p).Len()
+}
+
+// rectDistance returns the distance between the closest points of two rectangles.
+func rectDistance(min, max, otherMin, otherMax fixed.Vec2) fixed.Scalar {
+	dx := larger(0, larger(otherMin.X()-max.X(), min.X()-otherMax.X()))
+	dy := larger(0, larger(otherMin.Y()-max.Y(), min.Y()-otherMax.Y()))
+	return fixed.Vec2{dx, dy}.Len()
+}
+
+// clampPoint returns the point of a rectangle closest to p.
+func clampPoint(p, min, max fixed.Vec2) fixed.Vec2 {
+	return fixed.Vec2{p.X().Clamp(min.X(), max.X()), p.Y().Clamp(min.Y(), max.Y())}
+}
+
+func larger(a, b fixed.Scalar) fixed.Scalar {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clamp(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
@@ -0,0 +1,300 @@
+package navmesh
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/objloader"
+	"game-engine/rts/internal/pathfinding"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t testing.TB, ascii string) *pathfinding.Grid {
+	g, _, err := pathfinding.ParseGrid(ascii)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+var config = Config{TileSize: 1.0, CellsPerTile: 4, AgentRadius: 0.1}
+
+// floats converts a path to float points, to measure it.
+func floats(path []fixed.Vec2) []mgl32.Vec2 {
+	points := make([]mgl32.Vec2, len(path))
+	for i, p := range path {
+		points[i] = p.Float()
+	}
+	return points
+}
+
+// clearance returns how close a path gets to the obstacles.
+func clearance(path []fixed.Vec2, obstacles []Obstacle) float32 {
+	closest := float32(1e9)
+	points := floats(path)
+	for i := 1; i < len(points); i++ {
+		for _, o := range obstacles {
+			if d := segmentDistance(o.Center, points[i-1], points[i]) - o.Radius; d < closest {
+				closest = d
+			}
+		}
+	}
+	return closest
+}
+
+func segmentDistance(p, a, b mgl32.Vec2) float32 {
+	ab := b.Sub(a)
+	t := float32(0)
+	if ab.LenSqr() > 0 {
+		t = p.Sub(a).Dot(ab) / ab.LenSqr()
+	}
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+	return a.Add(ab.Mul(t)).Sub(p).Len()
+}
+
+// blockedTiles reports if a path crosses the blocked tiles of a grid, sampling it finely.
+func blockedTiles(g *pathfinding.Grid, path []fixed.Vec2) bool {
+	points := floats(path)
+	for i := 1; i < len(points); i++ {
+		for s := float32(0); s <= 1; s += 0.01 {
+			p := points[i-1].Add(points[i].Sub(points[i-1]).Mul(s))
+			if g.Cost(pathfinding.Tile{X: int(p.X()), Y: int(p.Y())}) <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestFindPath(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		ascii     string
+		obstacles []Obstacle
+		from, to  mgl32.Vec2
+		// corners is how many points the path has, including its ends
+		corners int
+	}{
+		{
+			desc:    "open ground is a straight line",
+			ascii:   "......\n......\n......\n......",
+			from:    mgl32.Vec2{0.5, 0.5},
+			to:      mgl32.Vec2{5.5, 3.2},
+			corners: 2,
+		},
+		{
+			desc:    "around a wall",
+			ascii:   "......\n.####.\n......",
+			from:    mgl32.Vec2{3, 0.5},
+			to:      mgl32.Vec2{3, 2.5},
+			corners: 4,
+		},
+		{
+			desc:      "around a tree",
+			ascii:     "......\n......\n......",
+			obstacles: []Obstacle{{Center: mgl32.Vec2{3, 1.5}, Radius: 0.3}},
+			from:      mgl32.Vec2{0.5, 1.5},
+			to:        mgl32.Vec2{5.5, 1.5},
+			corners:   4,
+		},
+		{
+			desc:    "through a gap",
+			ascii:   "...#...\n.......\n...#...",
+			from:    mgl32.Vec2{0.5, 0.5},
+			to:      mgl32.Vec2{6.5, 0.5},
+			corners: 4,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g := parse(t, tc.ascii)
+			m := New(g, tc.obstacles, config)
+			from, to := fixed.FromVec2(tc.from), fixed.FromVec2(tc.to)
+			path, err := m.FindPath(from, to)
+			assert.NoError(t, err)
+			assert.Len(t, path, tc.corners, "%v", path)
+			assert.Equal(t, from, path[0])
+			assert.Equal(t, to, path[len(path)-1])
+			assert.False(t, blockedTiles(g, path), "%v", path)
+			if len(tc.obstacles) > 0 {
+				// Cells are blocked as soon as any part of them is too close, so paths keep at most a cell more
+				// than the agent radius
+				cellSize := config.TileSize / float32(config.CellsPerTile)
+				assert.GreaterOrEqual(t, clearance(path, tc.obstacles), config.AgentRadius, "%v", path)
+				assert.LessOrEqual(t, clearance(path, tc.obstacles), config.AgentRadius+cellSize, "%v", path)
+			}
+		})
+	}
+}
+
+func TestNoPath(t *testing.T) {
+	m := New(parse(t, "..#..\n..#..\n..#.."), nil, config)
+	_, err := m.FindPath(fixed.FromVec2(mgl32.Vec2{0.5, 0.5}), fixed.FromVec2(mgl32.Vec2{4.5, 0.5}))
+	assert.ErrorIs(t, err, ErrNoPath)
+
+	// A gap narrower than agents is closed
+	m = New(parse(t, "..#..\n.....\n..#.."), nil, Config{TileSize: 1.0, CellsPerTile: 4, AgentRadius: 0.6})
+	_, err = m.FindPath(fixed.FromVec2(mgl32.Vec2{0.5, 0.5}), fixed.FromVec2(mgl32.Vec2{4.5, 0.5}))
+	assert.ErrorIs(t, err, ErrNoPath)
+
+	m = New(parse(t, "##\n##"), nil, config)
+	_, err = m.FindPath(fixed.FromVec2(mgl32.Vec2{0.5, 0.5}), fixed.FromVec2(mgl32.Vec2{1.5, 0.5}))
+	assert.ErrorIs(t, err, ErrEmpty)
+}
+
+func TestErosion(t *testing.T) {
+	g := parse(t, "....\n.#..\n....")
+	tree := Obstacle{Center: mgl32.Vec2{3, 1}, Radius: 0.25}
+	m := New(g, []Obstacle{tree}, config)
+	wall := [2]fixed.Vec2{fixed.FromVec2(mgl32.Vec2{1, 1}), fixed.FromVec2(mgl32.Vec2{2, 2})}
+	radius := fixed.FromFloat32(config.AgentRadius)
+	for _, p := range m.Polygons() {
+		assert.GreaterOrEqual(t, rectDistance(p.Min, p.Max, wall[0], wall[1]), radius, "%v", p)
+		assert.GreaterOrEqual(t, distanceToRect(fixed.FromVec2(tree.Center), p.Min, p.Max), fixed.FromFloat32(tree.Radius)+radius, "%v", p)
+		assert.GreaterOrEqual(t, p.Min.X(), radius, "%v keeps off the edge", p)
+		assert.LessOrEqual(t, p.Max.Y(), fixed.FromInt(3)-radius, "%v keeps off the edge", p)
+	}
+}
+
+func TestProject(t *testing.T) {
+	m := New(parse(t, "...\n.#.\n..."), nil, config)
+	testCases := []struct {
+		desc     string
+		point    mgl32.Vec2
+		expected mgl32.Vec2
+	}{
+		{desc: "on the mesh", point: mgl32.Vec2{0.5, 0.5}, expected: mgl32.Vec2{0.5, 0.5}},
+		{desc: "in a wall", point: mgl32.Vec2{1.5, 1.3}, expected: mgl32.Vec2{1.5, 0.75}},
+		{desc: "off the map", point: mgl32.Vec2{-1, 1.5}, expected: mgl32.Vec2{0.25, 1.5}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p, index, ok := m.Project(fixed.FromVec2(tc.point))
+			assert.True(t, ok)
+			assert.InDelta(t, 0, p.Float().Sub(tc.expected).Len(), 1e-5, "%v", p)
+			polygon := m.polygons[index]
+			assert.Equal(t, p, clampPoint(p, polygon.Min, polygon.Max), "in polygon %v", polygon)
+		})
+	}
+}
+
+// coverage returns which cells are on the mesh, and checks each is in the polygon that claims it.
+func coverage(t *testing.T, m *NavMesh) []bool {
+	covered := make([]bool, m.width*m.height)
+	for index, p := range m.polygons {
+		if !p.alive {
+			continue
+		}
+		for y := p.cells.y0; y < p.cells.y1; y++ {
+			for x := p.cells.x0; x < p.cells.x1; x++ {
+				assert.False(t, covered[y*m.width+x], "cell %d,%d is in two polygons", x, y)
+				assert.Equal(t, index, m.owners[y*m.width+x])
+				covered[y*m.width+x] = true
+			}
+		}
+	}
+	return covered
+}
+
+func TestChangingObstacles(t *testing.T) {
+	g := parse(t, "........\n........\n........\n........\n........\n........")
+	random := rand.New(rand.NewSource(1))
+	trees := []Obstacle{}
+	for i := 0; i < 12; i++ {
+		trees = append(trees, Obstacle{Center: mgl32.Vec2{random.Float32() * 8, random.Float32() * 6}, Radius: 0.2})
+	}
+	m := New(g, trees[:6], config)
+	for _, tree := range trees[6:] {
+		m.AddObstacle(tree)
+	}
+	// Chopping down trees only rebuilds the mesh around them, and ends up walkable in the same places
+	for _, tree := range trees[:4] {
+		assert.True(t, m.RemoveObstacle(tree))
+	}
+	assert.False(t, m.RemoveObstacle(trees[0]), "already removed")
+	fresh := New(g, trees[4:], config)
+	assert.Equal(t, coverage(t, fresh), coverage(t, m))
+	assert.ElementsMatch(t, fresh.Obstacles(), m.Obstacles())
+
+	// The polygons may be split differently, but are connected the same
+	for i := 0; i < 20; i++ {
+		from := mgl32.Vec2{random.Float32() * 8, random.Float32() * 6}
+		to := mgl32.Vec2{random.Float32() * 8, random.Float32() * 6}
+		expected, expectedErr := fresh.FindPath(fixed.FromVec2(from), fixed.FromVec2(to))
+		path, err := m.FindPath(fixed.FromVec2(from), fixed.FromVec2(to))
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, len(expected) > 0, len(path) > 0)
+		assert.False(t, blockedTiles(g, path))
+		assert.GreaterOrEqual(t, clearance(path, m.Obstacles()), config.AgentRadius-1e-4, "from %v to %v: %v", from, to, path)
+	}
+}
+
+func TestPath(t *testing.T) {
+	m := New(parse(t, "....\n....\n...."), []Obstacle{{Center: mgl32.Vec2{2, 1.5}, Radius: 0.3}}, config)
+	waypoints, err := m.Path(fixed.FromVec2(mgl32.Vec2{0.5, 1.5}), fixed.FromVec2(mgl32.Vec2{3.5, 1.5}))
+	assert.NoError(t, err)
+	assert.Len(t, waypoints, 3, "the unit is on the first point already")
+	assert.Equal(t, fixed.FromVec2(mgl32.Vec2{3.5, 1.5}), waypoints[2])
+
+	// A unit harvesting a tree walks to it from the edge of the mesh
+	waypoints, err = m.Path(fixed.FromVec2(mgl32.Vec2{0.5, 1.5}), fixed.FromVec2(mgl32.Vec2{2, 1.5}))
+	assert.NoError(t, err)
+	assert.Len(t, waypoints, 3)
+	assert.Equal(t, fixed.FromVec2(mgl32.Vec2{2, 1}), waypoints[1], "the edge of the mesh")
+	assert.Equal(t, fixed.FromVec2(mgl32.Vec2{2, 1.5}), waypoints[2])
+}
+
+func TestWriteOBJ(t *testing.T) {
+	m := New(parse(t, "...\n.#.\n..."), nil, config)
+	var b bytes.Buffer
+	assert.NoError(t, m.WriteOBJ(&b, 0.01))
+
+	vertices, hasUV, hasNormals := objloader.Load(strings.Split(b.String(), "\n"))
+	assert.False(t, hasUV)
+	assert.False(t, hasNormals)
+	assert.Len(t, vertices, 2*len(m.Polygons())*3*3, "two triangles per polygon")
+
+	// The triangles face up and cover the ground agents can stand on
+	area := float32(0)
+	for i := 0; i < len(vertices); i += 9 {
+		a := mgl32.Vec3{vertices[i], vertices[i+1], vertices[i+2]}
+		b := mgl32.Vec3{vertices[i+3], vertices[i+4], vertices[i+5]}
+		c := mgl32.Vec3{vertices[i+6], vertices[i+7], vertices[i+8]}
+		normal := b.Sub(a).Cross(c.Sub(a))
+		assert.Greater(t, normal.Y(), float32(0))
+		assert.Equal(t, float32(0.01), a.Y())
+		area += normal.Len() / 2
+	}
+	assert.InDelta(t, 2.5*2.5-1.5*1.5, area, 1e-4, "eroded by a cell")
+}
+
+func BenchmarkFindPath(b *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	g := pathfinding.NewGrid(64, 64)
+	trees := []Obstacle{}
+	for i := 0; i < 400; i++ {
+		trees = append(trees, Obstacle{Center: mgl32.Vec2{random.Float32() * 64, random.Float32() * 64}, Radius: 0.2})
+	}
+	m := New(g, trees, config)
+	b.Run("path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = m.FindPath(fixed.Vec2{fixed.FromInt(1), fixed.FromInt(1)}, fixed.Vec2{fixed.FromInt(63), fixed.FromInt(63)})
+		}
+	})
+	b.Run("chop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree := trees[i%len(trees)]
+			m.RemoveObstacle(tree)
+			m.AddObstacle(tree)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package navmesh
+
+import (
+	"io"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/objloader"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// WriteOBJ writes the polygons of the mesh as triangles of an OBJ file at the given height, to look at it in
+// a model viewer or load it with mesh.FromFile.
+func (m *NavMesh) WriteOBJ(w io.Writer, height float32) error {
+	positions := []mgl32.Vec3{}
+	faces := [][]uint32{}
+	// Polygons share corners
+	indices := map[fixed.Vec2]uint32{}
+	index := func(corner fixed.Vec2) uint32 {
+		i, exists := indices[corner]
+		if !exists {
+			i = uint32(len(positions))
+			indices[corner] = i
+			positions = append(positions, mgl32.Vec3{corner.X().Float32(), height, corner.Y().Float32()})
+		}
+		return i
+	}
+	for _, p := range m.Polygons() {
+		// Counter-clockwise seen from above, with y up and z towards the viewer
+		a := index(p.Min)
+		b := index(fixed.Vec2{p.Min.X(), p.Max.Y()})
+		c := index(p.Max)
+		d := index(fixed.Vec2{p.Max.X(), p.Min.Y()})
+		faces = append(faces, []uint32{a, b, c}, []uint32{a, c, d})
+	}
+	return objloader.Write(w, positions, faces)
+}
@@ -0,0 +1,206 @@
+package navmesh
+
+import (
+	"container/heap"
+
+	"game-engine/rts/internal/fixed"
+)
+
+// PolygonAt returns the index of the polygon a point is in, or false if it is not on the mesh.
+func (m *NavMesh) PolygonAt(p fixed.Vec2) (int, bool) {
+	index := m.owner(m.cellAt(p))
+	return index, index != noPolygon
+}
+
+// Project returns the point of the mesh closest to p, and the polygon it is in. It returns false if the mesh
+// is empty.
+func (m *NavMesh) Project(p fixed.Vec2) (fixed.Vec2, int, bool) {
+	if index, ok := m.PolygonAt(p); ok {
+		return p, index, true
+	}
+	closest, closestIndex, closestDistance := fixed.Vec2{}, noPolygon, fixed.Scalar(0)
+	for i, polygon := range m.polygons {
+		if !polygon.alive {
+			continue
+		}
+		point := clampPoint(p, polygon.Min, polygon.Max)
+		if d := point.Sub(p).LenSqr(); closestIndex == noPolygon || d < closestDistance {
+			closest, closestIndex, closestDistance = point, i, d
+		}
+	}
+	return closest, closestIndex, closestIndex != noPolygon
+}
+
+// FindPath returns the corners of the shortest path from one point to another over the mesh, starting and
+// ending with the points. Points off the mesh are first moved to the closest point on it.
+func (m *NavMesh) FindPath(from, to fixed.Vec2) ([]fixed.Vec2, error) {
+	from, start, ok := m.Project(from)
+	if !ok {
+		return nil, ErrEmpty
+	}
+	to, goal, _ := m.Project(to)
+	portals, err := m.corridor(start, goal, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return funnel(from, to, portals), nil
+}
+
+// Path returns the waypoints to walk through to get from one position on the ground to another, ending with
+// to, like pathfinding.Navigator.Path. Positions off the mesh, like an obstacle that is being harvested, are
+// walked to from the closest point on the mesh.
+func (m *NavMesh) Path(from, to fixed.Vec2) ([]fixed.Vec2, error) {
+	points, err := m.FindPath(from, to)
+	if err != nil {
+		return nil, err
+	}
+	// The unit is already on the first point, unless it has to walk back onto the mesh
+	if points[0] == from {
+		points = points[1:]
+	}
+	if len(points) == 0 || points[len(points)-1] != to {
+		points = append(points, to)
+	}
+	return points, nil
+}
+
+// openPolygon is a polygon to visit in the A* search, entered at a point.
+type openPolygon struct {
+	index     int
+	estimate  fixed.Scalar
+	heuristic fixed.Scalar
+	// seq breaks ties in the order polygons were added, so paths don't depend on the heap implementation
+	seq int
+}
+
+type openList []openPolygon
+
+func (l openList) Len() int { return len(l) }
+func (l openList) Less(i, j int) bool {
+	if l[i].estimate != l[j].estimate {
+		return l[i].estimate < l[j].estimate
+	}
+	if l[i].heuristic != l[j].heuristic {
+		return l[i].heuristic < l[j].heuristic
+	}
+	return l[i].seq < l[j].seq
+}
+func (l openList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l *openList) Push(x any)   { *l = append(*l, x.(openPolygon)) }
+func (l *openList) Pop() any {
+	old := *l
+	n := len(old)
+	item := old[n-1]
+	*l = old[:n-1]
+	return item
+}
+
+// visit is how a polygon was reached in the A* search.
+type visit struct {
+	cost fixed.Scalar
+	// entry is where the polygon was entered, the middle of the portal from the previous polygon
+	entry    fixed.Vec2
+	previous int
+	portal   portal
+	closed   bool
+}
+
+// corridor returns the portals to go through to get from the start polygon to the goal polygon, found with
+// A* between the middles of the portals.
+func (m *NavMesh) corridor(start, goal int, from, to fixed.Vec2) ([]portal, error) {
+	visits := map[int]*visit{start: {entry: from, previous: noPolygon}}
+	open := &openList{{index: start, estimate: to.Sub(from).Len()}}
+	seq := 0
+	for open.Len() > 0 {
+		current := heap.Pop(open).(openPolygon)
+		v := visits[current.index]
+		if v.closed {
+			continue
+		}
+		v.closed = true
+		if current.index == goal {
+			break
+		}
+		for _, p := range m.polygons[current.index].portals {
+			entry := p.left.Add(p.right).Mul(fixed.Half)
+			cost := v.cost + entry.Sub(v.entry).Len()
+			if p.to == goal {
+				cost += to.Sub(entry).Len()
+				entry = to
+			}
+			next, seen := visits[p.to]
+			if seen && (next.closed || next.cost <= cost) {
+				continue
+			}
+			visits[p.to] = &visit{cost: cost, entry: entry, previous: current.index, portal: p}
+			heuristic := to.Sub(entry).Len()
+			seq++
+			heap.Push(open, openPolygon{index: p.to, estimate: cost + heuristic, heuristic: heuristic, seq: seq})
+		}
+	}
+
+	v, found := visits[goal]
+	if !found || !v.closed {
+		return nil, ErrNoPath
+	}
+	portals := []portal{}
+	for index := goal; index != start; index = visits[index].previous {
+		portals = append(portals, visits[index].portal)
+	}
+	for i, j := 0, len(portals)-1; i < j; i, j = i+1, j-1 {
+		portals[i], portals[j] = portals[j], portals[i]
+	}
+	return portals, nil
+}
+
+// funnel pulls a path through the portals tight, and returns its corners from start to end. It is the simple
+// stupid funnel algorithm: the funnel from the apex narrows through each portal, and when a side would cross
+// the other the corner it crosses becomes the new apex.
+func funnel(start, end fixed.Vec2, portals []portal) []fixed.Vec2 {
+	lefts := make([]fixed.Vec2, 0, len(portals)+2)
+	rights := make([]fixed.Vec2, 0, len(portals)+2)
+	lefts, rights = append(lefts, start), append(rights, start)
+	for _, p := range portals {
+		lefts, rights = append(lefts, p.left), append(rights, p.right)
+	}
+	lefts, rights = append(lefts, end), append(rights, end)
+
+	points := []fixed.Vec2{start}
+	apex, left, right := start, start, start
+	apexIndex, leftIndex, rightIndex := 0, 0, 0
+	for i := 1; i < len(lefts); i++ {
+		// Narrow the right side, unless it would cross the left side
+		if cross(right.Sub(apex), rights[i].Sub(apex)) >= 0 {
+			if apex == right || cross(left.Sub(apex), rights[i].Sub(apex)) < 0 {
+				right, rightIndex = rights[i], i
+			} else {
+				points = append(points, left)
+				apex, apexIndex = left, leftIndex
+				left, right, leftIndex, rightIndex = apex, apex, apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+		// Narrow the left side, unless it would cross the right side
+		if cross(left.Sub(apex), lefts[i].Sub(apex)) <= 0 {
+			if apex == left || cross(right.Sub(apex), lefts[i].Sub(apex)) > 0 {
+				left, leftIndex = lefts[i], i
+			} else {
+				points = append(points, right)
+				apex, apexIndex = right, rightIndex
+				left, right, leftIndex, rightIndex = apex, apex, apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+	}
+	if points[len(points)-1] != end {
+		points = append(points, end)
+	}
+	return points
+}
+
+// cross is the z of the cross product of a and b, positive when b is counter-clockwise from a.
+func cross(a, b fixed.Vec2) fixed.Scalar {
+	return a.X().Mul(b.Y()) - a.Y().Mul(b.X())
+}
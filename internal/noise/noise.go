@@ -0,0 +1,126 @@
+// Package noise makes smooth random patterns, for generating terrain that looks natural. The same seed always
+// makes the same pattern.
+package noise
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Noise is a smooth random function of a position, between about -1 and 1.
+type Noise interface {
+	At(x, y float64) float64
+}
+
+// permutation shuffles the lattice points of noise, so each seed gives a different pattern.
+type permutation [512]uint8
+
+func newPermutation(seed int64) permutation {
+	var p permutation
+	for i, v := range rand.New(rand.NewSource(seed)).Perm(256) {
+		p[i] = uint8(v)
+		p[i+256] = uint8(v)
+	}
+	return p
+}
+
+// hash returns a random byte for the lattice point x, y.
+func (p *permutation) hash(x, y int) uint8 {
+	return p[int(p[x&255])+(y&255)]
+}
+
+// Perlin is gradient noise: each lattice point has a random slope, and the noise is 0 on the lattice points.
+type Perlin struct {
+	permutation
+}
+
+var _ Noise = (*Perlin)(nil)
+
+// NewPerlin creates Perlin noise with lattice points 1 apart.
+func NewPerlin(seed int64) *Perlin {
+	return &Perlin{newPermutation(seed)}
+}
+
+// gradients are the slopes lattice points of Perlin noise can have.
+var gradients = [8][2]float64{{1, 1}, {-1, 1}, {1, -1}, {-1, -1}, {1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+func (p *Perlin) At(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0), int(y0)
+	fx, fy := x-x0, y-y0
+	dot := func(i, j int) float64 {
+		g := gradients[p.hash(xi+i, yi+j)&7]
+		return g[0]*(fx-float64(i)) + g[1]*(fy-float64(j))
+	}
+	u, v := fade(fx), fade(fy)
+	return lerp(lerp(dot(0, 0), dot(1, 0), u), lerp(dot(0, 1), dot(1, 1), u), v)
+}
+
+// Value is value noise: each lattice point has a random value, smoothly interpolated in between. It is
+// blockier than Perlin noise.
+type Value struct {
+	permutation
+}
+
+var _ Noise = (*Value)(nil)
+
+// NewValue creates value noise with lattice points 1 apart.
+func NewValue(seed int64) *Value {
+	return &Value{newPermutation(seed)}
+}
+
+func (n *Value) At(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0), int(y0)
+	value := func(i, j int) float64 {
+		return float64(n.hash(xi+i, yi+j))/127.5 - 1
+	}
+	u, v := fade(x-x0), fade(y-y0)
+	return lerp(lerp(value(0, 0), value(1, 0), u), lerp(value(0, 1), value(1, 1), u), v)
+}
+
+// Fractal adds up octaves of noise, each smaller and fainter than the one before, for detail at all scales.
+type Fractal struct {
+	Noise Noise
+	// Octaves is how many layers of noise are added up, 1 if 0.
+	Octaves int
+	// Lacunarity is how much smaller each octave is than the one before, 2 if 0.
+	Lacunarity float64
+	// Gain is how much fainter each octave is than the one before, 0.5 if 0.
+	Gain float64
+}
+
+var _ Noise = Fractal{}
+
+// At returns the sum of the octaves, scaled to stay in the range of the noise.
+func (f Fractal) At(x, y float64) float64 {
+	octaves, lacunarity, gain := f.Octaves, f.Lacunarity, f.Gain
+	if octaves < 1 {
+		octaves = 1
+	}
+	if lacunarity == 0 {
+		lacunarity = 2
+	}
+	if gain == 0 {
+		gain = 0.5
+	}
+	sum, total, amplitude, frequency := 0.0, 0.0, 1.0, 1.0
+	for i := 0; i < octaves; i++ {
+		// Offset each octave so their lattice points don't line up
+		offset := float64(i) * 17.31
+		sum += amplitude * f.Noise.At(x*frequency+offset, y*frequency+offset)
+		total += amplitude
+		amplitude *= gain
+		frequency *= lacunarity
+	}
+	return sum / total
+}
+
+// fade eases t from 0 to 1 with zero first and second derivatives at both ends, so noise has no creases.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
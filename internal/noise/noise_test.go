@@ -0,0 +1,63 @@
+package noise
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoise(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		noise func(seed int64) Noise
+	}{
+		{desc: "perlin", noise: func(seed int64) Noise { return NewPerlin(seed) }},
+		{desc: "value", noise: func(seed int64) Noise { return NewValue(seed) }},
+		{desc: "fractal", noise: func(seed int64) Noise { return Fractal{Noise: NewPerlin(seed), Octaves: 5} }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			n := tc.noise(1)
+			assert.Equal(t, n.At(3.7, -12.2), tc.noise(1).At(3.7, -12.2), "same seed, same noise")
+			assert.NotEqual(t, n.At(3.7, -12.2), tc.noise(2).At(3.7, -12.2))
+
+			low, high := 1.0, -1.0
+			for x := -20.0; x < 20; x += 0.13 {
+				for y := -20.0; y < 20; y += 0.13 {
+					v := n.At(x, y)
+					low, high = math.Min(low, v), math.Max(high, v)
+					// Smooth: a small step makes a small change
+					assert.InDelta(t, v, n.At(x+0.001, y), 0.02, "at %v, %v", x, y)
+				}
+			}
+			assert.GreaterOrEqual(t, low, -1.0)
+			assert.LessOrEqual(t, high, 1.0)
+			assert.Greater(t, high-low, 0.8, "not flat")
+		})
+	}
+}
+
+func TestPerlinIsZeroOnLatticePoints(t *testing.T) {
+	p := NewPerlin(1)
+	for x := -3; x <= 3; x++ {
+		for y := -3; y <= 3; y++ {
+			assert.Equal(t, 0.0, p.At(float64(x), float64(y)))
+		}
+	}
+}
+
+func TestFractalDefaults(t *testing.T) {
+	p := NewPerlin(1)
+	assert.Equal(t, p.At(0.3, 0.6), Fractal{Noise: p}.At(0.3, 0.6), "one octave is the noise itself")
+	assert.Equal(t,
+		Fractal{Noise: p, Octaves: 3, Lacunarity: 2, Gain: 0.5}.At(0.3, 0.6),
+		Fractal{Noise: p, Octaves: 3}.At(0.3, 0.6))
+}
+
+func BenchmarkFractal(b *testing.B) {
+	f := Fractal{Noise: NewPerlin(1), Octaves: 5}
+	for i := 0; i < b.N; i++ {
+		f.At(float64(i)*0.01, 3.3)
+	}
+}
@@ -1,7 +1,9 @@
 package objloader
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -146,3 +148,23 @@ func parseFace(line string) (
 
 	return vertIndices, uvIndices, normalIndices, nil
 }
+
+// Write writes positions and faces as an OBJ file. Faces index into positions, starting at 0.
+func Write(w io.Writer, positions []mgl32.Vec3, faces [][]uint32) error {
+	out := bufio.NewWriter(w)
+	for _, p := range positions {
+		fmt.Fprintf(out, "v %s %s %s\n", formatFloat(p.X()), formatFloat(p.Y()), formatFloat(p.Z()))
+	}
+	for _, face := range faces {
+		out.WriteString("f")
+		for _, index := range face {
+			fmt.Fprintf(out, " %d", index+1)
+		}
+		out.WriteString("\n")
+	}
+	return out.Flush()
+}
+
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 32)
+}
@@ -1,8 +1,10 @@
 package objloader
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,3 +54,17 @@ func TestParseFace(t *testing.T) {
 		})
 	}
 }
+
+func TestWrite(t *testing.T) {
+	positions := []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {1, 0, 1.5}, {0, 0, 1.5}}
+	faces := [][]uint32{{0, 2, 1}, {0, 3, 2}}
+	var out strings.Builder
+
+	assert.NoError(t, Write(&out, positions, faces))
+	assert.Equal(t, "v 0 0 0\nv 1 0 0\nv 1 0 1.5\nv 0 0 1.5\nf 1 3 2\nf 1 4 3\n", out.String())
+
+	vertices, hasUV, hasNormals := Load(strings.Split(out.String(), "\n"))
+	assert.False(t, hasUV)
+	assert.False(t, hasNormals)
+	assert.Equal(t, []float32{0, 0, 0, 1, 0, 1.5, 1, 0, 0, 0, 0, 0, 0, 0, 1.5, 1, 0, 1.5}, vertices)
+}
@@ -0,0 +1,229 @@
+package pathfinding
+
+import (
+	"errors"
+
+	"game-engine/rts/internal/fixed"
+)
+
+var (
+	// ErrNoPath is returned when the goal can't be reached from the start.
+	ErrNoPath = errors.New("no path")
+	// ErrOutOfBounds is returned when the start or goal is outside the map.
+	ErrOutOfBounds = errors.New("tile out of bounds")
+)
+
+// Diagonals decides when units may move diagonally between tiles.
+type Diagonals int
+
+const (
+	// NoCornerCutting allows diagonal moves when both tiles next to the move are free, so units never clip the
+	// corner of a blocked tile.
+	NoCornerCutting Diagonals = iota
+	// CornerCutting allows diagonal moves when one of the tiles next to the move is free.
+	CornerCutting
+	// NoDiagonals only allows moving up, down, left and right.
+	NoDiagonals
+)
+
+var sqrt2 = fixed.FromInt(2).Sqrt()
+
+// Step is a move to a neighbouring tile.
+type Step struct {
+	To Tile
+	// Distance is 1 for straight moves and √2 for diagonal moves.
+	Distance fixed.Scalar
+}
+
+var straightDirections = [4]Tile{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+var diagonalDirections = [4]Tile{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+
+// Neighbours appends the tiles that can be moved to from t to steps and returns it. Blocked tiles are
+// skipped, unless they are the goal.
+func Neighbours(m Map, t, goal Tile, diagonals Diagonals, steps []Step) []Step {
+	enterable := func(n Tile) bool {
+		return passable(m, n) || (n == goal && inBounds(m, n))
+	}
+	for _, d := range straightDirections {
+		if n := (Tile{X: t.X + d.X, Y: t.Y + d.Y}); enterable(n) {
+			steps = append(steps, Step{To: n, Distance: fixed.One})
+		}
+	}
+	if diagonals == NoDiagonals {
+		return steps
+	}
+	for _, d := range diagonalDirections {
+		n := Tile{X: t.X + d.X, Y: t.Y + d.Y}
+		if enterable(n) && canCutCorner(m, t, n, diagonals) {
+			steps = append(steps, Step{To: n, Distance: sqrt2})
+		}
+	}
+	return steps
+}
+
+// canCutCorner reports if a diagonal move between tiles a and b is allowed by the tiles next to it.
+func canCutCorner(m Map, a, b Tile, diagonals Diagonals) bool {
+	first := passable(m, Tile{X: b.X, Y: a.Y})
+	second := passable(m, Tile{X: a.X, Y: b.Y})
+	if diagonals == CornerCutting {
+		return first || second
+	}
+	return first && second
+}
+
+// Heuristic estimates the cost of the cheapest path between two tiles, assuming all tiles cost 1.
+func Heuristic(a, b Tile, diagonals Diagonals) fixed.Scalar {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if diagonals == NoDiagonals {
+		return fixed.FromInt(dx + dy)
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return fixed.FromInt(dx-dy) + sqrt2*fixed.Scalar(dy)
+}
+
+// stepCost is the cost of a step, the distance times the cost of the tile moved onto. Blocked goals cost
+// as much as a free tile.
+func stepCost(m Map, step Step) fixed.Scalar {
+	cost := m.Cost(step.To)
+	if cost <= 0 {
+		cost = fixed.One
+	}
+	return step.Distance.Mul(cost)
+}
+
+// FindPath finds the cheapest path from one tile to another with A*. The path starts with from and ends with
+// to. The start and goal may be blocked, so units can find a way out of a blocked tile or to a tree.
+func FindPath(m Map, from, to Tile, diagonals Diagonals) ([]Tile, error) {
+	if !inBounds(m, from) || !inBounds(m, to) {
+		return nil, ErrOutOfBounds
+	}
+	width, height := m.Size()
+	index := func(t Tile) int { return t.Y*width + t.X }
+
+	costs := make([]fixed.Scalar, width*height)
+	for i := range costs {
+		costs[i] = fixed.Max
+	}
+	parents := make([]int32, width*height)
+	closed := make([]bool, width*height)
+
+	open := &openList{}
+	costs[index(from)] = 0
+	parents[index(from)] = -1
+	open.push(openTile{tile: from, estimate: Heuristic(from, to, diagonals)})
+
+	var seq uint64
+	steps := make([]Step, 0, 8)
+	for len(*open) > 0 {
+		current := open.pop()
+		i := index(current.tile)
+		if closed[i] {
+			continue
+		}
+		closed[i] = true
+		if current.tile == to {
+			return tracePath(parents, to, width), nil
+		}
+
+		steps = Neighbours(m, current.tile, to, diagonals, steps[:0])
+		for _, step := range steps {
+			j := index(step.To)
+			if closed[j] {
+				continue
+			}
+			cost := costs[i] + stepCost(m, step)
+			if cost >= costs[j] {
+				continue
+			}
+			costs[j] = cost
+			parents[j] = int32(i)
+			seq++
+			open.push(openTile{
+				tile:      step.To,
+				estimate:  cost + Heuristic(step.To, to, diagonals),
+				heuristic: Heuristic(step.To, to, diagonals),
+				seq:       seq,
+			})
+		}
+	}
+	return nil, ErrNoPath
+}
+
+func tracePath(parents []int32, to Tile, width int) []Tile {
+	path := []Tile{}
+	for i := int32(to.Y*width + to.X); i >= 0; i = parents[i] {
+		path = append(path, Tile{X: int(i) % width, Y: int(i) / width})
+	}
+	for a, b := 0, len(path)-1; a < b; a, b = a+1, b-1 {
+		path[a], path[b] = path[b], path[a]
+	}
+	return path
+}
+
+type openTile struct {
+	tile      Tile
+	estimate  fixed.Scalar
+	heuristic fixed.Scalar
+	// seq breaks ties so paths don't depend on how the heap orders equal tiles
+	seq uint64
+}
+
+// openList is a min-heap of tiles by estimated total cost. Ties go to the tile closest to the goal, then to
+// the tile found first.
+type openList []openTile
+
+func (l openList) less(i, j int) bool {
+	if l[i].estimate != l[j].estimate {
+		return l[i].estimate < l[j].estimate
+	}
+	if l[i].heuristic != l[j].heuristic {
+		return l[i].heuristic < l[j].heuristic
+	}
+	return l[i].seq < l[j].seq
+}
+
+func (l *openList) push(t openTile) {
+	*l = append(*l, t)
+	h := *l
+	for i := len(h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !h.less(i, parent) {
+			break
+		}
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func (l *openList) pop() openTile {
+	h := *l
+	top := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	h = h[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(h) && h.less(left, smallest) {
+			smallest = left
+		}
+		if right < len(h) && h.less(right, smallest) {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		h[i], h[smallest] = h[smallest], h[i]
+		i = smallest
+	}
+	*l = h
+	return top
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,311 @@
+package pathfinding
+
+import (
+	"strings"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// parse reads a map for a test, the start is marked with S and the goal with G.
+func parse(t *testing.T, ascii string) (g *Grid, start, goal Tile) {
+	t.Helper()
+	g, markers, err := ParseGrid(ascii)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, markers['S'], markers['G']
+}
+
+// dedent removes the indentation of expected maps.
+func dedent(ascii string) string {
+	lines := []string{}
+	for _, line := range strings.Split(ascii, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ground returns a position on the ground in fixed point.
+func ground(x, z float64) fixed.Vec2 {
+	return fixed.Vec2{fixed.FromFloat(x), fixed.FromFloat(z)}
+}
+
+// pathCost checks that every step of the path is allowed and returns the cost of the path.
+func pathCost(t *testing.T, m Map, path []Tile, diagonals Diagonals) fixed.Scalar {
+	t.Helper()
+	cost := fixed.Scalar(0)
+	for i := 1; i < len(path); i++ {
+		found := false
+		for _, step := range Neighbours(m, path[i-1], path[len(path)-1], diagonals, nil) {
+			if step.To == path[i] {
+				cost += stepCost(m, step)
+				found = true
+			}
+		}
+		assert.True(t, found, "can't step from %v to %v", path[i-1], path[i])
+	}
+	return cost
+}
+
+func TestFindPath(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		ascii     string
+		diagonals Diagonals
+		expected  string
+		cost      fixed.Scalar
+	}{
+		{
+			desc: "straight",
+			ascii: `
+				S....G
+				......`,
+			expected: `
+				******
+				......`,
+			cost: fixed.FromInt(5),
+		},
+		{
+			desc: "diagonal",
+			ascii: `
+				S...
+				....
+				...G`,
+			expected: `
+				*...
+				.*..
+				..**`,
+			cost: fixed.FromInt(1) + 2*sqrt2,
+		},
+		{
+			desc: "around a wall",
+			ascii: `
+				....#...
+				.S..#.G.
+				....#...
+				........`,
+			expected: `
+				....#...
+				.*..#.*.
+				..*.#.*.
+				...***..`,
+			cost: fixed.FromInt(3) + 3*sqrt2,
+		},
+		{
+			desc: "corridor",
+			ascii: `
+				S#.....
+				.#.###.
+				.#.#G#.
+				...#.#.
+				####...`,
+			diagonals: NoDiagonals,
+			expected: `
+				*#*****
+				*#*###*
+				*#*#*#*
+				***#*#*
+				####***`,
+			cost: fixed.FromInt(20),
+		},
+		{
+			desc: "around expensive tiles",
+			ascii: `
+				.....
+				S999G
+				.....`,
+			expected: `
+				.....
+				*999*
+				.***.`,
+			cost: fixed.FromInt(2) + 2*sqrt2,
+		},
+		{
+			desc: "through expensive tiles when cheaper",
+			ascii: `
+				#####
+				S222G
+				#####`,
+			expected: `
+				#####
+				*****
+				#####`,
+			cost: fixed.FromInt(7),
+		},
+		{
+			desc: "to a blocked goal",
+			ascii: `
+				S..#`,
+			expected: `
+				****`,
+			cost: fixed.FromInt(3),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g, start, goal := parse(t, tc.ascii)
+			if tc.desc == "to a blocked goal" {
+				goal = Tile{X: 3}
+			}
+
+			path, err := FindPath(g, start, goal, tc.diagonals)
+
+			assert.NoError(t, err)
+			assert.Equal(t, start, path[0])
+			assert.Equal(t, goal, path[len(path)-1])
+			assert.Equal(t, tc.cost, pathCost(t, g, path, tc.diagonals))
+			assert.Equal(t, dedent(tc.expected), g.Format(path))
+		})
+	}
+}
+
+func TestCornerCutting(t *testing.T) {
+	g, start, goal := parse(t, `
+		S#
+		.G`)
+
+	path, err := FindPath(g, start, goal, NoCornerCutting)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tile{start, {X: 0, Y: 1}, goal}, path)
+
+	path, err = FindPath(g, start, goal, CornerCutting)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tile{start, goal}, path)
+
+	g.SetCost(Tile{X: 0, Y: 1}, 0)
+	_, err = FindPath(g, start, goal, CornerCutting)
+	assert.ErrorIs(t, err, ErrNoPath, "can't squeeze between two blocked tiles")
+}
+
+func TestFindPathErrors(t *testing.T) {
+	g, start, goal := parse(t, `
+		S.#..
+		..#.G
+		..#..`)
+
+	_, err := FindPath(g, start, goal, NoCornerCutting)
+	assert.ErrorIs(t, err, ErrNoPath)
+
+	_, err = FindPath(g, start, Tile{X: 5, Y: 0}, NoCornerCutting)
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+}
+
+func TestParseGrid(t *testing.T) {
+	g, markers, err := ParseGrid(`
+		.#3
+		a..`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[rune]Tile{'a': {X: 0, Y: 1}}, markers)
+	assert.Equal(t, fixed.Scalar(0), g.Cost(Tile{X: 1, Y: 0}))
+	assert.Equal(t, fixed.FromInt(3), g.Cost(Tile{X: 2, Y: 0}))
+	assert.Equal(t, fixed.Scalar(0), g.Cost(Tile{X: 3, Y: 0}), "outside the grid is blocked")
+
+	_, _, err = ParseGrid(".?.")
+	assert.ErrorIs(t, err, ErrInvalidMap)
+	_, _, err = ParseGrid("..\n.")
+	assert.ErrorIs(t, err, ErrInvalidMap)
+}
+
+func TestSmooth(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		ascii     string
+		diagonals Diagonals
+		expected  string
+	}{
+		{
+			desc: "open",
+			ascii: `
+				S.....
+				......
+				.....G`,
+			expected: `
+				*.....
+				......
+				.....*`,
+		},
+		{
+			desc: "around a corner",
+			ascii: `
+				S.....
+				####..
+				.....G`,
+			expected: `
+				*...*.
+				####..
+				.....*`,
+		},
+		{
+			desc: "not through expensive tiles",
+			ascii: `
+				S.....
+				..99..
+				.....G`,
+			expected: `
+				*.....
+				.*99..
+				..*..*`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g, start, goal := parse(t, tc.ascii)
+			path, err := FindPath(g, start, goal, tc.diagonals)
+			assert.NoError(t, err)
+
+			smoothed := Smooth(g, path, tc.diagonals)
+
+			assert.Equal(t, dedent(tc.expected), g.Format(smoothed))
+			for i := 1; i < len(smoothed); i++ {
+				assert.True(t, LineOfSight(g, smoothed[i-1], smoothed[i], tc.diagonals, fixed.FromInt(9)))
+			}
+		})
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	g, _, _ := parse(t, `
+		.#..
+		....
+		..9.`)
+
+	assert.False(t, LineOfSight(g, Tile{X: 0, Y: 0}, Tile{X: 2, Y: 0}, NoCornerCutting, fixed.One))
+	assert.False(t, LineOfSight(g, Tile{X: 0, Y: 0}, Tile{X: 1, Y: 1}, NoCornerCutting, fixed.One), "corner")
+	assert.True(t, LineOfSight(g, Tile{X: 0, Y: 0}, Tile{X: 1, Y: 1}, CornerCutting, fixed.One))
+	assert.False(t, LineOfSight(g, Tile{X: 0, Y: 2}, Tile{X: 3, Y: 2}, NoCornerCutting, fixed.One))
+	assert.True(t, LineOfSight(g, Tile{X: 0, Y: 2}, Tile{X: 3, Y: 2}, NoCornerCutting, fixed.FromInt(9)))
+	assert.False(t, LineOfSight(g, Tile{X: 0, Y: 1}, Tile{X: 3, Y: 0}, NoCornerCutting, fixed.One), "passes a corner")
+	assert.True(t, LineOfSight(g, Tile{X: 0, Y: 1}, Tile{X: 3, Y: 0}, CornerCutting, fixed.One))
+	assert.True(t, LineOfSight(g, Tile{X: 0, Y: 1}, Tile{X: 3, Y: 1}, NoCornerCutting, fixed.One))
+}
+
+func TestNavigator(t *testing.T) {
+	g, _, _ := parse(t, `
+		....
+		.##.
+		....`)
+	nav := &Navigator{Grid: g, Origin: mgl32.Vec2{-1.0, -1.0}, TileSize: 0.5}
+
+	assert.Equal(t, Tile{X: 1, Y: 2}, nav.TileAt(ground(-0.4, 0.1)))
+	assert.Equal(t, Tile{X: -1, Y: 0}, nav.TileAt(ground(-1.1, -0.9)))
+	assert.Equal(t, ground(-0.25, 0.25), nav.Center(Tile{X: 1, Y: 2}))
+
+	waypoints, err := nav.Path(ground(-0.9, -0.4), ground(0.9, -0.4))
+	assert.NoError(t, err)
+	assert.Equal(t, ground(0.9, -0.4), waypoints[len(waypoints)-1], "ends at the target")
+	assert.Greater(t, len(waypoints), 1, "goes around the wall")
+
+	waypoints, err = nav.Path(ground(-0.9, -0.9), ground(-0.6, -0.6))
+	assert.NoError(t, err)
+	assert.Equal(t, []fixed.Vec2{ground(-0.6, -0.6)}, waypoints, "on the same tile")
+
+	_, err = nav.Path(ground(-2.0, 0.0), fixed.Vec2{})
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+}
@@ -0,0 +1,82 @@
+package pathfinding
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+)
+
+var benchmarkMapSizes = []int{128, 512}
+
+const benchmarkChunkSize = 16
+
+// benchmarkQueries returns pairs of free tiles far apart from each other that are connected.
+func benchmarkQueries(g *Grid, n int) [][2]Tile {
+	rng := rand.New(rand.NewSource(2))
+	width, height := g.Size()
+	queries := [][2]Tile{}
+	for len(queries) < n {
+		from := Tile{X: rng.Intn(width / 4), Y: rng.Intn(height)}
+		to := Tile{X: width - 1 - rng.Intn(width/4), Y: rng.Intn(height)}
+		if !passable(g, from) || !passable(g, to) {
+			continue
+		}
+		if _, err := FindPath(g, from, to, NoCornerCutting); err == nil {
+			queries = append(queries, [2]Tile{from, to})
+		}
+	}
+	return queries
+}
+
+// BenchmarkFindPath finds paths across maps with A* over all tiles, and over chunks.
+func BenchmarkFindPath(b *testing.B) {
+	for _, size := range benchmarkMapSizes {
+		g := randomGrid(size, size, 0.2, 1)
+		queries := benchmarkQueries(g, 16)
+
+		b.Run(fmt.Sprintf("astar/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q := queries[i%len(queries)]
+				FindPath(g, q[0], q[1], NoCornerCutting)
+			}
+		})
+		h := NewHierarchy(g, benchmarkChunkSize, NoCornerCutting)
+		b.Run(fmt.Sprintf("hierarchy/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q := queries[i%len(queries)]
+				h.FindPath(q[0], q[1])
+			}
+		})
+	}
+}
+
+func BenchmarkNewHierarchy(b *testing.B) {
+	for _, size := range benchmarkMapSizes {
+		g := randomGrid(size, size, 0.2, 1)
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				NewHierarchy(g, benchmarkChunkSize, NoCornerCutting)
+			}
+		})
+	}
+}
+
+// BenchmarkHierarchySetCost blocks and frees a tile, like a tree growing and being chopped, and finds a path
+// after each change.
+func BenchmarkHierarchySetCost(b *testing.B) {
+	for _, size := range benchmarkMapSizes {
+		g := randomGrid(size, size, 0.2, 1)
+		queries := benchmarkQueries(g, 16)
+		h := NewHierarchy(g, benchmarkChunkSize, NoCornerCutting)
+		changed := Tile{X: size / 2, Y: size / 2}
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h.SetCost(changed, fixed.FromInt(i%2))
+				q := queries[i%len(queries)]
+				h.FindPath(q[0], q[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package pathfinding
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"game-engine/rts/internal/fixed"
+)
+
+const (
+	// regionSize is the width and height in tiles of the regions flow fields are invalidated by.
+	regionSize = 16
+	// maxFlowFields is how many flow fields FlowFields keeps before dropping the oldest.
+	maxFlowFields = 32
+)
+
+// directions are the moves between neighbouring tiles, straight ones first.
+var directions = [8]Tile{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}, {X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+
+// noDirection marks tiles of a flow field that are goals or can't reach one.
+const noDirection int8 = -1
+
+// FlowField tells units anywhere on a map which way to move to get to the closest of a set of goals. It is
+// built from the cost field of the map, the costs of the tiles, into an integration field, the cost of the
+// cheapest path from each tile to a goal, and a direction field, the first step of that path.
+type FlowField struct {
+	width, height int
+	goals         []Tile
+	integration   []fixed.Scalar
+	directions    []int8
+	// regions are the regions with tiles the field was built from, changing them invalidates the field
+	regions []bool
+}
+
+// NewFlowField builds a flow field towards a set of goals with Dijkstra, searching from the goals outwards.
+// Paths follow the same rules as FindPath: goals may be blocked, and units on blocked tiles are led out of
+// them.
+func NewFlowField(m Map, goals []Tile, diagonals Diagonals) (*FlowField, error) {
+	width, height := m.Size()
+	index := func(t Tile) int { return t.Y*width + t.X }
+	regionsX, regionsY := (width+regionSize-1)/regionSize, (height+regionSize-1)/regionSize
+	f := &FlowField{
+		width:       width,
+		height:      height,
+		goals:       append([]Tile(nil), goals...),
+		integration: make([]fixed.Scalar, width*height),
+		directions:  make([]int8, width*height),
+		regions:     make([]bool, regionsX*regionsY),
+	}
+	for i := range f.integration {
+		f.integration[i] = fixed.Max
+		f.directions[i] = noDirection
+	}
+	in := func(t Tile) bool { return t.X >= 0 && t.Y >= 0 && t.X < width && t.Y < height }
+	// touch marks the regions of a settled tile and of the tiles next to it, whose costs were looked at
+	touch := func(t Tile) {
+		rx, ry := t.X/regionSize, t.Y/regionSize
+		f.regions[ry*regionsX+rx] = true
+		if x, y := t.X%regionSize, t.Y%regionSize; x != 0 && y != 0 && x != regionSize-1 && y != regionSize-1 {
+			return
+		}
+		for _, d := range directions {
+			if n := (Tile{X: t.X + d.X, Y: t.Y + d.Y}); in(n) {
+				f.regions[n.Y/regionSize*regionsX+n.X/regionSize] = true
+			}
+		}
+	}
+
+	open := &openList{}
+	var seq uint64
+	isGoal := make([]bool, width*height)
+	for _, goal := range goals {
+		if !in(goal) {
+			return nil, fmt.Errorf("%w: goal %v", ErrOutOfBounds, goal)
+		}
+		f.integration[index(goal)] = 0
+		isGoal[index(goal)] = true
+		seq++
+		open.push(openTile{tile: goal, seq: seq})
+	}
+	closed := make([]bool, width*height)
+	for len(*open) > 0 {
+		current := open.pop()
+		i := index(current.tile)
+		if closed[i] {
+			continue
+		}
+		closed[i] = true
+		touch(current.tile)
+		// Units can leave a blocked tile, but not walk through one
+		cost := m.Cost(current.tile)
+		if cost <= 0 {
+			if !isGoal[i] {
+				continue
+			}
+			cost = fixed.One
+		}
+
+		// Moving from a neighbour onto the current tile costs as much as walking onto the current tile
+		for dir, d := range directions {
+			n := Tile{X: current.tile.X - d.X, Y: current.tile.Y - d.Y}
+			if !in(n) || closed[index(n)] {
+				continue
+			}
+			distance := fixed.One
+			if d.X != 0 && d.Y != 0 {
+				if diagonals == NoDiagonals || !canCutCorner(m, n, current.tile, diagonals) {
+					continue
+				}
+				distance = sqrt2
+			}
+			j := index(n)
+			integration := f.integration[i] + distance.Mul(cost)
+			if integration >= f.integration[j] {
+				continue
+			}
+			f.integration[j] = integration
+			f.directions[j] = int8(dir)
+			seq++
+			open.push(openTile{tile: n, estimate: integration, seq: seq})
+		}
+	}
+	return f, nil
+}
+
+// Goals returns the tiles the field leads to.
+func (f *FlowField) Goals() []Tile {
+	return f.goals
+}
+
+// Integration returns the cost of the cheapest path from a tile to a goal, it is fixed.Max if no goal can be
+// reached.
+func (f *FlowField) Integration(t Tile) fixed.Scalar {
+	if !f.inBounds(t) {
+		return fixed.Max
+	}
+	return f.integration[t.Y*f.width+t.X]
+}
+
+// Next returns the tile to move to from a tile to get closer to a goal. It returns false on goals and tiles
+// that can't reach a goal.
+func (f *FlowField) Next(t Tile) (Tile, bool) {
+	if !f.inBounds(t) {
+		return Tile{}, false
+	}
+	dir := f.directions[t.Y*f.width+t.X]
+	if dir == noDirection {
+		return Tile{}, false
+	}
+	d := directions[dir]
+	return Tile{X: t.X + d.X, Y: t.Y + d.Y}, true
+}
+
+// Format draws the direction field with an arrow on each tile, G on goals and # on tiles that can't reach a
+// goal. Diagonal arrows are drawn as the corner they point to: 7 9 1 3 like on a keypad, with y growing
+// downwards.
+func (f *FlowField) Format() string {
+	arrows := [8]byte{'>', '<', 'v', '^', '3', '9', '1', '7'}
+	var sb strings.Builder
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			i := y*f.width + x
+			switch {
+			case f.directions[i] != noDirection:
+				sb.WriteByte(arrows[f.directions[i]])
+			case f.integration[i] == 0:
+				sb.WriteByte('G')
+			default:
+				sb.WriteByte('#')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (f *FlowField) inBounds(t Tile) bool {
+	return t.X >= 0 && t.Y >= 0 && t.X < f.width && t.Y < f.height
+}
+
+// FlowFields caches flow fields over a grid by their goals. Change the grid through SetCost, so the fields
+// built from the changed tiles are dropped.
+type FlowFields struct {
+	grid      *Grid
+	diagonals Diagonals
+	fields    map[string]*cachedFlowField
+	seq       uint64
+}
+
+type cachedFlowField struct {
+	field *FlowField
+	// used is when the field was last returned, the least recently used field is dropped first
+	used uint64
+}
+
+// NewFlowFields creates an empty cache of flow fields over a grid.
+func NewFlowFields(grid *Grid, diagonals Diagonals) *FlowFields {
+	return &FlowFields{grid: grid, diagonals: diagonals, fields: map[string]*cachedFlowField{}}
+}
+
+// Get returns the flow field towards a set of goals, building it if it is not cached.
+func (c *FlowFields) Get(goals ...Tile) (*FlowField, error) {
+	key := goalsKey(goals)
+	c.seq++
+	if cached, exists := c.fields[key]; exists {
+		cached.used = c.seq
+		return cached.field, nil
+	}
+	field, err := NewFlowField(c.grid, goals, c.diagonals)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.fields) >= maxFlowFields {
+		c.dropLeastRecentlyUsed()
+	}
+	c.fields[key] = &cachedFlowField{field: field, used: c.seq}
+	return field, nil
+}
+
+// Len returns the number of cached flow fields.
+func (c *FlowFields) Len() int {
+	return len(c.fields)
+}
+
+// SetCost changes the cost of a tile of the grid and drops the flow fields built from the region it is in.
+func (c *FlowFields) SetCost(t Tile, cost fixed.Scalar) {
+	if !c.grid.InBounds(t) || c.grid.Cost(t) == cost {
+		return
+	}
+	c.grid.SetCost(t, cost)
+	c.Invalidate(t)
+}
+
+// Invalidate drops the flow fields built from the region a tile is in, call it after changing the grid
+// directly.
+func (c *FlowFields) Invalidate(t Tile) {
+	if !c.grid.InBounds(t) {
+		return
+	}
+	width, _ := c.grid.Size()
+	region := t.Y/regionSize*((width+regionSize-1)/regionSize) + t.X/regionSize
+	for key, cached := range c.fields {
+		if cached.field.regions[region] {
+			delete(c.fields, key)
+		}
+	}
+}
+
+func (c *FlowFields) dropLeastRecentlyUsed() {
+	oldest := ""
+	for key, cached := range c.fields {
+		if oldest == "" || cached.used < c.fields[oldest].used {
+			oldest = key
+		}
+	}
+	delete(c.fields, oldest)
+}
+
+// goalsKey identifies a set of goals, whatever order they are in.
+func goalsKey(goals []Tile) string {
+	sorted := append([]Tile(nil), goals...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Y != sorted[j].Y {
+			return sorted[i].Y < sorted[j].Y
+		}
+		return sorted[i].X < sorted[j].X
+	})
+	var sb strings.Builder
+	for _, t := range sorted {
+		fmt.Fprintf(&sb, "%d,%d;", t.X, t.Y)
+	}
+	return sb.String()
+}
@@ -0,0 +1,192 @@
+package pathfinding
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowField(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		ascii    string
+		expected string
+	}{
+		{
+			desc: "open",
+			ascii: `
+				......
+				......
+				.....G`,
+			expected: `
+				33333v
+				33333v
+				>>>>>G`,
+		},
+		{
+			desc: "around a wall",
+			ascii: `
+				G...
+				.##.
+				.#..
+				....`,
+			expected: `
+				G<<<
+				^7^^
+				^<>^
+				^<<^`,
+		},
+		{
+			desc: "unreachable",
+			ascii: `
+				G..#..
+				...#..
+				...#..`,
+			expected: `
+				G<<<##
+				^77<##
+				^77<##`,
+		},
+		{
+			desc: "around expensive tiles",
+			ascii: `
+				G.....
+				.999..
+				......`,
+			expected: `
+				G<<<<<
+				^77777
+				^7<<^7`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			g, _, goal := parse(t, tc.ascii)
+			f, err := NewFlowField(g, []Tile{goal}, NoCornerCutting)
+			assert.NoError(t, err)
+			assert.Equal(t, dedent(tc.expected), f.Format())
+		})
+	}
+}
+
+func TestFlowFieldMatchesFindPath(t *testing.T) {
+	g, _, goal := parse(t, `
+		....#.......
+		.##.#.5555..
+		..#...#..#..
+		..###.#G.#..
+		......#..#2.
+		.99.....##..`)
+	for _, diagonals := range []Diagonals{NoCornerCutting, CornerCutting, NoDiagonals} {
+		f, err := NewFlowField(g, []Tile{goal}, diagonals)
+		assert.NoError(t, err)
+
+		width, height := g.Size()
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				start := Tile{X: x, Y: y}
+				path, err := FindPath(g, start, goal, diagonals)
+				if err != nil {
+					assert.Equal(t, fixed.Max, f.Integration(start), "%v can't reach the goal", start)
+					continue
+				}
+
+				// Following the field is as cheap as the path from A*
+				followed := []Tile{start}
+				for next, ok := f.Next(start); ok; next, ok = f.Next(next) {
+					followed = append(followed, next)
+				}
+				assert.Equal(t, goal, followed[len(followed)-1])
+				expected := pathCost(t, g, path, diagonals)
+				assert.Equal(t, expected, f.Integration(start), "integration of %v", start)
+				assert.Equal(t, expected, pathCost(t, g, followed, diagonals), "following from %v", start)
+			}
+		}
+	}
+}
+
+func TestFlowFieldSeveralGoals(t *testing.T) {
+	g, markers, err := ParseGrid(`
+		A.......B`)
+	assert.NoError(t, err)
+	f, err := NewFlowField(g, []Tile{markers['A'], markers['B']}, NoCornerCutting)
+	assert.NoError(t, err)
+	assert.Equal(t, "G<<<<>>>G\n", f.Format(), "goes to the closest goal")
+
+	_, err = NewFlowField(g, []Tile{{X: 9}}, NoCornerCutting)
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+}
+
+func TestFlowFieldsCache(t *testing.T) {
+	// The wall keeps fields towards the left from ever looking at the right of the map
+	g := NewGrid(3*regionSize, regionSize)
+	for y := 0; y < regionSize; y++ {
+		g.SetCost(Tile{X: regionSize + 4, Y: y}, 0)
+	}
+	c := NewFlowFields(g, NoCornerCutting)
+
+	left, err := c.Get(Tile{X: 2, Y: 2})
+	assert.NoError(t, err)
+	again, _ := c.Get(Tile{X: 2, Y: 2})
+	assert.Same(t, left, again, "cached")
+	both, _ := c.Get(Tile{X: 2, Y: 2}, Tile{X: 4, Y: 4})
+	again, _ = c.Get(Tile{X: 4, Y: 4}, Tile{X: 2, Y: 2})
+	assert.Same(t, both, again, "goals in any order")
+	right, _ := c.Get(Tile{X: 40, Y: 2})
+	assert.Equal(t, 3, c.Len())
+
+	c.SetCost(Tile{X: 2*regionSize + 5, Y: 3}, 0)
+	again, _ = c.Get(Tile{X: 2, Y: 2})
+	assert.Same(t, left, again, "change out of reach")
+	again, _ = c.Get(Tile{X: 40, Y: 2})
+	assert.NotSame(t, right, again, "change in reach")
+	assert.Equal(t, fixed.Scalar(0), g.Cost(Tile{X: 2*regionSize + 5, Y: 3}))
+
+	c.SetCost(Tile{X: 10, Y: 10}, fixed.FromInt(3))
+	again, _ = c.Get(Tile{X: 2, Y: 2})
+	assert.NotSame(t, left, again)
+	assert.Greater(t, again.Integration(Tile{X: 11, Y: 11}), 9*sqrt2, "goes around the new cost")
+}
+
+func TestFlowFieldsDropLeastRecentlyUsed(t *testing.T) {
+	c := NewFlowFields(NewGrid(maxFlowFields+1, 1), NoDiagonals)
+	first, _ := c.Get(Tile{X: 0})
+	second, _ := c.Get(Tile{X: 1})
+	for x := 2; x < maxFlowFields; x++ {
+		c.Get(Tile{X: x})
+	}
+	c.Get(Tile{X: 0})
+	c.Get(Tile{X: maxFlowFields})
+	assert.Equal(t, maxFlowFields, c.Len())
+
+	again, _ := c.Get(Tile{X: 0})
+	assert.Same(t, first, again)
+	again, _ = c.Get(Tile{X: 1})
+	assert.NotSame(t, second, again)
+}
+
+func TestNavigatorFlow(t *testing.T) {
+	g, _, _ := parse(t, `
+		....
+		.##.
+		....`)
+	n := &Navigator{Grid: g, Origin: mgl32.Vec2{-1.0, -1.0}, TileSize: 0.5}
+	to := ground(-0.8, -0.8)
+
+	f, err := n.Flow(to)
+	assert.NoError(t, err)
+	next, ok := n.FlowStep(f, ground(0.6, -0.1), to)
+	assert.True(t, ok)
+	assert.Equal(t, ground(0.75, -0.75), next, "walks around the wall")
+	next, _ = n.FlowStep(f, ground(-0.9, -0.9), to)
+	assert.Equal(t, to, next, "walks straight to the goal on its tile")
+
+	n.SetCost(Tile{X: 2, Y: 1}, fixed.One)
+	again, _ := n.Flow(to)
+	assert.NotSame(t, f, again)
+	next, _ = n.FlowStep(again, ground(0.6, -0.1), to)
+	assert.Equal(t, ground(0.25, -0.75), next, "cuts the corner it could not before")
+}
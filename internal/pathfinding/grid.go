@@ -0,0 +1,149 @@
+// Package pathfinding finds paths for units over a grid of tiles. Costs are fixed point, so every machine
+// finds the same paths and breaks ties between them the same way.
+package pathfinding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"game-engine/rts/internal/fixed"
+)
+
+// Tile is the position of a tile in a grid.
+type Tile struct {
+	X, Y int
+}
+
+// Map is a grid of tiles that paths can be found over.
+type Map interface {
+	// Size returns the number of tiles along each axis.
+	Size() (width, height int)
+	// Cost returns the cost of walking onto a tile. Tiles with a cost of 0 or less are blocked, the cost of
+	// other tiles is at least 1.
+	Cost(t Tile) fixed.Scalar
+}
+
+// Grid is a Map storing the cost of each tile.
+type Grid struct {
+	width, height int
+	costs         []fixed.Scalar
+}
+
+var _ Map = (*Grid)(nil)
+
+// NewGrid creates a grid where all tiles have a cost of 1.
+func NewGrid(width, height int) *Grid {
+	g := &Grid{width: width, height: height, costs: make([]fixed.Scalar, width*height)}
+	for i := range g.costs {
+		g.costs[i] = fixed.One
+	}
+	return g
+}
+
+// ErrInvalidMap is returned when parsing a map that is not a rectangle of known tiles.
+var ErrInvalidMap = errors.New("invalid map")
+
+// ParseGrid creates a grid from ASCII art, one line per row starting with y = 0. Spaces and tabs around lines
+// and empty lines are ignored.
+//
+//	.      cost 1
+//	1-9    that cost
+//	#      blocked
+//	a-zA-Z cost 1, the tile is returned as a marker
+func ParseGrid(ascii string) (*Grid, map[rune]Tile, error) {
+	rows := []string{}
+	for _, line := range strings.Split(ascii, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, line)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: no rows", ErrInvalidMap)
+	}
+
+	g := NewGrid(len(rows[0]), len(rows))
+	markers := map[rune]Tile{}
+	for y, row := range rows {
+		if len(row) != g.width {
+			return nil, nil, fmt.Errorf("%w: row %d is %d tiles wide, expected %d", ErrInvalidMap, y, len(row), g.width)
+		}
+		for x, c := range row {
+			t := Tile{X: x, Y: y}
+			switch {
+			case c == '.':
+			case c == '#':
+				g.SetCost(t, 0)
+			case c >= '1' && c <= '9':
+				g.SetCost(t, fixed.FromInt(int(c-'0')))
+			case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+				markers[c] = t
+			default:
+				return nil, nil, fmt.Errorf("%w: unknown tile %q at %d,%d", ErrInvalidMap, c, x, y)
+			}
+		}
+	}
+	return g, markers, nil
+}
+
+func (g *Grid) Size() (width, height int) {
+	return g.width, g.height
+}
+
+// Cost returns the cost of a tile, tiles outside the grid are blocked.
+func (g *Grid) Cost(t Tile) fixed.Scalar {
+	if !g.InBounds(t) {
+		return 0
+	}
+	return g.costs[t.Y*g.width+t.X]
+}
+
+// SetCost changes the cost of a tile, use 0 to block it. Tiles outside the grid are ignored.
+func (g *Grid) SetCost(t Tile, cost fixed.Scalar) {
+	if g.InBounds(t) {
+		g.costs[t.Y*g.width+t.X] = cost
+	}
+}
+
+// InBounds reports if a tile is in the grid.
+func (g *Grid) InBounds(t Tile) bool {
+	return t.X >= 0 && t.Y >= 0 && t.X < g.width && t.Y < g.height
+}
+
+// Format draws the grid like ParseGrid reads it, with the tiles of a path drawn as *.
+func (g *Grid) Format(path []Tile) string {
+	onPath := map[Tile]bool{}
+	for _, t := range path {
+		onPath[t] = true
+	}
+	var sb strings.Builder
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			t := Tile{X: x, Y: y}
+			cost := g.Cost(t)
+			switch {
+			case onPath[t]:
+				sb.WriteByte('*')
+			case cost <= 0:
+				sb.WriteByte('#')
+			case cost == fixed.One:
+				sb.WriteByte('.')
+			default:
+				sb.WriteByte('0' + byte(cost.Int()))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// inBounds reports if a tile is in a map.
+func inBounds(m Map, t Tile) bool {
+	width, height := m.Size()
+	return t.X >= 0 && t.Y >= 0 && t.X < width && t.Y < height
+}
+
+// passable reports if a tile is in a map and not blocked.
+func passable(m Map, t Tile) bool {
+	return inBounds(m, t) && m.Cost(t) > 0
+}
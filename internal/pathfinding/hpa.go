@@ -0,0 +1,472 @@
+package pathfinding
+
+import "game-engine/rts/internal/fixed"
+
+// maxEntranceWidth is the widest opening between chunks that gets a single entrance in its middle, wider
+// openings get an entrance at each end.
+const maxEntranceWidth = 6
+
+// Hierarchy finds paths over large grids with HPA*. The grid is split into square chunks, and the tiles where
+// units can cross from one chunk to the next are the nodes of an abstract graph. The paths between the nodes
+// of a chunk are found once and cached, so a search only looks at the abstract graph and the chunks of the
+// start and goal. Paths are close to, but not always, the cheapest.
+//
+// The hierarchy shares the grid of the caller. Change the grid through SetCost, or call Invalidate after
+// changing it, so the chunks around the changed tile are rebuilt.
+type Hierarchy struct {
+	grid             *Grid
+	diagonals        Diagonals
+	chunkSize        int
+	chunksX, chunksY int
+	// chunks are the nodes of each chunk
+	chunks [][]*abstractNode
+	nodes  map[Tile]*abstractNode
+	dirty  []bool
+	// rebuilt counts how many times chunks were rebuilt
+	rebuilt int
+}
+
+// abstractNode is a tile next to another chunk, that units can cross to.
+type abstractNode struct {
+	tile Tile
+	// across are the tiles of the other chunks that can be moved to
+	across []Tile
+	// edges are the cached paths to the other nodes of the chunk
+	edges []abstractEdge
+}
+
+// abstractEdge is a path between two tiles of the abstract graph.
+type abstractEdge struct {
+	to   Tile
+	cost fixed.Scalar
+	// path are the tiles walked onto, ending with to
+	path []Tile
+}
+
+// NewHierarchy splits a grid into chunks of chunkSize by chunkSize tiles and builds the abstract graph.
+func NewHierarchy(grid *Grid, chunkSize int, diagonals Diagonals) *Hierarchy {
+	width, height := grid.Size()
+	h := &Hierarchy{
+		grid:      grid,
+		diagonals: diagonals,
+		chunkSize: chunkSize,
+		chunksX:   (width + chunkSize - 1) / chunkSize,
+		chunksY:   (height + chunkSize - 1) / chunkSize,
+		nodes:     map[Tile]*abstractNode{},
+	}
+	h.chunks = make([][]*abstractNode, h.chunksX*h.chunksY)
+	h.dirty = make([]bool, len(h.chunks))
+	for i := range h.dirty {
+		h.dirty[i] = true
+	}
+	h.rebuild()
+	return h
+}
+
+// SetCost changes the cost of a tile of the grid, the chunks around it are rebuilt before the next search.
+func (h *Hierarchy) SetCost(t Tile, cost fixed.Scalar) {
+	if !h.grid.InBounds(t) || h.grid.Cost(t) == cost {
+		return
+	}
+	h.grid.SetCost(t, cost)
+	h.Invalidate(t)
+}
+
+// Invalidate rebuilds the chunks around a tile before the next search, call it after changing the grid
+// directly.
+func (h *Hierarchy) Invalidate(t Tile) {
+	if h.grid.InBounds(t) {
+		h.dirty[h.chunkOf(t)] = true
+	}
+}
+
+// FindPath finds a path from one tile to another over the abstract graph, and returns all tiles on it like
+// the FindPath function.
+func (h *Hierarchy) FindPath(from, to Tile) ([]Tile, error) {
+	if !inBounds(h.grid, from) || !inBounds(h.grid, to) {
+		return nil, ErrOutOfBounds
+	}
+	if from == to {
+		return []Tile{from}, nil
+	}
+	h.rebuild()
+
+	// Connect the start and goal to the nodes of their chunks for this search only. Blocked starts and goals
+	// are connected through the tiles next to them, which may be in another chunk.
+	extra := map[Tile][]abstractEdge{}
+	starts, goals := []Tile{from}, []Tile{to}
+	if !passable(h.grid, from) {
+		for _, step := range Neighbours(h.grid, from, to, h.diagonals, nil) {
+			extra[from] = append(extra[from], abstractEdge{to: step.To, cost: stepCost(h.grid, step), path: []Tile{step.To}})
+			starts = append(starts, step.To)
+		}
+	}
+	if !passable(h.grid, to) {
+		for _, step := range Neighbours(h.grid, to, to, h.diagonals, nil) {
+			back := Step{To: to, Distance: step.Distance}
+			extra[step.To] = append(extra[step.To], abstractEdge{to: to, cost: stepCost(h.grid, back), path: []Tile{to}})
+			goals = append(goals, step.To)
+		}
+	}
+	// The paths from a start are the paths to it walked backwards, they cost about the same
+	for _, start := range starts {
+		towards := h.chunkField(start)
+		for _, node := range h.chunks[h.chunkOf(start)] {
+			if edge, ok := towards.edge(node.tile); ok && node.tile != start {
+				extra[start] = append(extra[start], h.reverse(node.tile, edge))
+			}
+		}
+	}
+	for _, goal := range goals {
+		towards := h.chunkField(goal)
+		for _, node := range h.chunks[h.chunkOf(goal)] {
+			if edge, ok := towards.edge(node.tile); ok && node.tile != goal {
+				extra[node.tile] = append(extra[node.tile], edge)
+			}
+		}
+	}
+	// Short paths between neighbouring chunks are found directly, the abstract graph would make them detour
+	// through the entrances
+	for _, start := range starts {
+		for _, goal := range goals {
+			if edge, ok := h.directEdge(start, goal); ok {
+				extra[start] = append(extra[start], edge)
+			}
+		}
+	}
+
+	type visit struct {
+		cost   fixed.Scalar
+		parent Tile
+		path   []Tile
+		closed bool
+	}
+	visits := map[Tile]*visit{from: {cost: 0}}
+	open := &openList{}
+	open.push(openTile{tile: from, estimate: Heuristic(from, to, h.diagonals)})
+	var seq uint64
+	edges := []abstractEdge{}
+	for len(*open) > 0 {
+		current := open.pop()
+		v := visits[current.tile]
+		if v.closed {
+			continue
+		}
+		v.closed = true
+		if current.tile == to {
+			segments := [][]Tile{}
+			for t := to; t != from; t = visits[t].parent {
+				segments = append(segments, visits[t].path)
+			}
+			path := []Tile{from}
+			for i := len(segments) - 1; i >= 0; i-- {
+				path = append(path, segments[i]...)
+			}
+			return path, nil
+		}
+
+		edges = append(edges[:0], extra[current.tile]...)
+		if node, exists := h.nodes[current.tile]; exists {
+			edges = append(edges, node.edges...)
+			for _, across := range node.across {
+				edges = append(edges, abstractEdge{to: across, cost: stepCost(h.grid, Step{To: across, Distance: fixed.One}), path: []Tile{across}})
+			}
+		}
+
+		for _, edge := range edges {
+			cost := v.cost + edge.cost
+			next, seen := visits[edge.to]
+			if seen && (next.closed || cost >= next.cost) {
+				continue
+			}
+			visits[edge.to] = &visit{cost: cost, parent: current.tile, path: edge.path}
+			seq++
+			open.push(openTile{
+				tile:      edge.to,
+				estimate:  cost + Heuristic(edge.to, to, h.diagonals),
+				heuristic: Heuristic(edge.to, to, h.diagonals),
+				seq:       seq,
+			})
+		}
+	}
+	return nil, ErrNoPath
+}
+
+// rebuild finds the nodes of the dirty chunks and their neighbours, whose entrances may have changed too, and
+// the paths between them.
+func (h *Hierarchy) rebuild() {
+	affected := make([]bool, len(h.chunks))
+	changed := false
+	for c, dirty := range h.dirty {
+		if !dirty {
+			continue
+		}
+		changed = true
+		cx, cy := c%h.chunksX, c/h.chunksX
+		affected[c] = true
+		for _, d := range straightDirections {
+			if n, ok := h.chunkAt(cx+d.X, cy+d.Y); ok {
+				affected[n] = true
+			}
+		}
+		h.dirty[c] = false
+	}
+	if !changed {
+		return
+	}
+
+	for c, rebuild := range affected {
+		if !rebuild {
+			continue
+		}
+		for _, node := range h.chunks[c] {
+			delete(h.nodes, node.tile)
+		}
+		h.chunks[c] = h.findNodes(c)
+		for _, node := range h.chunks[c] {
+			h.nodes[node.tile] = node
+		}
+	}
+	for c, rebuild := range affected {
+		if rebuild {
+			h.connect(c)
+			h.rebuilt++
+		}
+	}
+}
+
+// findNodes returns the nodes of a chunk, in the order of the borders they are on: right, left, bottom, top.
+func (h *Hierarchy) findNodes(c int) []*abstractNode {
+	cx, cy := c%h.chunksX, c/h.chunksX
+	nodes := []*abstractNode{}
+	add := func(inside, outside Tile) {
+		for _, node := range nodes {
+			if node.tile == inside {
+				node.across = append(node.across, outside)
+				return
+			}
+		}
+		nodes = append(nodes, &abstractNode{tile: inside, across: []Tile{outside}})
+	}
+	for _, d := range straightDirections {
+		n, ok := h.chunkAt(cx+d.X, cy+d.Y)
+		if !ok {
+			continue
+		}
+		// Entrances are always found from the chunk on the left or top, so both chunks agree on them
+		if d.X > 0 || d.Y > 0 {
+			for _, e := range h.entrances(c, n) {
+				add(e[0], e[1])
+			}
+		} else {
+			for _, e := range h.entrances(n, c) {
+				add(e[1], e[0])
+			}
+		}
+	}
+	return nodes
+}
+
+// entrances returns the pairs of tiles units can cross between two chunks, first is on the left or top of
+// the border.
+func (h *Hierarchy) entrances(first, second int) [][2]Tile {
+	firstStart, firstPast := h.chunkBounds(first)
+	secondStart, _ := h.chunkBounds(second)
+	// along is the direction along the border, pair returns the tiles on each side at an offset along it
+	var along int
+	var pair func(i int) (Tile, Tile)
+	if secondStart.X > firstStart.X {
+		along = firstPast.Y - firstStart.Y
+		pair = func(i int) (Tile, Tile) {
+			return Tile{X: firstPast.X - 1, Y: firstStart.Y + i}, Tile{X: secondStart.X, Y: firstStart.Y + i}
+		}
+	} else {
+		along = firstPast.X - firstStart.X
+		pair = func(i int) (Tile, Tile) {
+			return Tile{X: firstStart.X + i, Y: firstPast.Y - 1}, Tile{X: firstStart.X + i, Y: secondStart.Y}
+		}
+	}
+
+	entrances := [][2]Tile{}
+	addRun := func(start, end int) {
+		if end-start < maxEntranceWidth {
+			a, b := pair((start + end - 1) / 2)
+			entrances = append(entrances, [2]Tile{a, b})
+			return
+		}
+		a, b := pair(start)
+		entrances = append(entrances, [2]Tile{a, b})
+		a, b = pair(end - 1)
+		entrances = append(entrances, [2]Tile{a, b})
+	}
+	start := -1
+	for i := 0; i < along; i++ {
+		a, b := pair(i)
+		open := passable(h.grid, a) && passable(h.grid, b)
+		switch {
+		case open && start < 0:
+			start = i
+		case !open && start >= 0:
+			addRun(start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		addRun(start, along)
+	}
+	return entrances
+}
+
+// connect finds and caches the paths between the nodes of a chunk, with one search from each node.
+func (h *Hierarchy) connect(c int) {
+	nodes := h.chunks[c]
+	for _, node := range nodes {
+		node.edges = nil
+	}
+	for _, b := range nodes {
+		towards := h.chunkField(b.tile)
+		for _, a := range nodes {
+			if a == b {
+				continue
+			}
+			if edge, ok := towards.edge(a.tile); ok {
+				a.edges = append(a.edges, edge)
+			}
+		}
+	}
+}
+
+// chunkFlow is a flow field over one chunk.
+type chunkFlow struct {
+	field *FlowField
+	first Tile
+	goal  Tile
+}
+
+// chunkField builds the flow field towards a tile over its chunk.
+func (h *Hierarchy) chunkField(goal Tile) chunkFlow {
+	first, past := h.chunkBounds(h.chunkOf(goal))
+	window := &window{grid: h.grid, first: first, past: past}
+	// The goal is in bounds, so building the field can't fail
+	field, _ := NewFlowField(window, []Tile{{X: goal.X - first.X, Y: goal.Y - first.Y}}, h.diagonals)
+	return chunkFlow{field: field, first: first, goal: goal}
+}
+
+// edge returns the path from a tile of the chunk to the goal of the field. It returns false if the goal can't
+// be reached without leaving the chunk.
+func (f chunkFlow) edge(from Tile) (abstractEdge, bool) {
+	t := Tile{X: from.X - f.first.X, Y: from.Y - f.first.Y}
+	cost := f.field.Integration(t)
+	if cost == fixed.Max {
+		return abstractEdge{}, false
+	}
+	path := []Tile{}
+	for next, ok := f.field.Next(t); ok; next, ok = f.field.Next(next) {
+		path = append(path, Tile{X: next.X + f.first.X, Y: next.Y + f.first.Y})
+	}
+	return abstractEdge{to: f.goal, cost: cost, path: path}, true
+}
+
+// reverse returns the same tiles as an edge, walked from its end to from.
+func (h *Hierarchy) reverse(from Tile, edge abstractEdge) abstractEdge {
+	back := make([]Tile, len(edge.path))
+	for i := range edge.path[:len(edge.path)-1] {
+		back[len(back)-2-i] = edge.path[i]
+	}
+	back[len(back)-1] = from
+	return abstractEdge{to: from, cost: h.pathCost(edge.to, back), path: back}
+}
+
+// directEdge finds a path between two tiles in the same or neighbouring chunks that stays inside those
+// chunks. It returns false for tiles further apart.
+func (h *Hierarchy) directEdge(from, to Tile) (abstractEdge, bool) {
+	fromFirst, fromPast := h.chunkBounds(h.chunkOf(from))
+	toFirst, toPast := h.chunkBounds(h.chunkOf(to))
+	if abs(fromFirst.X-toFirst.X) > h.chunkSize || abs(fromFirst.Y-toFirst.Y) > h.chunkSize {
+		return abstractEdge{}, false
+	}
+	first, past := fromFirst, fromPast
+	if toFirst.X < first.X {
+		first.X = toFirst.X
+	}
+	if toFirst.Y < first.Y {
+		first.Y = toFirst.Y
+	}
+	if toPast.X > past.X {
+		past.X = toPast.X
+	}
+	if toPast.Y > past.Y {
+		past.Y = toPast.Y
+	}
+	return h.windowEdge(from, to, first, past)
+}
+
+// windowEdge finds a path between two tiles that stays inside the window from first up to past.
+func (h *Hierarchy) windowEdge(from, to, first, past Tile) (abstractEdge, bool) {
+	window := &window{grid: h.grid, first: first, past: past}
+	path, err := FindPath(window, Tile{X: from.X - first.X, Y: from.Y - first.Y}, Tile{X: to.X - first.X, Y: to.Y - first.Y}, h.diagonals)
+	if err != nil {
+		return abstractEdge{}, false
+	}
+	path = path[1:]
+	for i := range path {
+		path[i] = Tile{X: path[i].X + first.X, Y: path[i].Y + first.Y}
+	}
+	return abstractEdge{to: to, cost: h.pathCost(from, path), path: path}, true
+}
+
+// pathCost returns the cost of walking a path, path doesn't include the tile it starts from.
+func (h *Hierarchy) pathCost(from Tile, path []Tile) fixed.Scalar {
+	cost := fixed.Scalar(0)
+	for _, t := range path {
+		step := Step{To: t, Distance: fixed.One}
+		if t.X != from.X && t.Y != from.Y {
+			step.Distance = sqrt2
+		}
+		cost += stepCost(h.grid, step)
+		from = t
+	}
+	return cost
+}
+
+func (h *Hierarchy) chunkOf(t Tile) int {
+	return t.Y/h.chunkSize*h.chunksX + t.X/h.chunkSize
+}
+
+func (h *Hierarchy) chunkAt(cx, cy int) (int, bool) {
+	if cx < 0 || cy < 0 || cx >= h.chunksX || cy >= h.chunksY {
+		return 0, false
+	}
+	return cy*h.chunksX + cx, true
+}
+
+// chunkBounds returns the first tile of a chunk and the tile past its last one.
+func (h *Hierarchy) chunkBounds(c int) (first, past Tile) {
+	width, height := h.grid.Size()
+	first = Tile{X: c % h.chunksX * h.chunkSize, Y: c / h.chunksX * h.chunkSize}
+	past = Tile{X: first.X + h.chunkSize, Y: first.Y + h.chunkSize}
+	if past.X > width {
+		past.X = width
+	}
+	if past.Y > height {
+		past.Y = height
+	}
+	return first, past
+}
+
+// window is the part of a grid from its first tile up to past, as a map of its own.
+type window struct {
+	grid        *Grid
+	first, past Tile
+}
+
+func (w *window) Size() (width, height int) {
+	return w.past.X - w.first.X, w.past.Y - w.first.Y
+}
+
+func (w *window) Cost(t Tile) fixed.Scalar {
+	if t.X < 0 || t.Y < 0 || t.X >= w.past.X-w.first.X || t.Y >= w.past.Y-w.first.Y {
+		return 0
+	}
+	return w.grid.Cost(Tile{X: t.X + w.first.X, Y: t.Y + w.first.Y})
+}
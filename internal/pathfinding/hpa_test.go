@@ -0,0 +1,115 @@
+package pathfinding
+
+import (
+	"math/rand"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// randomGrid creates a grid with a share of blocked tiles and some expensive ones, the same for a seed.
+func randomGrid(width, height int, blocked float32, seed int64) *Grid {
+	r := rand.New(rand.NewSource(seed))
+	g := NewGrid(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			switch p := r.Float32(); {
+			case p < blocked:
+				g.SetCost(Tile{X: x, Y: y}, 0)
+			case p < blocked+0.05:
+				g.SetCost(Tile{X: x, Y: y}, fixed.FromInt(5))
+			}
+		}
+	}
+	return g
+}
+
+func TestHierarchyFindPath(t *testing.T) {
+	for _, diagonals := range []Diagonals{NoCornerCutting, CornerCutting, NoDiagonals} {
+		g := randomGrid(40, 30, 0.25, 1)
+		h := NewHierarchy(g, 8, diagonals)
+		r := rand.New(rand.NewSource(2))
+		for i := 0; i < 200; i++ {
+			from := Tile{X: r.Intn(40), Y: r.Intn(30)}
+			to := Tile{X: r.Intn(40), Y: r.Intn(30)}
+			optimal, err := FindPath(g, from, to, diagonals)
+			path, hierarchyErr := h.FindPath(from, to)
+			if err != nil {
+				assert.ErrorIs(t, hierarchyErr, ErrNoPath, "from %v to %v", from, to)
+				continue
+			}
+			if !assert.NoError(t, hierarchyErr, "from %v to %v", from, to) {
+				continue
+			}
+			assert.Equal(t, from, path[0])
+			assert.Equal(t, to, path[len(path)-1])
+			// Paths over the abstract graph are close to the cheapest
+			assert.LessOrEqual(t, pathCost(t, g, path, diagonals), pathCost(t, g, optimal, diagonals).Mul(fixed.FromFloat(1.5)), "from %v to %v", from, to)
+		}
+	}
+}
+
+func TestHierarchyFindPathErrors(t *testing.T) {
+	h := NewHierarchy(NewGrid(10, 10), 4, NoCornerCutting)
+	_, err := h.FindPath(Tile{X: -1}, Tile{})
+	assert.ErrorIs(t, err, ErrOutOfBounds)
+	path, err := h.FindPath(Tile{X: 3, Y: 3}, Tile{X: 3, Y: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []Tile{{X: 3, Y: 3}}, path)
+}
+
+func TestHierarchySetCost(t *testing.T) {
+	g, start, goal := parse(t, `
+		S...#...
+		....#...
+		........
+		....#..G`)
+	h := NewHierarchy(g, 4, NoCornerCutting)
+	path, err := h.FindPath(start, goal)
+	assert.NoError(t, err)
+	assert.Contains(t, path, Tile{X: 4, Y: 2}, "goes through the gap")
+
+	h.SetCost(Tile{X: 4, Y: 2}, 0)
+	_, err = h.FindPath(start, goal)
+	assert.ErrorIs(t, err, ErrNoPath, "gap closed")
+
+	g.SetCost(Tile{X: 4, Y: 0}, fixed.One)
+	h.Invalidate(Tile{X: 4, Y: 0})
+	path, err = h.FindPath(start, goal)
+	assert.NoError(t, err)
+	assert.Contains(t, path, Tile{X: 4, Y: 0}, "goes through the new gap")
+}
+
+func TestHierarchyRebuildsAffectedChunks(t *testing.T) {
+	h := NewHierarchy(NewGrid(16, 16), 4, NoCornerCutting)
+	assert.Equal(t, 16, h.rebuilt, "all chunks are built at first")
+
+	h.SetCost(Tile{X: 1, Y: 1}, 0)
+	h.FindPath(Tile{}, Tile{X: 15, Y: 15})
+	assert.Equal(t, 16+3, h.rebuilt, "corner chunk and its two neighbours")
+
+	h.SetCost(Tile{X: 5, Y: 5}, 0)
+	h.SetCost(Tile{X: 6, Y: 5}, 0)
+	h.FindPath(Tile{}, Tile{X: 15, Y: 15})
+	assert.Equal(t, 16+3+5, h.rebuilt, "chunk and its four neighbours, once")
+
+	h.SetCost(Tile{X: 6, Y: 5}, 0)
+	h.FindPath(Tile{}, Tile{X: 15, Y: 15})
+	assert.Equal(t, 16+3+5, h.rebuilt, "nothing changed")
+}
+
+func TestNavigatorChunks(t *testing.T) {
+	n := &Navigator{Grid: randomGrid(64, 64, 0.2, 3), TileSize: 0.5, ChunkSize: 16}
+	n.Grid.SetCost(Tile{X: 1, Y: 1}, fixed.One)
+	n.Grid.SetCost(Tile{X: 60, Y: 62}, fixed.One)
+
+	waypoints, err := n.Path(ground(0.6, 0.6), ground(30.1, 31.1))
+	assert.NoError(t, err)
+	assert.NotNil(t, n.hierarchy)
+	assert.Equal(t, ground(30.1, 31.1), waypoints[len(waypoints)-1])
+
+	n.SetCost(Tile{X: 60, Y: 62}, 0)
+	assert.True(t, n.hierarchy.dirty[n.hierarchy.chunkOf(Tile{X: 60, Y: 62})])
+}
@@ -0,0 +1,98 @@
+package pathfinding
+
+import (
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Navigator finds paths between positions on the ground, over a grid laid out on the ground plane. Tile x
+// runs along the world x axis and tile y along the world z axis. Positions on the ground are world x and z in
+// fixed point, so every machine finds the same tiles and waypoints. Change the grid through SetCost so the flow
+// fields of the navigator are kept up to date.
+type Navigator struct {
+	Grid *Grid
+	// Origin is the world x and z of the corner of tile 0,0.
+	Origin    mgl32.Vec2
+	TileSize  float32
+	Diagonals Diagonals
+	// ChunkSize makes Path use hierarchical pathfinding over chunks of that many tiles, for large grids where
+	// A* over all tiles is too slow. A* is used when it is 0.
+	ChunkSize int
+
+	flows     *FlowFields
+	hierarchy *Hierarchy
+}
+
+// TileAt returns the tile a position on the ground is on, it may be outside the grid.
+func (n *Navigator) TileAt(position fixed.Vec2) Tile {
+	local := position.Sub(fixed.FromVec2(n.Origin))
+	size := fixed.FromFloat32(n.TileSize)
+	return Tile{X: local.X().Div(size).Int(), Y: local.Y().Div(size).Int()}
+}
+
+// Center returns the center of a tile on the ground.
+func (n *Navigator) Center(t Tile) fixed.Vec2 {
+	size := fixed.FromFloat32(n.TileSize)
+	return fixed.FromVec2(n.Origin).Add(fixed.Vec2{fixed.FromInt(t.X) + fixed.Half, fixed.FromInt(t.Y) + fixed.Half}.Mul(size))
+}
+
+// Path returns the waypoints on the ground to walk through to get from one position to another, ending with to.
+func (n *Navigator) Path(from, to fixed.Vec2) ([]fixed.Vec2, error) {
+	var tiles []Tile
+	var err error
+	if n.ChunkSize > 0 {
+		if n.hierarchy == nil {
+			n.hierarchy = NewHierarchy(n.Grid, n.ChunkSize, n.Diagonals)
+		}
+		tiles, err = n.hierarchy.FindPath(n.TileAt(from), n.TileAt(to))
+	} else {
+		tiles, err = FindPath(n.Grid, n.TileAt(from), n.TileAt(to), n.Diagonals)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tiles = Smooth(n.Grid, tiles, n.Diagonals)
+
+	// The unit is already on the first tile, and walks to the exact position on the last one
+	waypoints := make([]fixed.Vec2, 0, len(tiles))
+	for i := 1; i < len(tiles)-1; i++ {
+		waypoints = append(waypoints, n.Center(tiles[i]))
+	}
+	return append(waypoints, to), nil
+}
+
+// SetCost changes the cost of a tile, use 0 to block it.
+func (n *Navigator) SetCost(t Tile, cost fixed.Scalar) {
+	n.flowFields().SetCost(t, cost)
+	if n.hierarchy != nil {
+		n.hierarchy.Invalidate(t)
+	}
+}
+
+// Flow returns the flow field towards the tile a position is on. Flow fields are cached, so units going to the
+// same place share one.
+func (n *Navigator) Flow(to fixed.Vec2) (*FlowField, error) {
+	return n.flowFields().Get(n.TileAt(to))
+}
+
+// FlowStep returns where to walk to from a position to follow a flow field: the center of the next tile, or
+// the goal itself once on its tile. It returns false if the goal can't be reached from the position.
+func (n *Navigator) FlowStep(field *FlowField, from, to fixed.Vec2) (fixed.Vec2, bool) {
+	tile := n.TileAt(from)
+	if tile == n.TileAt(to) {
+		return to, true
+	}
+	next, ok := field.Next(tile)
+	if !ok {
+		return fixed.Vec2{}, false
+	}
+	return n.Center(next), true
+}
+
+func (n *Navigator) flowFields() *FlowFields {
+	if n.flows == nil {
+		n.flows = NewFlowFields(n.Grid, n.Diagonals)
+	}
+	return n.flows
+}
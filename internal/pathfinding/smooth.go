@@ -0,0 +1,86 @@
+package pathfinding
+
+import "game-engine/rts/internal/fixed"
+
+// LineOfSight reports if a unit can walk in a straight line from the center of tile a to the center of tile b
+// without crossing a blocked tile or a tile costing more than maxCost. When the line passes exactly through
+// the corner between tiles, the tiles next to the corner must allow cutting it. a and b themselves are not
+// checked.
+func LineOfSight(m Map, a, b Tile, diagonals Diagonals, maxCost fixed.Scalar) bool {
+	walkable := func(t Tile) bool {
+		return t == b || (passable(m, t) && m.Cost(t) <= maxCost)
+	}
+
+	dx, dy := b.X-a.X, b.Y-a.Y
+	nx, ny := abs(dx), abs(dy)
+	sx, sy := sign(dx), sign(dy)
+	t := a
+	// Walk through all tiles the line touches, one tile boundary at a time
+	for ix, iy := 0, 0; ix < nx || iy < ny; {
+		decision := (1+2*ix)*ny - (1+2*iy)*nx
+		switch {
+		case decision == 0:
+			// Through a corner
+			next := Tile{X: t.X + sx, Y: t.Y + sy}
+			first, second := Tile{X: next.X, Y: t.Y}, Tile{X: t.X, Y: next.Y}
+			if diagonals == CornerCutting {
+				if !walkable(first) && !walkable(second) {
+					return false
+				}
+			} else if !walkable(first) || !walkable(second) {
+				return false
+			}
+			t = next
+			ix++
+			iy++
+		case decision < 0:
+			t.X += sx
+			ix++
+		default:
+			t.Y += sy
+			iy++
+		}
+		if !walkable(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Smooth removes the tiles of a path that a unit can walk past in a straight line, pulling the path tight
+// around corners like a string. Straight lines are only taken over tiles that cost no more than the tiles of
+// the path they replace, so smoothing never leads units through expensive terrain.
+func Smooth(m Map, path []Tile, diagonals Diagonals) []Tile {
+	if len(path) < 3 {
+		return append([]Tile{}, path...)
+	}
+	smoothed := []Tile{path[0]}
+	anchor := path[0]
+	maxCost := max(m.Cost(path[0]), m.Cost(path[1]))
+	for i := 2; i < len(path); i++ {
+		maxCost = max(maxCost, m.Cost(path[i]))
+		if !LineOfSight(m, anchor, path[i], diagonals, maxCost) {
+			anchor = path[i-1]
+			smoothed = append(smoothed, anchor)
+			maxCost = max(m.Cost(path[i-1]), m.Cost(path[i]))
+		}
+	}
+	return append(smoothed, path[len(path)-1])
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+func max(a, b fixed.Scalar) fixed.Scalar {
+	if a > b {
+		return a
+	}
+	return b
+}
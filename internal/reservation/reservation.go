@@ -0,0 +1,202 @@
+package reservation
+
+import (
+	"errors"
+	"sort"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/spatial"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ErrClaimed is returned when claiming a node that has already been claimed by someone else.
+var ErrClaimed = errors.New("node already claimed")
+
+// Reason tells a claimant why it lost its claim.
+type Reason int
+
+const (
+	// Expired means the claim was not renewed before it timed out.
+	Expired Reason = iota
+	// Removed means the node was removed from the world.
+	Removed
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Claimant is notified when it loses a claim without releasing it.
+type Claimant[N comparable] interface {
+	ClaimLost(node N, reason Reason)
+}
+
+type claim[N comparable] struct {
+	claimant  Claimant[N]
+	expiresAt fixed.Scalar
+	// seq orders claims so notifications are sent in the order the claims were made
+	seq uint64
+}
+
+// Reservations keeps track of which claimant is using which node, e.g. which worker is chopping which
+// tree. A node can only be claimed by one claimant at a time. Claims expire unless they are renewed by
+// claiming the node again within the timeout. Time is kept in fixed point, so claims expire on the same tick
+// on every machine.
+type Reservations[N comparable] struct {
+	timeout fixed.Scalar
+
+	time    fixed.Scalar
+	nextSeq uint64
+	claims  map[N]*claim[N]
+}
+
+// New creates reservations where claims expire after timeout seconds.
+func New[N comparable](timeout float32) *Reservations[N] {
+	return &Reservations[N]{
+		timeout: fixed.FromFloat32(timeout),
+		claims:  map[N]*claim[N]{},
+	}
+}
+
+// Claim reserves a node for the claimant, or renews the claim if it already holds it.
+func (r *Reservations[N]) Claim(node N, claimant Claimant[N]) error {
+	if c, exists := r.claims[node]; exists {
+		if c.claimant != claimant {
+			return ErrClaimed
+		}
+		c.expiresAt = r.time + r.timeout
+		return nil
+	}
+
+	r.claims[node] = &claim[N]{claimant: claimant, expiresAt: r.time + r.timeout, seq: r.nextSeq}
+	r.nextSeq++
+	return nil
+}
+
+// Snapshot is the state of reservations, for saving them.
+type Snapshot[N comparable] struct {
+	Time    fixed.Scalar
+	NextSeq uint64
+	// Claims are in the order they were made.
+	Claims []ClaimSnapshot[N]
+}
+
+// ClaimSnapshot is a claim on a node, for saving reservations.
+type ClaimSnapshot[N comparable] struct {
+	Node      N
+	Claimant  Claimant[N]
+	ExpiresAt fixed.Scalar
+	Seq       uint64
+}
+
+// Snapshot returns the state of the reservations.
+func (r *Reservations[N]) Snapshot() Snapshot[N] {
+	s := Snapshot[N]{Time: r.time, NextSeq: r.nextSeq, Claims: make([]ClaimSnapshot[N], 0, len(r.claims))}
+	for node, c := range r.claims {
+		s.Claims = append(s.Claims, ClaimSnapshot[N]{Node: node, Claimant: c.claimant, ExpiresAt: c.expiresAt, Seq: c.seq})
+	}
+	sort.Slice(s.Claims, func(i, j int) bool { return s.Claims[i].Seq < s.Claims[j].Seq })
+	return s
+}
+
+// Restore replaces the state of the reservations with a snapshot, without notifying anyone.
+func (r *Reservations[N]) Restore(s Snapshot[N]) {
+	r.time = s.Time
+	r.nextSeq = s.NextSeq
+	r.claims = make(map[N]*claim[N], len(s.Claims))
+	for _, c := range s.Claims {
+		r.claims[c.Node] = &claim[N]{claimant: c.Claimant, expiresAt: c.ExpiresAt, seq: c.Seq}
+	}
+}
+
+// Release gives up the claim on a node if it is held by the claimant.
+func (r *Reservations[N]) Release(node N, claimant Claimant[N]) {
+	if c, exists := r.claims[node]; exists && c.claimant == claimant {
+		delete(r.claims, node)
+	}
+}
+
+// ReleaseAll gives up all claims held by the claimant, e.g. when it dies.
+func (r *Reservations[N]) ReleaseAll(claimant Claimant[N]) {
+	for node, c := range r.claims {
+		if c.claimant == claimant {
+			delete(r.claims, node)
+		}
+	}
+}
+
+// Remove drops the claim on a node that has been removed from the world and notifies the claimant.
+func (r *Reservations[N]) Remove(node N) {
+	c, exists := r.claims[node]
+	if !exists {
+		return
+	}
+	delete(r.claims, node)
+	c.claimant.ClaimLost(node, Removed)
+}
+
+// Update advances time and notifies the claimants of claims that have expired.
+func (r *Reservations[N]) Update(dt float32) {
+	r.time += fixed.FromFloat32(dt)
+
+	type expiredClaim struct {
+		node N
+		*claim[N]
+	}
+	expired := []expiredClaim{}
+	for node, c := range r.claims {
+		if c.expiresAt <= r.time {
+			expired = append(expired, expiredClaim{node: node, claim: c})
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].seq < expired[j].seq })
+
+	for _, e := range expired {
+		delete(r.claims, e.node)
+	}
+	for _, e := range expired {
+		e.claimant.ClaimLost(e.node, Expired)
+	}
+}
+
+// ClaimedBy returns the claimant holding the claim on a node.
+func (r *Reservations[N]) ClaimedBy(node N) (Claimant[N], bool) {
+	c, exists := r.claims[node]
+	if !exists {
+		return nil, false
+	}
+	return c.claimant, true
+}
+
+// Holds reports if the claimant holds the claim on a node.
+func (r *Reservations[N]) Holds(node N, claimant Claimant[N]) bool {
+	c, exists := r.claims[node]
+	return exists && c.claimant == claimant
+}
+
+// Available reports if a node is unclaimed or claimed by the claimant itself.
+func (r *Reservations[N]) Available(node N, claimant Claimant[N]) bool {
+	c, exists := r.claims[node]
+	return !exists || c.claimant == claimant
+}
+
+// NearestAvailable returns the node of the index closest to from that is available to the claimant. Only nodes
+// for which filter returns true are considered, all nodes are if filter is nil. The index measures distances
+// in fixed point, so every machine picks the same node.
+func (r *Reservations[N]) NearestAvailable(nodes spatial.Index[N], from mgl32.Vec2, claimant Claimant[N], filter func(N) bool) (N, bool) {
+	nearest := nodes.Nearest(from, 1, func(node N) bool {
+		return (filter == nil || filter(node)) && r.Available(node, claimant)
+	})
+	if len(nearest) == 0 {
+		var none N
+		return none, false
+	}
+	return nearest[0], true
+}
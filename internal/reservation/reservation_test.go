@@ -0,0 +1,159 @@
+package reservation
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/spatial"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct {
+	name string
+}
+
+type lostClaim struct {
+	node   *node
+	reason Reason
+}
+
+type claimant struct {
+	lost []lostClaim
+}
+
+func (c *claimant) ClaimLost(n *node, reason Reason) {
+	c.lost = append(c.lost, lostClaim{node: n, reason: reason})
+}
+
+func newReservations() *Reservations[*node] {
+	return New[*node](10.0)
+}
+
+func TestClaim(t *testing.T) {
+	r := newReservations()
+	tree := &node{}
+	first, second := &claimant{}, &claimant{}
+
+	assert.NoError(t, r.Claim(tree, first))
+	assert.NoError(t, r.Claim(tree, first), "renewing own claim")
+	assert.ErrorIs(t, r.Claim(tree, second), ErrClaimed)
+
+	holder, claimed := r.ClaimedBy(tree)
+	assert.True(t, claimed)
+	assert.Equal(t, first, holder)
+	assert.True(t, r.Holds(tree, first))
+	assert.True(t, r.Available(tree, first))
+	assert.False(t, r.Available(tree, second))
+
+	r.Release(tree, second)
+	assert.True(t, r.Holds(tree, first), "only the holder can release")
+	r.Release(tree, first)
+	assert.NoError(t, r.Claim(tree, second))
+	assert.Empty(t, first.lost)
+}
+
+func TestReleaseAll(t *testing.T) {
+	r := newReservations()
+	a, b, c := &node{}, &node{}, &node{}
+	dead, alive := &claimant{}, &claimant{}
+	assert.NoError(t, r.Claim(a, dead))
+	assert.NoError(t, r.Claim(b, dead))
+	assert.NoError(t, r.Claim(c, alive))
+
+	r.ReleaseAll(dead)
+
+	assert.True(t, r.Available(a, alive))
+	assert.True(t, r.Available(b, alive))
+	assert.True(t, r.Holds(c, alive))
+	assert.Empty(t, dead.lost)
+}
+
+func TestClaimsExpire(t *testing.T) {
+	r := newReservations()
+	first, second := &node{name: "first"}, &node{name: "second"}
+	worker := &claimant{}
+	assert.NoError(t, r.Claim(second, worker))
+	r.Update(5.0)
+	assert.NoError(t, r.Claim(first, worker))
+	r.Update(4.0)
+	// Renewed claim lasts for the whole timeout again
+	assert.NoError(t, r.Claim(first, worker))
+
+	r.Update(1.0)
+	assert.Equal(t, []lostClaim{{node: second, reason: Expired}}, worker.lost)
+	assert.True(t, r.Holds(first, worker))
+
+	r.Update(10.0)
+	assert.Equal(t, []lostClaim{{node: second, reason: Expired}, {node: first, reason: Expired}}, worker.lost)
+	assert.False(t, r.Holds(first, worker))
+}
+
+func TestSnapshot(t *testing.T) {
+	r := newReservations()
+	first, second := &node{name: "first"}, &node{name: "second"}
+	worker, other := &claimant{}, &claimant{}
+	assert.NoError(t, r.Claim(second, worker))
+	r.Update(5.0)
+	assert.NoError(t, r.Claim(first, other))
+	snapshot := r.Snapshot()
+	assert.Equal(t, []ClaimSnapshot[*node]{
+		{Node: second, Claimant: worker, ExpiresAt: fixed.FromInt(10), Seq: 0},
+		{Node: first, Claimant: other, ExpiresAt: fixed.FromInt(15), Seq: 1},
+	}, snapshot.Claims)
+
+	restored := newReservations()
+	restored.Restore(snapshot)
+	assert.True(t, restored.Holds(first, other))
+	assert.Equal(t, snapshot, restored.Snapshot())
+	restored.Update(5.0)
+	assert.Equal(t, []lostClaim{{node: second, reason: Expired}}, worker.lost, "expires at the same time")
+	assert.True(t, restored.Holds(first, other))
+}
+
+func TestRemoveNotifiesClaimant(t *testing.T) {
+	r := newReservations()
+	tree, other := &node{}, &node{}
+	worker := &claimant{}
+	assert.NoError(t, r.Claim(tree, worker))
+
+	r.Remove(other)
+	r.Remove(tree)
+
+	assert.Equal(t, []lostClaim{{node: tree, reason: Removed}}, worker.lost)
+	_, claimed := r.ClaimedBy(tree)
+	assert.False(t, claimed)
+}
+
+func TestNearestAvailable(t *testing.T) {
+	r := newReservations()
+	nodes := []*node{{name: "far"}, {name: "near"}, {name: "middle"}, {name: "filtered"}}
+	index := spatial.NewGrid[*node](2.0)
+	index.Insert(nodes[0], mgl32.Vec2{10.0, 0.0})
+	index.Insert(nodes[1], mgl32.Vec2{1.0, 0.0})
+	index.Insert(nodes[2], mgl32.Vec2{0.0, -5.0})
+	index.Insert(nodes[3], mgl32.Vec2{0.5, 0.0})
+	notFiltered := func(n *node) bool { return n.name != "filtered" }
+	me, other := &claimant{}, &claimant{}
+
+	nearest, found := r.NearestAvailable(index, mgl32.Vec2{}, me, notFiltered)
+	assert.True(t, found)
+	assert.Equal(t, "near", nearest.name)
+	nearest, _ = r.NearestAvailable(index, mgl32.Vec2{}, me, nil)
+	assert.Equal(t, "filtered", nearest.name, "all nodes without a filter")
+
+	assert.NoError(t, r.Claim(nodes[1], other))
+	nearest, _ = r.NearestAvailable(index, mgl32.Vec2{}, me, notFiltered)
+	assert.Equal(t, "middle", nearest.name)
+
+	assert.NoError(t, r.Claim(nodes[2], me))
+	nearest, _ = r.NearestAvailable(index, mgl32.Vec2{}, me, notFiltered)
+	assert.Equal(t, "middle", nearest.name, "own claims are available")
+
+	assert.NoError(t, r.Claim(nodes[0], other))
+	r.Release(nodes[2], me)
+	assert.NoError(t, r.Claim(nodes[2], other))
+	_, found = r.NearestAvailable(index, mgl32.Vec2{}, me, notFiltered)
+	assert.False(t, found)
+}
@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"sort"
+)
+
+// Inventory holds the resources a unit is carrying. It can carry up to a capacity of each kind of resource,
+// kinds without a capacity can't be carried at all.
+type Inventory struct {
+	capacity map[Kind]int
+	amounts  map[Kind]int
+}
+
+// NewInventory creates an empty inventory with the given carry capacity per kind of resource.
+func NewInventory(capacity map[Kind]int) *Inventory {
+	c := make(map[Kind]int, len(capacity))
+	for kind, amount := range capacity {
+		c[kind] = amount
+	}
+	return &Inventory{capacity: c, amounts: map[Kind]int{}}
+}
+
+// Add adds up to amount of a resource and returns how much was added, which is less than amount if the
+// inventory gets full.
+func (i *Inventory) Add(kind Kind, amount int) int {
+	if free := i.Free(kind); amount > free {
+		amount = free
+	}
+	if amount <= 0 {
+		return 0
+	}
+	i.amounts[kind] += amount
+	return amount
+}
+
+// Take removes everything of a resource from the inventory and returns how much there was.
+func (i *Inventory) Take(kind Kind) int {
+	amount := i.amounts[kind]
+	delete(i.amounts, kind)
+	return amount
+}
+
+// Amount returns how much of a resource is carried.
+func (i *Inventory) Amount(kind Kind) int {
+	return i.amounts[kind]
+}
+
+// Capacity returns how much of a resource can be carried.
+func (i *Inventory) Capacity(kind Kind) int {
+	return i.capacity[kind]
+}
+
+// Free returns how much more of a resource can be carried.
+func (i *Inventory) Free(kind Kind) int {
+	return i.capacity[kind] - i.amounts[kind]
+}
+
+// Full reports if no more of a resource can be carried.
+func (i *Inventory) Full(kind Kind) bool {
+	return i.Free(kind) <= 0
+}
+
+// Empty reports if nothing is carried.
+func (i *Inventory) Empty() bool {
+	return len(i.amounts) == 0
+}
+
+// Kinds returns the kinds of resources carried, sorted by name.
+func (i *Inventory) Kinds() []Kind {
+	kinds := make([]Kind, 0, len(i.amounts))
+	for kind := range i.amounts {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(a, b int) bool { return kinds[a] < kinds[b] })
+	return kinds
+}
@@ -0,0 +1,146 @@
+package resource
+
+import (
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// minNodeScale is how small a node is drawn when it is almost depleted, compared to when it is full.
+const minNodeScale float32 = 0.3
+
+// Depletion is what happens to a node when there is nothing left in it.
+type Depletion int
+
+const (
+	// Removed nodes disappear when depleted, like a tree that has been chopped down.
+	Removed Depletion = iota
+	// Regrowing nodes can't be harvested for a while when depleted, then they are full again.
+	Regrowing
+)
+
+// NodeType describes a kind of resource node, like a tree or a gold mine.
+type NodeType struct {
+	Kind Kind
+	// Amount is how much of the resource a new node holds.
+	Amount int
+	// Difficulty divides the harvest rate of a worker, gold is harder to mine than wood is to chop.
+	Difficulty float32
+	Depletion  Depletion
+	// RegrowthTime is how many seconds a Regrowing node takes to be full again.
+	RegrowthTime float32
+	// Radius is how far from its center the node blocks units walking around it.
+	Radius float32
+}
+
+// Types of nodes found in the world.
+var (
+	Tree      = NodeType{Kind: Wood, Amount: 10, Difficulty: 1.0, Depletion: Removed, Radius: 0.15}
+	Rock      = NodeType{Kind: Stone, Amount: 50, Difficulty: 2.0, Depletion: Removed, Radius: 0.25}
+	GoldMine  = NodeType{Kind: Gold, Amount: 40, Difficulty: 3.0, Depletion: Removed, Radius: 0.4}
+	BerryBush = NodeType{Kind: Food, Amount: 8, Difficulty: 1.0, Depletion: Regrowing, RegrowthTime: 60.0, Radius: 0.15}
+)
+
+// Node is a place in the world where a resource can be harvested.
+type Node struct {
+	NodeType
+	// Remaining is how much of the resource is left.
+	Remaining int
+
+	position mgl32.Vec3
+	// scale is how big the node is drawn, it shrinks from full as the node is depleted
+	scale, full mgl32.Vec3
+	// regrowIn is counted down in fixed point, so the node regrows on the same tick on every machine
+	regrowIn fixed.Scalar
+}
+
+// NewNode creates a full node of the given type at a position, drawn at a scale when full.
+func NewNode(nodeType NodeType, position, scale mgl32.Vec3) *Node {
+	return &Node{
+		NodeType:  nodeType,
+		Remaining: nodeType.Amount,
+		position:  position,
+		scale:     scale,
+		full:      scale,
+	}
+}
+
+// Position returns where the node is.
+func (n *Node) Position() mgl32.Vec3 {
+	return n.position
+}
+
+// Scale returns how big the node is drawn, smaller the more it is depleted.
+func (n *Node) Scale() mgl32.Vec3 {
+	return n.scale
+}
+
+// Depleted reports if there is nothing left to harvest.
+func (n *Node) Depleted() bool {
+	return n.Remaining <= 0
+}
+
+// Harvest takes up to amount from the node and returns how much was taken.
+func (n *Node) Harvest(amount int) int {
+	if amount > n.Remaining {
+		amount = n.Remaining
+	}
+	if amount <= 0 {
+		return 0
+	}
+	n.Remaining -= amount
+	if n.Depleted() {
+		n.regrowIn = fixed.FromFloat32(n.RegrowthTime)
+	}
+	n.resize()
+	return amount
+}
+
+// Update regrows a depleted node once its regrowth time has passed.
+func (n *Node) Update(dt float32) {
+	if !n.Depleted() || n.Depletion != Regrowing {
+		return
+	}
+	n.regrowIn -= fixed.FromFloat32(dt)
+	if n.regrowIn <= 0 {
+		n.Remaining = n.Amount
+		n.resize()
+	}
+}
+
+// RegrowIn returns how many seconds a depleted Regrowing node has left before it is full again.
+func (n *Node) RegrowIn() fixed.Scalar {
+	return n.regrowIn
+}
+
+// DelayRegrowth adds to how many seconds a depleted node has left before it regrows.
+func (n *Node) DelayRegrowth(seconds fixed.Scalar) {
+	n.regrowIn += seconds
+}
+
+// Restore sets how much is left in the node and how long it has left to regrow, to load a saved game.
+func (n *Node) Restore(remaining int, regrowIn fixed.Scalar) {
+	n.Remaining = remaining
+	n.regrowIn = regrowIn
+	n.resize()
+}
+
+// resize scales the node by how much is left in it.
+func (n *Node) resize() {
+	left := float32(0.0)
+	if n.Amount > 0 {
+		left = float32(n.Remaining) / float32(n.Amount)
+	}
+	n.scale = n.full.Mul(minNodeScale + (1.0-minNodeScale)*left)
+}
+
+// Harvestable returns the nodes of a kind that are not depleted.
+func Harvestable(nodes []*Node, kind Kind) []*Node {
+	harvestable := []*Node{}
+	for _, n := range nodes {
+		if n.Kind == kind && !n.Depleted() {
+			harvestable = append(harvestable, n)
+		}
+	}
+	return harvestable
+}
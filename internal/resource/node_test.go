@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNode(nodeType NodeType) *Node {
+	return NewNode(nodeType, mgl32.Vec3{}, mgl32.Vec3{1.0, 2.0, 1.0})
+}
+
+func TestHarvestShrinksNode(t *testing.T) {
+	tree := newNode(Tree)
+
+	assert.Equal(t, 4, tree.Harvest(4))
+	assert.Equal(t, 6, tree.Remaining)
+	assert.InDelta(t, 2.0*(minNodeScale+(1.0-minNodeScale)*0.6), tree.Scale().Y(), 1e-6)
+
+	assert.Equal(t, 6, tree.Harvest(10), "only what is left")
+	assert.True(t, tree.Depleted())
+	assert.Equal(t, 0, tree.Harvest(1))
+	assert.InDelta(t, minNodeScale, tree.Scale().X(), 1e-6)
+}
+
+func TestNodeRegrowth(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		nodeType  NodeType
+		remaining int
+	}{
+		{desc: "regrowing", nodeType: BerryBush, remaining: BerryBush.Amount},
+		{desc: "removed", nodeType: Tree, remaining: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			node := newNode(tc.nodeType)
+			node.Harvest(node.Amount)
+
+			node.Update(BerryBush.RegrowthTime / 2)
+			assert.True(t, node.Depleted())
+			node.Update(BerryBush.RegrowthTime / 2)
+
+			assert.Equal(t, tc.remaining, node.Remaining)
+		})
+	}
+}
+
+func TestRestoreNode(t *testing.T) {
+	bush := newNode(BerryBush)
+	bush.Harvest(bush.Amount)
+	bush.Update(10.0)
+
+	restored := newNode(BerryBush)
+	restored.Restore(bush.Remaining, bush.RegrowIn())
+	assert.Equal(t, bush.Scale(), restored.Scale())
+	restored.Update(BerryBush.RegrowthTime - 10.0)
+	assert.Equal(t, BerryBush.Amount, restored.Remaining, "regrown on time")
+}
+
+func TestDelayRegrowth(t *testing.T) {
+	bush := newNode(BerryBush)
+	bush.Harvest(bush.Amount)
+	bush.DelayRegrowth(fixed.FromInt(5))
+
+	bush.Update(BerryBush.RegrowthTime)
+	assert.True(t, bush.Depleted())
+	bush.Update(5.0)
+	assert.Equal(t, BerryBush.Amount, bush.Remaining)
+}
+
+func TestHarvestable(t *testing.T) {
+	tree, rock, bush := newNode(Tree), newNode(Rock), newNode(BerryBush)
+	chopped := newNode(Tree)
+	chopped.Harvest(chopped.Amount)
+	nodes := []*Node{tree, rock, chopped, bush}
+
+	assert.Equal(t, []*Node{tree}, Harvestable(nodes, Wood))
+	assert.Equal(t, []*Node{rock}, Harvestable(nodes, Stone))
+	assert.Empty(t, Harvestable(nodes, Gold))
+}
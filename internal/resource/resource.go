@@ -0,0 +1,17 @@
+package resource
+
+// Kind is a kind of resource, like wood.
+type Kind string
+
+const (
+	Wood  Kind = "wood"
+	Stone Kind = "stone"
+	Gold  Kind = "gold"
+	Food  Kind = "food"
+)
+
+// Player identifies who owns a building or unit.
+type Player int
+
+// Kinds are all kinds of resources.
+var Kinds = []Kind{Wood, Stone, Gold, Food}
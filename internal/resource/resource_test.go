@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventory(t *testing.T) {
+	inv := NewInventory(map[Kind]int{Wood: 10})
+	assert.True(t, inv.Empty())
+
+	assert.Equal(t, 4, inv.Add(Wood, 4))
+	assert.Equal(t, 6, inv.Add(Wood, 8), "only adds up to the capacity")
+	assert.True(t, inv.Full(Wood))
+	assert.Equal(t, 0, inv.Add(Wood, 1))
+	assert.Equal(t, 0, inv.Add(Stone, 1), "no capacity for Stone")
+	assert.Equal(t, []Kind{Wood}, inv.Kinds())
+
+	assert.Equal(t, 10, inv.Take(Wood))
+	assert.True(t, inv.Empty())
+	assert.Equal(t, 10, inv.Free(Wood))
+}
+
+func TestDeposit(t *testing.T) {
+	counters := NewCounters()
+	inv := NewInventory(map[Kind]int{Wood: 10, Stone: 10})
+	inv.Add(Wood, 5)
+	inv.Add(Stone, 3)
+	lumberCamp := &Stockpile{Player: 1, Accepts: []Kind{Wood}}
+
+	assert.Equal(t, map[Kind]int{Wood: 5}, lumberCamp.Deposit(inv, counters))
+	assert.Equal(t, 5, counters.Get(1, Wood))
+	assert.Equal(t, 0, counters.Get(0, Wood))
+	assert.Equal(t, 3, inv.Amount(Stone), "Stone is not accepted")
+
+	townCenter := &Stockpile{Player: 1}
+	assert.Equal(t, map[Kind]int{Stone: 3}, townCenter.Deposit(inv, counters))
+	assert.Equal(t, 3, counters.Get(1, Stone))
+	assert.True(t, inv.Empty())
+}
+
+func TestNearestStockpile(t *testing.T) {
+	stockpiles := []*Stockpile{
+		{Position: mgl32.Vec3{1.0, 0.0, 0.0}, Player: 1},
+		{Position: mgl32.Vec3{2.0, 0.0, 0.0}, Player: 0, Accepts: []Kind{Stone}},
+		{Position: mgl32.Vec3{5.0, 0.0, 0.0}, Player: 0},
+	}
+	testCases := []struct {
+		desc     string
+		player   Player
+		kind     Kind
+		expected *Stockpile
+	}{
+		{desc: "own stockpile", player: 1, kind: Wood, expected: stockpiles[0]},
+		{desc: "accepting the resource", player: 0, kind: Wood, expected: stockpiles[2]},
+		{desc: "nearest", player: 0, kind: Stone, expected: stockpiles[1]},
+		{desc: "none", player: 2, kind: Wood, expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			nearest, found := NearestStockpile(stockpiles, mgl32.Vec3{}, tc.player, tc.kind)
+
+			assert.Equal(t, tc.expected != nil, found)
+			assert.Same(t, tc.expected, nearest)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Counters are the resources each player has gathered.
+type Counters struct {
+	amounts map[Player]map[Kind]int
+}
+
+// NewCounters creates counters where every player has nothing.
+func NewCounters() *Counters {
+	return &Counters{amounts: map[Player]map[Kind]int{}}
+}
+
+// Add adds an amount of a resource to what a player has.
+func (c *Counters) Add(player Player, kind Kind, amount int) {
+	if c.amounts[player] == nil {
+		c.amounts[player] = map[Kind]int{}
+	}
+	c.amounts[player][kind] += amount
+}
+
+// Get returns how much of a resource a player has.
+func (c *Counters) Get(player Player, kind Kind) int {
+	return c.amounts[player][kind]
+}
+
+// Stockpile is a drop-off building where units deposit the resources they carry.
+type Stockpile struct {
+	Position mgl32.Vec3 `json:"position"`
+	Player   Player     `json:"player"`
+	// Accepts are the kinds of resources that can be deposited, all kinds are accepted if empty.
+	Accepts []Kind `json:"accepts,omitempty"`
+}
+
+// Accepted reports if a kind of resource can be deposited at the stockpile.
+func (s *Stockpile) Accepted(kind Kind) bool {
+	if len(s.Accepts) == 0 {
+		return true
+	}
+	for _, k := range s.Accepts {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Deposit moves all accepted resources from the inventory to the counters of the owner of the stockpile.
+// It returns how much was deposited of each kind.
+func (s *Stockpile) Deposit(inventory *Inventory, counters *Counters) map[Kind]int {
+	deposited := map[Kind]int{}
+	for _, kind := range inventory.Kinds() {
+		if !s.Accepted(kind) {
+			continue
+		}
+		amount := inventory.Take(kind)
+		counters.Add(s.Player, kind, amount)
+		deposited[kind] = amount
+	}
+	return deposited
+}
+
+// NearestStockpile returns the stockpile of the player closest to position that accepts the kind of
+// resource, comparing distances in fixed point. It returns false if there is no such stockpile.
+func NearestStockpile(stockpiles []*Stockpile, position mgl32.Vec3, player Player, kind Kind) (*Stockpile, bool) {
+	var nearest *Stockpile
+	nearestSqLen := fixed.Scalar(0)
+	from := fixed.FromVec3(position)
+	for _, s := range stockpiles {
+		if s.Player != player || !s.Accepted(kind) {
+			continue
+		}
+		sqLen := fixed.FromVec3(s.Position).Sub(from).LenSqr()
+		if nearest == nil || sqLen < nearestSqLen {
+			nearest, nearestSqLen = s, sqLen
+		}
+	}
+	return nearest, nearest != nil
+}
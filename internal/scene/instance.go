@@ -0,0 +1,345 @@
+package scene
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/camera"
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/mesh"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/shader"
+	"game-engine/rts/internal/sim"
+	"game-engine/rts/internal/texture"
+	"game-engine/rts/internal/tilemap"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// defaultChunkSize is how many quads of the heightmap across hilly ground is drawn in.
+const defaultChunkSize = 16
+
+// Instance is a scene set up to draw a game.
+type Instance struct {
+	Camera *camera.Camera
+	// Entities are those of the game. The Systems give its units their models and update them before Render
+	// draws them.
+	Entities *ecs.World
+	Systems  ecs.Schedule
+	// Scenery are the land and the objects of the scene, which don't move and aren't part of the game.
+	Scenery *ecs.World
+	// Overlay are the objects drawn over everything else, by RenderOverlay.
+	Overlay *ecs.World
+	// Shaders are the shaders of textured and grid objects, which can be reloaded.
+	Shaders []*shader.Shader
+
+	scene    *Scene
+	meshes   map[string]*mesh.Mesh
+	solids   map[string]*shader.SolidShader
+	textures map[string]*texture.Texture
+	gizmo    *shader.XYZGizmoShader
+	// nodes, workers and stockpiles are what the units of the game are drawn with
+	nodes      map[resource.Kind]gameobject.Solid
+	workers    gameobject.Solid
+	stockpiles gameobject.Solid
+}
+
+// Instantiate loads the meshes, textures and shaders of the scene, and spawns the land and the objects to draw
+// a game with. It needs a GL context.
+func (s *Scene) Instantiate(game *sim.Game, windowWidth, windowHeight int) (*Instance, error) {
+	in := &Instance{
+		Entities: game.Entities,
+		Scenery:  ecs.NewWorld(),
+		Overlay:  ecs.NewWorld(),
+		scene:    s,
+		meshes:   map[string]*mesh.Mesh{},
+		solids:   map[string]*shader.SolidShader{},
+		textures: map[string]*texture.Texture{},
+		nodes:    map[resource.Kind]gameobject.Solid{},
+	}
+	for _, system := range []ecs.System{
+		{Name: "units", Before: []string{gameobject.ModelSystem.Name}, Run: in.giveModels},
+		gameobject.ModelSystem,
+		gameobject.InterpolationSystem(game.Alpha),
+		{Name: "stockpiles", After: []string{gameobject.ModelSystem.Name}, Run: in.scaleStockpiles},
+	} {
+		if err := in.Systems.Add(system); err != nil {
+			return nil, err
+		}
+	}
+	in.Camera = camera.NewCamera(s.Camera.Position, windowWidth, windowHeight)
+	if s.Camera.Yaw != nil || s.Camera.Pitch != nil {
+		yaw, pitch := float32(-90.0), float32(-10.0)
+		if s.Camera.Yaw != nil {
+			yaw = *s.Camera.Yaw
+		}
+		if s.Camera.Pitch != nil {
+			pitch = *s.Camera.Pitch
+		}
+		in.Camera.Look(yaw, pitch)
+	}
+
+	if err := in.land(game); err != nil {
+		return nil, err
+	}
+	if err := in.units(game); err != nil {
+		return nil, err
+	}
+	for i, o := range s.Objects {
+		transform := gameobject.Transform{Position: o.Position, Rotation: o.Rotation, Scale: o.Scale}
+		w := in.Scenery
+		if o.Overlay {
+			w = in.Overlay
+		}
+		if err := in.object(w, o.Mesh, o.Material, transform); err != nil {
+			return nil, fmt.Errorf("object %d: %w", i, err)
+		}
+	}
+	height := func(x, z float32) float32 {
+		return game.HeightAt(fixed.FromFloat32(x), fixed.FromFloat32(z)).Float32()
+	}
+	for i, scatter := range s.Scatter {
+		for _, p := range scatter.Place(game.Map.Tiles, height) {
+			transform := gameobject.Transform{
+				Position: p.Position,
+				Rotation: mgl32.Vec3{0, p.Rotation, 0},
+				Scale:    mgl32.Vec3{p.Scale, p.Scale, p.Scale},
+			}
+			if err := in.object(in.Scenery, scatter.Model.Mesh, scatter.Model.Material, transform); err != nil {
+				return nil, fmt.Errorf("scatter %d: %w", i, err)
+			}
+		}
+	}
+	return in, nil
+}
+
+// land spawns the tiles of the map, or the chunks of the heightmap for hilly ground.
+func (in *Instance) land(game *sim.Game) error {
+	tiles := in.scene.Tiles
+	if game.Heightmap != nil {
+		hills, err := in.solid(tiles.Hills)
+		if err != nil {
+			return err
+		}
+		chunkSize := tiles.ChunkSize
+		if chunkSize == 0 {
+			chunkSize = defaultChunkSize
+		}
+		for _, chunk := range game.Heightmap.Chunks(chunkSize) {
+			chunkMesh := mesh.FromVertices(chunk.Vertices, false, true)
+			gameobject.Spawn(in.Scenery, gameobject.Transform{}, gameobject.Solid{Mesh: &chunkMesh, Shader: hills})
+		}
+		return nil
+	}
+
+	block, err := in.mesh(tiles.Mesh)
+	if err != nil {
+		return err
+	}
+	shaders := map[tilemap.Terrain]*shader.SolidShader{}
+	for name, material := range tiles.Materials {
+		terrain, _ := tilemap.TerrainNamed(name)
+		if shaders[terrain], err = in.solid(material); err != nil {
+			return err
+		}
+	}
+	game.Map.Tiles.SpawnBlocks(in.Scenery, block, shaders)
+	return nil
+}
+
+// units loads the models of the nodes, workers and stockpiles of the game.
+func (in *Instance) units(game *sim.Game) error {
+	units := in.scene.Units
+	for _, node := range game.World.Nodes {
+		if _, loaded := in.nodes[node.Kind]; loaded {
+			continue
+		}
+		solid, err := in.model(units.Nodes[node.Kind])
+		if err != nil {
+			return err
+		}
+		in.nodes[node.Kind] = solid
+	}
+	var err error
+	if in.workers, err = in.model(units.Workers); err != nil {
+		return err
+	}
+	in.stockpiles, err = in.model(units.Stockpiles)
+	return err
+}
+
+// giveModels gives the units of the game that aren't drawn yet their models, the stockpiles built since the
+// last frame too.
+func (in *Instance) giveModels(w *ecs.World, _ float32) {
+	commands := w.Commands()
+	drawn := func(e ecs.Entity) bool { return ecs.Has[gameobject.Solid](w, e) }
+	give := func(e ecs.Entity, t *gameobject.Transform, solid gameobject.Solid) {
+		ecs.AddLater(commands, e, solid)
+		ecs.AddLater(commands, e, gameobject.Model(t.Model()))
+	}
+	ecs.Each2(w, func(e ecs.Entity, t *gameobject.Transform, node **resource.Node) {
+		if !drawn(e) {
+			give(e, t, in.nodes[(*node).Kind])
+		}
+	})
+	ecs.Each2(w, func(e ecs.Entity, t *gameobject.Transform, _ **worker.Worker) {
+		if !drawn(e) {
+			give(e, t, in.workers)
+		}
+	})
+	ecs.Each2(w, func(e ecs.Entity, t *gameobject.Transform, _ **resource.Stockpile) {
+		if !drawn(e) {
+			give(e, t, in.stockpiles)
+		}
+	})
+}
+
+// scaleStockpiles draws the stockpiles at the scale of the scene, leaving their transforms in the game as
+// they are.
+func (in *Instance) scaleStockpiles(w *ecs.World, _ float32) {
+	ecs.Each3(w, func(_ ecs.Entity, t *gameobject.Transform, _ **resource.Stockpile, m *gameobject.Model) {
+		drawn := *t
+		drawn.Scale = in.scene.Units.StockpileScale
+		*m = gameobject.Model(drawn.Model())
+	})
+}
+
+func (in *Instance) model(model Model) (gameobject.Solid, error) {
+	m, err := in.mesh(model.Mesh)
+	if err != nil {
+		return gameobject.Solid{}, err
+	}
+	s, err := in.solid(model.Material)
+	if err != nil {
+		return gameobject.Solid{}, err
+	}
+	return gameobject.Solid{Mesh: m, Shader: s}, nil
+}
+
+// Update runs the systems over the entities of the game.
+func (in *Instance) Update(dt float32) error {
+	return in.Systems.Run(in.Entities, dt)
+}
+
+// Render draws the scenery and the entities of the game.
+func (in *Instance) Render(c *camera.Camera) {
+	render(in.Scenery, c)
+	render(in.Entities, c)
+}
+
+// RenderOverlay draws the overlay, which is meant to be drawn over everything else with the depth buffer
+// cleared.
+func (in *Instance) RenderOverlay(c *camera.Camera) {
+	render(in.Overlay, c)
+}
+
+func render(w *ecs.World, c *camera.Camera) {
+	gameobject.RenderSolids(w, c)
+	gameobject.RenderTextured(w, c)
+	gameobject.RenderGizmos(w, c)
+}
+
+// object spawns an object with a material of any shader.
+func (in *Instance) object(w *ecs.World, meshName, materialName string, t gameobject.Transform) error {
+	if t.Scale == (mgl32.Vec3{}) {
+		t.Scale = mgl32.Vec3{1.0, 1.0, 1.0}
+	}
+	m, err := in.mesh(meshName)
+	if err != nil {
+		return err
+	}
+	material := in.scene.Materials[materialName]
+	switch material.Shader {
+	case Solid:
+		s, err := in.solid(materialName)
+		if err != nil {
+			return err
+		}
+		gameobject.Spawn(w, t, gameobject.Solid{Mesh: m, Shader: s})
+		return nil
+	case Gizmo:
+		if in.gizmo == nil {
+			s, err := shader.NewXYZGizmoShader()
+			if err != nil {
+				return err
+			}
+			in.gizmo = &s
+		}
+		ecs.Add(w, w.Spawn(), gameobject.Gizmo{Mesh: m, Shader: in.gizmo})
+		return nil
+	}
+
+	// Textured and grid shaders point their attributes into the vertices of the mesh bound when they are
+	// made, so every object has its own
+	textured := gameobject.Textured{Mesh: m}
+	m.Bind()
+	var s shader.Shader
+	if material.Shader == Grid {
+		s, err = shader.NewGridShader()
+	} else {
+		s, err = shader.NewBasicShader()
+		if err == nil {
+			textured.Texture, err = in.texture(material.Texture)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("material %q: %w", materialName, err)
+	}
+	textured.Shader = &s
+	in.Shaders = append(in.Shaders, &s)
+	e := w.Spawn()
+	ecs.Add(w, e, t)
+	ecs.Add(w, e, gameobject.Model(t.Model()))
+	ecs.Add(w, e, textured)
+	return nil
+}
+
+// mesh returns a mesh of the scene, loading it the first time.
+func (in *Instance) mesh(name string) (*mesh.Mesh, error) {
+	if m, exists := in.meshes[name]; exists {
+		return m, nil
+	}
+	var m mesh.Mesh
+	switch description := in.scene.Meshes[name]; description.Builtin {
+	case Cube:
+		m = mesh.MakeCube()
+	case GridLines:
+		m = mesh.MakeGrid()
+	default:
+		var err error
+		if m, err = mesh.FromFile(in.scene.Path(description.File)); err != nil {
+			return nil, fmt.Errorf("loading mesh %q: %w", name, err)
+		}
+	}
+	in.meshes[name] = &m
+	return &m, nil
+}
+
+// solid returns the shader of a solid material, lit by the light of the scene.
+func (in *Instance) solid(name string) (*shader.SolidShader, error) {
+	if s, exists := in.solids[name]; exists {
+		return s, nil
+	}
+	s, err := shader.NewSolidShader(in.scene.Materials[name].Color)
+	if err != nil {
+		return nil, fmt.Errorf("material %q: %w", name, err)
+	}
+	s.SetLightPos(in.scene.Light.Position)
+	s.SetLightColor(in.scene.Light.Color)
+	in.solids[name] = &s
+	return &s, nil
+}
+
+func (in *Instance) texture(file string) (*texture.Texture, error) {
+	if t, exists := in.textures[file]; exists {
+		return t, nil
+	}
+	t, err := texture.New(in.scene.Path(file))
+	if err != nil {
+		return nil, err
+	}
+	in.textures[file] = &t
+	return &t, nil
+}
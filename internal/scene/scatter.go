@@ -0,0 +1,96 @@
+package scene
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/tilemap"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// tries is how many places are tried for each scattered object before giving up, when few tiles are of the
+// terrains it goes on.
+const tries = 20
+
+// Scatter is a set of Count objects put at random on the map, on tiles of the terrains On, or any terrain if
+// On is empty. They are put between the world x and z of Min and Max, or anywhere on the map if both are left
+// out, Offset above the ground. Each is turned at random, and scaled at random between the two Scales, 1 if
+// left out. The same Seed scatters the objects the same way.
+type Scatter struct {
+	Model  `yaml:",inline"`
+	Count  int        `yaml:"count"`
+	Seed   int64      `yaml:"seed"`
+	On     []string   `yaml:"on"`
+	Min    mgl32.Vec2 `yaml:"min"`
+	Max    mgl32.Vec2 `yaml:"max"`
+	Offset float32    `yaml:"offset"`
+	Scale  [2]float32 `yaml:"scale"`
+}
+
+// Placement is where a scattered object is put, turned Rotation radians around y.
+type Placement struct {
+	Position mgl32.Vec3
+	Rotation float32
+	Scale    float32
+}
+
+func (s Scatter) validate() error {
+	if s.Count < 0 {
+		return errors.New("has a negative count")
+	}
+	for _, terrain := range s.On {
+		if _, exists := tilemap.TerrainNamed(terrain); !exists {
+			return errors.New("is on unknown terrain " + terrain)
+		}
+	}
+	if s.Scale[0] > s.Scale[1] {
+		return errors.New("has a smallest scale larger than its largest")
+	}
+	if s.Min.X() > s.Max.X() || s.Min.Y() > s.Max.Y() {
+		return errors.New("has an area with its min corner past its max")
+	}
+	return nil
+}
+
+// Place returns where the objects are put on a map, with the height of the ground at a world x and z given by
+// height. There are fewer than Count when not enough places on the right terrain are found.
+func (s Scatter) Place(m *tilemap.Map, height func(x, z float32) float32) []Placement {
+	low, high := s.Min, s.Max
+	if low == (mgl32.Vec2{}) && high == (mgl32.Vec2{}) {
+		width, depth := m.Size()
+		low = m.Origin
+		high = m.Origin.Add(mgl32.Vec2{float32(width) * m.TileSize, float32(depth) * m.TileSize})
+	}
+	on := map[tilemap.Terrain]bool{}
+	for _, name := range s.On {
+		terrain, _ := tilemap.TerrainNamed(name)
+		on[terrain] = true
+	}
+	smallest, largest := s.Scale[0], s.Scale[1]
+	if smallest == 0 && largest == 0 {
+		smallest, largest = 1, 1
+	}
+
+	random := rand.New(rand.NewSource(s.Seed))
+	placements := make([]Placement, 0, s.Count)
+	for i := 0; i < s.Count*tries && len(placements) < s.Count; i++ {
+		x := low.X() + random.Float32()*(high.X()-low.X())
+		z := low.Y() + random.Float32()*(high.Y()-low.Y())
+		// The rotation and scale are picked even for places that are skipped, so they don't depend on the terrain
+		rotation := random.Float32() * 2 * math.Pi
+		scale := smallest + random.Float32()*(largest-smallest)
+		tile := m.TileAt(fixed.Vec2{fixed.FromFloat32(x), fixed.FromFloat32(z)})
+		if !m.InBounds(tile) || len(on) > 0 && !on[m.At(tile).Terrain] {
+			continue
+		}
+		placements = append(placements, Placement{
+			Position: mgl32.Vec3{x, height(x, z) + s.Offset, z},
+			Rotation: rotation,
+			Scale:    scale,
+		})
+	}
+	return placements
+}
@@ -0,0 +1,278 @@
+// Package scene describes what the game is drawn with: meshes, materials, the light, where the camera starts,
+// the tiles of the ground and objects placed or scattered around the map. Scenes are loaded from YAML files,
+// or JSON, which is YAML too, so levels can be changed without recompiling.
+package scene
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/tilemap"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidScene is returned for scenes that refer to things they don't declare, or declare things that
+// can't be drawn.
+var ErrInvalidScene = errors.New("invalid scene")
+
+// Shaders materials are drawn with.
+const (
+	// Solid is lit by the light and has one colour.
+	Solid = "solid"
+	// Textured has a texture and isn't lit.
+	Textured = "textured"
+	// Grid draws the lines of a grid.
+	Grid = "grid"
+	// Gizmo draws the x, y and z axes in red, green and blue.
+	Gizmo = "gizmo"
+)
+
+// Built in meshes, which aren't loaded from files.
+const (
+	Cube      = "cube"
+	GridLines = "grid"
+)
+
+// Scene is a description of what is drawn.
+type Scene struct {
+	// Dir is the directory files are relative to, the directory of the scene file.
+	Dir string `yaml:"-"`
+
+	Camera Camera `yaml:"camera"`
+	Light  Light  `yaml:"light"`
+	// Meshes and Materials are what the rest of the scene refers to by name.
+	Meshes    map[string]Mesh     `yaml:"meshes"`
+	Materials map[string]Material `yaml:"materials"`
+	Tiles     Tiles               `yaml:"tiles"`
+	Units     Units               `yaml:"units"`
+	// Objects are placed one by one, Scatter puts sets of objects at random around the map.
+	Objects []Object  `yaml:"objects"`
+	Scatter []Scatter `yaml:"scatter"`
+}
+
+// Camera is where the camera starts, looking along yaw and pitch in degrees. The camera looks along -z and
+// slightly down when they are left out.
+type Camera struct {
+	Position mgl32.Vec3 `yaml:"position"`
+	Yaw      *float32   `yaml:"yaw"`
+	Pitch    *float32   `yaml:"pitch"`
+}
+
+// Light is the light solid materials are lit by.
+type Light struct {
+	Position mgl32.Vec3 `yaml:"position"`
+	Color    mgl32.Vec3 `yaml:"color"`
+}
+
+// Mesh is an OBJ file, or one of the built in meshes.
+type Mesh struct {
+	File    string `yaml:"file"`
+	Builtin string `yaml:"builtin"`
+}
+
+// Material is how a mesh is shaded: a Solid Color, a Textured image, or a Grid or Gizmo.
+type Material struct {
+	Shader  string     `yaml:"shader"`
+	Color   mgl32.Vec3 `yaml:"color"`
+	Texture string     `yaml:"texture"`
+}
+
+// Model is a mesh drawn with a solid material.
+type Model struct {
+	Mesh     string `yaml:"mesh"`
+	Material string `yaml:"material"`
+}
+
+// Tiles is how the ground is drawn: one block of Mesh for each tile, with the material of its terrain. Tiles
+// of terrains without a material aren't drawn. Hilly ground is drawn in chunks of ChunkSize quads of the
+// heightmap instead, with the Hills material. Whether the ground is hilly is only known when a game is made,
+// so Hills is required even of scenes only ever drawn with flat tiles.
+type Tiles struct {
+	Mesh      string            `yaml:"mesh"`
+	Materials map[string]string `yaml:"materials"`
+	Hills     string            `yaml:"hills"`
+	ChunkSize int               `yaml:"chunkSize"`
+}
+
+// Units is how the things the game simulates are drawn. Nodes and workers keep the size the game gives them,
+// stockpiles are drawn at StockpileScale.
+type Units struct {
+	Nodes          map[resource.Kind]Model `yaml:"nodes"`
+	Workers        Model                   `yaml:"workers"`
+	Stockpiles     Model                   `yaml:"stockpiles"`
+	StockpileScale mgl32.Vec3              `yaml:"stockpileScale"`
+}
+
+// Object is a mesh placed in the scene. Its scale is 1 if left out. Overlay objects are drawn over
+// everything else.
+type Object struct {
+	Mesh     string     `yaml:"mesh"`
+	Material string     `yaml:"material"`
+	Position mgl32.Vec3 `yaml:"position"`
+	Rotation mgl32.Vec3 `yaml:"rotation"`
+	Scale    mgl32.Vec3 `yaml:"scale"`
+	Overlay  bool       `yaml:"overlay"`
+}
+
+// Parse reads a scene from YAML or JSON. Files are relative to the working directory.
+func Parse(data []byte) (*Scene, error) {
+	s := &Scene{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScene, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load reads a scene file. Files in it are relative to the directory of the scene.
+func Load(name string) (*Scene, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	s.Dir = filepath.Dir(name)
+	return s, nil
+}
+
+// Path returns where a file of the scene is.
+func (s *Scene) Path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(s.Dir, file)
+}
+
+// validate checks that everything the scene refers to is declared, and can be drawn how it is used. Entries
+// are checked in order of their names, so the same scene is always rejected with the same error.
+func (s *Scene) validate() error {
+	for _, name := range sortedNames(s.Meshes) {
+		m := s.Meshes[name]
+		switch {
+		case m.File != "" && m.Builtin != "":
+			return fmt.Errorf("%w: mesh %q is both a file and built in", ErrInvalidScene, name)
+		case m.File == "" && m.Builtin != Cube && m.Builtin != GridLines:
+			return fmt.Errorf("%w: mesh %q has no file, and no built in mesh %q", ErrInvalidScene, name, m.Builtin)
+		}
+	}
+	for _, name := range sortedNames(s.Materials) {
+		m := s.Materials[name]
+		switch m.Shader {
+		case Solid, Grid, Gizmo:
+		case Textured:
+			if m.Texture == "" {
+				return fmt.Errorf("%w: textured material %q has no texture", ErrInvalidScene, name)
+			}
+		default:
+			return fmt.Errorf("%w: material %q has unknown shader %q", ErrInvalidScene, name, m.Shader)
+		}
+	}
+
+	if err := s.checkMesh(s.Tiles.Mesh, "tiles"); err != nil {
+		return err
+	}
+	for _, terrain := range sortedNames(s.Tiles.Materials) {
+		material := s.Tiles.Materials[terrain]
+		if _, exists := tilemap.TerrainNamed(terrain); !exists {
+			return fmt.Errorf("%w: tiles of unknown terrain %q", ErrInvalidScene, terrain)
+		}
+		if err := s.checkSolid(material, terrain+" tiles"); err != nil {
+			return err
+		}
+	}
+	if err := s.checkSolid(s.Tiles.Hills, "hills"); err != nil {
+		return err
+	}
+	if s.Tiles.ChunkSize < 0 {
+		return fmt.Errorf("%w: chunks of %d quads", ErrInvalidScene, s.Tiles.ChunkSize)
+	}
+
+	for _, kind := range resource.Kinds {
+		model, exists := s.Units.Nodes[kind]
+		if !exists {
+			return fmt.Errorf("%w: no model of %s nodes", ErrInvalidScene, kind)
+		}
+		if err := s.checkModel(model, string(kind)+" nodes"); err != nil {
+			return err
+		}
+	}
+	if len(s.Units.Nodes) != len(resource.Kinds) {
+		return fmt.Errorf("%w: models of unknown kinds of nodes", ErrInvalidScene)
+	}
+	if err := s.checkModel(s.Units.Workers, "workers"); err != nil {
+		return err
+	}
+	if err := s.checkModel(s.Units.Stockpiles, "stockpiles"); err != nil {
+		return err
+	}
+
+	for i, o := range s.Objects {
+		what := fmt.Sprintf("object %d", i)
+		if err := s.checkMesh(o.Mesh, what); err != nil {
+			return err
+		}
+		if _, exists := s.Materials[o.Material]; !exists {
+			return fmt.Errorf("%w: material %q of %s doesn't exist", ErrInvalidScene, o.Material, what)
+		}
+	}
+	for i, scatter := range s.Scatter {
+		what := fmt.Sprintf("scatter %d", i)
+		if err := s.checkModel(scatter.Model, what); err != nil {
+			return err
+		}
+		if err := scatter.validate(); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidScene, what, err)
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the names in a map in order.
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Scene) checkMesh(name, what string) error {
+	if _, exists := s.Meshes[name]; !exists {
+		return fmt.Errorf("%w: mesh %q of %s doesn't exist", ErrInvalidScene, name, what)
+	}
+	return nil
+}
+
+// checkSolid checks that a material exists and is solid, which the objects of the game are drawn with.
+func (s *Scene) checkSolid(name, what string) error {
+	material, exists := s.Materials[name]
+	if !exists {
+		return fmt.Errorf("%w: material %q of %s doesn't exist", ErrInvalidScene, name, what)
+	}
+	if material.Shader != Solid {
+		return fmt.Errorf("%w: material %q of %s isn't solid", ErrInvalidScene, name, what)
+	}
+	return nil
+}
+
+func (s *Scene) checkModel(model Model, what string) error {
+	if err := s.checkMesh(model.Mesh, what); err != nil {
+		return err
+	}
+	return s.checkSolid(model.Material, what)
+}
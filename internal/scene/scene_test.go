@@ -0,0 +1,181 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/tilemap"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+const minimal = `
+meshes:
+  cube: {file: cube.obj}
+materials:
+  grey: {shader: solid, color: [0.5, 0.5, 0.5]}
+tiles:
+  mesh: cube
+  materials: {grass: grey}
+  hills: grey
+units:
+  nodes:
+    wood: {mesh: cube, material: grey}
+    stone: {mesh: cube, material: grey}
+    gold: {mesh: cube, material: grey}
+    food: {mesh: cube, material: grey}
+  workers: {mesh: cube, material: grey}
+  stockpiles: {mesh: cube, material: grey}
+`
+
+func TestLoad(t *testing.T) {
+	s, err := Load("../../resources/scenes/default.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, mgl32.Vec3{4, 4, 10}, s.Camera.Position)
+	assert.Equal(t, float32(-90), *s.Camera.Yaw)
+	assert.Equal(t, Light{Position: mgl32.Vec3{10, 10, -10}, Color: mgl32.Vec3{1, 1, 1}}, s.Light)
+	assert.Equal(t, Material{Shader: Solid, Color: mgl32.Vec3{0.1, 0.3, 0.6}}, s.Materials[s.Tiles.Materials["water"]])
+	assert.Equal(t, Model{Mesh: "cylinder", Material: "tree"}, s.Units.Nodes[resource.Wood])
+	assert.Equal(t, "../../resources/meshes/cylinder.obj", s.Path(s.Meshes["cylinder"].File))
+	assert.FileExists(t, s.Path(s.Meshes["cylinder"].File))
+	assert.True(t, s.Objects[2].Overlay)
+
+	_, err = Load("missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestParseJSON(t *testing.T) {
+	s, err := Parse([]byte(minimal))
+	assert.NoError(t, err)
+	asJSON := `{
+		"meshes": {"cube": {"file": "cube.obj"}},
+		"materials": {"grey": {"shader": "solid", "color": [0.5, 0.5, 0.5]}},
+		"tiles": {"mesh": "cube", "materials": {"grass": "grey"}, "hills": "grey"},
+		"units": {
+			"nodes": {
+				"wood": {"mesh": "cube", "material": "grey"},
+				"stone": {"mesh": "cube", "material": "grey"},
+				"gold": {"mesh": "cube", "material": "grey"},
+				"food": {"mesh": "cube", "material": "grey"}
+			},
+			"workers": {"mesh": "cube", "material": "grey"},
+			"stockpiles": {"mesh": "cube", "material": "grey"}
+		}
+	}`
+	fromJSON, err := Parse([]byte(asJSON))
+	assert.NoError(t, err)
+	assert.Equal(t, s, fromJSON)
+}
+
+func TestInvalid(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		replace []string
+	}{
+		{desc: "unknown field", replace: []string{"meshes:", "shapes: {}\nmeshes:"}},
+		{desc: "not yaml", replace: []string{"meshes:", "meshes: ["}},
+		{desc: "unknown mesh", replace: []string{"workers: {mesh: cube", "workers: {mesh: sphere"}},
+		{desc: "unknown material", replace: []string{"stockpiles: {mesh: cube, material: grey", "stockpiles: {mesh: cube, material: brown"}},
+		{desc: "unknown shader", replace: []string{"shader: solid", "shader: toon"}},
+		{desc: "mesh without a file", replace: []string{"{file: cube.obj}", "{}"}},
+		{desc: "unknown built in mesh", replace: []string{"{file: cube.obj}", "{builtin: teapot}"}},
+		{desc: "mesh of a file and built in", replace: []string{"{file: cube.obj}", "{file: cube.obj, builtin: cube}"}},
+		{desc: "texture missing", replace: []string{"materials:\n", "materials:\n  square: {shader: textured}\n"}},
+		{desc: "unknown terrain", replace: []string{"{grass: grey}", "{lava: grey}"}},
+		{desc: "unsolid tiles", replace: []string{"materials:\n", "materials:\n  lines: {shader: grid}\n", "{grass: grey}", "{grass: lines}"}},
+		{desc: "missing node model", replace: []string{"    food: {mesh: cube, material: grey}\n", ""}},
+		{desc: "unknown node kind", replace: []string{"    food:", "    iron: {mesh: cube, material: grey}\n    food:"}},
+		{desc: "hills missing", replace: []string{"  hills: grey\n", ""}},
+		{desc: "negative chunk size", replace: []string{"hills: grey", "hills: grey\n  chunkSize: -1"}},
+		{desc: "object of unknown material", replace: []string{"units:", "objects: [{mesh: cube, material: brown}]\nunits:"}},
+		{desc: "scatter on unknown terrain", replace: []string{"units:", "scatter: [{mesh: cube, material: grey, on: [lava]}]\nunits:"}},
+		{desc: "scatter of negative count", replace: []string{"units:", "scatter: [{mesh: cube, material: grey, count: -1}]\nunits:"}},
+		{desc: "scatter scaled the wrong way", replace: []string{"units:", "scatter: [{mesh: cube, material: grey, scale: [2, 1]}]\nunits:"}},
+		{desc: "scatter in an inside out area", replace: []string{"units:", "scatter: [{mesh: cube, material: grey, min: [1, 0], max: [0, 1]}]\nunits:"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			scene := strings.NewReplacer(tc.replace...).Replace(minimal)
+			assert.NotEqual(t, minimal, scene)
+			_, err := Parse([]byte(scene))
+			assert.ErrorIs(t, err, ErrInvalidScene)
+		})
+	}
+}
+
+func TestInvalidIsAlwaysTheSameError(t *testing.T) {
+	scene := strings.Replace(minimal, "materials:\n", "materials:\n  b: {shader: toon}\n  a: {shader: cel}\n  c: {shader: textured}\n", 1)
+	for i := 0; i < 10; i++ {
+		_, err := Parse([]byte(scene))
+		assert.ErrorIs(t, err, ErrInvalidScene)
+		assert.ErrorContains(t, err, `material "a" has unknown shader "cel"`)
+	}
+}
+
+func TestScatter(t *testing.T) {
+	m, err := tilemap.Parse("..~~\n..~~\n^^~~", mgl32.Vec2{-1, -1}, 1.0, 2.0)
+	assert.NoError(t, err)
+	height := func(x, z float32) float32 {
+		return m.HeightAt(fixed.FromFloat32(x), fixed.FromFloat32(z)).Float32() + x/10
+	}
+
+	scatter := Scatter{Count: 50, Seed: 3, On: []string{"grass", "rock"}, Offset: 0.5, Scale: [2]float32{0.5, 2}}
+	placements := scatter.Place(m, height)
+	assert.Len(t, placements, 50)
+	for _, p := range placements {
+		terrain := m.At(m.TileAt(fixed.Vec2{fixed.FromFloat32(p.Position.X()), fixed.FromFloat32(p.Position.Z())})).Terrain
+		assert.Contains(t, []tilemap.Terrain{tilemap.Grass, tilemap.Rock}, terrain)
+		assert.Equal(t, height(p.Position.X(), p.Position.Z())+0.5, p.Position.Y())
+		assert.GreaterOrEqual(t, p.Scale, float32(0.5))
+		assert.LessOrEqual(t, p.Scale, float32(2))
+	}
+	assert.Equal(t, placements, scatter.Place(m, height), "same seed, same places")
+	scatter.Seed = 4
+	assert.NotEqual(t, placements, scatter.Place(m, height))
+
+	testCases := []struct {
+		desc     string
+		scatter  Scatter
+		expected int
+	}{
+		{desc: "anywhere", scatter: Scatter{Count: 30}, expected: 30},
+		{desc: "in an area", scatter: Scatter{Count: 30, Min: mgl32.Vec2{0, 0}, Max: mgl32.Vec2{1, 1}}, expected: 30},
+		{desc: "outside the map", scatter: Scatter{Count: 30, Min: mgl32.Vec2{10, 10}, Max: mgl32.Vec2{11, 11}}, expected: 0},
+		{desc: "on terrain the map doesn't have", scatter: Scatter{Count: 30, On: []string{"forest"}}, expected: 0},
+		{desc: "none", scatter: Scatter{}, expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			placements := tc.scatter.Place(m, height)
+			assert.Len(t, placements, tc.expected)
+			for _, p := range placements {
+				assert.Equal(t, float32(1), p.Scale)
+				if tc.scatter.Max != (mgl32.Vec2{}) {
+					assert.True(t, p.Position.X() >= tc.scatter.Min.X() && p.Position.X() <= tc.scatter.Max.X())
+					assert.True(t, p.Position.Z() >= tc.scatter.Min.Y() && p.Position.Z() <= tc.scatter.Max.Y())
+				}
+			}
+		})
+	}
+}
+
+func TestStockpilesKeepTheirTransform(t *testing.T) {
+	w := ecs.NewWorld()
+	transform := gameobject.Transform{Position: mgl32.Vec3{1, 2, 3}, Scale: mgl32.Vec3{1, 1, 1}}
+	e := w.Spawn()
+	ecs.Add(w, e, transform)
+	ecs.Add(w, e, gameobject.Model(transform.Model()))
+	ecs.Add(w, e, &resource.Stockpile{})
+
+	in := &Instance{scene: &Scene{Units: Units{StockpileScale: mgl32.Vec3{2, 3, 2}}}}
+	in.scaleStockpiles(w, 0)
+	drawn := transform
+	drawn.Scale = mgl32.Vec3{2, 3, 2}
+	assert.Equal(t, gameobject.Model(drawn.Model()), *ecs.Get[gameobject.Model](w, e))
+	assert.Equal(t, transform, *ecs.Get[gameobject.Transform](w, e), "the game's transform is left alone")
+}
@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"game-engine/rts/internal/formation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// formationSpacing is how far apart units moved by a command stand.
+const formationSpacing float32 = 0.4
+
+var (
+	// ErrLateCommand is returned when issuing a command for a tick that has already been run.
+	ErrLateCommand = errors.New("command is too late")
+	// ErrUnknownCommand is returned when issuing a command of a type that doesn't exist.
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownUnit is returned when issuing a command for a unit that doesn't exist.
+	ErrUnknownUnit = errors.New("unknown unit")
+)
+
+// CommandType is what a command tells units to do.
+type CommandType string
+
+const (
+	// Select makes Units the units of the player that the other commands are given to.
+	Select CommandType = "select"
+	// Move sends the selected units to Target, standing in Formation.
+	Move CommandType = "move"
+	// Gather makes the selected units harvest Kind, or go back to what they were harvesting if it is empty.
+	Gather CommandType = "gather"
+	// Build puts a stockpile of the player at Target.
+	Build CommandType = "build"
+)
+
+// Command is something a player wants done. Commands are applied at the start of their tick, so every game
+// given the same commands plays out the same.
+type Command struct {
+	Tick   uint64          `json:"tick"`
+	Player resource.Player `json:"player"`
+	Type   CommandType     `json:"type"`
+	// Units are the indices of workers in Game.Workers, for Select.
+	Units     []int           `json:"units,omitempty"`
+	Target    mgl32.Vec3      `json:"target"`
+	Kind      resource.Kind   `json:"kind,omitempty"`
+	Formation formation.Shape `json:"formation,omitempty"`
+}
+
+// Issue queues a command to be applied at the start of its tick, which can't have been run yet. Commands
+// for the same tick are applied by player, then in the order they were issued.
+func (g *Game) Issue(c Command) error {
+	switch c.Type {
+	case Select, Move, Gather, Build:
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownCommand, c.Type)
+	}
+	for _, unit := range c.Units {
+		if unit < 0 || unit >= len(g.Workers) {
+			return fmt.Errorf("%w %d", ErrUnknownUnit, unit)
+		}
+	}
+	if c.Tick < g.Tick() {
+		return fmt.Errorf("%w: for tick %d at tick %d", ErrLateCommand, c.Tick, g.Tick())
+	}
+	i := sort.Search(len(g.commands), func(i int) bool {
+		other := g.commands[i]
+		return other.Tick > c.Tick || other.Tick == c.Tick && other.Player > c.Player
+	})
+	g.commands = append(g.commands, Command{})
+	copy(g.commands[i+1:], g.commands[i:])
+	g.commands[i] = c
+	return nil
+}
+
+// applyCommands applies the commands for the tick about to be run.
+func (g *Game) applyCommands() {
+	tick := g.Tick()
+	applied := 0
+	for _, c := range g.commands {
+		if c.Tick != tick {
+			break
+		}
+		g.apply(c)
+		if g.recording != nil {
+			g.recording.Commands = append(g.recording.Commands, c)
+		}
+		applied++
+	}
+	g.commands = g.commands[applied:]
+}
+
+func (g *Game) apply(c Command) {
+	if g.selected == nil {
+		g.selected = map[resource.Player][]*worker.Worker{}
+	}
+	// Only workers controlled by a state machine take orders
+	ordered := []*worker.Worker{}
+	for _, w := range g.selected[c.Player] {
+		if w.State() != "" {
+			ordered = append(ordered, w)
+		}
+	}
+
+	switch c.Type {
+	case Select:
+		selected := []*worker.Worker{}
+		for _, unit := range c.Units {
+			if w := g.Workers[unit]; w.Player() == c.Player {
+				selected = append(selected, w)
+			}
+		}
+		g.selected[c.Player] = selected
+	case Move:
+		if len(ordered) > 0 {
+			worker.MoveInFormation(ordered, c.Target, formation.Formation{Shape: c.Formation, Spacing: formationSpacing})
+		}
+	case Gather:
+		for _, w := range g.selected[c.Player] {
+			if c.Kind != "" {
+				w.Gather(c.Kind)
+			}
+		}
+		for _, w := range ordered {
+			w.Work()
+		}
+	case Build:
+		position := mgl32.Vec3{c.Target.X(), g.groundHeight(c.Target.X(), c.Target.Z()), c.Target.Z()}
+		stockpile := &resource.Stockpile{Position: position, Player: c.Player}
+		g.World.Stockpiles = append(g.World.Stockpiles, stockpile)
+		g.spawnStockpile(stockpile)
+	}
+}
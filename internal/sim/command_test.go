@@ -0,0 +1,97 @@
+package sim
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/formation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssue(t *testing.T) {
+	game, err := NewGame(Config{Seed: 1})
+	assert.NoError(t, err)
+	game.Run(5)
+
+	testCases := []struct {
+		desc     string
+		command  Command
+		expected error
+	}{
+		{desc: "select", command: Command{Tick: 5, Type: Select, Units: []int{0, 4}}},
+		{desc: "later", command: Command{Tick: 100, Type: Build}},
+		{desc: "too late", command: Command{Tick: 4, Type: Move}, expected: ErrLateCommand},
+		{desc: "unknown type", command: Command{Tick: 5, Type: "dance"}, expected: ErrUnknownCommand},
+		{desc: "unknown unit", command: Command{Tick: 5, Type: Select, Units: []int{5}}, expected: ErrUnknownUnit},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.ErrorIs(t, game.Issue(tc.command), tc.expected)
+		})
+	}
+}
+
+func TestCommandOrder(t *testing.T) {
+	game, err := NewGame(Config{Seed: 1})
+	assert.NoError(t, err)
+	for _, c := range []Command{
+		{Tick: 2, Player: 1, Type: Move},
+		{Tick: 2, Player: 0, Type: Select},
+		{Tick: 1, Player: 1, Type: Build},
+		{Tick: 2, Player: 0, Type: Move},
+		{Tick: 2, Player: 1, Type: Gather},
+	} {
+		assert.NoError(t, game.Issue(c))
+	}
+	order := []string{}
+	for _, c := range game.commands {
+		order = append(order, string(c.Type))
+	}
+	assert.Equal(t, []string{"build", "select", "move", "move", "gather"}, order, "by tick, then player, then as issued")
+
+	game.Run(2)
+	assert.Len(t, game.commands, 4, "applied at the start of their tick")
+	assert.Len(t, game.World.Stockpiles, 2)
+	game.Run(1)
+	assert.Empty(t, game.commands)
+}
+
+func TestCommands(t *testing.T) {
+	game, err := NewGame(Config{Seed: 1})
+	assert.NoError(t, err)
+	target := game.Stockpile.Position.Add(mgl32.Vec3{0, 0, 2})
+	commands := []Command{
+		{Tick: 0, Type: Select, Units: []int{1, 2, 3}},
+		{Tick: 0, Type: Move, Target: target, Formation: formation.Box},
+		// Another player can't select the workers of player 0
+		{Tick: 0, Player: 1, Type: Select, Units: []int{0}},
+		{Tick: 0, Player: 1, Type: Move, Target: target},
+	}
+	for _, c := range commands {
+		assert.NoError(t, game.Issue(c))
+	}
+	game.Run(1)
+	assert.NotEqual(t, worker.StateMoving, game.Workers[0].State())
+	for _, w := range game.Workers[1:4] {
+		assert.Equal(t, worker.StateMoving, w.State())
+	}
+
+	game.Run(10 * DefaultRate)
+	for _, w := range game.Workers[1:4] {
+		assert.Less(t, w.WorldPosition().Sub(target).Len(), float32(1.0), "arrived")
+	}
+
+	assert.NoError(t, game.Issue(Command{Tick: game.Tick(), Type: Gather, Kind: resource.Stone}))
+	assert.NoError(t, game.Issue(Command{Tick: game.Tick(), Player: 1, Type: Build, Target: mgl32.Vec3{3, 0, -3}}))
+	game.Run(1)
+	for _, w := range game.Workers[1:4] {
+		assert.NotEqual(t, worker.StateMoving, w.State(), "back to work")
+	}
+	assert.Len(t, game.World.Stockpiles, 2)
+	built := game.World.Stockpiles[1]
+	assert.Equal(t, resource.Player(1), built.Player)
+	assert.Equal(t, mgl32.Vec3{3, game.groundHeight(3, -3), -3}, built.Position, "on the ground")
+}
@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// workerScale is how big workers are drawn.
+var workerScale = mgl32.Vec3{0.2, 0.2, 0.2}
+
+// addSystems adds the systems that run each tick: the world and then the workers are updated, and the
+// entities moved to where the workers are and shrunk with the nodes. Where the workers were is saved first,
+// so they can be drawn between ticks.
+func (g *Game) addSystems() error {
+	for _, system := range []ecs.System{
+		{Name: "positions", Run: gameobject.SavePositions},
+		{Name: "world", After: []string{"positions"}, Run: func(_ *ecs.World, dt float32) { g.World.Update(dt) }},
+		{Name: "workers", After: []string{"world"}, Run: updateWorkers},
+		{Name: "units", After: []string{"workers"}, Run: g.updateUnits},
+	} {
+		if err := g.systems.Add(system); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spawnEntities spawns the entities of the workers, of the nodes left in the world and of the stockpiles.
+func (g *Game) spawnEntities() {
+	for _, w := range g.Workers {
+		e := g.Entities.Spawn()
+		ecs.Add(g.Entities, e, gameobject.Transform{Position: w.WorldPosition(), Scale: workerScale})
+		ecs.Add(g.Entities, e, gameobject.Previous(w.WorldPosition()))
+		ecs.Add(g.Entities, e, w)
+	}
+	for _, node := range g.World.Nodes {
+		e := g.Entities.Spawn()
+		ecs.Add(g.Entities, e, gameobject.Transform{Position: node.Position(), Scale: node.Scale()})
+		ecs.Add(g.Entities, e, node)
+	}
+	for _, stockpile := range g.World.Stockpiles {
+		g.spawnStockpile(stockpile)
+	}
+}
+
+// spawnStockpile spawns the entity of a stockpile. Stockpiles don't change size, how big they are drawn is up to
+// what draws them.
+func (g *Game) spawnStockpile(stockpile *resource.Stockpile) {
+	e := g.Entities.Spawn()
+	ecs.Add(g.Entities, e, gameobject.Transform{Position: stockpile.Position, Scale: mgl32.Vec3{1.0, 1.0, 1.0}})
+	ecs.Add(g.Entities, e, stockpile)
+}
+
+// updateWorkers updates the workers, in the order they were spawned.
+func updateWorkers(entities *ecs.World, dt float32) {
+	ecs.Each1(entities, func(_ ecs.Entity, w **worker.Worker) {
+		(*w).Update(dt)
+	})
+}
+
+// updateUnits moves the entities of the workers to where they are, shrinks those of the nodes as they are
+// depleted and despawns those of the nodes removed from the world.
+func (g *Game) updateUnits(entities *ecs.World, _ float32) {
+	ecs.Each2(entities, func(_ ecs.Entity, t *gameobject.Transform, w **worker.Worker) {
+		t.Position = (*w).WorldPosition()
+	})
+	ecs.Each2(entities, func(e ecs.Entity, t *gameobject.Transform, node **resource.Node) {
+		if !g.World.HasNode(*node) {
+			entities.Commands().Despawn(e)
+			return
+		}
+		t.Scale = (*node).Scale()
+	})
+}
@@ -0,0 +1,312 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/fsm"
+	"game-engine/rts/internal/mapgen"
+	"game-engine/rts/internal/navmesh"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/steering"
+	"game-engine/rts/internal/terrain"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	defaultWorkers = 5
+	// stockpileGoal is how much the planning workers want in the stockpile.
+	stockpileGoal = 50
+)
+
+var (
+	// ErrUnknownBrain is returned for a Config with a brain that doesn't exist.
+	ErrUnknownBrain = errors.New("unknown brain")
+	// ErrUnknownAvoidance is returned for a Config with a kind of avoidance that doesn't exist.
+	ErrUnknownAvoidance = errors.New("unknown avoidance")
+)
+
+// gathering is what the workers harvest in turn, most of them chop wood.
+var gathering = []resource.Kind{resource.Wood, resource.Wood, resource.Stone, resource.Gold, resource.Food}
+
+// Config is how to set up a game.
+type Config struct {
+	// Seed is the seed of the generated map.
+	Seed int64 `json:"seed"`
+	// Terrain is hills, or the name of a grayscale PNG heightmap, for ground that isn't flat.
+	Terrain string `json:"terrain,omitempty"`
+	// Players is how many players there are, 1 if 0. Each starts at a start of the map with a stockpile.
+	Players int `json:"players,omitempty"`
+	// Workers is how many workers each player has, 5 if 0.
+	Workers int `json:"workers"`
+	// Brain is what controls the workers, fsm (state machine, the default), bt (behaviour tree) or goap
+	// (planner).
+	Brain string `json:"brain"`
+	// Avoidance is how workers avoid each other, orca (the default) or separation.
+	Avoidance string `json:"avoidance"`
+	// NavMesh makes workers find paths over a navigation mesh instead of the grid.
+	NavMesh bool `json:"navmesh,omitempty"`
+	// Rate is how many ticks are simulated per second, DefaultRate if 0.
+	Rate int `json:"rate,omitempty"`
+}
+
+// Game is everything that is simulated: the map, the resources and the workers harvesting them. It needs no
+// window or GL context, the entities of the nodes and workers have no meshes or shaders until they are given
+// some to be drawn.
+type Game struct {
+	// Loop runs the ticks of the game.
+	*Loop
+	Config Config
+	Map    *mapgen.Map
+	// Heightmap is the ground when it isn't flat, nil otherwise.
+	Heightmap *terrain.Heightmap
+	World     *worker.World
+	// Stockpile is the stockpile of the first player, at the first start of the map.
+	Stockpile *resource.Stockpile
+	// Workers are the workers of every player, those of the first player first.
+	Workers []*worker.Worker
+	// Rand is where anything random while the game runs comes from, so it plays out the same on every
+	// machine, when played back and after loading.
+	Rand *fixed.Rand
+	// Entities are the workers, nodes and stockpiles and where they are. The systems of the game update them
+	// each tick, and whatever draws the game only gives them models.
+	Entities *ecs.World
+
+	systems ecs.Schedule
+
+	// nodes are all nodes the map was generated with, including those removed from the world since
+	nodes []*resource.Node
+
+	// commands are the commands issued that haven't been applied yet, in the order they will be
+	commands []Command
+	// selected are the units each player gives commands to
+	selected map[resource.Player][]*worker.Worker
+	// recording is the replay being recorded, if any
+	recording *Replay
+	// expected are the hashes of the replay being played back, if any
+	expected []uint64
+	desync   error
+}
+
+// NewGame generates the map of a game and puts workers on it.
+func NewGame(config Config) (*Game, error) {
+	g, err := newGame(config)
+	if err != nil {
+		return nil, err
+	}
+	g.spawnEntities()
+	return g, nil
+}
+
+// newGame is NewGame without the entities, which are spawned once the game is set up.
+func newGame(config Config) (*Game, error) {
+	if config.Players == 0 {
+		config.Players = 1
+	}
+	if config.Workers == 0 {
+		config.Workers = defaultWorkers
+	}
+	switch config.Brain {
+	case "":
+		config.Brain = "fsm"
+	case "fsm", "bt", "goap":
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownBrain, config.Brain)
+	}
+	switch config.Avoidance {
+	case "":
+		config.Avoidance = "orca"
+	case "orca", "separation":
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownAvoidance, config.Avoidance)
+	}
+
+	// Tile y = 0 is the far side of the land, at the lowest z
+	m, err := mapgen.Generate(mapgen.Config{
+		Seed:       config.Seed,
+		Width:      32,
+		Height:     32,
+		Origin:     mgl32.Vec2{-1.0, -19.0},
+		TileSize:   0.625,
+		Players:    max(config.Players, 2),
+		BaseHeight: 2.5,
+	})
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{Config: config, Map: m, Entities: ecs.NewWorld()}
+	g.Loop = NewLoop(config.Rate, g.update)
+	if err := g.addSystems(); err != nil {
+		return nil, err
+	}
+
+	ground := m.Tiles
+	sizeX, sizeZ := ground.Size()
+	switch config.Terrain {
+	case "":
+	case "hills":
+		g.Heightmap = terrain.FromFunc(sizeX*8+1, sizeZ*8+1, ground.Origin, ground.TileSize/8, terrain.Hills(config.Seed, 2.5, 0.4, 10.0))
+	default:
+		g.Heightmap, err = terrain.Load(config.Terrain, ground.Origin, ground.TileSize/8, 2.0, 3.5)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodes := make([]*resource.Node, 0, len(m.Trees)+len(m.Deposits))
+	for _, tree := range m.Trees {
+		position := mgl32.Vec3{tree.X(), g.groundHeight(tree.X(), tree.Y()) + 0.5, tree.Y()}
+		nodes = append(nodes, resource.NewNode(resource.Tree, position, mgl32.Vec3{0.1, 0.5, 0.1}))
+	}
+	for _, deposit := range m.Deposits {
+		x, z := deposit.Position.X(), deposit.Position.Y()
+		nodes = append(nodes, resource.NewNode(deposit.Type, mgl32.Vec3{x, g.groundHeight(x, z) + 0.1, z}, mgl32.Vec3{0.2, 0.2, 0.2}))
+	}
+	stockpiles := make([]*resource.Stockpile, config.Players)
+	for player := range stockpiles {
+		start := m.Starts[player]
+		stockpiles[player] = &resource.Stockpile{
+			Position: mgl32.Vec3{start.X(), g.groundHeight(start.X(), start.Y()), start.Y()},
+			Player:   resource.Player(player),
+		}
+	}
+	g.Stockpile = stockpiles[0]
+	g.nodes = nodes
+
+	g.Rand = fixed.NewRand(config.Seed)
+	g.World = worker.NewWorld(nodes, stockpiles...)
+	g.World.Rand = g.Rand
+	g.World.Ground = ground
+	if g.Heightmap != nil {
+		g.World.Ground = g.Heightmap
+	}
+	// Workers walk around the nodes on a grid of quarter tiles covering the land
+	g.World.SetNavigator(&pathfinding.Navigator{
+		Grid:     ground.Grid(4),
+		Origin:   ground.Origin,
+		TileSize: ground.TileSize / 4,
+	})
+	if config.NavMesh {
+		g.World.SetNavMesh(navmesh.New(ground, nil, navmesh.Config{
+			Origin:       ground.Origin,
+			TileSize:     ground.TileSize,
+			CellsPerTile: 4,
+			AgentRadius:  0.1,
+		}))
+	}
+	if config.Avoidance == "separation" {
+		g.World.Crowd.Avoidance = steering.SeparationAvoidance
+	}
+
+	// The workers start next to the stockpile of their player
+	for _, stockpile := range stockpiles {
+		for i := 0; i < config.Workers; i++ {
+			position := stockpile.Position.Add(mgl32.Vec3{float32(i)*0.3 - 0.6, 0.0, 0.5})
+			var w *worker.Worker
+			switch config.Brain {
+			case "bt":
+				w = worker.NewWithBehaviourTree(position, g.World)
+			case "goap":
+				w = worker.NewWithPlanner(position, g.World, stockpileGoal)
+			default:
+				w = worker.New(position, g.World)
+			}
+			w.SetPlayer(stockpile.Player)
+			if config.Brain != "goap" {
+				w.Gather(gathering[i%len(gathering)])
+			}
+			g.Workers = append(g.Workers, w)
+		}
+	}
+	return g, nil
+}
+
+// HeightAt returns the height of the ground at a world x and z, in fixed point like the heights workers walk
+// at.
+func (g *Game) HeightAt(x, z fixed.Scalar) fixed.Scalar {
+	if g.Heightmap != nil {
+		return g.Heightmap.HeightAt(x, z)
+	}
+	return g.Map.Tiles.HeightAt(x, z)
+}
+
+// groundHeight returns HeightAt for a position in the world, to place things on the ground.
+func (g *Game) groundHeight(x, z float32) float32 {
+	return g.HeightAt(fixed.FromFloat32(x), fixed.FromFloat32(z)).Float32()
+}
+
+// update is one tick of the game.
+func (g *Game) update(dt float32) {
+	tick := g.Tick()
+	g.applyCommands()
+	// The systems are set up by the game and only despawn entities that are alive, so they can't fail
+	if err := g.systems.Run(g.Entities, dt); err != nil {
+		panic(err)
+	}
+	g.checkHash(tick)
+}
+
+// Stats are numbers about how a game is going.
+type Stats struct {
+	Ticks uint64
+	// Time is how many seconds have been simulated.
+	Time float32
+	// Resources are what the first player has delivered to stockpiles.
+	Resources map[resource.Kind]int
+	// Nodes is how many nodes are left in the world.
+	Nodes int
+	// States is how many workers are in each state, for workers controlled by a state machine.
+	States map[fsm.StateID]int
+}
+
+// Stats returns numbers about how the game is going.
+func (g *Game) Stats() Stats {
+	stats := Stats{
+		Ticks:     g.Tick(),
+		Time:      float32(g.Tick()) * g.Step(),
+		Resources: map[resource.Kind]int{},
+		Nodes:     len(g.World.Nodes),
+		States:    map[fsm.StateID]int{},
+	}
+	for _, kind := range resource.Kinds {
+		stats.Resources[kind] = g.World.Resources.Get(g.Stockpile.Player, kind)
+	}
+	for _, w := range g.Workers {
+		if state := w.State(); state != "" {
+			stats.States[state]++
+		}
+	}
+	return stats
+}
+
+func (s Stats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ticks: %d (%.1fs)\n", s.Ticks, s.Time)
+	for _, kind := range resource.Kinds {
+		fmt.Fprintf(&sb, "%s: %d\n", kind, s.Resources[kind])
+	}
+	fmt.Fprintf(&sb, "nodes left: %d\n", s.Nodes)
+	states := make([]string, 0, len(s.States))
+	for state := range s.States {
+		states = append(states, string(state))
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		fmt.Fprintf(&sb, "workers %s: %d\n", state, s.States[fsm.StateID(state)])
+	}
+	return sb.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGame(t *testing.T) {
+	game, err := NewGame(Config{Seed: 3})
+	assert.NoError(t, err)
+	assert.Len(t, game.Workers, defaultWorkers)
+	assert.Equal(t, "fsm", game.Config.Brain)
+	assert.Equal(t, len(game.Map.Trees)+len(game.Map.Deposits), len(game.World.Nodes))
+	assert.Equal(t, defaultWorkers, ecs.Count[*worker.Worker](game.Entities))
+	assert.Equal(t, len(game.World.Nodes), ecs.Count[*resource.Node](game.Entities))
+	assert.Equal(t, 1, ecs.Count[*resource.Stockpile](game.Entities))
+	assert.Zero(t, ecs.Count[gameobject.Solid](game.Entities), "nothing is set up to be drawn")
+	assert.Nil(t, game.Heightmap)
+	assert.Nil(t, game.World.NavMesh())
+
+	hills, err := NewGame(Config{Seed: 3, Terrain: "hills", NavMesh: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, hills.Heightmap)
+	assert.NotNil(t, hills.World.NavMesh())
+	assert.Equal(t, hills.Heightmap.HeightAt(fixed.FromInt(1), fixed.FromInt(-3)), hills.HeightAt(fixed.FromInt(1), fixed.FromInt(-3)))
+
+	_, err = NewGame(Config{Brain: "magic"})
+	assert.ErrorIs(t, err, ErrUnknownBrain)
+	_, err = NewGame(Config{Avoidance: "none"})
+	assert.ErrorIs(t, err, ErrUnknownAvoidance)
+	_, err = NewGame(Config{Terrain: "testdata/missing.png"})
+	assert.Error(t, err)
+}
+
+func TestWorkersGatherHeadless(t *testing.T) {
+	for _, brain := range []string{"fsm", "bt", "goap"} {
+		t.Run(brain, func(t *testing.T) {
+			game, err := NewGame(Config{Seed: 1, Brain: brain})
+			assert.NoError(t, err)
+			nodes := len(game.World.Nodes)
+
+			game.Run(60 * DefaultRate)
+			stats := game.Stats()
+			assert.Equal(t, uint64(60*DefaultRate), stats.Ticks)
+			assert.InDelta(t, 60.0, stats.Time, 1e-3)
+			assert.Greater(t, stats.Resources[resource.Wood], 0, "%v", stats)
+			assert.LessOrEqual(t, stats.Nodes, nodes)
+			assert.Equal(t, stats.Nodes, ecs.Count[*resource.Node](game.Entities), "the entities of removed nodes are despawned")
+			if brain != "fsm" {
+				assert.Empty(t, stats.States, "only state machines have states")
+			} else {
+				states := 0
+				for _, n := range stats.States {
+					states += n
+				}
+				assert.Equal(t, defaultWorkers, states)
+			}
+		})
+	}
+}
+
+func TestSameSeedSameGame(t *testing.T) {
+	a, err := NewGame(Config{Seed: 5, Workers: 8})
+	assert.NoError(t, err)
+	b, err := NewGame(Config{Seed: 5, Workers: 8})
+	assert.NoError(t, err)
+	a.Run(900)
+	b.Run(900)
+	assert.Equal(t, a.Stats().String(), b.Stats().String())
+	for i := range a.Workers {
+		assert.Equal(t, a.Workers[i].WorldPosition(), b.Workers[i].WorldPosition())
+	}
+}
@@ -0,0 +1,91 @@
+// Package sim runs the simulation of the game at a fixed rate, apart from how often frames are drawn.
+package sim
+
+import "math"
+
+const (
+	// DefaultRate is how many ticks the simulation runs per second.
+	DefaultRate = 30
+	// defaultMaxSteps is how many ticks are run at most for one frame.
+	defaultMaxSteps = 5
+	// epsilon is how much less than a tick still counts as a tick, for the rounding of adding up frame times.
+	epsilon = 1e-9
+)
+
+// Loop runs ticks of a fixed length for the time that has passed, so the simulation gives the same results
+// however fast frames are drawn.
+type Loop struct {
+	// MaxSteps is how many ticks Advance runs at most. When the simulation falls further behind than that the
+	// rest of the time is dropped and the game slows down, instead of taking ever longer to catch up.
+	MaxSteps int
+	// Scale speeds up the simulation, or slows it down when it is less than 1.
+	Scale float64
+	// Paused stops Advance from running ticks.
+	Paused bool
+
+	step   float64
+	update func(dt float32)
+	tick   uint64
+	// accumulator is the time that has passed but hasn't been simulated yet
+	accumulator float64
+}
+
+// NewLoop creates a loop calling update rate times per simulated second, at normal speed.
+func NewLoop(rate int, update func(dt float32)) *Loop {
+	if rate <= 0 {
+		rate = DefaultRate
+	}
+	return &Loop{
+		MaxSteps: defaultMaxSteps,
+		Scale:    1,
+		step:     1 / float64(rate),
+		update:   update,
+	}
+}
+
+// Step returns the simulated time of one tick.
+func (l *Loop) Step() float32 {
+	return float32(l.step)
+}
+
+// Tick returns how many ticks have been run.
+func (l *Loop) Tick() uint64 {
+	return l.tick
+}
+
+// Advance runs the ticks that fit in the time that has passed since the last frame, and returns how many it
+// ran. What is left over is run by later calls.
+func (l *Loop) Advance(elapsed float64) int {
+	if l.Paused || elapsed <= 0 {
+		return 0
+	}
+	l.accumulator += elapsed * l.Scale
+	steps := 0
+	for l.accumulator+epsilon >= l.step {
+		if steps == l.MaxSteps {
+			l.accumulator = math.Mod(l.accumulator+epsilon, l.step)
+			break
+		}
+		l.Run(1)
+		l.accumulator -= l.step
+		steps++
+	}
+	if l.accumulator < 0 {
+		l.accumulator = 0
+	}
+	return steps
+}
+
+// Run runs ticks straight away, whatever the time, to step the simulation without drawing it.
+func (l *Loop) Run(ticks int) {
+	for i := 0; i < ticks; i++ {
+		l.update(float32(l.step))
+		l.tick++
+	}
+}
+
+// Alpha returns how far the time that has passed is between the last tick and the next, from 0 to 1, to draw
+// things between where they were and where they are.
+func (l *Loop) Alpha() float32 {
+	return float32(l.accumulator / l.step)
+}
@@ -0,0 +1,124 @@
+package sim
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvance(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		frames []float64
+		ticks  uint64
+		alpha  float32
+	}{
+		{desc: "nothing", frames: nil, ticks: 0, alpha: 0},
+		{desc: "less than a tick", frames: []float64{0.01}, ticks: 0, alpha: 0.25},
+		{desc: "adds up", frames: []float64{0.02, 0.02, 0.02}, ticks: 1, alpha: 0.5},
+		{desc: "two ticks in one frame", frames: []float64{0.09}, ticks: 2, alpha: 0.25},
+		{desc: "stall", frames: []float64{2.0}, ticks: 5, alpha: 0},
+		{desc: "going backwards", frames: []float64{0.03, -1.0}, ticks: 0, alpha: 0.75},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			updates := 0
+			loop := NewLoop(25, func(dt float32) {
+				assert.Equal(t, float32(0.04), dt)
+				updates++
+			})
+			for _, frame := range tc.frames {
+				loop.Advance(frame)
+			}
+			assert.Equal(t, tc.ticks, loop.Tick())
+			assert.Equal(t, int(tc.ticks), updates)
+			assert.InDelta(t, tc.alpha, loop.Alpha(), 1e-4)
+		})
+	}
+}
+
+func TestPauseAndScale(t *testing.T) {
+	loop := NewLoop(10, func(float32) {})
+	assert.Equal(t, float32(0.1), loop.Step())
+
+	loop.Paused = true
+	assert.Equal(t, 0, loop.Advance(1.0))
+	assert.Equal(t, uint64(0), loop.Tick(), "no time passes while paused")
+
+	loop.Paused = false
+	loop.Scale = 2
+	assert.Equal(t, 4, loop.Advance(0.2))
+	loop.Scale = 0.5
+	assert.Equal(t, 1, loop.Advance(0.2))
+	assert.Equal(t, uint64(5), loop.Tick())
+
+	loop.Scale = 100
+	assert.Equal(t, 5, loop.Advance(1.0), "speeding up is limited by MaxSteps")
+	loop.MaxSteps = 50
+	assert.Equal(t, 50, loop.Advance(1.0))
+
+	// Running ticks straight away ignores pausing
+	loop.Paused = true
+	loop.Run(3)
+	assert.Equal(t, uint64(63), loop.Tick())
+	assert.Equal(t, DefaultRate, int(1/NewLoop(0, nil).Step()+0.5))
+}
+
+// newWorkers creates a world with a few trees and workers chopping them, and a loop simulating them.
+func newWorkers() (*Loop, []*worker.Worker) {
+	trees := []*resource.Node{}
+	for i := 0; i < 6; i++ {
+		position := mgl32.Vec3{float32(i%3)*1.5 - 1.5, 0.0, float32(i/3)*2.0 + 2.0}
+		trees = append(trees, resource.NewNode(resource.Tree, position, mgl32.Vec3{}))
+	}
+	world := worker.NewWorld(trees, &resource.Stockpile{})
+	workers := []*worker.Worker{}
+	for i := 0; i < 3; i++ {
+		workers = append(workers, worker.New(mgl32.Vec3{float32(i) * 0.5, 0.0, 0.0}, world))
+	}
+	loop := NewLoop(DefaultRate, func(dt float32) {
+		world.Update(dt)
+		for _, w := range workers {
+			w.Update(dt)
+		}
+	})
+	return loop, workers
+}
+
+func TestSameResultsAtAnyFrameRate(t *testing.T) {
+	positions := func(workers []*worker.Worker) []mgl32.Vec3 {
+		p := []mgl32.Vec3{}
+		for _, w := range workers {
+			p = append(p, w.WorldPosition())
+		}
+		return p
+	}
+	ticks := uint64(20 * DefaultRate)
+
+	testCases := []struct {
+		desc   string
+		frames []float64
+	}{
+		{desc: "60 fps", frames: []float64{1.0 / 60}},
+		{desc: "144 fps", frames: []float64{1.0 / 144}},
+		{desc: "uneven", frames: []float64{0.001, 0.05, 0.013, 0.12, 0.002}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			loop, workers := newWorkers()
+			loop.MaxSteps = 10
+			for i := 0; loop.Tick() < ticks; i++ {
+				loop.Advance(tc.frames[i%len(tc.frames)])
+			}
+			// The last frame may have run a few more ticks than needed
+			stepped, expected := newWorkers()
+			stepped.Run(int(loop.Tick()))
+			assert.NotEqual(t, mgl32.Vec3{}, expected[0].WorldPosition(), "the workers moved")
+			assert.Equal(t, positions(expected), positions(workers))
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package sim
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/resource"
+)
+
+// replayVersion is the version of the replay format written by WriteReplay.
+const replayVersion = 1
+
+var (
+	// ErrReplayVersion is returned when reading a replay written by another version of the game.
+	ErrReplayVersion = errors.New("unsupported replay version")
+	// ErrDesync is returned when a replay plays out differently than when it was recorded.
+	ErrDesync = errors.New("replay desynced")
+)
+
+// Replay is a recorded game: how it was set up, the commands given and a hash of the state after every tick,
+// to check that playing it back gives the same game.
+type Replay struct {
+	Version  int       `json:"version"`
+	Config   Config    `json:"config"`
+	Commands []Command `json:"commands"`
+	Hashes   []uint64  `json:"hashes"`
+}
+
+// Ticks returns how many ticks were recorded.
+func (r *Replay) Ticks() int {
+	return len(r.Hashes)
+}
+
+// WriteReplay writes a replay as JSON.
+func WriteReplay(w io.Writer, r *Replay) error {
+	r.Version = replayVersion
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(r); err != nil {
+		return fmt.Errorf("writing replay: %w", err)
+	}
+	return nil
+}
+
+// ReadReplay reads a replay written by WriteReplay.
+func ReadReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{}
+	if err := json.NewDecoder(r).Decode(replay); err != nil {
+		return nil, fmt.Errorf("reading replay: %w", err)
+	}
+	if replay.Version != replayVersion {
+		return nil, fmt.Errorf("%w %d", ErrReplayVersion, replay.Version)
+	}
+	return replay, nil
+}
+
+// LoadReplay reads a replay from a file.
+func LoadReplay(name string) (*Replay, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
+
+// SaveReplay writes a replay to a file.
+func SaveReplay(name string, r *Replay) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := WriteReplay(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Record starts recording the game, before it is run. The replay grows as the game is run.
+func (g *Game) Record() *Replay {
+	g.recording = &Replay{Version: replayVersion, Config: g.Config, Commands: []Command{}}
+	return g.recording
+}
+
+// NewReplayGame sets up a game to play back a replay. Running it checks every tick against the replay, Desync
+// returns the first tick that was different.
+func NewReplayGame(replay *Replay) (*Game, error) {
+	g, err := NewGame(replay.Config)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range replay.Commands {
+		if err := g.Issue(c); err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+	}
+	g.expected = replay.Hashes
+	return g, nil
+}
+
+// Desync returns an ErrDesync for the first tick a replay played back differently, or nil.
+func (g *Game) Desync() error {
+	return g.desync
+}
+
+// checkHash records the hash of the state after a tick, and checks it against the replay being played back.
+func (g *Game) checkHash(tick uint64) {
+	if g.recording == nil && g.expected == nil {
+		return
+	}
+	hash := g.Hash()
+	if g.recording != nil {
+		g.recording.Hashes = append(g.recording.Hashes, hash)
+	}
+	if g.desync == nil && tick < uint64(len(g.expected)) && g.expected[tick] != hash {
+		g.desync = fmt.Errorf("%w at tick %d", ErrDesync, tick)
+	}
+}
+
+// Hash returns a hash of the state of the game: where the workers are, what they are doing and carrying, what
+// is left in the nodes, what has been delivered and the state of Rand.
+func (g *Game) Hash() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 64)
+	number := func(n uint64) {
+		buf = binary.LittleEndian.AppendUint64(buf[:0], n)
+		h.Write(buf)
+	}
+	number(g.Tick())
+	number(g.Rand.State())
+	for _, w := range g.Workers {
+		for _, s := range w.Position() {
+			number(uint64(s))
+		}
+		h.Write([]byte(w.State()))
+		for _, kind := range resource.Kinds {
+			number(uint64(w.Inventory().Amount(kind)))
+		}
+	}
+	number(uint64(len(g.World.Nodes)))
+	for _, node := range g.World.Nodes {
+		for _, s := range fixed.FromVec3(node.Position()) {
+			number(uint64(s))
+		}
+		number(uint64(node.Remaining))
+	}
+	for _, stockpile := range g.World.Stockpiles {
+		for _, s := range fixed.FromVec3(stockpile.Position) {
+			number(uint64(s))
+		}
+		for _, kind := range resource.Kinds {
+			number(uint64(g.World.Resources.Get(stockpile.Player, kind)))
+		}
+	}
+	return h.Sum64()
+}
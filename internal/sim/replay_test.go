@@ -0,0 +1,67 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"game-engine/rts/internal/resource"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordGame records a game where the workers are sent somewhere and back to work on other resources.
+func recordGame(t *testing.T) (*Game, *Replay) {
+	game, err := NewGame(Config{Seed: 2, Workers: 4})
+	assert.NoError(t, err)
+	replay := game.Record()
+	target := game.Stockpile.Position.Add(mgl32.Vec3{1, 0, 1})
+	for _, c := range []Command{
+		{Tick: 10, Type: Select, Units: []int{0, 1}},
+		{Tick: 10, Type: Move, Target: target},
+		{Tick: 90, Type: Gather, Kind: resource.Gold},
+		{Tick: 120, Type: Build, Target: target},
+	} {
+		assert.NoError(t, game.Issue(c))
+	}
+	game.Run(300)
+	return game, replay
+}
+
+func TestReplay(t *testing.T) {
+	game, replay := recordGame(t)
+	assert.Equal(t, 300, replay.Ticks())
+	assert.Len(t, replay.Commands, 4)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReplay(&buf, replay))
+	read, err := ReadReplay(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, replay, read)
+
+	played, err := NewReplayGame(read)
+	assert.NoError(t, err)
+	played.Run(read.Ticks())
+	assert.NoError(t, played.Desync())
+	assert.Equal(t, game.Hash(), played.Hash())
+	assert.Equal(t, game.Stats(), played.Stats())
+}
+
+func TestReplayDesync(t *testing.T) {
+	_, replay := recordGame(t)
+	replay.Commands[1].Target = replay.Commands[1].Target.Add(mgl32.Vec3{1, 0, 0})
+
+	played, err := NewReplayGame(replay)
+	assert.NoError(t, err)
+	played.Run(replay.Ticks())
+	assert.ErrorIs(t, played.Desync(), ErrDesync)
+	assert.Contains(t, played.Desync().Error(), "tick 10")
+
+	_, err = ReadReplay(strings.NewReader(`{"version": 2}`))
+	assert.ErrorIs(t, err, ErrReplayVersion)
+	_, err = ReadReplay(strings.NewReader(`{"version": 1, "commands": [{"type": "dance"}]}`))
+	assert.NoError(t, err)
+	_, err = NewReplayGame(&Replay{Commands: []Command{{Type: "dance"}}})
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+}
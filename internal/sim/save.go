@@ -0,0 +1,344 @@
+package sim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/reservation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// saveVersion is the version of the save format written by WriteSave.
+const saveVersion = 1
+
+var (
+	// ErrSaveVersion is returned when reading a save of a version there is no migration from.
+	ErrSaveVersion = errors.New("unsupported save version")
+	// ErrInvalidSave is returned when loading a save that doesn't fit the game set up from its config.
+	ErrInvalidSave = errors.New("invalid save")
+)
+
+// binaryMagic starts every binary save, JSON saves start with {.
+var binaryMagic = []byte("RTSSAVE")
+
+// Format is how a save is encoded.
+type Format int
+
+const (
+	// JSON is readable and easy to debug.
+	JSON Format = iota
+	// Binary is smaller and faster to read and write.
+	Binary
+)
+
+// migrations upgrade a save of a version to the next version. Saves of older versions are read into the
+// current Save, where fields that have been added are zero, and migrations fill them in.
+var migrations = map[int]func(*Save) error{}
+
+// Save is the state of a game, to continue it later exactly as if it had never stopped. The map and the
+// nodes it was generated with are generated again from the config, nodes are referred to by their index in
+// those.
+type Save struct {
+	Version int    `json:"version"`
+	Config  Config `json:"config"`
+	Tick    uint64 `json:"tick"`
+	// Rand is the state of Game.Rand, so random choices come out the same after loading.
+	Rand uint64 `json:"rand"`
+	// Nodes are the nodes in the world, in order.
+	Nodes []NodeSave `json:"nodes"`
+	// Removed are the nodes removed from the world, in the order they were removed.
+	Removed      []int                `json:"removed"`
+	Stockpiles   []resource.Stockpile `json:"stockpiles"`
+	Resources    []ResourceSave       `json:"resources"`
+	Reservations ReservationSave      `json:"reservations"`
+	Workers      []worker.Snapshot    `json:"workers"`
+	// Crowd is where the crowd last saw each worker that is still alive, see steering.Crowd.Indexed.
+	Crowd    []mgl32.Vec2              `json:"crowd"`
+	Selected map[resource.Player][]int `json:"selected,omitempty"`
+	// Commands are the commands issued for ticks that haven't been run yet.
+	Commands []Command `json:"commands"`
+}
+
+// NodeSave is what is left in a node.
+type NodeSave struct {
+	Node      int          `json:"node"`
+	Remaining int          `json:"remaining"`
+	RegrowIn  fixed.Scalar `json:"regrowIn,omitempty"`
+}
+
+// ResourceSave is how much of a resource a player has delivered.
+type ResourceSave struct {
+	Player resource.Player `json:"player"`
+	Kind   resource.Kind   `json:"kind"`
+	Amount int             `json:"amount"`
+}
+
+// ReservationSave is which worker has claimed which node.
+type ReservationSave struct {
+	Time    fixed.Scalar `json:"time"`
+	NextSeq uint64       `json:"nextSeq"`
+	Claims  []ClaimSave  `json:"claims"`
+}
+
+// ClaimSave is a claim of a worker on a node.
+type ClaimSave struct {
+	Node      int          `json:"node"`
+	Worker    int          `json:"worker"`
+	ExpiresAt fixed.Scalar `json:"expiresAt"`
+	Seq       uint64       `json:"seq"`
+}
+
+// Save returns the state of the game.
+func (g *Game) Save() *Save {
+	nodes := make(map[*resource.Node]int, len(g.nodes))
+	for i, node := range g.nodes {
+		nodes[node] = i
+	}
+	workers := make(map[*worker.Worker]int, len(g.Workers))
+	for i, w := range g.Workers {
+		workers[w] = i
+	}
+
+	s := &Save{
+		Version:  saveVersion,
+		Config:   g.Config,
+		Tick:     g.Tick(),
+		Rand:     g.Rand.State(),
+		Crowd:    g.World.Crowd.Indexed(),
+		Commands: append([]Command{}, g.commands...),
+	}
+	for _, node := range g.World.Nodes {
+		s.Nodes = append(s.Nodes, NodeSave{Node: nodes[node], Remaining: node.Remaining, RegrowIn: node.RegrowIn()})
+	}
+	for _, node := range g.World.Removed() {
+		s.Removed = append(s.Removed, nodes[node])
+	}
+	for _, stockpile := range g.World.Stockpiles {
+		s.Stockpiles = append(s.Stockpiles, *stockpile)
+	}
+	for player := 0; player < g.Config.Players; player++ {
+		for _, kind := range resource.Kinds {
+			if amount := g.World.Resources.Get(resource.Player(player), kind); amount != 0 {
+				s.Resources = append(s.Resources, ResourceSave{Player: resource.Player(player), Kind: kind, Amount: amount})
+			}
+		}
+	}
+	reservations := g.World.Reservations.Snapshot()
+	s.Reservations = ReservationSave{Time: reservations.Time, NextSeq: reservations.NextSeq}
+	for _, c := range reservations.Claims {
+		s.Reservations.Claims = append(s.Reservations.Claims, ClaimSave{
+			Node:      nodes[c.Node],
+			Worker:    workers[c.Claimant.(*worker.Worker)],
+			ExpiresAt: c.ExpiresAt,
+			Seq:       c.Seq,
+		})
+	}
+	for _, w := range g.Workers {
+		s.Workers = append(s.Workers, w.Snapshot(func(node *resource.Node) int { return nodes[node] }, func(w *worker.Worker) int { return workers[w] }))
+	}
+	if len(g.selected) > 0 {
+		s.Selected = map[resource.Player][]int{}
+		for player, selected := range g.selected {
+			units := []int{}
+			for _, w := range selected {
+				units = append(units, workers[w])
+			}
+			s.Selected[player] = units
+		}
+	}
+	return s
+}
+
+// LoadGame sets up the game of a save, to continue where it was saved.
+func LoadGame(s *Save) (*Game, error) {
+	if s.Version != saveVersion {
+		return nil, fmt.Errorf("%w %d", ErrSaveVersion, s.Version)
+	}
+	g, err := newGame(s.Config)
+	if err != nil {
+		return nil, err
+	}
+	node := func(i int) *resource.Node {
+		if i < 0 || i >= len(g.nodes) {
+			return nil
+		}
+		return g.nodes[i]
+	}
+	unit := func(i int) *worker.Worker {
+		if i < 0 || i >= len(g.Workers) {
+			return nil
+		}
+		return g.Workers[i]
+	}
+
+	// Removing the same nodes in the same order leaves the world, its paths and obstacles, as they were
+	for _, i := range s.Removed {
+		if node(i) == nil {
+			return nil, fmt.Errorf("%w: no node %d to remove", ErrInvalidSave, i)
+		}
+		g.World.RemoveNode(node(i))
+	}
+	if len(s.Nodes) != len(g.World.Nodes) {
+		return nil, fmt.Errorf("%w: %d nodes left instead of %d", ErrInvalidSave, len(g.World.Nodes), len(s.Nodes))
+	}
+	for i, saved := range s.Nodes {
+		if g.World.Nodes[i] != node(saved.Node) {
+			return nil, fmt.Errorf("%w: node %d isn't where it was in the world", ErrInvalidSave, saved.Node)
+		}
+		g.World.Nodes[i].Restore(saved.Remaining, saved.RegrowIn)
+	}
+
+	g.World.Stockpiles = nil
+	for i := range s.Stockpiles {
+		stockpile := s.Stockpiles[i]
+		g.World.Stockpiles = append(g.World.Stockpiles, &stockpile)
+	}
+	if len(g.World.Stockpiles) == 0 {
+		return nil, fmt.Errorf("%w: no stockpiles", ErrInvalidSave)
+	}
+	g.Stockpile = g.World.Stockpiles[0]
+	for _, r := range s.Resources {
+		g.World.Resources.Add(r.Player, r.Kind, r.Amount)
+	}
+
+	if len(s.Workers) != len(g.Workers) {
+		return nil, fmt.Errorf("%w: %d workers instead of %d", ErrInvalidSave, len(s.Workers), len(g.Workers))
+	}
+	for i, snapshot := range s.Workers {
+		if err := g.Workers[i].Restore(snapshot, node, unit); err != nil {
+			return nil, fmt.Errorf("%w: worker %d: %v", ErrInvalidSave, i, err)
+		}
+	}
+	g.World.Crowd.SetIndexed(s.Crowd)
+	reservations := reservation.Snapshot[*resource.Node]{Time: s.Reservations.Time, NextSeq: s.Reservations.NextSeq}
+	for _, c := range s.Reservations.Claims {
+		if node(c.Node) == nil || unit(c.Worker) == nil {
+			return nil, fmt.Errorf("%w: claim of worker %d on node %d", ErrInvalidSave, c.Worker, c.Node)
+		}
+		reservations.Claims = append(reservations.Claims, reservation.ClaimSnapshot[*resource.Node]{
+			Node:      node(c.Node),
+			Claimant:  unit(c.Worker),
+			ExpiresAt: c.ExpiresAt,
+			Seq:       c.Seq,
+		})
+	}
+	g.World.Reservations.Restore(reservations)
+
+	g.tick = s.Tick
+	// The world shares the generator of the game
+	*g.Rand = *fixed.NewRand(int64(s.Rand))
+	if len(s.Selected) > 0 {
+		g.selected = map[resource.Player][]*worker.Worker{}
+	}
+	for player, units := range s.Selected {
+		selected := []*worker.Worker{}
+		for _, i := range units {
+			if unit(i) == nil {
+				return nil, fmt.Errorf("%w: %w %d selected", ErrInvalidSave, ErrUnknownUnit, i)
+			}
+			selected = append(selected, unit(i))
+		}
+		g.selected[player] = selected
+	}
+	for _, c := range s.Commands {
+		if err := g.Issue(c); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSave, err)
+		}
+	}
+	g.spawnEntities()
+	return g, nil
+}
+
+// WriteSave writes a save in a format.
+func WriteSave(w io.Writer, s *Save, format Format) error {
+	s.Version = saveVersion
+	var err error
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(s)
+	case Binary:
+		if _, err = w.Write(binaryMagic); err == nil {
+			err = gob.NewEncoder(w).Encode(s)
+		}
+	default:
+		err = fmt.Errorf("unknown format %d", format)
+	}
+	if err != nil {
+		return fmt.Errorf("writing save: %w", err)
+	}
+	return nil
+}
+
+// ReadSave reads a save written by WriteSave in either format, and migrates it to the current version.
+func ReadSave(r io.Reader) (*Save, error) {
+	buffered := bufio.NewReader(r)
+	s := &Save{}
+	start, err := buffered.Peek(len(binaryMagic))
+	if bytes.Equal(start, binaryMagic) {
+		if _, err = buffered.Discard(len(binaryMagic)); err == nil {
+			err = gob.NewDecoder(buffered).Decode(s)
+		}
+	} else {
+		err = json.NewDecoder(buffered).Decode(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading save: %w", err)
+	}
+	if err := migrate(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate upgrades a save to the current version.
+func migrate(s *Save) error {
+	for s.Version < saveVersion {
+		migration, exists := migrations[s.Version]
+		if !exists {
+			return fmt.Errorf("%w %d", ErrSaveVersion, s.Version)
+		}
+		if err := migration(s); err != nil {
+			return fmt.Errorf("migrating save from version %d: %w", s.Version, err)
+		}
+		s.Version++
+	}
+	if s.Version != saveVersion {
+		return fmt.Errorf("%w %d", ErrSaveVersion, s.Version)
+	}
+	return nil
+}
+
+// ReadSaveFile reads a save from a file.
+func ReadSaveFile(name string) (*Save, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSave(f)
+}
+
+// WriteSaveFile writes a save to a file in a format.
+func WriteSaveFile(name string, s *Save, format Format) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := WriteSave(f, s, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
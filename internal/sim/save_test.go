@@ -0,0 +1,149 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"game-engine/rts/internal/formation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/worker"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// startGame starts a game where the workers are sent off in a formation and back to work on other resources,
+// with commands still to come.
+func startGame(t *testing.T, config Config) *Game {
+	game, err := NewGame(config)
+	assert.NoError(t, err)
+	target := game.Stockpile.Position.Add(mgl32.Vec3{1, 0, 1})
+	for _, c := range []Command{
+		{Tick: 10, Type: Select, Units: []int{0, 1, 2}},
+		{Tick: 10, Type: Move, Target: target, Formation: formation.Wedge},
+		{Tick: 60, Type: Gather, Kind: resource.Food},
+		{Tick: 120, Type: Build, Target: target},
+		{Tick: 250, Type: Move, Target: target.Add(mgl32.Vec3{-1, 0, 0})},
+	} {
+		assert.NoError(t, game.Issue(c))
+	}
+	game.Run(200)
+	return game
+}
+
+func TestSaveAndContinue(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config Config
+		format Format
+	}{
+		{desc: "json", config: Config{Seed: 2}, format: JSON},
+		{desc: "binary", config: Config{Seed: 2}, format: Binary},
+		{desc: "separation", config: Config{Seed: 4, Avoidance: "separation"}, format: Binary},
+		{desc: "navmesh on hills", config: Config{Seed: 5, Terrain: "hills", NavMesh: true}, format: JSON},
+		{desc: "two players", config: Config{Seed: 6, Players: 2, Workers: 3}, format: Binary},
+		{desc: "behaviour trees", config: Config{Seed: 3, Brain: "bt"}, format: JSON},
+		{desc: "planners", config: Config{Seed: 3, Brain: "goap"}, format: Binary},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			game := startGame(t, tc.config)
+			// Something random happened before saving
+			game.Rand.Intn(6)
+			save := game.Save()
+
+			var buf bytes.Buffer
+			assert.NoError(t, WriteSave(&buf, save, tc.format))
+			read, err := ReadSave(&buf)
+			assert.NoError(t, err)
+			assert.Equal(t, save.Workers, read.Workers)
+			loaded, err := LoadGame(read)
+			assert.NoError(t, err)
+			assert.Equal(t, game.Tick(), loaded.Tick())
+			assert.Equal(t, game.Rand.State(), loaded.Rand.State())
+			assert.Equal(t, game.Hash(), loaded.Hash())
+			assert.Equal(t, game.Entities.Len(), loaded.Entities.Len())
+
+			// Loading plays out exactly as if the game had never been saved
+			for i := 0; i < 600; i++ {
+				game.Run(1)
+				loaded.Run(1)
+				if !assert.Equal(t, game.Hash(), loaded.Hash(), "at tick %d", game.Tick()) {
+					break
+				}
+			}
+			assert.Equal(t, game.Stats(), loaded.Stats())
+		})
+	}
+}
+
+func TestSaveFormats(t *testing.T) {
+	game := startGame(t, Config{Seed: 2})
+	save := game.Save()
+	var asJSON, asBinary bytes.Buffer
+	assert.NoError(t, WriteSave(&asJSON, save, JSON))
+	assert.NoError(t, WriteSave(&asBinary, save, Binary))
+	assert.True(t, strings.HasPrefix(asJSON.String(), "{"))
+	assert.Less(t, asBinary.Len(), asJSON.Len())
+
+	fromJSON, err := ReadSave(&asJSON)
+	assert.NoError(t, err)
+	fromBinary, err := ReadSave(&asBinary)
+	assert.NoError(t, err)
+	assert.Equal(t, fromJSON, fromBinary)
+}
+
+func TestSaveMigration(t *testing.T) {
+	game := startGame(t, Config{Seed: 2})
+	save := game.Save()
+	var buf bytes.Buffer
+	assert.NoError(t, WriteSave(&buf, save, JSON))
+	old := strings.Replace(buf.String(), `"version": 1`, `"version": 0`, 1)
+
+	_, err := ReadSave(strings.NewReader(old))
+	assert.ErrorIs(t, err, ErrSaveVersion, "no migration from version 0")
+
+	// Version 0 had no commands pending
+	migrations[0] = func(s *Save) error {
+		s.Commands = nil
+		return nil
+	}
+	defer delete(migrations, 0)
+	migrated, err := ReadSave(strings.NewReader(old))
+	assert.NoError(t, err)
+	assert.Equal(t, saveVersion, migrated.Version)
+	assert.Empty(t, migrated.Commands)
+	assert.NotEmpty(t, save.Commands)
+	_, err = LoadGame(migrated)
+	assert.NoError(t, err)
+}
+
+func TestSaveErrors(t *testing.T) {
+	_, err := ReadSave(strings.NewReader(`{"version": 2}`))
+	assert.ErrorIs(t, err, ErrSaveVersion)
+	_, err = ReadSave(strings.NewReader(`RTSSAVE garbage`))
+	assert.Error(t, err)
+
+	game := startGame(t, Config{Seed: 2})
+	testCases := []struct {
+		desc   string
+		change func(s *Save)
+	}{
+		{desc: "removed node that doesn't exist", change: func(s *Save) { s.Removed = append(s.Removed, 100000) }},
+		{desc: "node out of place", change: func(s *Save) { s.Nodes[0], s.Nodes[1] = s.Nodes[1], s.Nodes[0] }},
+		{desc: "missing worker", change: func(s *Save) { s.Workers = s.Workers[1:] }},
+		{desc: "unknown state", change: func(s *Save) { s.Workers[0].FSM.Current = "dancing" }},
+		{desc: "worker with another brain", change: func(s *Save) { s.Workers[0].FSM, s.Workers[0].Plan = nil, &worker.PlanSnapshot{} }},
+		{desc: "claim of unknown worker", change: func(s *Save) { s.Reservations.Claims[0].Worker = 99 }},
+		{desc: "unknown unit selected", change: func(s *Save) { s.Selected = map[resource.Player][]int{0: {99}} }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			save := game.Save()
+			tc.change(save)
+			_, err := LoadGame(save)
+			assert.ErrorIs(t, err, ErrInvalidSave)
+		})
+	}
+}
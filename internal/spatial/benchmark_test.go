@@ -0,0 +1,122 @@
+package spatial
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The world in the benchmarks is 1000x1000, with about the same density of items as the trees in the game.
+const benchmarkWorldSize = 500.0
+
+var benchmarkSizes = []int{10000, 100000}
+
+// scanNearest is how the closest tree used to be found, by looking at every tree.
+func scanNearest(positions []mgl32.Vec2, from mgl32.Vec2) int {
+	closest, closestSqLen := -1, float32(0.0)
+	for i, p := range positions {
+		sqLen := p.Sub(from).LenSqr()
+		if closest < 0 || sqLen < closestSqLen {
+			closest, closestSqLen = i, sqLen
+		}
+	}
+	return closest
+}
+
+func benchmarkIndexes() []struct {
+	desc  string
+	index func() Index[int]
+} {
+	bounds := AABB{Min: mgl32.Vec2{-benchmarkWorldSize, -benchmarkWorldSize}, Max: mgl32.Vec2{benchmarkWorldSize, benchmarkWorldSize}}
+	return []struct {
+		desc  string
+		index func() Index[int]
+	}{
+		{desc: "grid", index: func() Index[int] { return NewGrid[int](10.0) }},
+		{desc: "quadtree", index: func() Index[int] { return NewQuadtree[int](bounds) }},
+	}
+}
+
+func BenchmarkNearest(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		rng := rand.New(rand.NewSource(1))
+		positions := randomPositions(rng, n, benchmarkWorldSize)
+		queries := randomPositions(rng, 1024, benchmarkWorldSize)
+
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scanNearest(positions, queries[i%len(queries)])
+			}
+		})
+		for _, tc := range benchmarkIndexes() {
+			index := tc.index()
+			for i, p := range positions {
+				index.Insert(i, p)
+			}
+			b.Run(fmt.Sprintf("%s/%d", tc.desc, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					index.Nearest(queries[i%len(queries)], 1, nil)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkInRadius(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		rng := rand.New(rand.NewSource(1))
+		positions := randomPositions(rng, n, benchmarkWorldSize)
+		queries := randomPositions(rng, 1024, benchmarkWorldSize)
+
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				found := []int{}
+				for j, p := range positions {
+					if p.Sub(queries[i%len(queries)]).LenSqr() <= 20.0*20.0 {
+						found = append(found, j)
+					}
+				}
+			}
+		})
+		for _, tc := range benchmarkIndexes() {
+			index := tc.index()
+			for i, p := range positions {
+				index.Insert(i, p)
+			}
+			b.Run(fmt.Sprintf("%s/%d", tc.desc, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					index.InRadius(queries[i%len(queries)], 20.0)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkMove moves every item a bit each iteration, like units walking back and forth.
+func BenchmarkMove(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		for _, tc := range benchmarkIndexes() {
+			rng := rand.New(rand.NewSource(1))
+			positions := randomPositions(rng, n, benchmarkWorldSize)
+			steps := randomPositions(rng, n, 0.5)
+			index := tc.index()
+			for i, p := range positions {
+				index.Insert(i, p)
+			}
+			b.Run(fmt.Sprintf("%s/%d", tc.desc, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for j := range positions {
+						if i%2 == 0 {
+							positions[j] = positions[j].Add(steps[j])
+						} else {
+							positions[j] = positions[j].Sub(steps[j])
+						}
+						index.Move(j, positions[j])
+					}
+				}
+			})
+		}
+	}
+}
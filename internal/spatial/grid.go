@@ -0,0 +1,234 @@
+package spatial
+
+import (
+	"math"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type cell struct {
+	x, y int32
+}
+
+// Grid is a spatial hash, it splits the plane in square cells and keeps the items in each cell. It works
+// best when items are spread evenly and cells are about as big as the usual query radius.
+type Grid[T comparable] struct {
+	cellSize float32
+	// fixedCellSize is cellSize in fixed point, to compare with distances
+	fixedCellSize fixed.Scalar
+	cells         map[cell][]*entry[T]
+	entries       map[T]*entry[T]
+	nextSeq       uint64
+	// min and max are the corners of the cells that have held items, they are not shrunk on removal
+	min, max cell
+}
+
+var _ Index[int] = (*Grid[int])(nil)
+
+// NewGrid creates an empty grid with cells of the given size.
+func NewGrid[T comparable](cellSize float32) *Grid[T] {
+	return &Grid[T]{
+		cellSize:      cellSize,
+		fixedCellSize: fixed.FromFloat32(cellSize),
+		cells:         map[cell][]*entry[T]{},
+		entries:       map[T]*entry[T]{},
+	}
+}
+
+func (g *Grid[T]) cellOf(p mgl32.Vec2) cell {
+	return cell{
+		x: int32(math.Floor(float64(p.X() / g.cellSize))),
+		y: int32(math.Floor(float64(p.Y() / g.cellSize))),
+	}
+}
+
+func (g *Grid[T]) Insert(item T, position mgl32.Vec2) {
+	if _, exists := g.entries[item]; exists {
+		g.Move(item, position)
+		return
+	}
+	e := newEntry(item, position, g.nextSeq)
+	g.nextSeq++
+	g.entries[item] = e
+	g.add(g.cellOf(position), e)
+}
+
+func (g *Grid[T]) Move(item T, position mgl32.Vec2) {
+	e, exists := g.entries[item]
+	if !exists {
+		g.Insert(item, position)
+		return
+	}
+	from, to := g.cellOf(e.position), g.cellOf(position)
+	e.moveTo(position)
+	if from != to {
+		g.remove(from, e)
+		g.add(to, e)
+	}
+}
+
+func (g *Grid[T]) Remove(item T) {
+	e, exists := g.entries[item]
+	if !exists {
+		return
+	}
+	delete(g.entries, item)
+	g.remove(g.cellOf(e.position), e)
+}
+
+func (g *Grid[T]) Position(item T) (mgl32.Vec2, bool) {
+	e, exists := g.entries[item]
+	if !exists {
+		return mgl32.Vec2{}, false
+	}
+	return e.position, true
+}
+
+func (g *Grid[T]) Len() int {
+	return len(g.entries)
+}
+
+func (g *Grid[T]) add(c cell, e *entry[T]) {
+	if len(g.entries) == 1 {
+		g.min, g.max = c, c
+	}
+	g.min = cell{x: min32(g.min.x, c.x), y: min32(g.min.y, c.y)}
+	g.max = cell{x: max32(g.max.x, c.x), y: max32(g.max.y, c.y)}
+	g.cells[c] = append(g.cells[c], e)
+}
+
+func (g *Grid[T]) remove(c cell, e *entry[T]) {
+	entries := g.cells[c]
+	for i, other := range entries {
+		if other == e {
+			last := len(entries) - 1
+			entries[i] = entries[last]
+			entries[last] = nil
+			entries = entries[:last]
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(g.cells, c)
+		return
+	}
+	g.cells[c] = entries
+}
+
+// Nearest searches rings of cells around the cell of from, until the closest possible item in the next ring
+// is further away than the k closest items found.
+func (g *Grid[T]) Nearest(from mgl32.Vec2, k int, filter func(T) bool) []T {
+	if k <= 0 || len(g.entries) == 0 {
+		return nil
+	}
+	found := newCandidates(from, k, filter)
+	c := g.cellOf(from)
+	// Skip the empty rings between from and the cells that have items
+	start := max32(max32(g.min.x-c.x, c.x-g.max.x), max32(g.min.y-c.y, c.y-g.max.y))
+	for r := max32(start, 0); ; r++ {
+		if r > 0 && c.x-(r-1) <= g.min.x && c.x+(r-1) >= g.max.x && c.y-(r-1) <= g.min.y && c.y+(r-1) >= g.max.y {
+			// The previous ring covered all cells
+			break
+		}
+		if found.full() {
+			closest := fixed.FromInt(int(r - 1)).Mul(g.fixedCellSize)
+			if closest > 0 && closest.Mul(closest) > found.worst() {
+				break
+			}
+		}
+		side := int(2*r + 1)
+		if side*side > 2*len(g.cells) {
+			// Looking at every cell in the ring is slower than looking at every item
+			return g.nearestByScan(from, k, filter)
+		}
+		g.visitRing(c, r, found)
+	}
+	return found.items()
+}
+
+func (g *Grid[T]) visitRing(c cell, r int32, found *candidates[T]) {
+	visit := func(x, y int32) {
+		for _, e := range g.cells[cell{x: x, y: y}] {
+			found.offer(e)
+		}
+	}
+	if r == 0 {
+		visit(c.x, c.y)
+		return
+	}
+	for x := c.x - r; x <= c.x+r; x++ {
+		visit(x, c.y-r)
+		visit(x, c.y+r)
+	}
+	for y := c.y - r + 1; y <= c.y+r-1; y++ {
+		visit(c.x-r, y)
+		visit(c.x+r, y)
+	}
+}
+
+func (g *Grid[T]) nearestByScan(from mgl32.Vec2, k int, filter func(T) bool) []T {
+	found := newCandidates(from, k, filter)
+	for _, e := range g.entries {
+		found.offer(e)
+	}
+	return found.items()
+}
+
+func (g *Grid[T]) InRadius(center mgl32.Vec2, radius float32) []T {
+	box := AABB{Min: center.Sub(mgl32.Vec2{radius, radius}), Max: center.Add(mgl32.Vec2{radius, radius})}
+	at, r := fixed.FromVec2(center), fixed.FromFloat32(radius)
+	return inInsertionOrder(g.inBox(box, func(e *entry[T]) bool {
+		return e.within(at, r)
+	}))
+}
+
+func (g *Grid[T]) InBox(box AABB) []T {
+	return inInsertionOrder(g.inBox(box, nil))
+}
+
+func (g *Grid[T]) inBox(box AABB, keep func(e *entry[T]) bool) []*entry[T] {
+	found := []*entry[T]{}
+	lo, hi := g.cellOf(box.Min), g.cellOf(box.Max)
+	lo = cell{x: max32(lo.x, g.min.x), y: max32(lo.y, g.min.y)}
+	hi = cell{x: min32(hi.x, g.max.x), y: min32(hi.y, g.max.y)}
+	if int(hi.x-lo.x+1)*int(hi.y-lo.y+1) > len(g.cells) {
+		// The box covers more cells than have items
+		for c, entries := range g.cells {
+			if c.x >= lo.x && c.x <= hi.x && c.y >= lo.y && c.y <= hi.y {
+				found = appendInBox(found, entries, box, keep)
+			}
+		}
+		return found
+	}
+	for x := lo.x; x <= hi.x; x++ {
+		for y := lo.y; y <= hi.y; y++ {
+			found = appendInBox(found, g.cells[cell{x: x, y: y}], box, keep)
+		}
+	}
+	return found
+}
+
+func appendInBox[T comparable](found, entries []*entry[T], box AABB, keep func(e *entry[T]) bool) []*entry[T] {
+	for _, e := range entries {
+		if box.Contains(e.position) && (keep == nil || keep(e)) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,274 @@
+package spatial
+
+import (
+	"container/heap"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// quadCapacity is how many items a node holds before it is split.
+	quadCapacity = 8
+	// quadMaxDepth limits splitting when many items are at the same position.
+	quadMaxDepth = 16
+)
+
+type quadNode[T comparable] struct {
+	bounds   AABB
+	depth    int
+	parent   *quadNode[T]
+	children *[4]*quadNode[T]
+	entries  []*entry[T]
+	// count is the number of entries in the node and all its descendants
+	count int
+}
+
+// Quadtree splits its bounds in four quadrants whenever too many items are in one of them. It adapts to
+// items clustering together, unlike a Grid. Items outside the bounds are kept in the root and always looked
+// at, so the bounds should cover the world.
+type Quadtree[T comparable] struct {
+	root    *quadNode[T]
+	entries map[T]*entry[T]
+	nextSeq uint64
+}
+
+var _ Index[int] = (*Quadtree[int])(nil)
+
+// NewQuadtree creates an empty quadtree covering bounds.
+func NewQuadtree[T comparable](bounds AABB) *Quadtree[T] {
+	return &Quadtree[T]{
+		root:    &quadNode[T]{bounds: bounds},
+		entries: map[T]*entry[T]{},
+	}
+}
+
+func (q *Quadtree[T]) Insert(item T, position mgl32.Vec2) {
+	if _, exists := q.entries[item]; exists {
+		q.Move(item, position)
+		return
+	}
+	e := newEntry(item, position, q.nextSeq)
+	q.nextSeq++
+	q.entries[item] = e
+	q.root.insert(e)
+}
+
+func (q *Quadtree[T]) Move(item T, position mgl32.Vec2) {
+	e, exists := q.entries[item]
+	if !exists {
+		q.Insert(item, position)
+		return
+	}
+	n := e.node
+	if n.children == nil && n.bounds.Contains(position) {
+		// Still in the same leaf
+		e.moveTo(position)
+		return
+	}
+	n.remove(e)
+	e.moveTo(position)
+	q.root.insert(e)
+}
+
+func (q *Quadtree[T]) Remove(item T) {
+	e, exists := q.entries[item]
+	if !exists {
+		return
+	}
+	delete(q.entries, item)
+	e.node.remove(e)
+}
+
+func (q *Quadtree[T]) Position(item T) (mgl32.Vec2, bool) {
+	e, exists := q.entries[item]
+	if !exists {
+		return mgl32.Vec2{}, false
+	}
+	return e.position, true
+}
+
+func (q *Quadtree[T]) Len() int {
+	return len(q.entries)
+}
+
+func (n *quadNode[T]) insert(e *entry[T]) {
+	n.count++
+	if n.children != nil {
+		if child := n.childFor(e.position); child != nil {
+			child.insert(e)
+			return
+		}
+	}
+	e.node = n
+	n.entries = append(n.entries, e)
+	if n.children == nil && len(n.entries) > quadCapacity && n.depth < quadMaxDepth {
+		n.split()
+	}
+}
+
+// childFor returns the child whose quadrant the position is in, or nil if it is outside the node.
+func (n *quadNode[T]) childFor(p mgl32.Vec2) *quadNode[T] {
+	if !n.bounds.Contains(p) {
+		return nil
+	}
+	center := n.bounds.Min.Add(n.bounds.Max).Mul(0.5)
+	i := 0
+	if p.X() >= center.X() {
+		i |= 1
+	}
+	if p.Y() >= center.Y() {
+		i |= 2
+	}
+	return n.children[i]
+}
+
+func (n *quadNode[T]) split() {
+	lo, hi := n.bounds.Min, n.bounds.Max
+	center := lo.Add(hi).Mul(0.5)
+	n.children = &[4]*quadNode[T]{
+		{bounds: AABB{Min: lo, Max: center}},
+		{bounds: AABB{Min: mgl32.Vec2{center.X(), lo.Y()}, Max: mgl32.Vec2{hi.X(), center.Y()}}},
+		{bounds: AABB{Min: mgl32.Vec2{lo.X(), center.Y()}, Max: mgl32.Vec2{center.X(), hi.Y()}}},
+		{bounds: AABB{Min: center, Max: hi}},
+	}
+	for _, child := range n.children {
+		child.depth = n.depth + 1
+		child.parent = n
+	}
+	entries := n.entries
+	n.entries = nil
+	n.count -= len(entries)
+	for _, e := range entries {
+		n.insert(e)
+	}
+}
+
+func (n *quadNode[T]) remove(e *entry[T]) {
+	for i, other := range n.entries {
+		if other == e {
+			last := len(n.entries) - 1
+			n.entries[i] = n.entries[last]
+			n.entries[last] = nil
+			n.entries = n.entries[:last]
+			break
+		}
+	}
+	e.node = nil
+	var merge *quadNode[T]
+	for p := n; p != nil; p = p.parent {
+		p.count--
+		if p.children != nil && p.count <= quadCapacity {
+			merge = p
+		}
+	}
+	if merge != nil {
+		merge.merge()
+	}
+}
+
+// merge moves all entries of the descendants into the node and removes its children.
+func (n *quadNode[T]) merge() {
+	var collect func(c *quadNode[T])
+	collect = func(c *quadNode[T]) {
+		for _, e := range c.entries {
+			e.node = n
+			n.entries = append(n.entries, e)
+		}
+		if c.children != nil {
+			for _, child := range c.children {
+				collect(child)
+			}
+		}
+	}
+	for _, child := range n.children {
+		collect(child)
+	}
+	n.children = nil
+}
+
+// Nearest visits nodes closest first, until the next node is further away than the k closest items found.
+func (q *Quadtree[T]) Nearest(from mgl32.Vec2, k int, filter func(T) bool) []T {
+	if k <= 0 || len(q.entries) == 0 {
+		return nil
+	}
+	found := newCandidates(from, k, filter)
+	queue := &nodeQueue[T]{{node: q.root}}
+	for queue.Len() > 0 {
+		next := heap.Pop(queue).(queued[T])
+		if found.full() && next.dist > found.worst() {
+			break
+		}
+		for _, e := range next.node.entries {
+			found.offer(e)
+		}
+		if next.node.children != nil {
+			for _, child := range next.node.children {
+				if child.count == 0 {
+					continue
+				}
+				dist := child.bounds.fixedDistanceSq(found.from)
+				if !found.full() || dist <= found.worst() {
+					heap.Push(queue, queued[T]{node: child, dist: dist})
+				}
+			}
+		}
+	}
+	return found.items()
+}
+
+func (q *Quadtree[T]) InRadius(center mgl32.Vec2, radius float32) []T {
+	box := AABB{Min: center.Sub(mgl32.Vec2{radius, radius}), Max: center.Add(mgl32.Vec2{radius, radius})}
+	found := []*entry[T]{}
+	at, r := fixed.FromVec2(center), fixed.FromFloat32(radius)
+	q.root.inBox(box, func(e *entry[T]) {
+		if e.within(at, r) {
+			found = append(found, e)
+		}
+	})
+	return inInsertionOrder(found)
+}
+
+func (q *Quadtree[T]) InBox(box AABB) []T {
+	found := []*entry[T]{}
+	q.root.inBox(box, func(e *entry[T]) { found = append(found, e) })
+	return inInsertionOrder(found)
+}
+
+// inBox calls visit for the entries inside the box. The node itself is always searched, so entries outside
+// the bounds of the root are found too.
+func (n *quadNode[T]) inBox(box AABB, visit func(e *entry[T])) {
+	for _, e := range n.entries {
+		if box.Contains(e.position) {
+			visit(e)
+		}
+	}
+	if n.children == nil {
+		return
+	}
+	for _, child := range n.children {
+		if child.count > 0 && child.bounds.Intersects(box) {
+			child.inBox(box, visit)
+		}
+	}
+}
+
+type queued[T comparable] struct {
+	node *quadNode[T]
+	dist fixed.Scalar
+}
+
+// nodeQueue is a min-heap of nodes by distance.
+type nodeQueue[T comparable] []queued[T]
+
+func (q nodeQueue[T]) Len() int           { return len(q) }
+func (q nodeQueue[T]) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q nodeQueue[T]) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue[T]) Push(x any)        { *q = append(*q, x.(queued[T])) }
+func (q *nodeQueue[T]) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
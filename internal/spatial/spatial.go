@@ -0,0 +1,185 @@
+// Package spatial indexes positions on the ground plane, so units and resources near a position can be
+// found without looking at all of them.
+package spatial
+
+import (
+	"sort"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Index finds items by their position on the ground plane. Results are deterministic: distances are measured
+// in fixed point, so every machine finds the same items, and ties in distance are broken by the order items
+// were inserted in.
+type Index[T comparable] interface {
+	// Insert adds an item at a position, or moves it if it has already been added.
+	Insert(item T, position mgl32.Vec2)
+	// Move changes the position of an item, adding it if it has not been added yet.
+	Move(item T, position mgl32.Vec2)
+	// Remove removes an item, it does nothing if the item has not been added.
+	Remove(item T)
+	// Position returns the position of an item and if it has been added.
+	Position(item T) (mgl32.Vec2, bool)
+	// Len returns the number of items.
+	Len() int
+	// Nearest returns up to k items closest to a position, closest first. Only items for which filter
+	// returns true are considered, all items are if filter is nil.
+	Nearest(from mgl32.Vec2, k int, filter func(T) bool) []T
+	// InRadius returns the items within radius of a position, in insertion order.
+	InRadius(center mgl32.Vec2, radius float32) []T
+	// InBox returns the items inside a box, in insertion order.
+	InBox(box AABB) []T
+}
+
+// XZ returns the position of a point on the ground plane, dropping its height.
+func XZ(v mgl32.Vec3) mgl32.Vec2 {
+	return mgl32.Vec2{v.X(), v.Z()}
+}
+
+// AABB is an axis aligned box, edges included.
+type AABB struct {
+	Min, Max mgl32.Vec2
+}
+
+// Box returns the box with corners a and b.
+func Box(a, b mgl32.Vec2) AABB {
+	return AABB{
+		Min: mgl32.Vec2{min(a.X(), b.X()), min(a.Y(), b.Y())},
+		Max: mgl32.Vec2{max(a.X(), b.X()), max(a.Y(), b.Y())},
+	}
+}
+
+// Contains reports if a point is inside the box.
+func (b AABB) Contains(p mgl32.Vec2) bool {
+	return p.X() >= b.Min.X() && p.X() <= b.Max.X() && p.Y() >= b.Min.Y() && p.Y() <= b.Max.Y()
+}
+
+// Intersects reports if two boxes overlap.
+func (b AABB) Intersects(o AABB) bool {
+	return b.Min.X() <= o.Max.X() && o.Min.X() <= b.Max.X() && b.Min.Y() <= o.Max.Y() && o.Min.Y() <= b.Max.Y()
+}
+
+// DistanceSq returns the squared distance from a point to the closest point in the box.
+func (b AABB) DistanceSq(p mgl32.Vec2) float32 {
+	dx := max(max(b.Min.X()-p.X(), 0), p.X()-b.Max.X())
+	dy := max(max(b.Min.Y()-p.Y(), 0), p.Y()-b.Max.Y())
+	return dx*dx + dy*dy
+}
+
+// fixedDistanceSq is DistanceSq in fixed point.
+func (b AABB) fixedDistanceSq(p fixed.Vec2) fixed.Scalar {
+	lo, hi := fixed.FromVec2(b.Min), fixed.FromVec2(b.Max)
+	d := fixed.Vec2{}
+	for i := range d {
+		if p[i] < lo[i] {
+			d[i] = lo[i] - p[i]
+		} else if p[i] > hi[i] {
+			d[i] = p[i] - hi[i]
+		}
+	}
+	return d.LenSqr()
+}
+
+type entry[T comparable] struct {
+	item     T
+	position mgl32.Vec2
+	// at is the position in fixed point, which distances to the entry are measured from
+	at fixed.Vec2
+	// seq is the order the item was inserted in, used to break ties
+	seq uint64
+	// node is the quadtree node holding the entry
+	node *quadNode[T]
+}
+
+func newEntry[T comparable](item T, position mgl32.Vec2, seq uint64) *entry[T] {
+	return &entry[T]{item: item, position: position, at: fixed.FromVec2(position), seq: seq}
+}
+
+func (e *entry[T]) moveTo(position mgl32.Vec2) {
+	e.position, e.at = position, fixed.FromVec2(position)
+}
+
+// within reports if the entry is within radius of a position, in fixed point.
+func (e *entry[T]) within(center fixed.Vec2, radius fixed.Scalar) bool {
+	return e.at.Sub(center).LenSqr() <= radius.Mul(radius)
+}
+
+// candidates keeps the k closest entries found so far, closest first.
+type candidates[T comparable] struct {
+	from    fixed.Vec2
+	k       int
+	filter  func(T) bool
+	entries []*entry[T]
+	dists   []fixed.Scalar
+}
+
+func newCandidates[T comparable](from mgl32.Vec2, k int, filter func(T) bool) *candidates[T] {
+	return &candidates[T]{from: fixed.FromVec2(from), k: k, filter: filter}
+}
+
+func (c *candidates[T]) offer(e *entry[T]) {
+	d := e.at.Sub(c.from).LenSqr()
+	if c.full() && !c.closer(d, e.seq, len(c.entries)-1) {
+		return
+	}
+	if c.filter != nil && !c.filter(e.item) {
+		return
+	}
+	i := sort.Search(len(c.entries), func(i int) bool { return c.closer(d, e.seq, i) })
+	if c.full() {
+		c.entries, c.dists = c.entries[:c.k-1], c.dists[:c.k-1]
+	}
+	c.entries = append(c.entries, nil)
+	c.dists = append(c.dists, 0)
+	copy(c.entries[i+1:], c.entries[i:])
+	copy(c.dists[i+1:], c.dists[i:])
+	c.entries[i], c.dists[i] = e, d
+}
+
+// closer reports if an entry at squared distance d with sequence number seq is closer than candidate i.
+func (c *candidates[T]) closer(d fixed.Scalar, seq uint64, i int) bool {
+	return d < c.dists[i] || (d == c.dists[i] && seq < c.entries[i].seq)
+}
+
+func (c *candidates[T]) full() bool {
+	return len(c.entries) >= c.k
+}
+
+// worst returns the squared distance of the furthest candidate.
+func (c *candidates[T]) worst() fixed.Scalar {
+	return c.dists[len(c.dists)-1]
+}
+
+func (c *candidates[T]) items() []T {
+	items := make([]T, len(c.entries))
+	for i, e := range c.entries {
+		items[i] = e.item
+	}
+	return items
+}
+
+// inInsertionOrder returns the items of the entries, sorted by when they were inserted.
+func inInsertionOrder[T comparable](entries []*entry[T]) []T {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	items := make([]T, len(entries))
+	for i, e := range entries {
+		items[i] = e.item
+	}
+	return items
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
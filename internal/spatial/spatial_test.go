@@ -0,0 +1,166 @@
+package spatial
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+var worldBounds = AABB{Min: mgl32.Vec2{-100.0, -100.0}, Max: mgl32.Vec2{100.0, 100.0}}
+
+func indexes() []struct {
+	desc  string
+	index func() Index[int]
+} {
+	return []struct {
+		desc  string
+		index func() Index[int]
+	}{
+		{desc: "grid", index: func() Index[int] { return NewGrid[int](4.0) }},
+		{desc: "quadtree", index: func() Index[int] { return NewQuadtree[int](worldBounds) }},
+	}
+}
+
+func randomPositions(rng *rand.Rand, n int, size float32) []mgl32.Vec2 {
+	positions := make([]mgl32.Vec2, n)
+	for i := range positions {
+		positions[i] = mgl32.Vec2{(rng.Float32()*2 - 1) * size, (rng.Float32()*2 - 1) * size}
+	}
+	return positions
+}
+
+// nearestByScan is what the indexes are checked against.
+func nearestByScan(positions map[int]mgl32.Vec2, from mgl32.Vec2, k int, filter func(int) bool) []int {
+	items := []int{}
+	for item := range positions {
+		if filter == nil || filter(item) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		di, dj := positions[items[i]].Sub(from).LenSqr(), positions[items[j]].Sub(from).LenSqr()
+		return di < dj || (di == dj && items[i] < items[j])
+	})
+	if len(items) > k {
+		items = items[:k]
+	}
+	return items
+}
+
+func TestNearest(t *testing.T) {
+	for _, tc := range indexes() {
+		t.Run(tc.desc, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			index := tc.index()
+			positions := map[int]mgl32.Vec2{}
+			for i, p := range randomPositions(rng, 500, 50.0) {
+				index.Insert(i, p)
+				positions[i] = p
+			}
+			even := func(i int) bool { return i%2 == 0 }
+
+			for _, from := range randomPositions(rng, 50, 120.0) {
+				assert.Equal(t, nearestByScan(positions, from, 1, nil), index.Nearest(from, 1, nil))
+				assert.Equal(t, nearestByScan(positions, from, 10, nil), index.Nearest(from, 10, nil))
+				assert.Equal(t, nearestByScan(positions, from, 5, even), index.Nearest(from, 5, even))
+			}
+			assert.Empty(t, index.Nearest(mgl32.Vec2{}, 3, func(int) bool { return false }))
+			assert.Len(t, index.Nearest(mgl32.Vec2{}, 1000, nil), 500)
+		})
+	}
+}
+
+func TestNearestBreaksTiesByInsertionOrder(t *testing.T) {
+	for _, tc := range indexes() {
+		t.Run(tc.desc, func(t *testing.T) {
+			index := tc.index()
+			index.Insert(3, mgl32.Vec2{1.0, 0.0})
+			index.Insert(1, mgl32.Vec2{-1.0, 0.0})
+			index.Insert(2, mgl32.Vec2{0.0, 1.0})
+
+			assert.Equal(t, []int{3, 1}, index.Nearest(mgl32.Vec2{}, 2, nil))
+		})
+	}
+}
+
+func TestMoveAndRemove(t *testing.T) {
+	for _, tc := range indexes() {
+		t.Run(tc.desc, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(2))
+			index := tc.index()
+			positions := map[int]mgl32.Vec2{}
+			for i, p := range randomPositions(rng, 300, 80.0) {
+				index.Insert(i, p)
+				positions[i] = p
+			}
+			for step := 0; step < 2000; step++ {
+				item := rng.Intn(300)
+				switch rng.Intn(3) {
+				case 0:
+					index.Remove(item)
+					delete(positions, item)
+				default:
+					p := randomPositions(rng, 1, 80.0)[0]
+					index.Move(item, p)
+					positions[item] = p
+				}
+			}
+
+			assert.Equal(t, len(positions), index.Len())
+			for item, p := range positions {
+				actual, ok := index.Position(item)
+				assert.True(t, ok)
+				assert.Equal(t, p, actual)
+			}
+			for _, from := range randomPositions(rng, 20, 80.0) {
+				assert.Equal(t, nearestByScan(positions, from, 8, nil), index.Nearest(from, 8, nil))
+			}
+		})
+	}
+}
+
+func TestRangeQueries(t *testing.T) {
+	for _, tc := range indexes() {
+		t.Run(tc.desc, func(t *testing.T) {
+			index := tc.index()
+			index.Insert(0, mgl32.Vec2{0.0, 0.0})
+			index.Insert(1, mgl32.Vec2{3.0, 4.0})
+			index.Insert(2, mgl32.Vec2{-2.0, 1.0})
+			index.Insert(3, mgl32.Vec2{10.0, 10.0})
+			index.Insert(4, mgl32.Vec2{500.0, 0.0})
+
+			assert.Equal(t, []int{0, 1, 2}, index.InRadius(mgl32.Vec2{}, 5.0), "edges are included")
+			assert.Equal(t, []int{0, 2}, index.InRadius(mgl32.Vec2{}, 4.9))
+			assert.Equal(t, []int{1, 3}, index.InBox(Box(mgl32.Vec2{10.0, 10.0}, mgl32.Vec2{1.0, 2.0})))
+			assert.Equal(t, []int{4}, index.InBox(AABB{Min: mgl32.Vec2{400.0, -1.0}, Max: mgl32.Vec2{600.0, 1.0}}), "outside the world")
+			assert.Empty(t, index.InBox(AABB{Min: mgl32.Vec2{20.0, 20.0}, Max: mgl32.Vec2{30.0, 30.0}}))
+
+			index.Remove(1)
+			index.Move(3, mgl32.Vec2{1.0, 1.0})
+			assert.Equal(t, []int{0, 2, 3}, index.InRadius(mgl32.Vec2{}, 5.0))
+		})
+	}
+}
+
+func TestAABB(t *testing.T) {
+	box := Box(mgl32.Vec2{2.0, 2.0}, mgl32.Vec2{0.0, 0.0})
+
+	assert.Equal(t, AABB{Min: mgl32.Vec2{0.0, 0.0}, Max: mgl32.Vec2{2.0, 2.0}}, box)
+	assert.True(t, box.Contains(mgl32.Vec2{2.0, 1.0}))
+	assert.False(t, box.Contains(mgl32.Vec2{2.1, 1.0}))
+	assert.True(t, box.Intersects(AABB{Min: mgl32.Vec2{1.0, 1.0}, Max: mgl32.Vec2{3.0, 3.0}}))
+	assert.False(t, box.Intersects(AABB{Min: mgl32.Vec2{3.0, 0.0}, Max: mgl32.Vec2{4.0, 1.0}}))
+	assert.Equal(t, float32(0.0), box.DistanceSq(mgl32.Vec2{1.0, 1.0}))
+	assert.Equal(t, float32(25.0), box.DistanceSq(mgl32.Vec2{5.0, 6.0}))
+	assert.Equal(t, fixed.FromInt(25), box.fixedDistanceSq(fixed.Vec2{fixed.FromInt(5), fixed.FromInt(6)}))
+	assert.Equal(t, fixed.FromInt(4), box.fixedDistanceSq(fixed.Vec2{fixed.FromInt(-2), fixed.FromInt(1)}))
+}
+
+func TestXZ(t *testing.T) {
+	assert.Equal(t, mgl32.Vec2{1.0, 3.0}, XZ(mgl32.Vec3{1.0, 2.0, 3.0}))
+}
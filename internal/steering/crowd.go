@@ -0,0 +1,144 @@
+package steering
+
+import (
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/spatial"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Avoidance is how agents in a crowd keep out of each other's way.
+type Avoidance int
+
+const (
+	// SeparationAvoidance adds Separation to the steering of each agent, pushing agents apart once they get
+	// close. It is cheap, but agents still bump into each other in tight spots.
+	SeparationAvoidance Avoidance = iota
+	// ORCAAvoidance picks velocities that don't collide with ORCA, so agents go past each other smoothly.
+	ORCAAvoidance
+)
+
+var (
+	// defaultTimeHorizon is how far ahead, in seconds, ORCA avoids collisions.
+	defaultTimeHorizon = fixed.FromInt(2)
+	// separationRange is how far, in agent radii, Separation pushes agents apart.
+	separationRange = fixed.FromInt(3)
+)
+
+// Crowd moves agents while keeping them out of each other's way. Agents are only affected by other agents
+// within NeighbourRadius, which are found with a grid of where the crowd last saw each agent.
+type Crowd struct {
+	Avoidance       Avoidance
+	NeighbourRadius float32
+	// TimeHorizon is how far ahead, in seconds, ORCA avoids collisions.
+	TimeHorizon fixed.Scalar
+
+	agents []*Agent
+	index  *spatial.Grid[*Agent]
+}
+
+// NewCrowd creates an empty crowd where agents avoid others within neighbourRadius.
+func NewCrowd(neighbourRadius float32) *Crowd {
+	return &Crowd{
+		NeighbourRadius: neighbourRadius,
+		TimeHorizon:     defaultTimeHorizon,
+		index:           spatial.NewGrid[*Agent](neighbourRadius),
+	}
+}
+
+// Add adds an agent to the crowd.
+func (c *Crowd) Add(a *Agent) {
+	if _, exists := c.index.Position(a); exists {
+		return
+	}
+	c.agents = append(c.agents, a)
+	c.index.Insert(a, a.Position.Float())
+}
+
+// Remove removes an agent from the crowd.
+func (c *Crowd) Remove(a *Agent) {
+	if _, exists := c.index.Position(a); !exists {
+		return
+	}
+	c.index.Remove(a)
+	for i, other := range c.agents {
+		if other == a {
+			c.agents = append(c.agents[:i], c.agents[i+1:]...)
+			break
+		}
+	}
+}
+
+// Agents returns the agents of the crowd, in the order they were added.
+func (c *Crowd) Agents() []*Agent {
+	return c.agents
+}
+
+// Indexed returns where the crowd last saw each agent, in the order they were added. Agents moving by
+// themselves are only seen again when they are steered by the crowd.
+func (c *Crowd) Indexed() []mgl32.Vec2 {
+	positions := make([]mgl32.Vec2, len(c.agents))
+	for i, a := range c.agents {
+		positions[i], _ = c.index.Position(a)
+	}
+	return positions
+}
+
+// SetIndexed changes where the crowd last saw each agent, in the order they were added, to load a saved game.
+func (c *Crowd) SetIndexed(positions []mgl32.Vec2) {
+	for i, a := range c.agents {
+		if i < len(positions) {
+			c.index.Move(a, positions[i])
+		}
+	}
+}
+
+// Neighbours returns the other agents within NeighbourRadius of an agent, in the order they were added.
+func (c *Crowd) Neighbours(a *Agent) []*Agent {
+	neighbours := c.index.InRadius(a.Position.Float(), c.NeighbourRadius)
+	for i, n := range neighbours {
+		if n == a {
+			return append(neighbours[:i], neighbours[i+1:]...)
+		}
+	}
+	return neighbours
+}
+
+// Steer applies a steering acceleration to an agent while avoiding its neighbours, and moves it. Agents that
+// moved by themselves should have their Position updated before.
+func (c *Crowd) Steer(a *Agent, steering fixed.Vec2, dt fixed.Scalar) {
+	c.index.Move(a, a.Position.Float())
+	a.Velocity = c.velocity(a, steering, dt)
+	a.Position = a.Position.Add(a.Velocity.Mul(dt))
+	c.index.Move(a, a.Position.Float())
+}
+
+// Step moves all agents at once, with the steering returned by steer for each. The new velocities of all
+// agents are found before any of them move, so the result doesn't depend on the order of the agents.
+func (c *Crowd) Step(dt fixed.Scalar, steer func(a *Agent) fixed.Vec2) {
+	velocities := make([]fixed.Vec2, len(c.agents))
+	for _, a := range c.agents {
+		c.index.Move(a, a.Position.Float())
+	}
+	for i, a := range c.agents {
+		velocities[i] = c.velocity(a, steer(a), dt)
+	}
+	for i, a := range c.agents {
+		a.Velocity = velocities[i]
+		a.Position = a.Position.Add(a.Velocity.Mul(dt))
+		c.index.Move(a, a.Position.Float())
+	}
+}
+
+// velocity returns the velocity of an agent after steering and avoiding its neighbours.
+func (c *Crowd) velocity(a *Agent, steering fixed.Vec2, dt fixed.Scalar) fixed.Vec2 {
+	neighbours := c.Neighbours(a)
+	if c.Avoidance == SeparationAvoidance {
+		steering = steering.Add(Separation(a, neighbours, a.Radius.Mul(separationRange)))
+	}
+	preferred := truncate(a.Velocity.Add(truncate(steering, a.MaxAcceleration).Mul(dt)), a.MaxSpeed)
+	if c.Avoidance == ORCAAvoidance {
+		return ORCA(a, preferred, neighbours, c.TimeHorizon, dt)
+	}
+	return preferred
+}
@@ -0,0 +1,99 @@
+package steering
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/chewxy/math32"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// circleCrowd puts agents on a circle, each going to the opposite side so they all meet in the middle.
+func circleCrowd(n int, avoidance Avoidance) (*Crowd, map[*Agent]fixed.Vec2) {
+	c := NewCrowd(1.5)
+	c.Avoidance = avoidance
+	targets := map[*Agent]fixed.Vec2{}
+	for i := 0; i < n; i++ {
+		angle := fixed.Pi.Mul(fixed.Ratio(2*i, n))
+		position := fixed.Vec2{fixed.Cos(angle), fixed.Sin(angle)}.Mul(fixed.FromInt(3))
+		a := &Agent{Position: position, MaxSpeed: fixed.One, MaxAcceleration: fixed.FromInt(4), Radius: fixed.FromFloat(0.2)}
+		c.Add(a)
+		targets[a] = position.Mul(-fixed.One)
+	}
+	return c, targets
+}
+
+// runCrowd steps a crowd towards the targets for 30 seconds and returns the smallest distance between any two
+// agents relative to their combined radius.
+func runCrowd(c *Crowd, targets map[*Agent]fixed.Vec2) float32 {
+	closest := math32.Inf(1)
+	for i := 0; i < 3000; i++ {
+		c.Step(step, func(a *Agent) fixed.Vec2 { return Arrive(a, targets[a], fixed.Half) })
+		agents := c.Agents()
+		for j, a := range agents {
+			for _, b := range agents[j+1:] {
+				closest = math32.Min(closest, a.Position.Sub(b.Position).Len().Div(a.Radius+b.Radius).Float32())
+			}
+		}
+	}
+	return closest
+}
+
+func TestCrowdORCA(t *testing.T) {
+	c, targets := circleCrowd(8, ORCAAvoidance)
+	closest := runCrowd(c, targets)
+	assert.Greater(t, closest, float32(0.95), "agents don't run into each other")
+	for _, a := range c.Agents() {
+		assert.InDelta(t, 0, a.Position.Sub(targets[a]).Len().Float(), 0.05, "everyone gets to the other side")
+	}
+
+	// The same crowd ends up in exactly the same place
+	again, againTargets := circleCrowd(8, ORCAAvoidance)
+	runCrowd(again, againTargets)
+	for i, a := range c.Agents() {
+		assert.Equal(t, a.Position, again.Agents()[i].Position)
+	}
+}
+
+func TestCrowdWithoutAvoidanceCollides(t *testing.T) {
+	c, targets := circleCrowd(8, SeparationAvoidance)
+	closest := runCrowd(c, targets)
+	assert.Less(t, closest, float32(0.95), "separation alone lets agents bump into each other")
+}
+
+func TestCrowdSeparation(t *testing.T) {
+	c := NewCrowd(1.0)
+	a, b := newAgent(0, 0), newAgent(0.05, 0)
+	c.Add(a)
+	c.Add(b)
+	c.Add(a)
+	assert.Len(t, c.Agents(), 2)
+	assert.Equal(t, []*Agent{b}, c.Neighbours(a))
+
+	for i := 0; i < 200; i++ {
+		c.Steer(a, Arrive(a, fixed.Vec2{}, fixed.Half), step)
+		c.Steer(b, Arrive(b, fixed.Vec2{}, fixed.Half), step)
+	}
+	assert.Greater(t, a.Position.Sub(b.Position).Len(), a.Radius, "pushed apart around the shared target")
+
+	c.Remove(b)
+	assert.Equal(t, []*Agent{a}, c.Agents())
+	assert.Empty(t, c.Neighbours(a))
+}
+
+func TestCrowdIndexed(t *testing.T) {
+	c := NewCrowd(1.0)
+	a, b := newAgent(0, 0), newAgent(0.5, 0)
+	c.Add(a)
+	c.Add(b)
+	// Moving by itself, the crowd still sees b where it was
+	b.Position = vec(5, 0)
+	assert.Equal(t, []*Agent{b}, c.Neighbours(a))
+	assert.Equal(t, []mgl32.Vec2{{0, 0}, {0.5, 0}}, c.Indexed())
+
+	c.SetIndexed([]mgl32.Vec2{{0, 0}, {5, 0}})
+	assert.Empty(t, c.Neighbours(a))
+	assert.Equal(t, []mgl32.Vec2{{0, 0}, {5, 0}}, c.Indexed())
+}
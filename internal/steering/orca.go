@@ -0,0 +1,191 @@
+package steering
+
+import (
+	"game-engine/rts/internal/fixed"
+)
+
+// epsilon is how close to parallel two lines can be before they are treated as parallel.
+var epsilon = fixed.FromFloat(0.00001)
+
+// line is a half-plane of allowed velocities: those on the left of the line through point going in direction.
+type line struct {
+	point     fixed.Vec2
+	direction fixed.Vec2
+}
+
+// ORCA returns the velocity closest to preferred that won't collide with the neighbours within timeHorizon
+// seconds, assuming they do the same, using optimal reciprocal collision avoidance. dt is how long the
+// velocity is kept, agents that already overlap are pushed apart within it.
+func ORCA(a *Agent, preferred fixed.Vec2, neighbours []*Agent, timeHorizon, dt fixed.Scalar) fixed.Vec2 {
+	invTimeHorizon := fixed.One.Div(timeHorizon)
+	lines := make([]line, 0, len(neighbours))
+	for _, other := range neighbours {
+		if other == a {
+			continue
+		}
+		relativePosition := other.Position.Sub(a.Position)
+		relativeVelocity := a.Velocity.Sub(other.Velocity)
+		distSq := relativePosition.LenSqr()
+		combinedRadius := a.Radius + other.Radius
+		combinedRadiusSq := combinedRadius.Mul(combinedRadius)
+
+		var l line
+		var u fixed.Vec2
+		if distSq > combinedRadiusSq {
+			// No collision yet, w goes from the center of the cut-off circle to the relative velocity
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimeHorizon))
+			wLengthSq := w.LenSqr()
+			dot := w.Dot(relativePosition)
+			if dot < 0 && dot.Mul(dot) > combinedRadiusSq.Mul(wLengthSq) {
+				// Closest to the cut-off circle
+				wLength := wLengthSq.Sqrt()
+				unitW := w.Normalize()
+				l.direction = fixed.Vec2{unitW.Y(), -unitW.X()}
+				u = unitW.Mul(combinedRadius.Mul(invTimeHorizon) - wLength)
+			} else {
+				// Closest to one of the legs of the velocity obstacle
+				leg := (distSq - combinedRadiusSq).Sqrt()
+				x, y := relativePosition.X(), relativePosition.Y()
+				if cross(relativePosition, w) > 0 {
+					l.direction = fixed.Vec2{
+						(x.Mul(leg) - y.Mul(combinedRadius)).Div(distSq),
+						(x.Mul(combinedRadius) + y.Mul(leg)).Div(distSq),
+					}
+				} else {
+					l.direction = fixed.Vec2{
+						-(x.Mul(leg) + y.Mul(combinedRadius)).Div(distSq),
+						-(-x.Mul(combinedRadius) + y.Mul(leg)).Div(distSq),
+					}
+				}
+				u = l.direction.Mul(relativeVelocity.Dot(l.direction)).Sub(relativeVelocity)
+			}
+		} else {
+			// Already colliding, get apart within the time step
+			invTimeStep := fixed.One.Div(dt)
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimeStep))
+			wLength := w.Len()
+			if wLength == 0 {
+				// Exactly on top of each other with the same velocity, nothing to go by
+				continue
+			}
+			unitW := w.Normalize()
+			l.direction = fixed.Vec2{unitW.Y(), -unitW.X()}
+			u = unitW.Mul(combinedRadius.Mul(invTimeStep) - wLength)
+		}
+		// Each agent takes half of the responsibility to avoid the collision
+		l.point = a.Velocity.Add(u.Mul(fixed.Half))
+		lines = append(lines, l)
+	}
+
+	velocity := fixed.Vec2{}
+	if failed := linearProgram2(lines, a.MaxSpeed, preferred, false, &velocity); failed < len(lines) {
+		linearProgram3(lines, failed, a.MaxSpeed, &velocity)
+	}
+	return velocity
+}
+
+// linearProgram1 finds the velocity on line lineNo closest to optVelocity, or furthest in its direction when
+// directionOpt is set, that is within radius and allowed by the lines before it.
+func linearProgram1(lines []line, lineNo int, radius fixed.Scalar, optVelocity fixed.Vec2, directionOpt bool, result *fixed.Vec2) bool {
+	l := lines[lineNo]
+	dot := l.point.Dot(l.direction)
+	discriminant := dot.Mul(dot) + radius.Mul(radius) - l.point.LenSqr()
+	if discriminant < 0 {
+		// The line is outside the circle of allowed speeds
+		return false
+	}
+	sqrtDiscriminant := discriminant.Sqrt()
+	tLeft, tRight := -dot-sqrtDiscriminant, -dot+sqrtDiscriminant
+
+	for i := 0; i < lineNo; i++ {
+		denominator := cross(l.direction, lines[i].direction)
+		numerator := cross(lines[i].direction, l.point.Sub(lines[i].point))
+		if denominator.Abs() <= epsilon {
+			// Parallel lines
+			if numerator < 0 {
+				return false
+			}
+			continue
+		}
+		t := numerator.Div(denominator)
+		if denominator >= 0 {
+			if t < tRight {
+				tRight = t
+			}
+		} else if t > tLeft {
+			tLeft = t
+		}
+		if tLeft > tRight {
+			return false
+		}
+	}
+
+	switch {
+	case directionOpt && optVelocity.Dot(l.direction) > 0:
+		*result = l.point.Add(l.direction.Mul(tRight))
+	case directionOpt:
+		*result = l.point.Add(l.direction.Mul(tLeft))
+	default:
+		t := l.direction.Dot(optVelocity.Sub(l.point)).Clamp(tLeft, tRight)
+		*result = l.point.Add(l.direction.Mul(t))
+	}
+	return true
+}
+
+// linearProgram2 finds the velocity closest to optVelocity within radius allowed by all lines. It returns the
+// number of lines when it succeeds, or the line it failed on.
+func linearProgram2(lines []line, radius fixed.Scalar, optVelocity fixed.Vec2, directionOpt bool, result *fixed.Vec2) int {
+	switch {
+	case directionOpt:
+		*result = optVelocity.Mul(radius)
+	case optVelocity.LenSqr() > radius.Mul(radius):
+		*result = optVelocity.Normalize().Mul(radius)
+	default:
+		*result = optVelocity
+	}
+	for i, l := range lines {
+		if cross(l.direction, l.point.Sub(*result)) > 0 {
+			// The result is not allowed by this line
+			previous := *result
+			if !linearProgram1(lines, i, radius, optVelocity, directionOpt, result) {
+				*result = previous
+				return i
+			}
+		}
+	}
+	return len(lines)
+}
+
+// linearProgram3 finds the velocity that least breaks the lines from beginLine on, when there is no velocity
+// allowed by all of them because the agents are too crowded.
+func linearProgram3(lines []line, beginLine int, radius fixed.Scalar, result *fixed.Vec2) {
+	distance := fixed.Scalar(0)
+	for i := beginLine; i < len(lines); i++ {
+		if cross(lines[i].direction, lines[i].point.Sub(*result)) <= distance {
+			continue
+		}
+		projected := make([]line, 0, i)
+		for j := 0; j < i; j++ {
+			var l line
+			determinant := cross(lines[i].direction, lines[j].direction)
+			if determinant.Abs() <= epsilon {
+				if lines[i].direction.Dot(lines[j].direction) > 0 {
+					// Same direction
+					continue
+				}
+				l.point = lines[i].point.Add(lines[j].point).Mul(fixed.Half)
+			} else {
+				l.point = lines[i].point.Add(lines[i].direction.Mul(cross(lines[j].direction, lines[i].point.Sub(lines[j].point)).Div(determinant)))
+			}
+			l.direction = lines[j].direction.Sub(lines[i].direction).Normalize()
+			projected = append(projected, l)
+		}
+
+		previous := *result
+		if linearProgram2(projected, radius, fixed.Vec2{-lines[i].direction.Y(), lines[i].direction.X()}, true, result) < len(projected) {
+			// Can only fail because of rounding errors, keep the previous result
+			*result = previous
+		}
+		distance = cross(lines[i].direction, lines[i].point.Sub(*result))
+	}
+}
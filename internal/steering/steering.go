@@ -0,0 +1,209 @@
+// Package steering moves units smoothly towards where they want to go and around each other.
+//
+// Behaviours return a steering acceleration, the change in velocity the agent would like. They can be added up
+// and are applied with Agent.Apply, or Crowd.Steer to also avoid other agents. Everything is in fixed point, so
+// agents end up in the same place on every machine.
+package steering
+
+import (
+	"game-engine/rts/internal/fixed"
+)
+
+// responseTime is how fast, in seconds, behaviours try to reach the velocity they want. The acceleration is
+// still limited by the agent's maximum.
+var responseTime = fixed.FromFloat(0.1)
+
+// Agent is a unit moving on the ground plane.
+type Agent struct {
+	Position fixed.Vec2
+	Velocity fixed.Vec2
+	// MaxSpeed is how fast the agent can move, in units per second.
+	MaxSpeed fixed.Scalar
+	// MaxAcceleration is how fast the agent can change its velocity, in units per second squared.
+	MaxAcceleration fixed.Scalar
+	// Radius is how much room the agent takes.
+	Radius fixed.Scalar
+}
+
+// Apply accelerates the agent by a steering acceleration, limited to its maximum acceleration and speed, and
+// moves it.
+func (a *Agent) Apply(steering fixed.Vec2, dt fixed.Scalar) {
+	a.Velocity = truncate(a.Velocity.Add(truncate(steering, a.MaxAcceleration).Mul(dt)), a.MaxSpeed)
+	a.Position = a.Position.Add(a.Velocity.Mul(dt))
+}
+
+// Seek steers towards a target at full speed.
+func Seek(a *Agent, target fixed.Vec2) fixed.Vec2 {
+	offset := target.Sub(a.Position)
+	if offset.LenSqr() == 0 {
+		return match(a, fixed.Vec2{})
+	}
+	return match(a, offset.Normalize().Mul(a.MaxSpeed))
+}
+
+// Flee steers away from a position at full speed.
+func Flee(a *Agent, from fixed.Vec2) fixed.Vec2 {
+	offset := a.Position.Sub(from)
+	if offset.LenSqr() == 0 {
+		return fixed.Vec2{}
+	}
+	return match(a, offset.Normalize().Mul(a.MaxSpeed))
+}
+
+// Arrive steers towards a target like Seek, but slows down within slowRadius of it so the agent stops on the
+// target.
+func Arrive(a *Agent, target fixed.Vec2, slowRadius fixed.Scalar) fixed.Vec2 {
+	offset := target.Sub(a.Position)
+	distance := offset.Len()
+	if distance == 0 {
+		return match(a, fixed.Vec2{})
+	}
+	speed := a.MaxSpeed
+	if distance < slowRadius {
+		speed = speed.Mul(distance.Div(slowRadius))
+	}
+	// Braking distance at the maximum acceleration, so the agent can stop in time whatever its speed
+	if a.MaxAcceleration > 0 {
+		if braking := (2 * a.MaxAcceleration).Mul(distance).Sqrt(); braking < speed {
+			speed = braking
+		}
+	}
+	return match(a, offset.Mul(speed.Div(distance)))
+}
+
+// Separation steers away from neighbours closer than radius, more strongly the closer they are.
+func Separation(a *Agent, neighbours []*Agent, radius fixed.Scalar) fixed.Vec2 {
+	push := fixed.Vec2{}
+	for _, n := range neighbours {
+		offset := a.Position.Sub(n.Position)
+		distance := offset.Len()
+		if n == a || distance >= radius || distance == 0 {
+			continue
+		}
+		push = push.Add(offset.Mul((radius - distance).Div(radius.Mul(distance))))
+	}
+	return push.Mul(a.MaxSpeed.Div(responseTime))
+}
+
+// Cohesion steers towards the center of the neighbours.
+func Cohesion(a *Agent, neighbours []*Agent) fixed.Vec2 {
+	center, count := fixed.Vec2{}, 0
+	for _, n := range neighbours {
+		if n != a {
+			center = center.Add(n.Position)
+			count++
+		}
+	}
+	if count == 0 {
+		return fixed.Vec2{}
+	}
+	return Seek(a, center.Mul(fixed.Ratio(1, count)))
+}
+
+// Alignment steers towards the average velocity of the neighbours.
+func Alignment(a *Agent, neighbours []*Agent) fixed.Vec2 {
+	velocity, count := fixed.Vec2{}, 0
+	for _, n := range neighbours {
+		if n != a {
+			velocity = velocity.Add(n.Velocity)
+			count++
+		}
+	}
+	if count == 0 {
+		return fixed.Vec2{}
+	}
+	return match(a, velocity.Mul(fixed.Ratio(1, count)))
+}
+
+// Obstacle is something round agents can't walk through, like a tree.
+type Obstacle struct {
+	Center fixed.Vec2
+	Radius fixed.Scalar
+}
+
+// AvoidObstacles steers sideways away from the closest obstacle the agent would run into within lookAhead
+// seconds at its current velocity, until it is past it.
+func AvoidObstacles(a *Agent, obstacles []Obstacle, lookAhead fixed.Scalar) fixed.Vec2 {
+	speed := a.Velocity.Len()
+	if speed == 0 {
+		return fixed.Vec2{}
+	}
+	heading := a.Velocity.Normalize()
+	reach := speed.Mul(lookAhead)
+
+	var closest *Obstacle
+	closestAhead := fixed.Scalar(0)
+	for i := range obstacles {
+		o := &obstacles[i]
+		offset := o.Center.Sub(a.Position)
+		ahead := offset.Dot(heading)
+		side := cross(heading, offset)
+		radius := o.Radius + a.Radius
+		if ahead+radius <= 0 || ahead-radius > reach || side.Abs() >= radius {
+			continue
+		}
+		if closest == nil || ahead < closestAhead {
+			closest, closestAhead = o, ahead
+		}
+	}
+	if closest == nil {
+		return fixed.Vec2{}
+	}
+
+	// Sidestep away from the side the obstacle is on, faster when it is close
+	left := fixed.Vec2{-heading.Y(), heading.X()}
+	if cross(heading, closest.Center.Sub(a.Position)) > 0 {
+		left = fixed.Vec2{heading.Y(), -heading.X()}
+	}
+	strength := fixed.One - closestAhead.Div(reach+closest.Radius+a.Radius)
+	if strength > fixed.One {
+		strength = fixed.One
+	}
+	return left.Mul(a.MaxSpeed.Mul(strength).Div(responseTime))
+}
+
+// Path is a list of points for an agent to walk through.
+type Path struct {
+	Points []fixed.Vec2
+	// Next is the index of the point the agent is walking to.
+	Next int
+}
+
+// Done reports if the agent is walking to the last point of the path.
+func (p *Path) Done() bool {
+	return p.Next >= len(p.Points)-1
+}
+
+// FollowPath steers through the points of a path, going for the next point once within reach of the current
+// one, and arrives at the last point.
+func FollowPath(a *Agent, path *Path, reach, slowRadius fixed.Scalar) fixed.Vec2 {
+	if len(path.Points) == 0 {
+		return match(a, fixed.Vec2{})
+	}
+	for !path.Done() && path.Points[path.Next].Sub(a.Position).Len() < reach {
+		path.Next++
+	}
+	if path.Done() {
+		return Arrive(a, path.Points[len(path.Points)-1], slowRadius)
+	}
+	return Seek(a, path.Points[path.Next])
+}
+
+// match steers to reach a velocity within responseTime.
+func match(a *Agent, velocity fixed.Vec2) fixed.Vec2 {
+	change := velocity.Sub(a.Velocity)
+	return fixed.Vec2{change.X().Div(responseTime), change.Y().Div(responseTime)}
+}
+
+// truncate shortens v to at most length.
+func truncate(v fixed.Vec2, length fixed.Scalar) fixed.Vec2 {
+	if lenSqr := v.LenSqr(); lenSqr > length.Mul(length) {
+		return v.Mul(length.Div(lenSqr.Sqrt()))
+	}
+	return v
+}
+
+// cross is the z of the cross product of a and b, positive when b is counter-clockwise from a.
+func cross(a, b fixed.Vec2) fixed.Scalar {
+	return a.X().Mul(b.Y()) - a.Y().Mul(b.X())
+}
@@ -0,0 +1,153 @@
+package steering
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vec returns the fixed-point vector of x, y.
+func vec(x, y float64) fixed.Vec2 {
+	return fixed.Vec2{fixed.FromFloat(x), fixed.FromFloat(y)}
+}
+
+func newAgent(x, y float64) *Agent {
+	return &Agent{Position: vec(x, y), MaxSpeed: fixed.One, MaxAcceleration: fixed.FromInt(4), Radius: fixed.FromFloat(0.1)}
+}
+
+// step is how long each update of simulate is.
+var step = fixed.FromFloat(0.01)
+
+// simulate applies the steering of an agent every 10 ms for up to 100 seconds, until done returns true.
+func simulate(a *Agent, steer func() fixed.Vec2, done func() bool) {
+	for i := 0; i < 10000 && !done(); i++ {
+		a.Apply(steer(), step)
+	}
+}
+
+func TestApply(t *testing.T) {
+	a := newAgent(0, 0)
+	a.Apply(vec(100, 0), fixed.FromFloat(0.1))
+	assert.InDelta(t, 0.4, a.Velocity.X().Float(), 1e-6, "limited by acceleration")
+	assert.InDelta(t, 0.04, a.Position.X().Float(), 1e-6)
+
+	for i := 0; i < 10; i++ {
+		a.Apply(vec(100, 0), fixed.FromFloat(0.1))
+	}
+	assert.InDelta(t, 1.0, a.Velocity.Len().Float(), 1e-6, "limited by speed")
+}
+
+func TestSeek(t *testing.T) {
+	a := newAgent(0, 0)
+	target := vec(3, 4)
+	steering := Seek(a, target)
+	assert.InDelta(t, 0.6, steering.Normalize().X().Float(), 1e-6)
+	assert.InDelta(t, 0.8, steering.Normalize().Y().Float(), 1e-6)
+
+	a.Apply(steering, fixed.One)
+	assert.InDelta(t, 1.0, a.Velocity.Len().Float(), 1e-6, "full speed")
+	assert.InDelta(t, -0.8, Flee(a, target).Normalize().Y().Float(), 1e-6, "turns around")
+}
+
+func TestArrive(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		start  fixed.Vec2
+		target fixed.Vec2
+	}{
+		{desc: "far", start: vec(0, 0), target: vec(5, -3)},
+		{desc: "close", start: vec(0, 0), target: vec(0.1, 0)},
+		{desc: "already there", start: vec(1, 1), target: vec(1, 1)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := newAgent(tc.start.X().Float(), tc.start.Y().Float())
+			furthest := fixed.Scalar(0)
+			simulate(a, func() fixed.Vec2 { return Arrive(a, tc.target, fixed.Half) }, func() bool {
+				if d := a.Position.Sub(tc.start).Len(); d > furthest {
+					furthest = d
+				}
+				return false
+			})
+			assert.InDelta(t, 0, a.Position.Sub(tc.target).Len().Float(), 0.01, "stops on the target")
+			assert.InDelta(t, 0, a.Velocity.Len().Float(), 0.01)
+			assert.LessOrEqual(t, furthest.Float(), tc.target.Sub(tc.start).Len().Float()+0.01, "doesn't overshoot")
+		})
+	}
+}
+
+func TestSeparation(t *testing.T) {
+	a, near, far := newAgent(0, 0), newAgent(0.1, 0), newAgent(0, 2)
+	radius := fixed.FromFloat(0.3)
+	steering := Separation(a, []*Agent{a, near, far}, radius)
+	assert.Less(t, steering.X(), fixed.Scalar(0), "away from the near agent")
+	assert.Equal(t, fixed.Scalar(0), steering.Y(), "too far to matter")
+
+	closer := Separation(a, []*Agent{newAgent(0.05, 0)}, radius)
+	assert.Greater(t, closer.Len(), steering.Len(), "stronger when closer")
+}
+
+func TestFlocking(t *testing.T) {
+	a := newAgent(0, 0)
+	b, c := newAgent(2, 0), newAgent(0, 2)
+	b.Velocity, c.Velocity = vec(0, 1), vec(0, 1)
+	neighbours := []*Agent{a, b, c}
+
+	cohesion := Cohesion(a, neighbours)
+	assert.InDelta(t, cohesion.X().Float(), cohesion.Y().Float(), 1e-6, "towards the center at 1,1")
+	assert.Greater(t, cohesion.X(), fixed.Scalar(0))
+	assert.Equal(t, vec(0, 1), Alignment(a, neighbours).Normalize())
+
+	assert.Equal(t, fixed.Vec2{}, Cohesion(a, []*Agent{a}))
+	assert.Equal(t, fixed.Vec2{}, Alignment(a, nil))
+}
+
+func TestAvoidObstacles(t *testing.T) {
+	a := newAgent(0, 0)
+	a.Velocity = vec(1, 0)
+	treeRadius := fixed.FromFloat(0.2)
+	obstacles := []Obstacle{{Center: vec(1, 0.05), Radius: treeRadius}}
+	lookAhead := fixed.FromInt(2)
+
+	steering := AvoidObstacles(a, obstacles, lookAhead)
+	assert.Less(t, steering.Y(), fixed.Scalar(0), "turns away from the side the obstacle is on")
+	assert.Equal(t, fixed.Vec2{}, AvoidObstacles(a, []Obstacle{{Center: vec(-1, 0), Radius: treeRadius}}, lookAhead), "behind")
+	assert.Equal(t, fixed.Vec2{}, AvoidObstacles(a, []Obstacle{{Center: vec(1, 1), Radius: treeRadius}}, lookAhead), "to the side")
+	assert.Equal(t, fixed.Vec2{}, AvoidObstacles(a, []Obstacle{{Center: vec(5, 0), Radius: treeRadius}}, lookAhead), "too far ahead")
+
+	// Walking past a tree in the way
+	target := vec(3, 0)
+	closest := fixed.FromInt(10)
+	simulate(a, func() fixed.Vec2 {
+		return Arrive(a, target, fixed.Half).Add(AvoidObstacles(a, obstacles, fixed.One).Mul(fixed.FromInt(2)))
+	}, func() bool {
+		if d := a.Position.Sub(obstacles[0].Center).Len(); d < closest {
+			closest = d
+		}
+		return a.Position.Sub(target).Len().Float() < 0.01
+	})
+	assert.InDelta(t, (obstacles[0].Radius + a.Radius).Float(), closest.Float(), 0.01, "went around the obstacle, just brushing it")
+	assert.InDelta(t, 0, a.Position.Sub(target).Len().Float(), 0.01)
+}
+
+func TestFollowPath(t *testing.T) {
+	a := newAgent(0, 0)
+	path := &Path{Points: []fixed.Vec2{vec(1, 0), vec(1, 1), vec(0, 1)}}
+	reach, slowRadius := fixed.FromFloat(0.1), fixed.FromFloat(0.3)
+	visited := map[int]bool{}
+	simulate(a, func() fixed.Vec2 { return FollowPath(a, path, reach, slowRadius) }, func() bool {
+		for i, p := range path.Points {
+			if a.Position.Sub(p).Len().Float() < 0.15 {
+				visited[i] = true
+			}
+		}
+		return path.Done() && a.Velocity.Len().Float() < 0.001 && a.Position.Sub(path.Points[2]).Len().Float() < 0.001
+	})
+	assert.True(t, path.Done())
+	assert.Equal(t, map[int]bool{0: true, 1: true, 2: true}, visited)
+	assert.InDelta(t, 0, a.Position.Sub(vec(0, 1)).Len().Float(), 0.001)
+
+	assert.Equal(t, fixed.Vec2{}, FollowPath(newAgent(0, 0), &Path{}, reach, slowRadius))
+}
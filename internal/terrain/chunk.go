@@ -0,0 +1,69 @@
+package terrain
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Chunk is the mesh of a square part of a heightmap. The ground is split in chunks so that parts of it can be
+// rebuilt, or left out when they are not in view, without touching the rest.
+type Chunk struct {
+	// I and J are the sample at the corner of the chunk with the lowest x and z.
+	I, J int
+	// Min and Max are the corners of the box around the chunk.
+	Min, Max mgl32.Vec3
+	// Vertices are triangles with the position and then the normal of each vertex, to draw with
+	// mesh.FromVertices(vertices, false, true).
+	Vertices []float32
+}
+
+// Chunks splits the heightmap into chunks of size by size squares of samples, the chunks at the far edges may
+// be smaller.
+func (h *Heightmap) Chunks(size int) []Chunk {
+	chunks := []Chunk{}
+	for j := 0; j < h.depth-1; j += size {
+		for i := 0; i < h.width-1; i += size {
+			chunks = append(chunks, h.Chunk(i, j, size))
+		}
+	}
+	return chunks
+}
+
+// Chunk returns the chunk of size by size squares of samples starting at sample i, j.
+func (h *Heightmap) Chunk(i, j, size int) Chunk {
+	last := func(first, count int) int {
+		if first+size < count-1 {
+			return first + size
+		}
+		return count - 1
+	}
+	i1, j1 := last(i, h.width), last(j, h.depth)
+	c := Chunk{
+		I:        i,
+		J:        j,
+		Min:      h.Position(i, j),
+		Max:      h.Position(i1, j1),
+		Vertices: make([]float32, 0, (i1-i)*(j1-j)*6*6),
+	}
+	vertex := func(i, j int) {
+		p, n := h.Position(i, j), h.Normal(i, j)
+		c.Vertices = append(c.Vertices, p.X(), p.Y(), p.Z(), n.X(), n.Y(), n.Z())
+		if p.Y() < c.Min.Y() {
+			c.Min[1] = p.Y()
+		}
+		if p.Y() > c.Max.Y() {
+			c.Max[1] = p.Y()
+		}
+	}
+	for y := j; y < j1; y++ {
+		for x := i; x < i1; x++ {
+			// Two triangles per square, counter-clockwise seen from above
+			vertex(x, y)
+			vertex(x, y+1)
+			vertex(x+1, y+1)
+			vertex(x, y)
+			vertex(x+1, y+1)
+			vertex(x+1, y)
+		}
+	}
+	return c
+}
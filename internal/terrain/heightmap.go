@@ -0,0 +1,238 @@
+// Package terrain is ground with hills and valleys, described by a heightmap: heights sampled on a regular
+// grid over the ground plane, between which the ground is interpolated.
+package terrain
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/texture"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Heightmap is the height of the ground at samples spaced evenly along the x and z axes.
+type Heightmap struct {
+	// Origin is the world x and z of sample 0,0.
+	Origin mgl32.Vec2
+	// Spacing is the distance between neighbouring samples.
+	Spacing float32
+
+	width, depth int
+	heights      []float32
+	normals      []mgl32.Vec3
+}
+
+// New creates a flat heightmap of width samples along x and depth samples along z, at height 0.
+func New(width, depth int, origin mgl32.Vec2, spacing float32) *Heightmap {
+	h := &Heightmap{
+		Origin:  origin,
+		Spacing: spacing,
+		width:   width,
+		depth:   depth,
+		heights: make([]float32, width*depth),
+		normals: make([]mgl32.Vec3, width*depth),
+	}
+	for i := range h.normals {
+		h.normals[i] = mgl32.Vec3{0, 1, 0}
+	}
+	return h
+}
+
+// FromFunc creates a heightmap with the height of each sample given by height at its world x and z.
+func FromFunc(width, depth int, origin mgl32.Vec2, spacing float32, height func(x, z float32) float32) *Heightmap {
+	h := New(width, depth, origin, spacing)
+	for j := 0; j < depth; j++ {
+		for i := 0; i < width; i++ {
+			position := h.Position(i, j)
+			h.heights[j*width+i] = height(position.X(), position.Z())
+		}
+	}
+	h.updateNormals(0, 0, width, depth)
+	return h
+}
+
+// FromImage creates a heightmap with a sample for each pixel of a grayscale image, black at height low and
+// white at height high. The top row of the image is at the lowest z.
+func FromImage(img image.Image, origin mgl32.Vec2, spacing, low, high float32) *Heightmap {
+	bounds := img.Bounds()
+	return FromFunc(bounds.Dx(), bounds.Dy(), origin, spacing, func(x, z float32) float32 {
+		i := int(math.Round(float64((x - origin.X()) / spacing)))
+		j := int(math.Round(float64((z - origin.Y()) / spacing)))
+		gray := color.Gray16Model.Convert(img.At(bounds.Min.X+i, bounds.Min.Y+j)).(color.Gray16)
+		return low + (high-low)*float32(gray.Y)/math.MaxUint16
+	})
+}
+
+// Load reads a heightmap from a grayscale image file, like FromImage.
+func Load(file string, origin mgl32.Vec2, spacing, low, high float32) (*Heightmap, error) {
+	img, err := texture.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("loading heightmap: %w", err)
+	}
+	return FromImage(img, origin, spacing, low, high), nil
+}
+
+// hillOctaves is how many layers of waves Hills adds up, each half as long and high as the one before.
+const hillOctaves = 4
+
+// Hills returns a height function for FromFunc of rolling hills around base, at most amplitude higher or
+// lower, with the largest hills wavelength apart. The same seed makes the same hills.
+func Hills(seed int64, base, amplitude, wavelength float32) func(x, z float32) float32 {
+	type wave struct {
+		direction        mgl32.Vec2
+		frequency, phase float32
+		amplitude        float32
+	}
+	random := rand.New(rand.NewSource(seed))
+	waves := make([]wave, hillOctaves)
+	// The amplitudes of all octaves add up to the amplitude of the hills
+	scale := amplitude / (2 - float32(math.Pow(0.5, hillOctaves-1)))
+	for i := range waves {
+		angle := random.Float64() * 2 * math.Pi
+		waves[i] = wave{
+			direction: mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))},
+			frequency: 2 * math.Pi / wavelength * float32(math.Pow(2, float64(i))),
+			phase:     random.Float32() * 2 * math.Pi,
+			amplitude: scale * float32(math.Pow(0.5, float64(i))),
+		}
+	}
+	return func(x, z float32) float32 {
+		height := base
+		for _, w := range waves {
+			along := w.direction.Dot(mgl32.Vec2{x, z})
+			height += w.amplitude * float32(math.Sin(float64(along*w.frequency+w.phase)))
+		}
+		return height
+	}
+}
+
+// Size returns the number of samples along x and z.
+func (h *Heightmap) Size() (width, depth int) {
+	return h.width, h.depth
+}
+
+// Height returns the height of a sample, samples outside the heightmap have the height of the closest edge.
+func (h *Heightmap) Height(i, j int) float32 {
+	return h.heights[h.index(i, j)]
+}
+
+// Normal returns the normal of the ground at a sample, samples outside the heightmap have the normal of the
+// closest edge.
+func (h *Heightmap) Normal(i, j int) mgl32.Vec3 {
+	return h.normals[h.index(i, j)]
+}
+
+// SetHeight changes the height of a sample, e.g. to flatten the ground under a building. Samples outside the
+// heightmap are ignored.
+func (h *Heightmap) SetHeight(i, j int, height float32) {
+	if i < 0 || j < 0 || i >= h.width || j >= h.depth {
+		return
+	}
+	h.heights[j*h.width+i] = height
+	h.updateNormals(i-1, j-1, i+2, j+2)
+}
+
+// Position returns the world position of a sample.
+func (h *Heightmap) Position(i, j int) mgl32.Vec3 {
+	return mgl32.Vec3{
+		h.Origin.X() + float32(i)*h.Spacing,
+		h.Height(i, j),
+		h.Origin.Y() + float32(j)*h.Spacing,
+	}
+}
+
+// HeightAt returns the height of the ground at a world x and z, interpolated between the four samples around
+// it. Outside the heightmap the height of the closest edge is used. It is computed in fixed point, so units
+// stand at the same height on every machine.
+func (h *Heightmap) HeightAt(x, z fixed.Scalar) fixed.Scalar {
+	spacing := fixed.FromFloat32(h.Spacing)
+	fx := (x - fixed.FromFloat32(h.Origin.X())).Div(spacing).Clamp(0, fixed.FromInt(h.width-1))
+	fz := (z - fixed.FromFloat32(h.Origin.Y())).Div(spacing).Clamp(0, fixed.FromInt(h.depth-1))
+	i, j, u, v := fx.Int(), fz.Int(), fx.Frac(), fz.Frac()
+	height := func(i, j int) fixed.Scalar { return fixed.FromFloat32(h.Height(i, j)) }
+	return height(i, j).Lerp(height(i+1, j), u).Lerp(height(i, j+1).Lerp(height(i+1, j+1), u), v)
+}
+
+// NormalAt returns the normal of the ground at a world x and z, interpolated between the normals of the four
+// samples around it so that lighting is smooth.
+func (h *Heightmap) NormalAt(x, z float32) mgl32.Vec3 {
+	i, j, u, v := h.cell(x, z)
+	normal := h.Normal(i, j).Mul((1 - u) * (1 - v)).
+		Add(h.Normal(i+1, j).Mul(u * (1 - v))).
+		Add(h.Normal(i, j+1).Mul((1 - u) * v)).
+		Add(h.Normal(i+1, j+1).Mul(u * v))
+	return normal.Normalize()
+}
+
+// cell returns the sample at the corner of the square of samples a world x and z is in, and how far along
+// the square it is.
+func (h *Heightmap) cell(x, z float32) (i, j int, u, v float32) {
+	fx := clamp((x-h.Origin.X())/h.Spacing, 0, float32(h.width-1))
+	fz := clamp((z-h.Origin.Y())/h.Spacing, 0, float32(h.depth-1))
+	i, j = int(fx), int(fz)
+	return i, j, fx - float32(i), fz - float32(j)
+}
+
+// index returns where a sample is stored, clamping it to the heightmap.
+func (h *Heightmap) index(i, j int) int {
+	if i < 0 {
+		i = 0
+	} else if i >= h.width {
+		i = h.width - 1
+	}
+	if j < 0 {
+		j = 0
+	} else if j >= h.depth {
+		j = h.depth - 1
+	}
+	return j*h.width + i
+}
+
+// updateNormals recomputes the normals of the samples i0 <= i < i1 and j0 <= j < j1 from the slope between
+// their neighbours.
+func (h *Heightmap) updateNormals(i0, j0, i1, j1 int) {
+	for j := j0; j < j1; j++ {
+		for i := i0; i < i1; i++ {
+			if i < 0 || j < 0 || i >= h.width || j >= h.depth {
+				continue
+			}
+			left, right := h.neighbours(i, h.width)
+			back, front := h.neighbours(j, h.depth)
+			dx := (h.Height(right, j) - h.Height(left, j)) / (float32(right-left) * h.Spacing)
+			dz := (h.Height(i, front) - h.Height(i, back)) / (float32(front-back) * h.Spacing)
+			h.normals[j*h.width+i] = mgl32.Vec3{-dx, 1, -dz}.Normalize()
+		}
+	}
+}
+
+// neighbours returns the samples before and after i to take the slope between, only using i itself at the
+// edges.
+func (h *Heightmap) neighbours(i, count int) (int, int) {
+	before, after := i-1, i+1
+	if before < 0 {
+		before = 0
+	}
+	if after >= count {
+		after = count - 1
+	}
+	if before == after {
+		// A single sample is flat
+		return i, i + 1
+	}
+	return before, after
+}
+
+func clamp(v, low, high float32) float32 {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
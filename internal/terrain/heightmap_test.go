@@ -0,0 +1,142 @@
+package terrain
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// slope is a plane rising along x and z.
+func slope(x, z float32) float32 {
+	return 1 + 0.5*x + 0.25*z
+}
+
+func TestHeightAt(t *testing.T) {
+	h := New(2, 2, mgl32.Vec2{10, 20}, 2.0)
+	h.SetHeight(1, 0, 1)
+	h.SetHeight(0, 1, 2)
+	h.SetHeight(1, 1, 4)
+	testCases := []struct {
+		desc     string
+		x, z     float32
+		expected float32
+	}{
+		{desc: "on a sample", x: 12, z: 20, expected: 1},
+		{desc: "between two samples", x: 10, z: 21, expected: 1},
+		{desc: "middle", x: 11, z: 21, expected: 1.75},
+		{desc: "off the edge", x: 15, z: 25, expected: 4},
+		{desc: "before the origin", x: 0, z: 20, expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, h.HeightAt(fixed.FromFloat32(tc.x), fixed.FromFloat32(tc.z)).Float32(), 1e-5)
+		})
+	}
+	h.SetHeight(5, 5, 10)
+	assert.Equal(t, float32(4), h.Height(5, 5), "outside the heightmap is ignored")
+}
+
+func TestSlope(t *testing.T) {
+	h := FromFunc(9, 5, mgl32.Vec2{-2, -1}, 0.5, slope)
+	width, depth := h.Size()
+	assert.Equal(t, 9, width)
+	assert.Equal(t, 5, depth)
+	expected := mgl32.Vec3{-0.5, 1, -0.25}.Normalize()
+	for _, p := range []mgl32.Vec2{{-2, -1}, {0.3, 0.7}, {1.99, 0.2}, {2, 1}} {
+		assert.InDelta(t, slope(p.X(), p.Y()), h.HeightAt(fixed.FromFloat32(p.X()), fixed.FromFloat32(p.Y())).Float32(), 1e-5, "%v", p)
+		assert.InDelta(t, 0, h.NormalAt(p.X(), p.Y()).Sub(expected).Len(), 1e-5, "%v", p)
+	}
+	assert.Equal(t, mgl32.Vec3{-1.5, slope(-1.5, 0), 0}, h.Position(1, 2))
+}
+
+func TestNormalsFollowChanges(t *testing.T) {
+	h := New(5, 5, mgl32.Vec2{}, 1.0)
+	assert.Equal(t, mgl32.Vec3{0, 1, 0}, h.NormalAt(2, 2))
+	h.SetHeight(2, 2, 1)
+	assert.Equal(t, mgl32.Vec3{0, 1, 0}, h.Normal(2, 2), "top of the bump is flat")
+	assert.Less(t, h.Normal(1, 2).X(), float32(0), "leans away from the bump")
+	assert.Greater(t, h.Normal(3, 2).X(), float32(0), "leans away from the bump")
+	assert.Equal(t, mgl32.Vec3{0, 1, 0}, h.Normal(0, 2), "out of reach")
+	assert.InDelta(t, 1, h.NormalAt(1.5, 2.5).Len(), 1e-5)
+}
+
+func TestFromImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(1, 0, color.Gray{Y: 255})
+	img.SetGray(2, 1, color.Gray{Y: 51})
+	name := filepath.Join(t.TempDir(), "heightmap.png")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	assert.NoError(t, png.Encode(f, img))
+	assert.NoError(t, f.Close())
+
+	h, err := Load(name, mgl32.Vec2{}, 1.0, 2, 4)
+	assert.NoError(t, err)
+	width, depth := h.Size()
+	assert.Equal(t, 3, width)
+	assert.Equal(t, 2, depth)
+	assert.Equal(t, float32(2), h.Height(0, 0), "black is low")
+	assert.Equal(t, float32(4), h.Height(1, 0), "white is high")
+	assert.InDelta(t, 2.4, h.Height(2, 1), 1e-5)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.png"), mgl32.Vec2{}, 1.0, 0, 1)
+	assert.Error(t, err)
+}
+
+func TestHills(t *testing.T) {
+	hills := Hills(1, 2.5, 0.5, 8)
+	assert.Equal(t, hills(3.2, -1.7), Hills(1, 2.5, 0.5, 8)(3.2, -1.7), "same seed, same hills")
+	assert.NotEqual(t, hills(3.2, -1.7), Hills(2, 2.5, 0.5, 8)(3.2, -1.7))
+
+	low, high := float32(10), float32(0)
+	for x := float32(-20); x < 20; x += 0.25 {
+		for z := float32(-20); z < 20; z += 0.25 {
+			height := hills(x, z)
+			if height < low {
+				low = height
+			}
+			if height > high {
+				high = height
+			}
+		}
+	}
+	assert.GreaterOrEqual(t, low, float32(2.0))
+	assert.LessOrEqual(t, high, float32(3.0))
+	assert.Greater(t, high-low, float32(0.3), "not flat")
+}
+
+func TestChunks(t *testing.T) {
+	h := FromFunc(10, 7, mgl32.Vec2{}, 1.0, Hills(1, 0, 1, 4))
+	chunks := h.Chunks(4)
+	assert.Len(t, chunks, 3*2)
+
+	squares := 0
+	for _, c := range chunks {
+		assert.Equal(t, 0, len(c.Vertices)%(6*6), "two triangles of six floats per square")
+		squares += len(c.Vertices) / 36
+		for v := 0; v < len(c.Vertices); v += 18 {
+			var corners [3]mgl32.Vec3
+			for k := range corners {
+				f := c.Vertices[v+k*6:]
+				corners[k] = mgl32.Vec3{f[0], f[1], f[2]}
+				assert.Equal(t, h.HeightAt(fixed.FromFloat32(f[0]), fixed.FromFloat32(f[2])).Float32(), f[1])
+				assert.InDelta(t, 0, h.NormalAt(f[0], f[2]).Sub(mgl32.Vec3{f[3], f[4], f[5]}).Len(), 1e-5)
+				assert.True(t, f[1] >= c.Min.Y() && f[1] <= c.Max.Y(), "in the box of the chunk")
+			}
+			normal := corners[1].Sub(corners[0]).Cross(corners[2].Sub(corners[0]))
+			assert.Greater(t, normal.Y(), float32(0), "faces up")
+		}
+	}
+	assert.Equal(t, 9*6, squares, "covers the heightmap")
+	assert.Equal(t, 8, chunks[2].I)
+	assert.Equal(t, float32(9), chunks[2].Max.X(), "smaller at the edge")
+	assert.Equal(t, 4, chunks[3].J)
+}
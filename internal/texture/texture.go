@@ -15,24 +15,11 @@ type Texture struct {
 }
 
 func New(file string) (Texture, error) {
-	imgFile, err := os.Open(file)
+	rgba, err := Decode(file)
 	if err != nil {
-		return Texture{}, fmt.Errorf("texture %q not found on disk: %v", file, err)
-	}
-
-	img, _, err := image.Decode(imgFile)
-	if err != nil {
-		fmt.Println("Decode error")
 		return Texture{}, err
 	}
 
-	rgba := image.NewRGBA(img.Bounds())
-	if rgba.Stride != rgba.Rect.Size().X*4 {
-		return Texture{}, fmt.Errorf("unsupported stride")
-	}
-
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
-
 	var textureHandle uint32
 	gl.GenTextures(1, &textureHandle)
 	gl.ActiveTexture(gl.TEXTURE0)
@@ -56,6 +43,29 @@ func New(file string) (Texture, error) {
 	return Texture{Handle: textureHandle}, nil
 }
 
+// Decode reads an image file, like a PNG, into RGBA pixels.
+func Decode(file string) (*image.RGBA, error) {
+	imgFile, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("texture %q not found on disk: %v", file, err)
+	}
+	defer imgFile.Close()
+
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		fmt.Println("Decode error")
+		return nil, err
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	if rgba.Stride != rgba.Rect.Size().X*4 {
+		return nil, fmt.Errorf("unsupported stride")
+	}
+
+	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+	return rgba, nil
+}
+
 func (t Texture) Bind() {
 	gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindTexture(gl.TEXTURE_2D, t.Handle)
@@ -0,0 +1,35 @@
+package tilemap
+
+import (
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/mesh"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/shader"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// blockDepth is how far below its top a tile is drawn.
+const blockDepth float32 = 1.0
+
+// SpawnBlocks spawns the entities to draw the map with, one block per tile with its top at the height of the
+// tile, and returns them. blockMesh is a cube from -1 to 1, it is drawn with the shader of the terrain of each
+// tile. Tiles of a terrain without a shader are not drawn.
+func (m *Map) SpawnBlocks(w *ecs.World, blockMesh *mesh.Mesh, shaders map[Terrain]*shader.SolidShader) []ecs.Entity {
+	blocks := make([]ecs.Entity, 0, len(m.tiles))
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			t := pathfinding.Tile{X: x, Y: y}
+			s, exists := shaders[m.At(t).Terrain]
+			if !exists {
+				continue
+			}
+			blocks = append(blocks, gameobject.Spawn(w, gameobject.Transform{
+				Position: m.Center(t).Sub(mgl32.Vec3{0, blockDepth / 2, 0}),
+				Scale:    mgl32.Vec3{m.TileSize / 2, blockDepth / 2, m.TileSize / 2},
+			}, gameobject.Solid{Mesh: blockMesh, Shader: s}))
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,265 @@
+// Package tilemap holds the terrain of the world: a grid of tiles, each with a type of terrain and a height.
+//
+// Tile x runs along the world x axis and tile y along the world z axis, like the grids of the pathfinding
+// package, which the map can be used as.
+package tilemap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/pathfinding"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Terrain is the kind of ground a tile is.
+type Terrain uint8
+
+const (
+	Grass Terrain = iota
+	// Forest is slower to walk through than grass.
+	Forest
+	// Water can't be walked on.
+	Water
+	// Rock is rough ground, slower to walk on than forest.
+	Rock
+)
+
+// terrains are the properties of each terrain, and the character it is drawn with by Format.
+var terrains = [...]struct {
+	name string
+	char byte
+	cost fixed.Scalar
+}{
+	Grass:  {name: "grass", char: '.', cost: fixed.FromInt(1)},
+	Forest: {name: "forest", char: 'f', cost: fixed.FromInt(2)},
+	Water:  {name: "water", char: '~', cost: 0},
+	Rock:   {name: "rock", char: '^', cost: fixed.FromInt(3)},
+}
+
+func (t Terrain) String() string {
+	if int(t) < len(terrains) {
+		return terrains[t].name
+	}
+	return "unknown"
+}
+
+// TerrainNamed returns the terrain with a name, like "grass", and whether there is one.
+func TerrainNamed(name string) (Terrain, bool) {
+	for t := range terrains {
+		if terrains[t].name == name {
+			return Terrain(t), true
+		}
+	}
+	return 0, false
+}
+
+// Walkable reports if units can walk on the terrain.
+func (t Terrain) Walkable() bool {
+	return t.Cost() > 0
+}
+
+// Cost returns the cost of walking onto a tile of the terrain, 0 if it can't be walked on.
+func (t Terrain) Cost() fixed.Scalar {
+	if int(t) < len(terrains) {
+		return terrains[t].cost
+	}
+	return 0
+}
+
+// Tile is what is on a tile of the map.
+type Tile struct {
+	Terrain Terrain
+	// Height is the height of the top of the ground.
+	Height float32
+}
+
+// Map is a grid of tiles laid out on the ground plane.
+type Map struct {
+	// Origin is the world x and z of the corner of tile 0,0.
+	Origin   mgl32.Vec2
+	TileSize float32
+
+	width, height int
+	tiles         []Tile
+}
+
+var _ pathfinding.Map = (*Map)(nil)
+
+// New creates a map of grass tiles at height 0.
+func New(width, height int, origin mgl32.Vec2, tileSize float32) *Map {
+	return &Map{
+		Origin:   origin,
+		TileSize: tileSize,
+		width:    width,
+		height:   height,
+		tiles:    make([]Tile, width*height),
+	}
+}
+
+// ErrInvalidMap is returned when parsing a map that is not a rectangle of known terrains.
+var ErrInvalidMap = errors.New("invalid tile map")
+
+// Parse creates a map from ASCII art, one line per row starting with y = 0, with all tiles at the given
+// height. Spaces and tabs around lines and empty lines are ignored.
+//
+//	. grass
+//	f forest
+//	~ water
+//	^ rock
+func Parse(ascii string, origin mgl32.Vec2, tileSize, height float32) (*Map, error) {
+	rows := []string{}
+	for _, line := range strings.Split(ascii, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, line)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidMap)
+	}
+
+	m := New(len(rows[0]), len(rows), origin, tileSize)
+	for y, row := range rows {
+		if len(row) != m.width {
+			return nil, fmt.Errorf("%w: row %d is %d tiles wide, expected %d", ErrInvalidMap, y, len(row), m.width)
+		}
+	row:
+		for x := 0; x < len(row); x++ {
+			for terrain, properties := range terrains {
+				if properties.char == row[x] {
+					m.tiles[y*m.width+x] = Tile{Terrain: Terrain(terrain), Height: height}
+					continue row
+				}
+			}
+			return nil, fmt.Errorf("%w: unknown terrain %q at %d,%d", ErrInvalidMap, row[x], x, y)
+		}
+	}
+	return m, nil
+}
+
+// Format draws the terrain of the map like Parse reads it.
+func (m *Map) Format() string {
+	var sb strings.Builder
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			terrain := m.tiles[y*m.width+x].Terrain
+			if int(terrain) < len(terrains) {
+				sb.WriteByte(terrains[terrain].char)
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (m *Map) Size() (width, height int) {
+	return m.width, m.height
+}
+
+// InBounds reports if a tile is in the map.
+func (m *Map) InBounds(t pathfinding.Tile) bool {
+	return t.X >= 0 && t.Y >= 0 && t.X < m.width && t.Y < m.height
+}
+
+// At returns a tile of the map, tiles outside the map are water.
+func (m *Map) At(t pathfinding.Tile) Tile {
+	if !m.InBounds(t) {
+		return Tile{Terrain: Water}
+	}
+	return m.tiles[t.Y*m.width+t.X]
+}
+
+// Set changes a tile of the map. Tiles outside the map are ignored.
+func (m *Map) Set(t pathfinding.Tile, tile Tile) {
+	if m.InBounds(t) {
+		m.tiles[t.Y*m.width+t.X] = tile
+	}
+}
+
+// SetTerrain changes the terrain of a tile, keeping its height.
+func (m *Map) SetTerrain(t pathfinding.Tile, terrain Terrain) {
+	tile := m.At(t)
+	tile.Terrain = terrain
+	m.Set(t, tile)
+}
+
+// SetHeight changes the height of a tile, keeping its terrain.
+func (m *Map) SetHeight(t pathfinding.Tile, height float32) {
+	tile := m.At(t)
+	tile.Height = height
+	m.Set(t, tile)
+}
+
+// Walkable reports if units can walk on a tile.
+func (m *Map) Walkable(t pathfinding.Tile) bool {
+	return m.At(t).Terrain.Walkable()
+}
+
+// Cost returns the cost of walking onto a tile, 0 if it can't be walked on.
+func (m *Map) Cost(t pathfinding.Tile) fixed.Scalar {
+	return m.At(t).Terrain.Cost()
+}
+
+// TileAt returns the tile a world x and z is on, it may be outside the map. The tile is found in fixed point,
+// so every machine finds the same one.
+func (m *Map) TileAt(position fixed.Vec2) pathfinding.Tile {
+	local := position.Sub(fixed.FromVec2(m.Origin))
+	size := fixed.FromFloat32(m.TileSize)
+	return pathfinding.Tile{X: local.X().Div(size).Int(), Y: local.Y().Div(size).Int()}
+}
+
+// Center returns the center of the top of a tile.
+func (m *Map) Center(t pathfinding.Tile) mgl32.Vec3 {
+	return mgl32.Vec3{
+		m.Origin.X() + (float32(t.X)+0.5)*m.TileSize,
+		m.At(t).Height,
+		m.Origin.Y() + (float32(t.Y)+0.5)*m.TileSize,
+	}
+}
+
+// HeightAt returns the height of the tile at a world x and z, so the map can be the ground units walk on.
+func (m *Map) HeightAt(x, z fixed.Scalar) fixed.Scalar {
+	return fixed.FromFloat32(m.At(m.TileAt(fixed.Vec2{x, z})).Height)
+}
+
+var (
+	straightDirections = [4]pathfinding.Tile{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+	diagonalDirections = [4]pathfinding.Tile{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+)
+
+// Neighbours appends the tiles of the map next to t to neighbours and returns it, the diagonal ones too when
+// diagonals is set.
+func (m *Map) Neighbours(t pathfinding.Tile, diagonals bool, neighbours []pathfinding.Tile) []pathfinding.Tile {
+	for _, d := range straightDirections {
+		if n := (pathfinding.Tile{X: t.X + d.X, Y: t.Y + d.Y}); m.InBounds(n) {
+			neighbours = append(neighbours, n)
+		}
+	}
+	if !diagonals {
+		return neighbours
+	}
+	for _, d := range diagonalDirections {
+		if n := (pathfinding.Tile{X: t.X + d.X, Y: t.Y + d.Y}); m.InBounds(n) {
+			neighbours = append(neighbours, n)
+		}
+	}
+	return neighbours
+}
+
+// Grid returns a pathfinding grid with the costs of the map, each tile split into n by n tiles so that paths
+// can go between things smaller than a tile. Use it for a pathfinding.Navigator with a tile size of
+// TileSize/n and the same origin.
+func (m *Map) Grid(n int) *pathfinding.Grid {
+	g := pathfinding.NewGrid(m.width*n, m.height*n)
+	for y := 0; y < m.height*n; y++ {
+		for x := 0; x < m.width*n; x++ {
+			g.SetCost(pathfinding.Tile{X: x, Y: y}, m.Cost(pathfinding.Tile{X: x / n, Y: y / n}))
+		}
+	}
+	return g
+}
@@ -0,0 +1,176 @@
+package tilemap
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/ecs"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/gameobject"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/shader"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerrain(t *testing.T) {
+	testCases := []struct {
+		terrain  Terrain
+		walkable bool
+		cost     int
+	}{
+		{terrain: Grass, walkable: true, cost: 1},
+		{terrain: Forest, walkable: true, cost: 2},
+		{terrain: Water, walkable: false, cost: 0},
+		{terrain: Rock, walkable: true, cost: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.terrain.String(), func(t *testing.T) {
+			assert.Equal(t, tc.walkable, tc.terrain.Walkable())
+			assert.Equal(t, fixed.FromInt(tc.cost), tc.terrain.Cost())
+			named, exists := TerrainNamed(tc.terrain.String())
+			assert.True(t, exists)
+			assert.Equal(t, tc.terrain, named)
+		})
+	}
+	assert.Equal(t, "unknown", Terrain(10).String())
+	assert.False(t, Terrain(10).Walkable())
+	_, exists := TerrainNamed("lava")
+	assert.False(t, exists)
+}
+
+func TestParse(t *testing.T) {
+	ascii := "..f~\n^.ff\n~~.."
+	m, err := Parse(ascii, mgl32.Vec2{}, 1.0, 2.5)
+	assert.NoError(t, err)
+	assert.Equal(t, ascii+"\n", m.Format())
+	width, height := m.Size()
+	assert.Equal(t, 4, width)
+	assert.Equal(t, 3, height)
+	assert.Equal(t, Tile{Terrain: Rock, Height: 2.5}, m.At(pathfinding.Tile{X: 0, Y: 1}))
+	assert.Equal(t, Tile{Terrain: Water}, m.At(pathfinding.Tile{X: 4, Y: 0}), "outside the map is water")
+
+	_, err = Parse("..\n...", mgl32.Vec2{}, 1.0, 0)
+	assert.ErrorIs(t, err, ErrInvalidMap)
+	_, err = Parse("..x", mgl32.Vec2{}, 1.0, 0)
+	assert.ErrorIs(t, err, ErrInvalidMap)
+	_, err = Parse("  ", mgl32.Vec2{}, 1.0, 0)
+	assert.ErrorIs(t, err, ErrInvalidMap)
+}
+
+func TestSet(t *testing.T) {
+	m := New(2, 2, mgl32.Vec2{}, 1.0)
+	tile := pathfinding.Tile{X: 1, Y: 0}
+	assert.True(t, m.Walkable(tile))
+
+	m.SetHeight(tile, 3)
+	m.SetTerrain(tile, Water)
+	assert.Equal(t, Tile{Terrain: Water, Height: 3}, m.At(tile))
+	assert.False(t, m.Walkable(tile))
+	assert.Equal(t, fixed.Scalar(0), m.Cost(tile))
+
+	m.Set(pathfinding.Tile{X: 2, Y: 0}, Tile{Terrain: Rock})
+	assert.Equal(t, ".~\n..\n", m.Format(), "outside the map is ignored")
+}
+
+// ground returns the world x and z of a position in fixed point.
+func ground(position mgl32.Vec3) fixed.Vec2 {
+	return fixed.Vec2{fixed.FromFloat32(position.X()), fixed.FromFloat32(position.Z())}
+}
+
+func TestCoordinates(t *testing.T) {
+	m := New(10, 10, mgl32.Vec2{-1, -19}, 2.0)
+	m.SetHeight(pathfinding.Tile{X: 3, Y: 4}, 2.5)
+	testCases := []struct {
+		desc     string
+		position mgl32.Vec3
+		tile     pathfinding.Tile
+	}{
+		{desc: "corner of the first tile", position: mgl32.Vec3{-1, 0, -19}, tile: pathfinding.Tile{X: 0, Y: 0}},
+		{desc: "middle", position: mgl32.Vec3{6, 7, -10.5}, tile: pathfinding.Tile{X: 3, Y: 4}},
+		{desc: "just before the next tile", position: mgl32.Vec3{0.99, 0, -17.01}, tile: pathfinding.Tile{X: 0, Y: 0}},
+		{desc: "outside", position: mgl32.Vec3{-1.5, 0, 2}, tile: pathfinding.Tile{X: -1, Y: 10}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.tile, m.TileAt(ground(tc.position)))
+			assert.Equal(t, tc.tile, m.TileAt(ground(m.Center(tc.tile))), "center is on the tile")
+		})
+	}
+	assert.Equal(t, mgl32.Vec3{6, 2.5, -10}, m.Center(pathfinding.Tile{X: 3, Y: 4}), "at the height of the tile")
+	assert.Equal(t, fixed.FromFloat(2.5), m.HeightAt(fixed.FromFloat(5.1), fixed.FromFloat(-9.2)))
+	assert.Equal(t, fixed.Scalar(0), m.HeightAt(fixed.FromFloat(7.1), fixed.FromFloat(-9.2)))
+}
+
+func TestNeighbours(t *testing.T) {
+	m := New(3, 3, mgl32.Vec2{}, 1.0)
+	testCases := []struct {
+		desc      string
+		tile      pathfinding.Tile
+		diagonals bool
+		expected  int
+	}{
+		{desc: "middle", tile: pathfinding.Tile{X: 1, Y: 1}, expected: 4},
+		{desc: "middle with diagonals", tile: pathfinding.Tile{X: 1, Y: 1}, diagonals: true, expected: 8},
+		{desc: "edge", tile: pathfinding.Tile{X: 1, Y: 0}, expected: 3},
+		{desc: "corner with diagonals", tile: pathfinding.Tile{X: 2, Y: 2}, diagonals: true, expected: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			neighbours := m.Neighbours(tc.tile, tc.diagonals, nil)
+			assert.Len(t, neighbours, tc.expected)
+			for _, n := range neighbours {
+				assert.True(t, m.InBounds(n))
+				assert.NotEqual(t, tc.tile, n)
+				assert.LessOrEqual(t, abs(n.X-tc.tile.X)+abs(n.Y-tc.tile.Y), 2)
+			}
+		})
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestPathsAvoidWater(t *testing.T) {
+	m, err := Parse(`
+		.....
+		~~~f~
+		.....`, mgl32.Vec2{}, 1.0, 0)
+	assert.NoError(t, err)
+	path, err := pathfinding.FindPath(m, pathfinding.Tile{X: 0, Y: 0}, pathfinding.Tile{X: 0, Y: 2}, pathfinding.NoDiagonals)
+	assert.NoError(t, err)
+	assert.Contains(t, path, pathfinding.Tile{X: 3, Y: 1}, "crosses through the forest")
+
+	// Splitting tiles keeps their costs
+	g := m.Grid(2)
+	width, height := g.Size()
+	assert.Equal(t, 10, width)
+	assert.Equal(t, 6, height)
+	assert.Equal(t, fixed.FromInt(2), g.Cost(pathfinding.Tile{X: 7, Y: 3}))
+	assert.Equal(t, fixed.Scalar(0), g.Cost(pathfinding.Tile{X: 8, Y: 2}))
+	assert.Equal(t, fixed.One, g.Cost(pathfinding.Tile{X: 9, Y: 5}))
+}
+
+func TestSpawnBlocks(t *testing.T) {
+	m, err := Parse(".~\nf.", mgl32.Vec2{-1, -1}, 2.0, 2.5)
+	assert.NoError(t, err)
+	m.SetHeight(pathfinding.Tile{X: 1, Y: 0}, 2.0)
+	grass, water := &shader.SolidShader{}, &shader.SolidShader{}
+
+	w := ecs.NewWorld()
+	blocks := m.SpawnBlocks(w, nil, map[Terrain]*shader.SolidShader{Grass: grass, Water: water})
+	assert.Len(t, blocks, 3, "forest is not drawn")
+	assert.Equal(t, 3, w.Len())
+	transform := func(i int) gameobject.Transform { return *ecs.Get[gameobject.Transform](w, blocks[i]) }
+	solid := func(i int) gameobject.Solid { return *ecs.Get[gameobject.Solid](w, blocks[i]) }
+	assert.Equal(t, mgl32.Vec3{0, 2, 0}, transform(0).Position)
+	assert.Equal(t, mgl32.Vec3{1, 0.5, 1}, transform(0).Scale)
+	assert.Same(t, grass, solid(0).Shader)
+	assert.Equal(t, mgl32.Vec3{2, 1.5, 0}, transform(1).Position, "top at the height of the tile")
+	assert.Same(t, water, solid(1).Shader)
+	assert.Equal(t, mgl32.Vec3{2, 2, 2}, transform(2).Position)
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/formation"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MoveInFormation orders workers to a destination, where they wait in formation facing the way they went.
+// Each worker is given the slot that keeps the total distance walked low, so they don't cross each other's
+// paths.
+func MoveInFormation(workers []*Worker, destination mgl32.Vec3, f formation.Formation) {
+	targets := f.Arrange(positions(workers), ground(destination))
+	for i, w := range workers {
+		target := targets[i].Float()
+		w.MoveTo(mgl32.Vec3{target.X(), destination.Y(), target.Y()})
+	}
+}
+
+// FollowInFormation orders the leader to a destination and the other workers to follow it in formation. The
+// leader walks a bit slower than usual so the others can keep up.
+func FollowInFormation(leader *Worker, followers []*Worker, destination mgl32.Vec3, f formation.Formation) {
+	leader.give(moveOrder{destination: destination, speed: leaderSpeed})
+
+	// The first slot is the leader's
+	from := leader.agent.Position
+	facing := formation.Facing([]fixed.Vec2{from}, ground(destination))
+	offsets := f.Offsets(len(followers) + 1)[1:]
+	slots := make([]fixed.Vec2, len(offsets))
+	for i, offset := range offsets {
+		slots[i] = from.Add(formation.Rotate(offset, facing))
+	}
+	leader.heading = facing
+
+	for i, slot := range f.Assign(positions(followers), slots) {
+		followers[i].Follow(leader, offsets[slot])
+	}
+}
+
+// positions returns where the workers are on the ground.
+func positions(workers []*Worker) []fixed.Vec2 {
+	positions := make([]fixed.Vec2, len(workers))
+	for i, w := range workers {
+		positions[i] = w.agent.Position
+	}
+	return positions
+}
+
+// moving reports if the worker is walking to where it was ordered to go.
+func (w *Worker) moving() bool {
+	return !w.dead && w.State() == StateMoving
+}
@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/formation"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+// newGroup creates workers standing in a clump around the origin.
+func newGroup(world *World, n int) []*Worker {
+	workers := make([]*Worker, n)
+	for i := range workers {
+		position := mgl32.Vec3{float32(i%3) * 0.3, 2.5, float32(i/3) * 0.3}
+		workers[i] = New(position, world)
+	}
+	return workers
+}
+
+func allIdle(workers []*Worker) func() bool {
+	return func() bool {
+		for _, w := range workers {
+			if w.State() != StateIdle {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestMoveInFormation(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		formation formation.Formation
+	}{
+		{desc: "line", formation: formation.Formation{Shape: formation.Line, Spacing: 0.5}},
+		{desc: "column", formation: formation.Formation{Shape: formation.Column, Spacing: 0.5}},
+		{desc: "box", formation: formation.Formation{Shape: formation.Box, Spacing: 0.5, Assignment: formation.GreedyAssignment}},
+		{desc: "wedge", formation: formation.Formation{Shape: formation.Wedge, Spacing: 0.5}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			world := newTestWorld()
+			workers := newGroup(world, 6)
+			destination := mgl32.Vec3{0.0, 0.0, 6.0}
+			facing := formation.Facing(positions(workers), ground(destination))
+			slots := tc.formation.Slots(len(workers), ground(destination), facing)
+			targets := tc.formation.Arrange(positions(workers), ground(destination))
+
+			MoveInFormation(workers, destination, tc.formation)
+			assert.Equal(t, StateMoving, workers[0].State())
+			run(world, allIdle(workers), workers...)
+
+			for i, w := range workers {
+				assert.Equal(t, StateIdle, w.State())
+				assert.Less(t, w.distanceTo(targets[i]), reachDistance, "worker %d on its slot", i)
+				assert.Contains(t, slots, targets[i])
+				assert.Equal(t, float32(2.5), w.position.Y())
+			}
+		})
+	}
+}
+
+func TestMoveInFormationFacesTheWayOfTravel(t *testing.T) {
+	world := newTestWorld()
+	workers := newGroup(world, 4)
+	MoveInFormation(workers, mgl32.Vec3{10.0, 0.0, 0.3}, formation.Formation{Shape: formation.Line, Spacing: 0.5})
+	run(world, allIdle(workers), workers...)
+
+	// Going along X, the line is spread along Z
+	for _, w := range workers {
+		assert.InDelta(t, 10.0, w.position.X(), 0.1)
+	}
+	assert.Greater(t, spread(workers, 2), float32(1.4))
+}
+
+// spread returns how far apart the workers furthest from each other along an axis are.
+func spread(workers []*Worker, axis int) float32 {
+	lowest, highest := workers[0].position[axis], workers[0].position[axis]
+	for _, w := range workers {
+		if p := w.position[axis]; p < lowest {
+			lowest = p
+		} else if p > highest {
+			highest = p
+		}
+	}
+	return highest - lowest
+}
+
+func TestFollowInFormation(t *testing.T) {
+	world := newTestWorld()
+	workers := newGroup(world, 5)
+	leader, followers := workers[0], workers[1:]
+	f := formation.Formation{Shape: formation.Wedge, Spacing: 0.5}
+	destination := mgl32.Vec3{0.0, 0.0, 8.0}
+
+	FollowInFormation(leader, followers, destination, f)
+	for _, w := range workers {
+		assert.Equal(t, StateMoving, w.State())
+	}
+
+	// On the way the followers keep close to their slots
+	furthest := fixed.Scalar(0)
+	elapsed := float32(0)
+	run(world, func() bool {
+		elapsed += 0.01
+		if elapsed > 2.0 && leader.moving() {
+			for _, w := range followers {
+				slot, _ := w.orderTarget()
+				if d := w.distanceTo(slot); d > furthest {
+					furthest = d
+				}
+			}
+		}
+		return allIdle(workers)()
+	}, workers...)
+	assert.Less(t, furthest, fixed.Half, "kept up with the leader")
+
+	assert.Less(t, leader.distanceTo(ground(destination)), reachDistance)
+	slots := f.FollowSlots(len(workers), ground(destination), fixed.Vec2{0, fixed.One})
+	for _, w := range followers {
+		assert.Equal(t, StateIdle, w.State())
+		closest := fixed.One
+		for _, slot := range slots[1:] {
+			if d := w.distanceTo(slot); d < closest {
+				closest = d
+			}
+		}
+		assert.Less(t, closest, fixed.FromFloat(0.1), "worker at %v is in formation", w.position)
+	}
+	assert.Equal(t, fixed.FromFloat32(walkSpeed), leader.agent.MaxSpeed, "leader walks normally again")
+}
+
+func TestWorkerGoesBackToWorkAfterMoving(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{2.0, 3.0, 0.0})
+	w := New(mgl32.Vec3{}, world)
+	w.Update(0.01)
+	assert.True(t, world.Reservations.Holds(world.Nodes[0], w))
+
+	w.MoveTo(mgl32.Vec3{-1.0, 0.0, -1.0})
+	assert.False(t, world.Reservations.Holds(world.Nodes[0], w), "left the tree for others")
+	run(world, func() bool { return w.State() == StateIdle }, w)
+	assert.Less(t, w.distanceTo(ground(mgl32.Vec3{-1.0, 0.0, -1.0})), reachDistance)
+
+	w.Work()
+	run(world, func() bool { return w.State() == StateHarvesting }, w)
+	assert.Equal(t, StateHarvesting, w.State())
+}
+
+func TestWorkersWithoutStateMachineIgnoreOrders(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{2.0, 3.0, 0.0}, mgl32.Vec3{-2.0, 3.0, 0.0})
+	tree := NewWithBehaviourTree(mgl32.Vec3{}, world)
+	planner := NewWithPlanner(mgl32.Vec3{}, world, 100)
+	leader := New(mgl32.Vec3{}, world)
+	ignoring := []*Worker{tree, planner}
+	for _, w := range ignoring {
+		w.Update(0.01)
+	}
+
+	destination := mgl32.Vec3{-1.0, 0.0, -1.0}
+	for _, w := range ignoring {
+		w.MoveTo(destination)
+		w.Follow(leader, fixed.Vec2{fixed.One, 0})
+		w.Work()
+	}
+	MoveInFormation(append([]*Worker{leader}, ignoring...), destination, formation.Formation{Spacing: 0.5})
+	FollowInFormation(tree, []*Worker{planner, leader}, destination, formation.Formation{Spacing: 0.5})
+	assert.Equal(t, StateMoving, leader.State())
+
+	for _, w := range ignoring {
+		assert.Equal(t, moveOrder{}, w.order)
+		w.Update(0.01)
+		claimed := world.Reservations.Holds(world.Nodes[0], w) || world.Reservations.Holds(world.Nodes[1], w)
+		assert.True(t, claimed, "still working")
+	}
+}
@@ -0,0 +1,276 @@
+package worker
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/goap"
+	"game-engine/rts/internal/resource"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Facts used by the planner.
+const (
+	FactTreesAvailable = "trees-available"
+	FactHasTarget      = "has-target"
+	FactAtTarget       = "at-target"
+	FactCarriedWood    = "carried-wood"
+	FactAtStockpile    = "at-stockpile"
+	FactStockpiledWood = "stockpiled-wood"
+)
+
+// Actions the planner can use.
+const (
+	ActionFindTree  = "find-tree"
+	ActionWalkTo    = "walk-to"
+	ActionChop      = "chop"
+	ActionCarryWood = "carry-wood"
+	ActionDeposit   = "deposit"
+)
+
+// PlannerActions returns the actions a worker that can carry capacity wood can plan with.
+func PlannerActions(capacity int) []goap.Action {
+	return []goap.Action{
+		{
+			Name:          ActionFindTree,
+			Preconditions: []goap.Condition{goap.Is(FactTreesAvailable, 1), goap.Is(FactHasTarget, 0)},
+			Effects:       []goap.Effect{{Fact: FactHasTarget, Value: 1}, {Fact: FactAtTarget, Value: 0}},
+			Cost:          1,
+		},
+		{
+			Name:          ActionWalkTo,
+			Preconditions: []goap.Condition{goap.Is(FactHasTarget, 1), goap.Is(FactAtTarget, 0)},
+			Effects:       []goap.Effect{{Fact: FactAtTarget, Value: 1}, {Fact: FactAtStockpile, Value: 0}},
+			Cost:          2,
+		},
+		{
+			Name: ActionChop,
+			Preconditions: []goap.Condition{
+				goap.Is(FactAtTarget, 1),
+				{Fact: FactCarriedWood, Comparison: goap.AtMost, Value: capacity - 1},
+			},
+			Effects: []goap.Effect{
+				{Fact: FactCarriedWood, Value: capacity},
+				{Fact: FactHasTarget, Value: 0},
+				{Fact: FactAtTarget, Value: 0},
+			},
+			Cost: 3,
+		},
+		{
+			Name: ActionCarryWood,
+			Preconditions: []goap.Condition{
+				{Fact: FactCarriedWood, Comparison: goap.AtLeast, Value: 1},
+				goap.Is(FactAtStockpile, 0),
+			},
+			Effects: []goap.Effect{{Fact: FactAtStockpile, Value: 1}, {Fact: FactAtTarget, Value: 0}},
+			Cost:    2,
+		},
+		{
+			Name: ActionDeposit,
+			Preconditions: []goap.Condition{
+				{Fact: FactCarriedWood, Comparison: goap.AtLeast, Value: 1},
+				goap.Is(FactAtStockpile, 1),
+			},
+			Effects: []goap.Effect{
+				{Fact: FactStockpiledWood, Operation: goap.Add, Value: capacity},
+				{Fact: FactCarriedWood, Value: 0},
+			},
+			Cost: 1,
+		},
+	}
+}
+
+// NewWithPlanner creates a worker that plans how to get its player to have deposited at least goalWood wood.
+// The plan is remade whenever it can't be followed anymore, e.g. if the tree the worker is heading to is
+// removed from the world.
+func NewWithPlanner(position mgl32.Vec3, world *World, goalWood int) *Worker {
+	w := newWorker(position, world)
+	p := &planner{
+		worker:  w,
+		actions: PlannerActions(w.inventory.Capacity(resource.Wood)),
+		goal:    []goap.Condition{{Fact: FactStockpiledWood, Comparison: goap.AtLeast, Value: goalWood}},
+	}
+	w.brain = p.Update
+	w.planner = p
+	return w
+}
+
+type planner struct {
+	worker  *Worker
+	actions []goap.Action
+	goal    []goap.Condition
+
+	plan []*goap.Action
+	step int
+
+	chopProgress fixed.Scalar
+	// fleeing is how long the worker has been fleeing from a threat, while threatened is set
+	threatened bool
+	fleeing    fixed.Scalar
+	// replanIn is the time left until planning is tried again after no plan was needed or found
+	replanIn           fixed.Scalar
+	timeSinceLastPrint float32
+}
+
+func (p *planner) Update(dt float32) {
+	w := p.worker
+	if p.threatened {
+		p.flee(dt)
+		return
+	}
+	if w.currentTarget != nil && !w.holdsTarget() {
+		fmt.Printf("Target is gone, replanning\n")
+		p.plan = nil
+	}
+
+	if p.step >= len(p.plan) {
+		// Don't try to plan every update while idle
+		if p.replanIn > 0 {
+			p.replanIn -= fixed.FromFloat32(dt)
+			p.idle(dt)
+			return
+		}
+		if !p.replan() {
+			p.replanIn = fixed.FromInt(1)
+			p.idle(dt)
+			return
+		}
+	}
+
+	action := p.plan[p.step]
+	done, ok := p.perform(action, dt)
+	if !ok {
+		fmt.Printf("Failed to %s, replanning\n", action.Name)
+		p.plan = nil
+		return
+	}
+	if done {
+		p.step++
+	}
+}
+
+// threaten makes the worker flee, or flee for longer if it already is, and plan again afterwards.
+func (p *planner) threaten() {
+	p.threatened, p.fleeing = true, 0
+	p.plan = nil
+}
+
+func (p *planner) flee(dt float32) {
+	step := fixed.FromFloat32(dt)
+	p.worker.runAway(step)
+	p.fleeing += step
+	if p.fleeing >= fleeDuration {
+		p.threatened, p.fleeing = false, 0
+	}
+}
+
+// action returns the action the planner can use with a name, or nil if there is none.
+func (p *planner) action(name string) *goap.Action {
+	for i := range p.actions {
+		if p.actions[i].Name == name {
+			return &p.actions[i]
+		}
+	}
+	return nil
+}
+
+// WorldState describes the world as seen by the worker.
+func (w *Worker) WorldState() goap.WorldState {
+	ws := goap.WorldState{
+		FactCarriedWood:    w.inventory.Amount(resource.Wood),
+		FactStockpiledWood: w.world.Resources.Get(w.player, resource.Wood),
+	}
+	if _, exists := w.world.NearestNode(resource.Wood, w.position, w); exists {
+		ws[FactTreesAvailable] = 1
+	}
+	if w.currentTarget != nil && w.world.Reservations.Available(w.currentTarget, w) && w.world.NodeExists(w.currentTarget) {
+		ws[FactHasTarget] = 1
+		if w.distanceToTarget() < reachDistance {
+			ws[FactAtTarget] = 1
+		}
+	}
+	if stockpile := w.nearestStockpile(); stockpile != nil {
+		if w.distanceTo(ground(stockpile.Position)) < reachDistance {
+			ws[FactAtStockpile] = 1
+		}
+	}
+	return ws
+}
+
+func (p *planner) replan() bool {
+	p.plan, p.step = nil, 0
+	ws := p.worker.WorldState()
+	if ws.Satisfies(p.goal) {
+		return false
+	}
+
+	plan, err := goap.Plan(ws, p.goal, p.actions)
+	if err != nil {
+		fmt.Printf("Worker can't plan: %v\n", err)
+		return false
+	}
+	p.plan = plan
+	return len(plan) > 0
+}
+
+func (p *planner) idle(dt float32) {
+	p.timeSinceLastPrint += dt
+	if p.timeSinceLastPrint > 1.0 {
+		p.timeSinceLastPrint = 0.0
+		fmt.Printf("Worker is chillin\n")
+	}
+}
+
+// perform runs an action for one update. It returns if the action is done, and false for ok if it can't
+// be performed anymore.
+func (p *planner) perform(action *goap.Action, dt float32) (done, ok bool) {
+	w := p.worker
+	switch action.Name {
+	case ActionFindTree:
+		w.currentTarget = w.claimClosestNode()
+		p.chopProgress = 0
+		return true, w.currentTarget != nil
+
+	case ActionWalkTo:
+		if !w.holdsTarget() {
+			return false, false
+		}
+		return w.moveTo(w.currentTarget.Position(), dt) < reachDistance, true
+
+	case ActionChop:
+		if !w.holdsTarget() {
+			return false, false
+		}
+		// The worker forgets about the target if it is depleted while harvesting
+		node := w.currentTarget
+		p.chopProgress = w.harvestFrom(node, p.chopProgress, dt)
+		if w.full() {
+			w.currentTarget = nil
+			w.releaseClaims()
+			return true, true
+		}
+		if !w.world.NodeExists(node) {
+			w.currentTarget = nil
+			return true, true
+		}
+		return false, true
+
+	case ActionCarryWood:
+		stockpile := w.nearestStockpile()
+		if stockpile == nil {
+			return false, false
+		}
+		return w.moveToStockpile(stockpile, dt) < reachDistance, true
+
+	case ActionDeposit:
+		stockpile := w.nearestStockpile()
+		if stockpile == nil {
+			return false, false
+		}
+		w.deposit(stockpile)
+		return true, true
+	}
+
+	return false, false
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/resource"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlannerFillsStockpile(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-1.0, 3.0, 0.0}, mgl32.Vec3{0.0, 3.0, 3.0})
+	w := NewWithPlanner(mgl32.Vec3{}, world, 10)
+
+	for i := 0; i < 10000; i++ {
+		world.Update(0.01)
+		w.Update(0.01)
+	}
+
+	assert.Equal(t, 10, world.Resources.Get(0, resource.Wood))
+	assert.Len(t, world.Nodes, 2, "stops when the goal is reached")
+	assert.True(t, w.Inventory().Empty())
+}
+
+func TestPlannerReplansWhenTargetIsRemoved(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-3.0, 3.0, 0.0})
+	first, second := world.Nodes[0], world.Nodes[1]
+	w := NewWithPlanner(mgl32.Vec3{}, world, 5)
+	w.Update(0.01)
+	w.Update(0.01)
+	assert.Same(t, first, w.currentTarget)
+
+	world.RemoveNode(first)
+	w.Update(0.01)
+	w.Update(0.01)
+
+	assert.Same(t, second, w.currentTarget)
+}
+
+func TestWorldState(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0})
+	world.Resources.Add(0, resource.Wood, 3)
+	w := NewWithPlanner(mgl32.Vec3{}, world, 10)
+	assert.True(t, w.claim(world.Nodes[0]))
+	w.currentTarget = world.Nodes[0]
+	w.Inventory().Add(resource.Wood, 2)
+
+	ws := w.WorldState()
+
+	assert.Equal(t, 1, ws[FactTreesAvailable])
+	assert.Equal(t, 1, ws[FactHasTarget])
+	assert.Equal(t, 0, ws[FactAtTarget])
+	assert.Equal(t, 1, ws[FactAtStockpile])
+	assert.Equal(t, 2, ws[FactCarriedWood])
+	assert.Equal(t, 3, ws[FactStockpiledWood])
+
+	other := NewWithPlanner(mgl32.Vec3{}, world, 10)
+	assert.Equal(t, 0, other.WorldState()[FactTreesAvailable], "the only tree is claimed")
+}
@@ -0,0 +1,271 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+
+	"game-engine/rts/internal/bt"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/fsm"
+	"game-engine/rts/internal/goap"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/steering"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ErrOtherBrain is returned when restoring a worker from a snapshot of a worker controlled by another kind of
+// brain.
+var ErrOtherBrain = errors.New("snapshot is of a worker with another brain")
+
+// none is the index of a node or worker that isn't there in a Snapshot.
+const none = -1
+
+// Snapshot is the state of a worker, for saving games. Nodes and workers are referred to by an index given by
+// the game, see Worker.Snapshot.
+type Snapshot struct {
+	Player resource.Player `json:"player"`
+	Stats  Stats           `json:"stats"`
+	// Position is where the worker is on the ground, Height how high up it is drawn.
+	Position  fixed.Vec2    `json:"position"`
+	Height    float32       `json:"height"`
+	Velocity  fixed.Vec2    `json:"velocity"`
+	MaxSpeed  fixed.Scalar  `json:"maxSpeed"`
+	Heading   fixed.Vec2    `json:"heading"`
+	Dead      bool          `json:"dead,omitempty"`
+	Gathering resource.Kind `json:"gathering"`
+	// Carrying is how much of each kind of resource the worker carries.
+	Carrying map[resource.Kind]int `json:"carrying,omitempty"`
+	// Target is the node the worker is going for or harvesting.
+	Target   int            `json:"target"`
+	Threat   mgl32.Vec3     `json:"threat"`
+	Path     *steering.Path `json:"path,omitempty"`
+	PathGoal mgl32.Vec3     `json:"pathGoal"`
+	Order    OrderSnapshot  `json:"order"`
+	// FSM and States are where the state machine of a worker controlled by one is, Tree is where the
+	// behaviour tree of a worker controlled by one is, and Plan what a worker controlled by a planner is doing.
+	FSM    *fsm.Snapshot  `json:"fsm,omitempty"`
+	States *StateSnapshot `json:"states,omitempty"`
+	Tree   *TreeSnapshot  `json:"tree,omitempty"`
+	Plan   *PlanSnapshot  `json:"plan,omitempty"`
+}
+
+// OrderSnapshot is where a worker was told to go.
+type OrderSnapshot struct {
+	Destination mgl32.Vec3 `json:"destination"`
+	// Leader is the worker followed, at Offset.
+	Leader int        `json:"leader"`
+	Offset fixed.Vec2 `json:"offset"`
+	Speed  float32    `json:"speed"`
+}
+
+// StateSnapshot is what the states of the state machine keep, mostly timers.
+type StateSnapshot struct {
+	IdlePrint       float32      `json:"idlePrint"`
+	FleeTime        fixed.Scalar `json:"fleeTime"`
+	HarvestPrint    float32      `json:"harvestPrint"`
+	HarvestProgress fixed.Scalar `json:"harvestProgress"`
+	// Harvested is the node the harvest progress is for.
+	Harvested    int     `json:"harvested"`
+	WalkPrint    float32 `json:"walkPrint"`
+	DeliverPrint float32 `json:"deliverPrint"`
+	MovePrint    float32 `json:"movePrint"`
+}
+
+// TreeSnapshot is where the behaviour tree of a worker is and what is on its blackboard.
+type TreeSnapshot struct {
+	Tree bt.Snapshot `json:"tree"`
+	// Target is the node on the blackboard, HarvestProgress how far the worker has come harvesting the next
+	// unit of it.
+	Target          int          `json:"target"`
+	HarvestProgress fixed.Scalar `json:"harvestProgress"`
+	// FleeTime is how long the worker has been fleeing, when Fleeing.
+	Fleeing  bool         `json:"fleeing,omitempty"`
+	FleeTime fixed.Scalar `json:"fleeTime,omitempty"`
+}
+
+// PlanSnapshot is what a worker controlled by a planner is doing.
+type PlanSnapshot struct {
+	// Actions are the names of the actions of the plan, Step is the one being performed.
+	Actions      []string     `json:"actions,omitempty"`
+	Step         int          `json:"step"`
+	ChopProgress fixed.Scalar `json:"chopProgress"`
+	ReplanIn     fixed.Scalar `json:"replanIn"`
+	IdlePrint    float32      `json:"idlePrint"`
+	// FleeTime is how long the worker has been fleeing, when Fleeing.
+	Fleeing  bool         `json:"fleeing,omitempty"`
+	FleeTime fixed.Scalar `json:"fleeTime,omitempty"`
+}
+
+// Snapshot returns the state of the worker. nodeIndex and workerIndex give the indices the game refers to
+// nodes and workers by.
+func (w *Worker) Snapshot(nodeIndex func(*resource.Node) int, workerIndex func(*Worker) int) Snapshot {
+	nodeOrNone := func(node *resource.Node) int {
+		if node == nil {
+			return none
+		}
+		return nodeIndex(node)
+	}
+	s := Snapshot{
+		Player:    w.player,
+		Stats:     w.stats,
+		Position:  w.agent.Position,
+		Height:    w.position.Y(),
+		Velocity:  w.agent.Velocity,
+		MaxSpeed:  w.agent.MaxSpeed,
+		Heading:   w.heading,
+		Dead:      w.dead,
+		Gathering: w.gathering,
+		Target:    nodeOrNone(w.currentTarget),
+		Threat:    w.threat,
+		PathGoal:  w.pathGoal,
+		Order: OrderSnapshot{
+			Destination: w.order.destination,
+			Leader:      none,
+			Offset:      w.order.offset,
+			Speed:       w.order.speed,
+		},
+	}
+	switch {
+	case w.fsm != nil:
+		machine := w.fsm.Snapshot()
+		s.FSM = &machine
+		s.States = &StateSnapshot{
+			IdlePrint:       w.idleState.timeSinceLastPrint,
+			FleeTime:        w.fleeState.timeFleeing,
+			HarvestPrint:    w.harvestingState.timeSinceLastPrint,
+			HarvestProgress: w.harvestingState.progress,
+			Harvested:       nodeOrNone(w.harvestingState.target),
+			WalkPrint:       w.walkState.timeSinceLastPrint,
+			DeliverPrint:    w.deliveringState.timeSinceLastPrint,
+			MovePrint:       w.movingState.timeSinceLastPrint,
+		}
+	case w.tree != nil:
+		target, _ := bt.Get[*resource.Node](w.tree.Blackboard(), keyTarget)
+		progress, _ := bt.Get[fixed.Scalar](w.tree.Blackboard(), keyHarvestProgress)
+		s.Tree = &TreeSnapshot{Tree: w.tree.Snapshot(), Target: nodeOrNone(target), HarvestProgress: progress}
+		s.Tree.FleeTime, s.Tree.Fleeing = bt.Get[fixed.Scalar](w.tree.Blackboard(), keyFleeing)
+	case w.planner != nil:
+		p := w.planner
+		s.Plan = &PlanSnapshot{
+			Step:         p.step,
+			ChopProgress: p.chopProgress,
+			ReplanIn:     p.replanIn,
+			IdlePrint:    p.timeSinceLastPrint,
+			Fleeing:      p.threatened,
+			FleeTime:     p.fleeing,
+		}
+		for _, action := range p.plan {
+			s.Plan.Actions = append(s.Plan.Actions, action.Name)
+		}
+	}
+	for _, kind := range w.inventory.Kinds() {
+		if s.Carrying == nil {
+			s.Carrying = map[resource.Kind]int{}
+		}
+		s.Carrying[kind] = w.inventory.Amount(kind)
+	}
+	if w.path != nil {
+		s.Path = &steering.Path{Points: append([]fixed.Vec2{}, w.path.Points...), Next: w.path.Next}
+	}
+	if w.order.leader != nil {
+		s.Order.Leader = workerIndex(w.order.leader)
+	}
+	return s
+}
+
+// Restore puts a worker back in the state of a snapshot of a worker with the same kind of brain, without it
+// leaving or entering any states or resetting any nodes of its tree. node and worker return the node and
+// worker at an index, nil if there is none.
+func (w *Worker) Restore(s Snapshot, node func(int) *resource.Node, worker func(int) *Worker) error {
+	if err := w.restoreBrain(s, node); err != nil {
+		return err
+	}
+	w.SetPlayer(s.Player)
+	w.SetStats(s.Stats)
+	for kind, amount := range s.Carrying {
+		w.inventory.Add(kind, amount)
+	}
+	w.agent.Position = s.Position
+	w.position[1] = s.Height
+	w.syncPosition()
+	w.agent.Velocity = s.Velocity
+	w.agent.MaxSpeed = s.MaxSpeed
+	w.heading = s.Heading
+	w.gathering = s.Gathering
+	w.currentTarget = node(s.Target)
+	w.threat = s.Threat
+	w.path = nil
+	if s.Path != nil {
+		w.path = &steering.Path{Points: append([]fixed.Vec2{}, s.Path.Points...), Next: s.Path.Next}
+	}
+	w.pathGoal = s.PathGoal
+	w.order = moveOrder{
+		destination: s.Order.Destination,
+		leader:      worker(s.Order.Leader),
+		offset:      s.Order.Offset,
+		speed:       s.Order.Speed,
+	}
+
+	if s.Dead && !w.dead {
+		w.Die()
+	}
+	return nil
+}
+
+// restoreBrain puts the state machine, behaviour tree or planner of the worker back in the state of a
+// snapshot.
+func (w *Worker) restoreBrain(s Snapshot, node func(int) *resource.Node) error {
+	switch {
+	case w.fsm != nil && s.FSM != nil && s.States != nil:
+		if err := w.fsm.Restore(*s.FSM); err != nil {
+			return err
+		}
+		w.idleState.timeSinceLastPrint = s.States.IdlePrint
+		w.fleeState.timeFleeing = s.States.FleeTime
+		w.harvestingState.timeSinceLastPrint = s.States.HarvestPrint
+		w.harvestingState.progress = s.States.HarvestProgress
+		w.harvestingState.target = node(s.States.Harvested)
+		w.walkState.timeSinceLastPrint = s.States.WalkPrint
+		w.deliveringState.timeSinceLastPrint = s.States.DeliverPrint
+		w.movingState.timeSinceLastPrint = s.States.MovePrint
+
+	case w.tree != nil && s.Tree != nil:
+		if err := w.tree.Restore(s.Tree.Tree); err != nil {
+			return err
+		}
+		blackboard := w.tree.Blackboard()
+		blackboard.Delete(keyTarget)
+		if target := node(s.Tree.Target); target != nil {
+			blackboard.Set(keyTarget, target)
+		}
+		blackboard.Set(keyHarvestProgress, s.Tree.HarvestProgress)
+		blackboard.Delete(keyFleeing)
+		if s.Tree.Fleeing {
+			blackboard.Set(keyFleeing, s.Tree.FleeTime)
+		}
+
+	case w.planner != nil && s.Plan != nil:
+		p := w.planner
+		var plan []*goap.Action
+		for _, name := range s.Plan.Actions {
+			action := p.action(name)
+			if action == nil {
+				return fmt.Errorf("%w: unknown action %q", ErrOtherBrain, name)
+			}
+			plan = append(plan, action)
+		}
+		if s.Plan.Step < 0 || s.Plan.Step > len(plan) {
+			return fmt.Errorf("%w: step %d of a plan of %d actions", ErrOtherBrain, s.Plan.Step, len(plan))
+		}
+		p.plan, p.step = plan, s.Plan.Step
+		p.chopProgress = s.Plan.ChopProgress
+		p.replanIn = s.Plan.ReplanIn
+		p.timeSinceLastPrint = s.Plan.IdlePrint
+		p.threatened, p.fleeing = s.Plan.Fleeing, s.Plan.FleeTime
+
+	default:
+		return ErrOtherBrain
+	}
+	return nil
+}
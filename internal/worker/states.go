@@ -0,0 +1,230 @@
+package worker
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/resource"
+)
+
+type WorkerIdleState struct {
+	worker             *Worker
+	timeSinceLastPrint float32
+}
+
+func (s *WorkerIdleState) OnUpdate(dt float32) {
+	s.timeSinceLastPrint += dt
+	if s.timeSinceLastPrint > 1.0 {
+		s.timeSinceLastPrint = 0.0
+		fmt.Printf("Worker is chillin\n")
+	}
+}
+func (s *WorkerIdleState) OnEnter() {
+	s.timeSinceLastPrint = 0.0
+	fmt.Printf("Entering idle state\n")
+}
+func (s *WorkerIdleState) OnLeave() { fmt.Printf("Leaving idle state\n") }
+
+var (
+	fleeDuration = fixed.FromInt(3)
+	fleeSpeed    = fixed.FromInt(2)
+)
+
+type WorkerFleeState struct {
+	worker      *Worker
+	timeFleeing fixed.Scalar
+}
+
+func (s *WorkerFleeState) OnUpdate(dt float32) {
+	step := fixed.FromFloat32(dt)
+	s.worker.runAway(step)
+
+	s.timeFleeing += step
+	if s.timeFleeing >= fleeDuration {
+		s.worker.Work()
+	}
+}
+func (s *WorkerFleeState) OnEnter() {
+	s.timeFleeing = 0
+	s.worker.forgetPath()
+	fmt.Printf("Entering flee state\n")
+}
+func (s *WorkerFleeState) OnLeave() { fmt.Printf("Leaving flee state\n") }
+
+type WorkerWorkingState struct {
+	worker *Worker
+}
+
+func (s *WorkerWorkingState) OnUpdate(dt float32) {}
+func (s *WorkerWorkingState) OnEnter()            { fmt.Printf("Entering working state\n") }
+
+// OnLeave lets other workers harvest the node while this worker does something else. The claim is taken
+// again if the node is still free when going back to work.
+func (s *WorkerWorkingState) OnLeave() {
+	s.worker.releaseClaims()
+	fmt.Printf("Leaving working state\n")
+}
+
+type WorkerHarvestingState struct {
+	worker             *Worker
+	timeSinceLastPrint float32
+	// progress is how far the worker has come harvesting the next unit of the resource
+	progress fixed.Scalar
+	// target is the node being harvested, progress is kept when resuming harvesting the same node
+	target *resource.Node
+}
+
+func (s *WorkerHarvestingState) OnUpdate(dt float32) {
+	if !s.worker.holdsTarget() {
+		s.worker.PickNode()
+		return
+	}
+	// The worker might have been chased away from the node while harvesting
+	if s.worker.distanceToTarget() >= reachDistance {
+		s.worker.Walk()
+		return
+	}
+
+	s.timeSinceLastPrint += dt
+	if s.timeSinceLastPrint > 1.0 {
+		s.timeSinceLastPrint = 0
+		kind := s.worker.gathering
+		fmt.Printf("Harvesting %s: carrying %d/%d\n", kind, s.worker.inventory.Amount(kind), s.worker.inventory.Capacity(kind))
+	}
+
+	node := s.worker.currentTarget
+	s.progress = s.worker.harvestFrom(node, s.progress, dt)
+	if s.worker.full() {
+		s.worker.Deliver()
+		return
+	}
+	if !s.worker.world.NodeExists(node) {
+		s.worker.currentTarget = nil
+		s.worker.PickNode()
+	}
+}
+func (s *WorkerHarvestingState) OnEnter() {
+	if s.target != s.worker.currentTarget {
+		s.target = s.worker.currentTarget
+		s.progress = 0
+	}
+	s.timeSinceLastPrint = 0
+	fmt.Printf("Entering harvesting state\n")
+}
+func (s *WorkerHarvestingState) OnLeave() { fmt.Printf("Leaving harvesting state\n") }
+
+type WorkerPickingState struct {
+	worker *Worker
+}
+
+func (s *WorkerPickingState) OnUpdate(dt float32) {
+	node := s.worker.claimClosestNode()
+	if node == nil {
+		fmt.Printf("No more %s\n", s.worker.gathering)
+		if !s.worker.inventory.Empty() {
+			s.worker.Deliver()
+			return
+		}
+		s.worker.Idle()
+		return
+	}
+	s.worker.currentTarget = node
+	s.worker.Walk()
+}
+func (s *WorkerPickingState) OnEnter() { fmt.Printf("Entering picking state\n") }
+func (s *WorkerPickingState) OnLeave() { fmt.Printf("Leaving picking state\n") }
+
+type WorkerWalkState struct {
+	worker             *Worker
+	timeSinceLastPrint float32
+}
+
+func (s *WorkerWalkState) OnUpdate(dt float32) {
+	if !s.worker.holdsTarget() {
+		s.worker.PickNode()
+		return
+	}
+	remainingDistance := s.worker.moveTo(s.worker.currentTarget.Position(), dt)
+	if remainingDistance < reachDistance {
+		s.worker.Harvest()
+		return
+	}
+
+	s.timeSinceLastPrint += dt
+	if s.timeSinceLastPrint > 1.0 {
+		s.timeSinceLastPrint = 0.0
+		fmt.Printf("Walking towards %s node, remaining distance: %v\n", s.worker.currentTarget.Kind, remainingDistance)
+	}
+}
+func (s *WorkerWalkState) OnEnter() { fmt.Printf("Entering walking state\n") }
+func (s *WorkerWalkState) OnLeave() { fmt.Printf("Leaving walking state\n") }
+
+// WorkerDeliveringState carries what the worker has harvested to the nearest stockpile. The node the worker
+// was harvesting is left for others in the meantime, the worker picks the closest node again when done.
+type WorkerDeliveringState struct {
+	worker             *Worker
+	timeSinceLastPrint float32
+}
+
+func (s *WorkerDeliveringState) OnUpdate(dt float32) {
+	stockpile := s.worker.nearestStockpile()
+	if stockpile == nil {
+		fmt.Printf("No stockpile to deliver to\n")
+		s.worker.Idle()
+		return
+	}
+	remainingDistance := s.worker.moveToStockpile(stockpile, dt)
+	if remainingDistance < reachDistance {
+		s.worker.deposit(stockpile)
+		s.worker.PickNode()
+		return
+	}
+
+	s.timeSinceLastPrint += dt
+	if s.timeSinceLastPrint > 1.0 {
+		s.timeSinceLastPrint = 0.0
+		fmt.Printf("Walking towards stockpile, remaining distance: %v\n", remainingDistance)
+	}
+}
+func (s *WorkerDeliveringState) OnEnter() {
+	s.worker.currentTarget = nil
+	s.worker.releaseClaims()
+	fmt.Printf("Entering delivering state\n")
+}
+func (s *WorkerDeliveringState) OnLeave() { fmt.Printf("Leaving delivering state\n") }
+
+// WorkerMovingState walks to where the worker was ordered to go, then idles. Workers following a leader keep
+// to their slot next to it until it has stopped.
+type WorkerMovingState struct {
+	worker             *Worker
+	timeSinceLastPrint float32
+}
+
+func (s *WorkerMovingState) OnUpdate(dt float32) {
+	target, following := s.worker.orderTarget()
+	var remainingDistance fixed.Scalar
+	if following {
+		// The slot moves with the leader, so there is no point finding a path to it
+		remainingDistance = s.worker.walkTowards(target, dt)
+	} else {
+		remainingDistance = s.worker.moveTo(s.worker.order.destination, dt)
+	}
+	if remainingDistance < reachDistance && (!following || !s.worker.order.leader.moving()) {
+		s.worker.Idle()
+		return
+	}
+
+	s.timeSinceLastPrint += dt
+	if s.timeSinceLastPrint > 1.0 {
+		s.timeSinceLastPrint = 0.0
+		fmt.Printf("Moving, remaining distance: %v\n", remainingDistance)
+	}
+}
+func (s *WorkerMovingState) OnEnter() {
+	s.worker.agent.MaxSpeed = fixed.FromFloat32(s.worker.stats.WalkSpeed).Mul(fixed.FromFloat32(s.worker.order.speed))
+	fmt.Printf("Entering moving state\n")
+}
+func (s *WorkerMovingState) OnLeave() {
+	s.worker.agent.MaxSpeed = fixed.FromFloat32(s.worker.stats.WalkSpeed)
+	fmt.Printf("Leaving moving state\n")
+}
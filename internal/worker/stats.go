@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"game-engine/rts/internal/resource"
+)
+
+// Stats describe how fast a worker walks and harvests and how much it can carry. They are plain data so different
+// kinds of workers can be made without changing code.
+type Stats struct {
+	// HarvestRate is how much of each kind of resource is harvested per second.
+	HarvestRate map[resource.Kind]float32
+	// CarryCapacity is how much of each kind of resource can be carried before going to a stockpile.
+	CarryCapacity map[resource.Kind]int
+	// WalkSpeed is how fast the worker walks, in units per second. Zero uses the default.
+	WalkSpeed float32
+	// Acceleration is how fast the worker speeds up, slows down and turns, in units per second squared. Zero
+	// uses the default.
+	Acceleration float32
+}
+
+// DefaultStats returns the stats of a regular worker.
+func DefaultStats() Stats {
+	return Stats{
+		HarvestRate: map[resource.Kind]float32{
+			resource.Wood:  1.0,
+			resource.Stone: 1.0,
+			resource.Gold:  1.0,
+			resource.Food:  1.5,
+		},
+		CarryCapacity: map[resource.Kind]int{
+			resource.Wood:  4,
+			resource.Stone: 4,
+			resource.Gold:  4,
+			resource.Food:  4,
+		},
+		WalkSpeed:    walkSpeed,
+		Acceleration: walkAcceleration,
+	}
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/bt"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/resource"
+)
+
+// Blackboard keys used by the worker behaviour tree.
+const (
+	keyTarget          = "target"
+	keyHarvestProgress = "harvest-progress"
+	// keyFleeing is how long the worker has been fleeing, it is only set while fleeing
+	keyFleeing = "fleeing"
+)
+
+// NewBehaviourTree creates a tree doing the same thing as the worker state machine:
+//
+//	selector
+//	  sequence
+//	    threatened
+//	    flee
+//	  sequence
+//	    has room
+//	    pick node
+//	    walk to node
+//	    harvest node
+//	  sequence
+//	    carries something
+//	    walk to stockpile
+//	    deposit
+//	  cooldown 1s
+//	    idle
+//
+// The target, harvesting progress and how long the worker has been fleeing are kept on the blackboard of the
+// tree. A threat resets the tree, so the worker flees whatever it was doing. Walking and harvesting fail if
+// the claim on the target is lost, so the worker picks a new node. Harvesting succeeds when the worker can't
+// carry more or the node is depleted, the worker then goes on to the next node or to the stockpile.
+func NewBehaviourTree(w *Worker) *bt.Tree {
+	threatened := bt.NewCondition(func(ctx *bt.Context) bool {
+		return ctx.Blackboard.Has(keyFleeing)
+	})
+
+	flee := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		step := fixed.FromFloat32(ctx.DT)
+		w.runAway(step)
+		fleeing, _ := bt.Get[fixed.Scalar](ctx.Blackboard, keyFleeing)
+		fleeing += step
+		if fleeing >= fleeDuration {
+			ctx.Blackboard.Delete(keyFleeing)
+			return bt.Success
+		}
+		ctx.Blackboard.Set(keyFleeing, fleeing)
+		return bt.Running
+	})
+
+	hasRoom := bt.NewCondition(func(ctx *bt.Context) bool {
+		return !w.full()
+	})
+
+	pickNode := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		node := w.claimClosestNode()
+		if node == nil {
+			return bt.Failure
+		}
+		if target, _ := bt.Get[*resource.Node](ctx.Blackboard, keyTarget); target != node {
+			ctx.Blackboard.Set(keyHarvestProgress, fixed.Scalar(0))
+		}
+		ctx.Blackboard.Set(keyTarget, node)
+		return bt.Success
+	})
+
+	walkToNode := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		target, ok := bt.Get[*resource.Node](ctx.Blackboard, keyTarget)
+		if !ok || !w.claim(target) {
+			return bt.Failure
+		}
+		if w.moveTo(target.Position(), ctx.DT) < reachDistance {
+			return bt.Success
+		}
+		return bt.Running
+	})
+
+	harvestNode := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		target, ok := bt.Get[*resource.Node](ctx.Blackboard, keyTarget)
+		if !ok || !w.claim(target) {
+			return bt.Failure
+		}
+		progress, _ := bt.Get[fixed.Scalar](ctx.Blackboard, keyHarvestProgress)
+		progress = w.harvestFrom(target, progress, ctx.DT)
+		ctx.Blackboard.Set(keyHarvestProgress, progress)
+		if !w.world.NodeExists(target) {
+			ctx.Blackboard.Delete(keyTarget)
+			return bt.Success
+		}
+		if w.full() {
+			w.releaseClaims()
+			return bt.Success
+		}
+		return bt.Running
+	})
+
+	isCarrying := bt.NewCondition(func(ctx *bt.Context) bool {
+		return !w.inventory.Empty()
+	})
+
+	walkToStockpile := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		stockpile := w.nearestStockpile()
+		if stockpile == nil {
+			return bt.Failure
+		}
+		if w.moveToStockpile(stockpile, ctx.DT) < reachDistance {
+			return bt.Success
+		}
+		return bt.Running
+	})
+
+	deposit := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		stockpile := w.nearestStockpile()
+		if stockpile == nil {
+			return bt.Failure
+		}
+		w.deposit(stockpile)
+		return bt.Success
+	})
+
+	idle := bt.NewAction(func(ctx *bt.Context) bt.Status {
+		fmt.Printf("Worker is chillin\n")
+		return bt.Success
+	})
+
+	return bt.New(bt.NewSelector(
+		bt.NewSequence(threatened, flee),
+		bt.NewSequence(hasRoom, pickNode, walkToNode, harvestNode),
+		bt.NewSequence(isCarrying, walkToStockpile, deposit),
+		bt.NewCooldown(1.0, idle),
+	))
+}
+
+// threatenTree makes a worker controlled by a tree start fleeing, or flee for longer if it already is.
+func threatenTree(tree *bt.Tree) {
+	tree.Blackboard().Set(keyFleeing, fixed.Scalar(0))
+	tree.Reset()
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/resource"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBehaviourTreeChopsAllTrees(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-1.0, 3.0, 0.5})
+	first := world.Nodes[0]
+	world.Stockpiles[0].Position = mgl32.Vec3{2.0, 2.5, 1.0}
+	w := newWorker(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+	tree := NewBehaviourTree(w)
+
+	tree.Tick(0.01)
+	target, _ := tree.Blackboard().Get(keyTarget)
+	assert.Same(t, first, target)
+
+	for i := 0; i < 20000 && (len(world.Nodes) > 0 || !w.inventory.Empty()); i++ {
+		tree.Tick(0.01)
+	}
+
+	assert.Empty(t, world.Nodes)
+	assert.False(t, tree.Blackboard().Has(keyTarget))
+	assert.Equal(t, 2*resource.Tree.Amount, world.Resources.Get(0, resource.Wood))
+	assert.InDelta(t, 2.0, w.position.X(), reachDistance.Float())
+	assert.InDelta(t, 1.0, w.position.Z(), reachDistance.Float())
+}
+
+func TestBehaviourTreePicksNewTreeWhenTargetIsRemoved(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-2.0, 3.0, 0.0})
+	first, second := world.Nodes[0], world.Nodes[1]
+	w := NewWithBehaviourTree(mgl32.Vec3{}, world)
+	w.Update(0.01)
+
+	world.RemoveNode(first)
+	w.Update(0.01)
+	w.Update(0.01)
+
+	assert.True(t, world.Reservations.Holds(second, w))
+}
+
+func TestWorkerWithBehaviourTree(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{0.5, 3.0, 0.0})
+	w := NewWithBehaviourTree(mgl32.Vec3{}, world)
+
+	run(world, func() bool { return len(world.Nodes) == 0 && w.inventory.Empty() }, w)
+
+	assert.Empty(t, world.Nodes)
+	assert.Equal(t, resource.Tree.Amount, world.Resources.Get(0, resource.Wood))
+	assert.Empty(t, w.State())
+}
@@ -0,0 +1,567 @@
+package worker
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/bt"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/fsm"
+	"game-engine/rts/internal/reservation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/spatial"
+	"game-engine/rts/internal/steering"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	StateIdle    fsm.StateID = "idle"
+	StateFleeing fsm.StateID = "fleeing"
+	// StateWorking is the parent of the states used to gather resources. It resumes the last active child when
+	// entered again, so a worker continues where it left off after fleeing.
+	StateWorking    fsm.StateID = "working"
+	StatePicking    fsm.StateID = "picking"
+	StateWalk       fsm.StateID = "walk"
+	StateHarvesting fsm.StateID = "harvesting"
+	StateDelivering fsm.StateID = "delivering"
+	// StateMoving walks to where the worker was ordered to go, then idles there.
+	StateMoving fsm.StateID = "moving"
+)
+
+const (
+	walkSpeed        float32 = 1.0
+	walkAcceleration float32 = 8.0
+	// leaderSpeed is how fast a worker leading others walks compared to its walk speed, so they can keep up.
+	leaderSpeed float32 = 0.8
+)
+
+// Distances are in fixed point, like the positions of workers, so workers arrive on the same tick on every
+// machine.
+var (
+	// reachDistance is how close to a node or stockpile the worker needs to be to use it.
+	reachDistance = fixed.FromFloat(0.05)
+	// radius is how much room a worker takes, other workers keep out of it.
+	radius = fixed.FromFloat(0.1)
+	// slowRadius is how far from where it is going a worker starts slowing down.
+	slowRadius = fixed.FromFloat(0.1)
+	// waypointReach is how close a worker gets to a waypoint of its path before heading for the next one.
+	waypointReach = fixed.FromFloat(0.1)
+	// arriveRadius is how close to where it is going a worker stops avoiding other workers, so those waiting
+	// there don't keep it from arriving.
+	arriveRadius = fixed.FromFloat(0.3)
+	// headingSpeed is how slow a worker can walk before the way it walks is no longer trusted as its heading.
+	headingSpeed = fixed.FromFloat(1e-4)
+)
+
+// EventThreatened is sent to the worker FSM when something scares the worker.
+const EventThreatened fsm.Event = "threatened"
+
+type Worker struct {
+	// brain decides what the worker does every update
+	brain func(dt float32)
+	// tree and planner are the brain of workers not controlled by a state machine
+	tree    *bt.Tree
+	planner *planner
+
+	fsm             *fsm.FSM
+	idleState       WorkerIdleState
+	fleeState       WorkerFleeState
+	workingState    WorkerWorkingState
+	harvestingState WorkerHarvestingState
+	pickingState    WorkerPickingState
+	walkState       WorkerWalkState
+	deliveringState WorkerDeliveringState
+	movingState     WorkerMovingState
+
+	world *World
+	// position is where the worker is drawn, at the height of the ground under its agent
+	position      mgl32.Vec3
+	currentTarget *resource.Node
+	threat        mgl32.Vec3
+	// gathering is the kind of resource the worker harvests
+	gathering resource.Kind
+	stats     Stats
+	inventory *resource.Inventory
+	player    resource.Player
+	dead      bool
+	// agent is how the worker moves, position follows it
+	agent *steering.Agent
+	// path are the waypoints to walk to pathGoal, the last one is pathGoal itself
+	path     *steering.Path
+	pathGoal mgl32.Vec3
+	// heading is the way the worker last walked
+	heading fixed.Vec2
+	// walked is set when the worker walked this update, it stands still otherwise
+	walked bool
+	// order is where the worker was told to go
+	order moveOrder
+}
+
+// moveOrder is where a worker was told to go, either a position or a slot next to a leader.
+type moveOrder struct {
+	destination mgl32.Vec3
+	// leader is followed at offset, in the frame of the way the leader is heading
+	leader *Worker
+	offset fixed.Vec2
+	// speed is how fast to walk compared to the walk speed
+	speed float32
+}
+
+func newWorker(position mgl32.Vec3, world *World) *Worker {
+	stats := DefaultStats()
+	w := &Worker{
+		world:     world,
+		position:  position,
+		gathering: resource.Wood,
+		stats:     stats,
+		inventory: resource.NewInventory(stats.CarryCapacity),
+		agent: &steering.Agent{
+			Position:        ground(position),
+			MaxSpeed:        fixed.FromFloat32(stats.WalkSpeed),
+			MaxAcceleration: fixed.FromFloat32(stats.Acceleration),
+			Radius:          radius,
+		},
+		heading: fixed.Vec2{0, fixed.One},
+	}
+	world.Crowd.Add(w.agent)
+	return w
+}
+
+// New creates a worker controlled by a state machine, that will start looking for wood to harvest in the
+// world.
+func New(position mgl32.Vec3, world *World) *Worker {
+	w := newWorker(position, world)
+	w.fsm = fsm.New()
+	w.brain = w.fsm.Run
+	w.idleState = WorkerIdleState{worker: w}
+	w.fleeState = WorkerFleeState{worker: w}
+	w.workingState = WorkerWorkingState{worker: w}
+	w.harvestingState = WorkerHarvestingState{worker: w}
+	w.pickingState = WorkerPickingState{worker: w}
+	w.walkState = WorkerWalkState{worker: w}
+	w.deliveringState = WorkerDeliveringState{worker: w}
+	w.movingState = WorkerMovingState{worker: w}
+
+	hasTarget := func() bool { return w.currentTarget != nil }
+	isCarrying := func() bool { return !w.inventory.Empty() }
+
+	mustAdd(w.fsm.AddState(StateIdle, &w.idleState))
+	mustAdd(w.fsm.AddState(StateFleeing, &w.fleeState))
+	mustAdd(w.fsm.AddState(StateWorking, &w.workingState))
+	mustAdd(w.fsm.AddState(StateMoving, &w.movingState))
+	mustAdd(w.fsm.AddSubState(StateWorking, StatePicking, &w.pickingState))
+	mustAdd(w.fsm.AddSubState(StateWorking, StateWalk, &w.walkState))
+	mustAdd(w.fsm.AddSubState(StateWorking, StateHarvesting, &w.harvestingState))
+	mustAdd(w.fsm.AddSubState(StateWorking, StateDelivering, &w.deliveringState))
+	mustAdd(w.fsm.SetHistoryMode(StateWorking, fsm.HistoryShallow))
+
+	mustAdd(w.fsm.AddTransition(StatePicking, StateWalk, hasTarget))
+	mustAdd(w.fsm.AddTransition(StateWalk, StateHarvesting, hasTarget))
+	mustAdd(w.fsm.AddTransition(StateWalk, StatePicking, nil))
+	mustAdd(w.fsm.AddTransition(StateHarvesting, StatePicking, nil))
+	mustAdd(w.fsm.AddTransition(StateHarvesting, StateWalk, hasTarget))
+	mustAdd(w.fsm.AddTransition(StateHarvesting, StateDelivering, isCarrying))
+	mustAdd(w.fsm.AddTransition(StatePicking, StateDelivering, isCarrying))
+	mustAdd(w.fsm.AddTransition(StateDelivering, StatePicking, nil))
+	mustAdd(w.fsm.AddTransition(StateWorking, StateIdle, nil))
+	mustAdd(w.fsm.AddTransition(StateIdle, StateWorking, nil))
+	mustAdd(w.fsm.AddTransition(fsm.AnyState, StateFleeing, nil))
+	mustAdd(w.fsm.AddTransition(StateFleeing, StateWorking, nil))
+	mustAdd(w.fsm.AddTransition(fsm.AnyState, StateMoving, nil))
+	mustAdd(w.fsm.AddTransition(StateMoving, StateIdle, nil))
+	mustAdd(w.fsm.AddTransition(StateMoving, StateWorking, nil))
+
+	flee := func() bool {
+		w.Flee()
+		return true
+	}
+	w.fsm.On(StateWorking, EventThreatened, flee)
+	w.fsm.On(StateIdle, EventThreatened, flee)
+	w.fsm.On(StateMoving, EventThreatened, flee)
+	w.fsm.On(StateFleeing, EventThreatened, func() bool {
+		w.fleeState.timeFleeing = 0
+		return true
+	})
+
+	mustAdd(w.fsm.Start(StateWorking))
+
+	return w
+}
+
+// mustAdd panics on errors building the state machine, they can only be caused by a bug in New.
+func mustAdd(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewWithBehaviourTree creates a worker controlled by the tree from NewBehaviourTree.
+func NewWithBehaviourTree(position mgl32.Vec3, world *World) *Worker {
+	w := newWorker(position, world)
+	w.tree = NewBehaviourTree(w)
+	w.brain = func(dt float32) { w.tree.Tick(dt) }
+	return w
+}
+
+// Update lets the brain of the worker decide what to do.
+func (w *Worker) Update(dt float32) {
+	if w.dead {
+		return
+	}
+	w.walked = false
+	w.brain(dt)
+	if !w.walked {
+		w.agent.Velocity = fixed.Vec2{}
+	}
+}
+
+// SetStats changes how the worker walks and harvests. Anything the worker was carrying is lost.
+func (w *Worker) SetStats(stats Stats) {
+	if stats.WalkSpeed == 0 {
+		stats.WalkSpeed = walkSpeed
+	}
+	if stats.Acceleration == 0 {
+		stats.Acceleration = walkAcceleration
+	}
+	w.stats = stats
+	w.inventory = resource.NewInventory(stats.CarryCapacity)
+	w.agent.MaxSpeed = fixed.FromFloat32(stats.WalkSpeed)
+	w.agent.MaxAcceleration = fixed.FromFloat32(stats.Acceleration)
+}
+
+// SetPlayer changes who the worker works for, it only delivers to stockpiles of that player.
+func (w *Worker) SetPlayer(player resource.Player) {
+	w.player = player
+}
+
+// Player returns who the worker works for.
+func (w *Worker) Player() resource.Player {
+	return w.player
+}
+
+// Position returns where the worker is on the ground.
+func (w *Worker) Position() fixed.Vec2 {
+	return w.agent.Position
+}
+
+// WorldPosition returns where the worker is drawn, on the ground.
+func (w *Worker) WorldPosition() mgl32.Vec3 {
+	return w.position
+}
+
+// Inventory returns what the worker is carrying.
+func (w *Worker) Inventory() *resource.Inventory {
+	return w.inventory
+}
+
+// Gather makes the worker harvest another kind of resource.
+func (w *Worker) Gather(kind resource.Kind) {
+	if kind == w.gathering {
+		return
+	}
+	w.gathering = kind
+	w.currentTarget = nil
+	w.releaseClaims()
+	if w.fsm != nil && w.fsm.IsActive(StateWorking) {
+		w.PickNode()
+	}
+}
+
+// Die stops the worker and releases the nodes it had claimed.
+func (w *Worker) Die() {
+	w.dead = true
+	w.currentTarget = nil
+	w.world.Reservations.ReleaseAll(w)
+	w.world.Crowd.Remove(w.agent)
+}
+
+// ClaimLost is called when the worker loses its claim on a node, e.g. because someone else depleted it.
+func (w *Worker) ClaimLost(node *resource.Node, reason reservation.Reason) {
+	fmt.Printf("Worker lost claim on %s node, %v\n", node.Kind, reason)
+	if node == w.currentTarget {
+		w.currentTarget = nil
+	}
+}
+
+// State returns the ID of the current state, or an empty ID if the worker is not controlled by a state
+// machine.
+func (w *Worker) State() fsm.StateID {
+	if w.fsm == nil {
+		return ""
+	}
+	return w.fsm.Current()
+}
+
+// Threaten makes the worker flee from the position of the threat, then go back to what it was doing.
+func (w *Worker) Threaten(from mgl32.Vec3) {
+	w.threat = from
+	switch {
+	case w.fsm != nil:
+		w.fsm.Send(EventThreatened)
+		return
+	case w.tree != nil:
+		threatenTree(w.tree)
+	case w.planner != nil:
+		w.planner.threaten()
+	}
+	// Like leaving the working state, fleeing lets other workers have the nodes
+	w.releaseClaims()
+	w.forgetPath()
+}
+
+// runAway moves the worker away from the threat for step seconds, in fixed point so the worker ends up in the
+// same place on every machine.
+func (w *Worker) runAway(step fixed.Scalar) {
+	away := w.agent.Position.Sub(ground(w.threat))
+	if away.LenSqr() > 0 {
+		w.agent.Position = w.agent.Position.Add(away.Normalize().Mul(fleeSpeed.Mul(step)))
+		w.syncPosition()
+	}
+}
+
+// MoveTo orders the worker to walk to a position and wait there, leaving what it was doing. Work sends it
+// back to work. Only workers controlled by a state machine take orders, others ignore them.
+func (w *Worker) MoveTo(position mgl32.Vec3) {
+	w.give(moveOrder{destination: position, speed: 1.0})
+}
+
+// Follow orders the worker to walk next to a leader while the leader is moving, keeping at an offset in the
+// frame of the way the leader is heading, where Y is ahead and X to its right.
+func (w *Worker) Follow(leader *Worker, offset fixed.Vec2) {
+	w.give(moveOrder{leader: leader, offset: offset, speed: 1.0})
+}
+
+// give makes the worker carry out an order. Workers not controlled by a state machine ignore it.
+func (w *Worker) give(order moveOrder) {
+	if w.fsm == nil {
+		return
+	}
+	w.order = order
+	w.changeState(StateMoving)
+}
+
+func (w *Worker) Idle() {
+	w.changeState(StateIdle)
+}
+func (w *Worker) Flee() {
+	w.changeState(StateFleeing)
+}
+func (w *Worker) Work() {
+	w.changeState(StateWorking)
+}
+func (w *Worker) Walk() {
+	w.changeState(StateWalk)
+}
+func (w *Worker) Harvest() {
+	w.changeState(StateHarvesting)
+}
+func (w *Worker) PickNode() {
+	w.changeState(StatePicking)
+}
+func (w *Worker) Deliver() {
+	w.changeState(StateDelivering)
+}
+
+// distanceToTarget returns the distance to the current target, ignoring height.
+func (w *Worker) distanceToTarget() fixed.Scalar {
+	return w.distanceTo(ground(w.currentTarget.Position()))
+}
+
+// distanceTo returns the distance to a position on the ground.
+func (w *Worker) distanceTo(position fixed.Vec2) fixed.Scalar {
+	return position.Sub(w.agent.Position).Len()
+}
+
+// walkTowards moves the worker towards a position on the ground, slowing down to stop there, and returns the
+// remaining distance.
+func (w *Worker) walkTowards(target fixed.Vec2, dt float32) fixed.Scalar {
+	step := fixed.FromFloat32(dt)
+	from := w.agent.Position
+	arrive := steering.Arrive(w.agent, target, slowRadius)
+	if target.Sub(from).Len() < arriveRadius {
+		w.agent.Apply(arrive, step)
+	} else {
+		w.world.Crowd.Steer(w.agent, arrive, step)
+	}
+	// Long updates could take the worker past the position
+	if target.Sub(from).Dot(target.Sub(w.agent.Position)) < 0 {
+		w.agent.Position = target
+		w.agent.Velocity = fixed.Vec2{}
+	}
+	w.stepped()
+	return w.distanceTo(target)
+}
+
+// moveTo moves the worker towards a position along a path around the nodes, and returns the remaining
+// distance, ignoring height. Without a navigation mesh or navigator, or when there is no path, the worker
+// walks in a straight line.
+func (w *Worker) moveTo(position mgl32.Vec3, dt float32) fixed.Scalar {
+	finder := w.world.pathFinder()
+	if finder == nil {
+		return w.walkTowards(ground(position), dt)
+	}
+	if w.path == nil || w.pathGoal != position {
+		path, err := finder.Path(w.agent.Position, ground(position))
+		if err != nil {
+			fmt.Printf("No path to %v: %v\n", position, err)
+			path = []fixed.Vec2{ground(position)}
+		}
+		w.path = &steering.Path{Points: path}
+		w.pathGoal = position
+	}
+
+	if w.path.Done() {
+		return w.walkTowards(ground(position), dt)
+	}
+	w.world.Crowd.Steer(w.agent, steering.FollowPath(w.agent, w.path, waypointReach, slowRadius), fixed.FromFloat32(dt))
+	w.stepped()
+	return w.distanceTo(ground(position))
+}
+
+// moveToStockpile moves the worker towards a stockpile and returns the remaining distance, ignoring height.
+// Workers follow the flow field towards the stockpile, which all workers delivering there share, instead of
+// each finding a path. Without a navigator, or when the stockpile can't be reached, the worker walks in a
+// straight line.
+func (w *Worker) moveToStockpile(stockpile *resource.Stockpile, dt float32) fixed.Scalar {
+	target := ground(stockpile.Position)
+	navigator := w.world.Navigator()
+	if navigator == nil {
+		return w.walkTowards(target, dt)
+	}
+	field, err := navigator.Flow(target)
+	if err != nil {
+		return w.walkTowards(target, dt)
+	}
+	next, ok := navigator.FlowStep(field, w.agent.Position, target)
+	if !ok || next == target {
+		return w.walkTowards(target, dt)
+	}
+	w.world.Crowd.Steer(w.agent, steering.Seek(w.agent, next), fixed.FromFloat32(dt))
+	w.stepped()
+	return w.distanceTo(target)
+}
+
+// stepped turns the worker the way it walked this update and moves it to where its agent is now.
+func (w *Worker) stepped() {
+	if w.agent.Velocity.Len() > headingSpeed {
+		w.heading = w.agent.Velocity.Normalize()
+	}
+	w.walked = true
+	w.syncPosition()
+}
+
+// syncPosition moves the worker to where the agent is, on the ground if the world has one and otherwise
+// keeping its height.
+func (w *Worker) syncPosition() {
+	position := w.agent.Position.Float()
+	height := w.position.Y()
+	if w.world.Ground != nil {
+		height = w.world.Ground.HeightAt(w.agent.Position.X(), w.agent.Position.Y()).Float32()
+	}
+	w.position = mgl32.Vec3{position.X(), height, position.Y()}
+}
+
+// orderTarget returns where on the ground the order of the worker currently takes it, and if it is following
+// a leader.
+func (w *Worker) orderTarget() (fixed.Vec2, bool) {
+	leader := w.order.leader
+	if leader == nil {
+		return ground(w.order.destination), false
+	}
+	// The offset is in the frame of the leader, with X to its right and Y ahead
+	facing := leader.heading
+	right := fixed.Vec2{facing.Y(), -facing.X()}
+	return leader.agent.Position.Add(right.Mul(w.order.offset.X())).Add(facing.Mul(w.order.offset.Y())), true
+}
+
+// ground returns where a position is on the ground, in fixed point.
+func ground(position mgl32.Vec3) fixed.Vec2 {
+	return fixed.FromVec2(spatial.XZ(position))
+}
+
+// forgetPath makes the worker look for a new path the next time it moves, e.g. after being chased away from
+// it.
+func (w *Worker) forgetPath() {
+	w.path = nil
+}
+
+// claimClosestNode claims the closest node of the kind the worker gathers that is not claimed by another
+// worker. It returns nil if there are no such nodes left.
+func (w *Worker) claimClosestNode() *resource.Node {
+	node, exists := w.world.NearestNode(w.gathering, w.position, w)
+	if !exists {
+		return nil
+	}
+	// A worker only ever holds on to the node it is going for
+	w.releaseClaims()
+	if err := w.world.Reservations.Claim(node, w); err != nil {
+		return nil
+	}
+	return node
+}
+
+// claim claims a node, or renews the claim if the worker already holds it. It returns false if the node is
+// gone or has been claimed by another worker.
+func (w *Worker) claim(node *resource.Node) bool {
+	return w.world.NodeExists(node) && w.world.Reservations.Claim(node, w) == nil
+}
+
+// releaseClaims releases all nodes claimed by the worker.
+func (w *Worker) releaseClaims() {
+	w.world.Reservations.ReleaseAll(w)
+}
+
+// harvestFrom harvests a node for dt seconds. progress is how far the worker has come harvesting the next
+// unit of the resource, the updated progress is returned. It is kept in fixed point, so every machine harvests
+// the unit on the same tick.
+func (w *Worker) harvestFrom(node *resource.Node, progress fixed.Scalar, dt float32) fixed.Scalar {
+	rate := fixed.FromFloat32(w.stats.HarvestRate[node.Kind]).Div(fixed.FromFloat32(node.Difficulty))
+	progress += rate.Mul(fixed.FromFloat32(dt))
+	for progress >= fixed.One && !w.inventory.Full(node.Kind) && w.world.NodeExists(node) {
+		progress -= fixed.One
+		w.inventory.Add(node.Kind, w.world.Harvest(node, 1))
+	}
+	return progress
+}
+
+// full reports if the worker can't carry more of what it gathers.
+func (w *Worker) full() bool {
+	return w.inventory.Full(w.gathering)
+}
+
+// nearestStockpile returns the closest stockpile of the player of the worker that accepts what it gathers,
+// or nil if there is none.
+func (w *Worker) nearestStockpile() *resource.Stockpile {
+	stockpile, _ := resource.NearestStockpile(w.world.Stockpiles, w.position, w.player, w.gathering)
+	return stockpile
+}
+
+// deposit puts everything the worker carries in the stockpile.
+func (w *Worker) deposit(stockpile *resource.Stockpile) {
+	for kind, amount := range stockpile.Deposit(w.inventory, w.world.Resources) {
+		fmt.Printf("Deposited %d %s, player %d has %d\n", amount, kind, stockpile.Player, w.world.Resources.Get(stockpile.Player, kind))
+	}
+}
+
+// changeState moves the state machine to a state. Workers not controlled by one stay as they are.
+func (w *Worker) changeState(id fsm.StateID) {
+	if w.fsm == nil {
+		return
+	}
+	if err := w.fsm.ChangeState(id); err != nil {
+		fmt.Printf("Worker: %v\n", err)
+	}
+}
+
+// holdsTarget renews the claim on the current target. It returns false, and forgets about the target, if
+// the node is gone or has been claimed by another worker.
+func (w *Worker) holdsTarget() bool {
+	if w.currentTarget == nil {
+		return false
+	}
+	if !w.claim(w.currentTarget) {
+		w.currentTarget = nil
+		return false
+	}
+	return true
+}
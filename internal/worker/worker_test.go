@@ -0,0 +1,603 @@
+package worker
+
+import (
+	"testing"
+
+	"game-engine/rts/internal/bt"
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/navmesh"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/steering"
+	"game-engine/rts/internal/terrain"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNode(nodeType resource.NodeType, position mgl32.Vec3) *resource.Node {
+	return resource.NewNode(nodeType, position, mgl32.Vec3{})
+}
+
+// newTestWorld creates a world with trees at the given positions and a stockpile at the origin.
+func newTestWorld(positions ...mgl32.Vec3) *World {
+	trees := []*resource.Node{}
+	for _, position := range positions {
+		trees = append(trees, newNode(resource.Tree, position))
+	}
+	return NewWorld(trees, &resource.Stockpile{})
+}
+
+// run updates the world and workers until done returns true, or gives up after simulating 100 seconds.
+func run(world *World, done func() bool, workers ...*Worker) {
+	for i := 0; i < 10000 && !done(); i++ {
+		world.Update(0.01)
+		for _, w := range workers {
+			w.Update(0.01)
+		}
+	}
+}
+
+func TestWorkerChopsAllTrees(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-1.0, 3.0, 0.5})
+	first := world.Nodes[0]
+	w := New(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+	assert.Equal(t, StatePicking, w.State())
+
+	w.Update(0.01)
+	assert.Equal(t, StateWalk, w.State())
+	assert.True(t, w.fsm.IsActive(StateWorking))
+	assert.Same(t, first, w.currentTarget)
+	assert.True(t, world.Reservations.Holds(first, w))
+
+	run(world, func() bool { return w.State() == StateIdle }, w)
+
+	assert.Equal(t, StateIdle, w.State())
+	assert.Empty(t, world.Nodes)
+	assert.Nil(t, w.currentTarget)
+	assert.Equal(t, 2*resource.Tree.Amount, world.Resources.Get(0, resource.Wood))
+	assert.True(t, w.Inventory().Empty())
+}
+
+func TestWorkerHarvestsInIncrements(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{0.5, 3.0, 0.0})
+	tree := world.Nodes[0]
+	w := New(mgl32.Vec3{0.5, 2.5, 0.0}, world)
+	w.Update(0.01)
+	w.Update(0.01)
+	assert.Equal(t, StateHarvesting, w.State())
+
+	w.Update(2.5)
+	assert.Equal(t, 2, w.Inventory().Amount(resource.Wood))
+	assert.Equal(t, resource.Tree.Amount-2, tree.Remaining)
+
+	w.Update(2.0)
+	assert.Equal(t, StateDelivering, w.State(), "full")
+	assert.False(t, world.Reservations.Holds(tree, w), "tree is free while delivering")
+
+	run(world, func() bool { return w.State() != StateDelivering }, w)
+	assert.Equal(t, StatePicking, w.State())
+	assert.Equal(t, 4, world.Resources.Get(0, resource.Wood))
+	assert.True(t, w.Inventory().Empty())
+
+	w.Update(0.01)
+	assert.Same(t, tree, w.currentTarget, "goes back to the same tree")
+}
+
+func TestWorkerDeliversToOwnStockpile(t *testing.T) {
+	world := NewWorld(
+		[]*resource.Node{newNode(resource.Tree, mgl32.Vec3{0.0, 3.0, 0.0})},
+		&resource.Stockpile{Position: mgl32.Vec3{1.0, 2.5, 0.0}, Player: 0},
+		&resource.Stockpile{Position: mgl32.Vec3{5.0, 2.5, 0.0}, Player: 1},
+	)
+	w := New(mgl32.Vec3{}, world)
+	w.SetPlayer(1)
+	w.SetStats(Stats{
+		HarvestRate:   map[resource.Kind]float32{resource.Wood: 5.0},
+		CarryCapacity: map[resource.Kind]int{resource.Wood: resource.Tree.Amount},
+	})
+
+	run(world, func() bool { return w.State() == StateIdle }, w)
+
+	assert.Equal(t, resource.Tree.Amount, world.Resources.Get(1, resource.Wood))
+	assert.Equal(t, 0, world.Resources.Get(0, resource.Wood))
+	assert.InDelta(t, 5.0, w.position.X(), reachDistance.Float())
+}
+
+func TestWorkerWithoutStockpileKeepsWood(t *testing.T) {
+	world := NewWorld([]*resource.Node{newNode(resource.Tree, mgl32.Vec3{0.0, 3.0, 0.0})})
+	w := New(mgl32.Vec3{}, world)
+
+	run(world, func() bool { return w.State() == StateIdle }, w)
+
+	assert.Equal(t, 4, w.Inventory().Amount(resource.Wood))
+	assert.Len(t, world.Nodes, 1)
+}
+
+func TestWorkerRejectsWalkWithoutTarget(t *testing.T) {
+	w := New(mgl32.Vec3{}, newTestWorld())
+
+	w.Walk()
+	assert.Equal(t, StatePicking, w.State())
+
+	w.Update(0.01)
+	assert.Equal(t, StateIdle, w.State())
+}
+
+func TestWorkerResumesAfterFleeing(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{0.5, 3.0, 0.0})
+	w := New(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+	run(world, func() bool { return w.State() == StateHarvesting }, w)
+	w.Update(0.5)
+	assert.Equal(t, StateHarvesting, w.State())
+	progress := w.harvestingState.progress
+
+	w.Threaten(mgl32.Vec3{1.0, 2.5, 0.0})
+	assert.Equal(t, StateFleeing, w.State())
+	assert.False(t, world.Reservations.Holds(world.Nodes[0], w), "claims are released when leaving work")
+	run(world, func() bool { return w.State() != StateFleeing }, w)
+
+	// Resumes chopping, notices it was chased away and walks back to the same tree
+	assert.Equal(t, StateHarvesting, w.State())
+	w.Update(0.01)
+	assert.Equal(t, StateWalk, w.State())
+	assert.Same(t, world.Nodes[0], w.currentTarget)
+	run(world, func() bool { return w.State() == StateHarvesting }, w)
+	assert.Equal(t, progress, w.harvestingState.progress)
+}
+
+func TestWorkerFleesFromIdle(t *testing.T) {
+	w := New(mgl32.Vec3{}, newTestWorld())
+	w.Update(0.01)
+	assert.Equal(t, StateIdle, w.State())
+
+	w.Threaten(mgl32.Vec3{1.0, 0.0, 0.0})
+	w.Update(1.0)
+
+	assert.Equal(t, StateFleeing, w.State())
+	assert.Less(t, w.position.X(), float32(0.0))
+}
+
+func TestWorkersWithoutStateMachineFlee(t *testing.T) {
+	testCases := []struct {
+		desc string
+		new  func(position mgl32.Vec3, world *World) *Worker
+	}{
+		{desc: "behaviour tree", new: NewWithBehaviourTree},
+		{desc: "planner", new: func(position mgl32.Vec3, world *World) *Worker {
+			return NewWithPlanner(position, world, resource.Tree.Amount)
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			world := newTestWorld(mgl32.Vec3{0.5, 3.0, 0.0})
+			w := tc.new(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+			run(world, func() bool { return world.Reservations.Holds(world.Nodes[0], w) }, w)
+
+			w.Threaten(mgl32.Vec3{1.0, 2.5, 0.0})
+			assert.False(t, world.Reservations.Holds(world.Nodes[0], w), "claims are released when fleeing")
+			x := w.position.X()
+			w.Update(1.0)
+			w.Update(1.0)
+			assert.Less(t, w.position.X(), x, "ran away")
+			assert.False(t, world.Reservations.Holds(world.Nodes[0], w), "doesn't work while fleeing")
+
+			// Goes back to work once it has fled
+			run(world, func() bool { return len(world.Nodes) == 0 && w.inventory.Empty() }, w)
+			assert.Equal(t, resource.Tree.Amount, world.Resources.Get(0, resource.Wood))
+		})
+	}
+}
+
+func TestWorkersTargetDifferentTrees(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-2.0, 3.0, 0.0}, mgl32.Vec3{5.0, 3.0, 0.0})
+	workers := []*Worker{
+		New(mgl32.Vec3{}, world),
+		NewWithBehaviourTree(mgl32.Vec3{}, world),
+		NewWithPlanner(mgl32.Vec3{}, world, 100),
+	}
+
+	targets := map[*resource.Node]bool{}
+	for _, w := range workers {
+		w.Update(0.01)
+		claimed := 0
+		for _, tree := range world.Nodes {
+			if world.Reservations.Holds(tree, w) {
+				targets[tree] = true
+				claimed++
+			}
+		}
+		assert.Equal(t, 1, claimed)
+	}
+	assert.Len(t, targets, 3)
+
+	run(world, func() bool { return len(world.Nodes) == 0 }, workers...)
+	assert.Empty(t, world.Nodes)
+}
+
+func TestWorkerNotifiedWhenTreeIsRemoved(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-2.0, 3.0, 0.0})
+	first, second := world.Nodes[0], world.Nodes[1]
+	w := New(mgl32.Vec3{}, world)
+	w.Update(0.01)
+	assert.Same(t, first, w.currentTarget)
+
+	world.RemoveNode(first)
+	assert.Nil(t, w.currentTarget)
+	w.Update(0.01)
+	w.Update(0.01)
+
+	assert.Equal(t, StateWalk, w.State())
+	assert.Same(t, second, w.currentTarget)
+}
+
+func TestDeadWorkerReleasesClaims(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0})
+	dead := New(mgl32.Vec3{}, world)
+	dead.Update(0.01)
+	alive := New(mgl32.Vec3{}, world)
+	alive.Update(0.01)
+	assert.Equal(t, StateIdle, alive.State(), "only tree is claimed")
+
+	dead.Die()
+	dead.Update(0.01)
+	alive.Work()
+	alive.Update(0.01)
+
+	assert.Same(t, world.Nodes[0], alive.currentTarget)
+	assert.True(t, world.Reservations.Holds(world.Nodes[0], alive))
+}
+
+func TestExpiredClaimsAreReleased(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0})
+	stuck := New(mgl32.Vec3{}, world)
+	stuck.Update(0.01)
+
+	world.Update(claimTimeout)
+
+	assert.Nil(t, stuck.currentTarget)
+	_, claimed := world.Reservations.ClaimedBy(world.Nodes[0])
+	assert.False(t, claimed)
+}
+
+func TestWorkerGathersKind(t *testing.T) {
+	tree := newNode(resource.Tree, mgl32.Vec3{0.5, 3.0, 0.0})
+	rock := newNode(resource.Rock, mgl32.Vec3{3.0, 3.0, 0.0})
+	world := NewWorld([]*resource.Node{tree, rock}, &resource.Stockpile{})
+	w := New(mgl32.Vec3{3.0, 2.5, 0.0}, world)
+	w.Gather(resource.Stone)
+	w.Update(0.01)
+	assert.Same(t, rock, w.currentTarget)
+
+	w.Update(0.01)
+	w.Update(0.01)
+	assert.Equal(t, StateHarvesting, w.State())
+	w.Update(1.5)
+	assert.Equal(t, 0, w.Inventory().Amount(resource.Stone), "stone is harder to harvest")
+	w.Update(0.5)
+	assert.Equal(t, 1, w.Inventory().Amount(resource.Stone))
+
+	w.Gather(resource.Wood)
+	assert.Equal(t, StatePicking, w.State())
+	assert.False(t, world.Reservations.Holds(rock, w))
+	w.Update(0.01)
+	assert.Same(t, tree, w.currentTarget)
+}
+
+func TestWorkerWaitsForRegrowth(t *testing.T) {
+	bush := newNode(resource.BerryBush, mgl32.Vec3{0.5, 3.0, 0.0})
+	world := NewWorld([]*resource.Node{bush}, &resource.Stockpile{})
+	w := New(mgl32.Vec3{}, world)
+	w.Gather(resource.Food)
+
+	run(world, func() bool { return w.State() == StateIdle }, w)
+	assert.True(t, bush.Depleted())
+	assert.Equal(t, []*resource.Node{bush}, world.Nodes, "bushes regrow instead of disappearing")
+	assert.Equal(t, resource.BerryBush.Amount, world.Resources.Get(0, resource.Food))
+
+	world.Update(resource.BerryBush.RegrowthTime)
+	w.Work()
+	w.Update(0.01)
+	assert.Same(t, bush, w.currentTarget)
+}
+
+func TestWorldSpreadsRegrowth(t *testing.T) {
+	bushes := []*resource.Node{}
+	for i := 0; i < 4; i++ {
+		bushes = append(bushes, newNode(resource.BerryBush, mgl32.Vec3{float32(i), 3.0, 0.0}))
+	}
+	world := NewWorld(bushes)
+	world.Rand = fixed.NewRand(1)
+	regrowIn := map[fixed.Scalar]bool{}
+	for _, bush := range bushes {
+		world.Harvest(bush, bush.Amount)
+		assert.GreaterOrEqual(t, bush.RegrowIn(), fixed.FromFloat32(bush.RegrowthTime))
+		assert.Less(t, bush.RegrowIn(), fixed.FromFloat32(bush.RegrowthTime)+regrowthSpread)
+		regrowIn[bush.RegrowIn()] = true
+	}
+	assert.Len(t, regrowIn, len(bushes), "each bush regrows on its own tick")
+}
+
+func TestWorldNearestNode(t *testing.T) {
+	near := newNode(resource.Tree, mgl32.Vec3{1.0, 3.0, 0.0})
+	far := newNode(resource.Tree, mgl32.Vec3{20.0, 3.0, -20.0})
+	rock := newNode(resource.Rock, mgl32.Vec3{0.5, 2.5, 0.0})
+	world := NewWorld([]*resource.Node{near, rock, far})
+	me, other := &Worker{}, &Worker{}
+
+	nearest, found := world.NearestNode(resource.Wood, mgl32.Vec3{}, me)
+	assert.True(t, found)
+	assert.Same(t, near, nearest)
+
+	assert.NoError(t, world.Reservations.Claim(near, other))
+	nearest, _ = world.NearestNode(resource.Wood, mgl32.Vec3{}, me)
+	assert.Same(t, far, nearest)
+
+	world.RemoveNode(near)
+	world.RemoveNode(far)
+	assert.Equal(t, []*resource.Node{rock}, world.Nodes)
+	_, found = world.NearestNode(resource.Wood, mgl32.Vec3{}, me)
+	assert.False(t, found)
+	assert.True(t, world.NodeExists(rock))
+}
+
+func TestWorkerWalksAroundBlockedTiles(t *testing.T) {
+	grid, _, err := pathfinding.ParseGrid(`
+		.......
+		.#####.
+		.......`)
+	assert.NoError(t, err)
+	navigator := &pathfinding.Navigator{Grid: grid, TileSize: 1.0}
+	world := newTestWorld(mgl32.Vec3{3.5, 3.0, 2.5})
+	tree := world.Nodes[0]
+	world.SetNavigator(navigator)
+	assert.Equal(t, fixed.Scalar(0), grid.Cost(pathfinding.Tile{X: 3, Y: 2}), "tree blocks its tile")
+
+	w := New(mgl32.Vec3{3.5, 2.5, 0.5}, world)
+	walked := []pathfinding.Tile{}
+	run(world, func() bool {
+		tile := navigator.TileAt(w.Position())
+		if len(walked) == 0 || walked[len(walked)-1] != tile {
+			walked = append(walked, tile)
+		}
+		return w.State() == StateHarvesting
+	}, w)
+
+	assert.Equal(t, StateHarvesting, w.State())
+	assert.Equal(t, float32(2.5), w.position.Y(), "keeps its height")
+	assert.Contains(t, walked, pathfinding.Tile{X: 6, Y: 1}, "goes around the wall")
+	for _, tile := range walked[:len(walked)-1] {
+		assert.Greater(t, grid.Cost(tile), fixed.Scalar(0), "walked through a blocked tile at %v", tile)
+	}
+
+	world.RemoveNode(tree)
+	assert.Equal(t, fixed.One, grid.Cost(pathfinding.Tile{X: 3, Y: 2}), "removed tree frees its tile")
+}
+
+func TestWorkerWalksAroundNodesOnNavMesh(t *testing.T) {
+	grid := pathfinding.NewGrid(6, 3)
+	rock := newNode(resource.Rock, mgl32.Vec3{2.5, 3.0, 1.5})
+	tree := newNode(resource.Tree, mgl32.Vec3{5.0, 3.0, 1.5})
+	world := NewWorld([]*resource.Node{rock, tree}, &resource.Stockpile{Position: mgl32.Vec3{0.5, 2.5, 1.5}})
+	world.SetNavMesh(navmesh.New(grid, nil, navmesh.Config{TileSize: 1.0, CellsPerTile: 4, AgentRadius: radius.Float32()}))
+	assert.Len(t, world.NavMesh().Obstacles(), 2)
+
+	w := New(mgl32.Vec3{0.5, 2.5, 1.5}, world)
+	closest := fixed.FromInt(10)
+	run(world, func() bool {
+		if d := w.distanceTo(ground(rock.Position())); d < closest {
+			closest = d
+		}
+		return w.State() == StateHarvesting
+	}, w)
+
+	assert.Equal(t, StateHarvesting, w.State())
+	assert.GreaterOrEqual(t, closest, fixed.FromFloat32(rock.Radius), "walked through the rock")
+
+	world.RemoveNode(tree)
+	assert.Equal(t, []navmesh.Obstacle{{Center: mgl32.Vec2{2.5, 1.5}, Radius: rock.Radius}}, world.NavMesh().Obstacles(), "chopped tree no longer blocks")
+}
+
+func TestWorkerWalksOnTheGround(t *testing.T) {
+	world := newTestWorld(mgl32.Vec3{4.0, 3.0, 3.0})
+	world.Ground = terrain.FromFunc(9, 9, mgl32.Vec2{-1, -1}, 1.0, func(x, z float32) float32 {
+		return 2 + 0.5*x - 0.25*z
+	})
+	w := New(mgl32.Vec3{0.0, 2.0, 0.0}, world)
+	run(world, func() bool {
+		if w.State() == StateWalk {
+			position := w.position
+			assert.InDelta(t, world.Ground.HeightAt(fixed.FromFloat32(position.X()), fixed.FromFloat32(position.Z())).Float32(), position.Y(), 1e-5, "at %v", position)
+		}
+		return w.State() == StateHarvesting
+	}, w)
+
+	assert.Equal(t, StateHarvesting, w.State())
+	assert.Greater(t, w.position.Y(), float32(2.5), "walked up the slope")
+}
+
+func TestWorkerDeliversAlongFlowField(t *testing.T) {
+	grid, _, err := pathfinding.ParseGrid(`
+		.......
+		#####..
+		.......`)
+	assert.NoError(t, err)
+	navigator := &pathfinding.Navigator{Grid: grid, TileSize: 1.0}
+	world := newTestWorld(mgl32.Vec3{0.5, 3.0, 2.5})
+	world.SetNavigator(navigator)
+
+	// The worker starts on the tile of the tree, which is blocked
+	w := New(mgl32.Vec3{0.5, 2.5, 2.5}, world)
+	run(world, func() bool { return w.State() == StateDelivering }, w)
+	walked := []pathfinding.Tile{}
+	run(world, func() bool {
+		walked = append(walked, navigator.TileAt(w.Position()))
+		return w.State() != StateDelivering
+	}, w)
+
+	assert.Equal(t, 4, world.Resources.Get(0, resource.Wood))
+	assert.Contains(t, walked, pathfinding.Tile{X: 5, Y: 1}, "goes around the wall")
+	for _, tile := range walked {
+		if tile != (pathfinding.Tile{X: 0, Y: 2}) {
+			assert.Greater(t, grid.Cost(tile), fixed.Scalar(0), "walked through a blocked tile at %v", tile)
+		}
+	}
+}
+
+func TestWorkersWalkPastEachOther(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		avoidance steering.Avoidance
+		// keepsApart is set when the workers never touch, separation only pushes them apart once they do
+		keepsApart bool
+	}{
+		{desc: "separation", avoidance: steering.SeparationAvoidance},
+		{desc: "orca", avoidance: steering.ORCAAvoidance, keepsApart: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			world := newTestWorld()
+			world.Crowd.Avoidance = tc.avoidance
+			left := newWorker(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+			right := newWorker(mgl32.Vec3{4.0, 2.5, 0.01}, world)
+
+			leftGoal, rightGoal := fixed.Vec2{fixed.FromInt(4), 0}, fixed.Vec2{}
+			closest := fixed.FromInt(4)
+			for i := 0; i < 1000; i++ {
+				left.walkTowards(leftGoal, 0.01)
+				right.walkTowards(rightGoal, 0.01)
+				if d := left.distanceTo(right.agent.Position); d < closest {
+					closest = d
+				}
+			}
+
+			if tc.keepsApart {
+				assert.Greater(t, closest, (2 * radius).Mul(fixed.FromFloat(0.95)), "bumped into each other")
+			}
+			assert.Less(t, left.distanceTo(leftGoal), reachDistance)
+			assert.Less(t, right.distanceTo(rightGoal), reachDistance)
+			assert.Equal(t, float32(2.5), left.position.Y(), "kept its height")
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	testCases := []struct {
+		desc string
+		new  func(position mgl32.Vec3, world *World) *Worker
+		// harvesting is if the worker is in the middle of harvesting a unit
+		harvesting func(w *Worker) bool
+	}{
+		{
+			desc: "state machine",
+			new:  New,
+			harvesting: func(w *Worker) bool {
+				return w.State() == StateHarvesting && w.harvestingState.progress > 0
+			},
+		},
+		{
+			desc: "behaviour tree",
+			new:  NewWithBehaviourTree,
+			harvesting: func(w *Worker) bool {
+				progress, _ := bt.Get[fixed.Scalar](w.tree.Blackboard(), keyHarvestProgress)
+				return progress > 0
+			},
+		},
+		{
+			desc: "planner",
+			new: func(position mgl32.Vec3, world *World) *Worker {
+				return NewWithPlanner(position, world, 100)
+			},
+			harvesting: func(w *Worker) bool { return w.planner.chopProgress > 0 },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			newWorld := func() (*World, *Worker) {
+				world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0}, mgl32.Vec3{-1.0, 3.0, 0.5}, mgl32.Vec3{2.0, 3.0, 2.0})
+				return world, tc.new(mgl32.Vec3{0.0, 2.5, 0.0}, world)
+			}
+			world, w := newWorld()
+			run(world, func() bool { return len(world.Nodes) == 2 && tc.harvesting(w) }, w)
+			index := func(nodes []*resource.Node) func(*resource.Node) int {
+				return func(node *resource.Node) int {
+					for i, n := range nodes {
+						if n == node {
+							return i
+						}
+					}
+					return none
+				}
+			}
+			initial := append([]*resource.Node{}, world.Removed()...)
+			initial = append(initial, world.Nodes...)
+			snapshot := w.Snapshot(index(initial), func(*Worker) int { return none })
+
+			// A world set up the same way, with the same nodes removed, continues the same from the snapshot
+			restoredWorld, restored := newWorld()
+			nodes := map[mgl32.Vec3]*resource.Node{}
+			for _, node := range restoredWorld.Nodes {
+				nodes[node.Position()] = node
+			}
+			node := func(i int) *resource.Node {
+				if i == none {
+					return nil
+				}
+				return nodes[initial[i].Position()]
+			}
+			for _, removed := range world.Removed() {
+				restoredWorld.RemoveNode(nodes[removed.Position()])
+			}
+			claims := world.Reservations.Snapshot()
+			for i := range claims.Claims {
+				claims.Claims[i].Node = nodes[claims.Claims[i].Node.Position()]
+				claims.Claims[i].Claimant = restored
+			}
+			restoredWorld.Reservations.Restore(claims)
+			for _, n := range world.Nodes {
+				nodes[n.Position()].Restore(n.Remaining, n.RegrowIn())
+			}
+			restoredWorld.Resources.Add(0, resource.Wood, world.Resources.Get(0, resource.Wood))
+			assert.NoError(t, restored.Restore(snapshot, node, func(int) *Worker { return nil }))
+			assert.True(t, tc.harvesting(restored))
+
+			for i := 0; i < 500; i++ {
+				for _, ww := range []struct {
+					world  *World
+					worker *Worker
+				}{{world, w}, {restoredWorld, restored}} {
+					ww.world.Update(0.01)
+					ww.worker.Update(0.01)
+				}
+				assert.Equal(t, w.position, restored.position)
+				assert.Equal(t, w.State(), restored.State())
+			}
+			assert.Equal(t, world.Resources.Get(0, resource.Wood), restoredWorld.Resources.Get(0, resource.Wood))
+			assert.Equal(t, w.Inventory().Amount(resource.Wood), restored.Inventory().Amount(resource.Wood))
+		})
+	}
+
+	// Workers without a state machine keep fleeing after being restored
+	for _, tc := range testCases[1:] {
+		t.Run(tc.desc+" fleeing", func(t *testing.T) {
+			w := tc.new(mgl32.Vec3{}, newTestWorld())
+			w.Threaten(mgl32.Vec3{1.0, 0.0, 0.0})
+			w.Update(1.0)
+			restored := tc.new(mgl32.Vec3{}, newTestWorld())
+			noIndex := func(*resource.Node) int { return none }
+			noNode := func(int) *resource.Node { return nil }
+			assert.NoError(t, restored.Restore(w.Snapshot(noIndex, nil), noNode, func(int) *Worker { return nil }))
+			for i := 0; i < 3; i++ {
+				w.Update(1.0)
+				restored.Update(1.0)
+				assert.Equal(t, w.position, restored.position)
+			}
+			assert.Less(t, w.position.X(), float32(-2.9), "fled for 3s")
+		})
+	}
+
+	world := newTestWorld(mgl32.Vec3{1.0, 3.0, 0.0})
+	snapshot := New(mgl32.Vec3{}, world).Snapshot(func(*resource.Node) int { return none }, nil)
+	err := NewWithBehaviourTree(mgl32.Vec3{}, world).Restore(snapshot, nil, nil)
+	assert.ErrorIs(t, err, ErrOtherBrain)
+}
@@ -0,0 +1,282 @@
+package worker
+
+import (
+	"fmt"
+
+	"game-engine/rts/internal/fixed"
+	"game-engine/rts/internal/navmesh"
+	"game-engine/rts/internal/pathfinding"
+	"game-engine/rts/internal/reservation"
+	"game-engine/rts/internal/resource"
+	"game-engine/rts/internal/spatial"
+	"game-engine/rts/internal/steering"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// claimTimeout is how long a claim on a node lasts unless the worker renews it.
+	claimTimeout float32 = 30.0
+	// nodeCellSize is the size of the cells used to find nodes, about the distance between trees.
+	nodeCellSize float32 = 2.0
+	// crowdNeighbourRadius is how far workers look for other workers to keep out of the way of.
+	crowdNeighbourRadius float32 = 1.0
+)
+
+// regrowthSpread is the most seconds Rand delays the regrowth of a node by.
+var regrowthSpread = fixed.FromInt(10)
+
+// World is the part of the game world shared by all workers.
+type World struct {
+	// Nodes are where resources can be harvested. Use AddNode and RemoveNode to change them.
+	Nodes []*resource.Node
+	// Stockpiles are where workers deposit what they have harvested.
+	Stockpiles []*resource.Stockpile
+	// Resources are what each player has deposited.
+	Resources *resource.Counters
+	// Reservations makes sure two workers never go for the same node.
+	Reservations *reservation.Reservations[*resource.Node]
+	// Crowd keeps workers out of each other's way while they walk, with ORCA unless changed.
+	Crowd *steering.Crowd
+	// Ground is what workers walk on, they keep their height when it is nil.
+	Ground Surface
+	// Rand spreads out when depleted regrowing nodes come back, so they don't all regrow on the same tick.
+	// They regrow after exactly their regrowth time when it is nil.
+	Rand *fixed.Rand
+
+	nodeIndex *spatial.Grid[*resource.Node]
+	// slots are the indices of the nodes in Nodes
+	slots map[*resource.Node]int
+	// navigator finds paths around the nodes, workers walk in a straight line when it is nil
+	navigator *pathfinding.Navigator
+	// blocked are the tiles of the navigator grid with nodes on them
+	blocked map[pathfinding.Tile]*blockedTile
+	// mesh finds paths around the nodes instead of the navigator when it is set
+	mesh *navmesh.NavMesh
+	// removed are the nodes removed from the world, in the order they were removed
+	removed []*resource.Node
+}
+
+// Surface is ground with hills and valleys, like terrain.Heightmap.
+type Surface interface {
+	// HeightAt returns the height of the ground at a world x and z, in fixed point.
+	HeightAt(x, z fixed.Scalar) fixed.Scalar
+}
+
+// pathFinder finds paths between positions on the ground, like pathfinding.Navigator and navmesh.NavMesh.
+type pathFinder interface {
+	Path(from, to fixed.Vec2) ([]fixed.Vec2, error)
+}
+
+// blockedTile is a tile of the navigator grid with nodes on it.
+type blockedTile struct {
+	nodes int
+	// cost is the cost of the tile when there are no nodes on it
+	cost fixed.Scalar
+}
+
+// NewWorld creates a world with the given resource nodes and stockpiles.
+func NewWorld(nodes []*resource.Node, stockpiles ...*resource.Stockpile) *World {
+	crowd := steering.NewCrowd(crowdNeighbourRadius)
+	crowd.Avoidance = steering.ORCAAvoidance
+	w := &World{
+		Stockpiles:   stockpiles,
+		Resources:    resource.NewCounters(),
+		Reservations: reservation.New[*resource.Node](claimTimeout),
+		Crowd:        crowd,
+		nodeIndex:    spatial.NewGrid[*resource.Node](nodeCellSize),
+		slots:        make(map[*resource.Node]int, len(nodes)),
+		blocked:      make(map[pathfinding.Tile]*blockedTile),
+	}
+	for _, node := range nodes {
+		w.AddNode(node)
+	}
+	return w
+}
+
+// AddNode adds a node to the world.
+func (w *World) AddNode(node *resource.Node) {
+	if _, exists := w.slots[node]; exists {
+		return
+	}
+	w.slots[node] = len(w.Nodes)
+	w.Nodes = append(w.Nodes, node)
+	w.nodeIndex.Insert(node, spatial.XZ(node.Position()))
+	w.block(node)
+	if w.mesh != nil {
+		w.mesh.AddObstacle(obstacle(node))
+	}
+}
+
+// SetNavigator makes workers walk around nodes using the navigator. The tiles nodes are on are blocked in the
+// grid of the navigator, and freed again when the nodes are removed.
+func (w *World) SetNavigator(navigator *pathfinding.Navigator) {
+	if w.navigator != nil {
+		for _, node := range w.Nodes {
+			w.unblock(node)
+		}
+	}
+	w.navigator = navigator
+	if navigator != nil {
+		for _, node := range w.Nodes {
+			w.block(node)
+		}
+	}
+}
+
+// Navigator returns the navigator workers use to find paths, or nil if they walk in a straight line.
+func (w *World) Navigator() *pathfinding.Navigator {
+	return w.navigator
+}
+
+// SetNavMesh makes workers find paths over a navigation mesh, which are straighter than paths over the grid of
+// the navigator. The nodes are added to the mesh as obstacles, and removed again when they are removed from
+// the world. Deliveries to stockpiles still follow the flow fields of the navigator if there is one.
+func (w *World) SetNavMesh(mesh *navmesh.NavMesh) {
+	if w.mesh != nil {
+		for _, node := range w.Nodes {
+			w.mesh.RemoveObstacle(obstacle(node))
+		}
+	}
+	w.mesh = mesh
+	if mesh != nil {
+		for _, node := range w.Nodes {
+			mesh.AddObstacle(obstacle(node))
+		}
+	}
+}
+
+// NavMesh returns the navigation mesh workers find paths over, or nil if there is none.
+func (w *World) NavMesh() *navmesh.NavMesh {
+	return w.mesh
+}
+
+// pathFinder returns what workers find paths with, the navigation mesh over the navigator, or nil if they
+// walk in a straight line.
+func (w *World) pathFinder() pathFinder {
+	if w.mesh != nil {
+		return w.mesh
+	}
+	if w.navigator != nil {
+		return w.navigator
+	}
+	return nil
+}
+
+// obstacle returns the footprint of a node on the navigation mesh.
+func obstacle(node *resource.Node) navmesh.Obstacle {
+	return navmesh.Obstacle{Center: spatial.XZ(node.Position()), Radius: node.Radius}
+}
+
+// block marks the tile a node is on as blocked in the navigator grid.
+func (w *World) block(node *resource.Node) {
+	if w.navigator == nil {
+		return
+	}
+	tile := w.navigator.TileAt(ground(node.Position()))
+	if !w.navigator.Grid.InBounds(tile) {
+		return
+	}
+	blocked, exists := w.blocked[tile]
+	if !exists {
+		blocked = &blockedTile{cost: w.navigator.Grid.Cost(tile)}
+		w.blocked[tile] = blocked
+	}
+	blocked.nodes++
+	w.navigator.SetCost(tile, 0)
+}
+
+// unblock frees the tile a node is on once no other nodes are on it.
+func (w *World) unblock(node *resource.Node) {
+	if w.navigator == nil {
+		return
+	}
+	tile := w.navigator.TileAt(ground(node.Position()))
+	blocked, exists := w.blocked[tile]
+	if !exists {
+		return
+	}
+	blocked.nodes--
+	if blocked.nodes == 0 {
+		w.navigator.SetCost(tile, blocked.cost)
+		delete(w.blocked, tile)
+	}
+}
+
+// Update advances the time of the world, regrowing nodes and letting claims that have not been renewed
+// expire.
+func (w *World) Update(dt float32) {
+	for _, node := range w.Nodes {
+		node.Update(dt)
+	}
+	w.Reservations.Update(dt)
+}
+
+// Removed returns the nodes removed from the world, in the order they were removed. Removing them in the same
+// order from a world with the same nodes gives the same world.
+func (w *World) Removed() []*resource.Node {
+	return w.removed
+}
+
+// NodeExists reports if the node is in the world and can be harvested.
+func (w *World) NodeExists(node *resource.Node) bool {
+	return w.HasNode(node) && !node.Depleted()
+}
+
+// HasNode reports if the node is in the world, depleted or not.
+func (w *World) HasNode(node *resource.Node) bool {
+	_, exists := w.slots[node]
+	return exists
+}
+
+// NearestNode returns the closest node of a kind that can be harvested and is not claimed by someone other
+// than claimant. It returns false if there is no such node.
+func (w *World) NearestNode(kind resource.Kind, from mgl32.Vec3, claimant reservation.Claimant[*resource.Node]) (*resource.Node, bool) {
+	return w.Reservations.NearestAvailable(w.nodeIndex, spatial.XZ(from), claimant, func(node *resource.Node) bool {
+		return node.Kind == kind && !node.Depleted()
+	})
+}
+
+// Harvest takes up to amount from a node and returns how much was taken. Depleted nodes are removed from the
+// world or left to regrow depending on their type, either way the worker that had claimed it loses the claim.
+func (w *World) Harvest(node *resource.Node, amount int) int {
+	if !w.NodeExists(node) {
+		return 0
+	}
+	amount = node.Harvest(amount)
+	if node.Depleted() {
+		fmt.Printf("Depleted %s node\n", node.Kind)
+		if node.Depletion == resource.Removed {
+			w.RemoveNode(node)
+		} else {
+			w.Reservations.Remove(node)
+			if w.Rand != nil {
+				node.DelayRegrowth(w.Rand.Range(0, regrowthSpread))
+			}
+		}
+	}
+	return amount
+}
+
+// RemoveNode removes a node from the world, notifying the worker that had claimed it.
+func (w *World) RemoveNode(node *resource.Node) {
+	i, exists := w.slots[node]
+	if !exists {
+		return
+	}
+	last := len(w.Nodes) - 1
+	if i != last {
+		w.Nodes[i] = w.Nodes[last]
+		w.slots[w.Nodes[i]] = i
+	}
+	w.Nodes[last] = nil
+	w.Nodes = w.Nodes[:last]
+	delete(w.slots, node)
+	w.removed = append(w.removed, node)
+	w.nodeIndex.Remove(node)
+	w.unblock(node)
+	if w.mesh != nil {
+		w.mesh.RemoveObstacle(obstacle(node))
+	}
+	w.Reservations.Remove(node)
+}